@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+/*
+Config selects and configures the auth provider of a test.
+
+Structure Overview
+  - Type - basic, bearer, oauth2, hmac or sigv4; no authentication when empty
+  - Username / Password - the Basic credentials, or the resource owner of the OAuth2 password grant
+  - Token - the Bearer token
+  - TokenURL, ClientID, ClientSecret, Scopes, RefreshBefore - the OAuth2 settings (see OAuth2)
+  - Shared - one OAuth2 token for every virtual user instead of one per user
+  - KeyID / Secret - the HMAC key, or the SigV4 access and secret keys
+  - SessionToken, Region, Service - the SigV4 settings (see SigV4)
+*/
+type Config struct {
+	Type          string        `json:"type"`
+	Username      string        `json:"username"`
+	Password      string        `json:"password"`
+	Token         string        `json:"token"`
+	TokenURL      string        `json:"token_url"`
+	ClientID      string        `json:"client_id"`
+	ClientSecret  string        `json:"client_secret"`
+	Scopes        []string      `json:"scopes"`
+	RefreshBefore time.Duration `json:"refresh_before"`
+	Shared        bool          `json:"shared"`
+	KeyID         string        `json:"key_id"`
+	Secret        string        `json:"secret"`
+	SessionToken  string        `json:"session_token"`
+	Region        string        `json:"region"`
+	Service       string        `json:"service"`
+}
+
+// Validate checks that the settings the type needs are set
+func (c *Config) Validate() error {
+	var missing string
+	switch c.Type {
+	case "":
+		return nil
+	case TypeBasic:
+		if c.Username == "" {
+			missing = "a username"
+		}
+	case TypeBearer:
+		if c.Token == "" {
+			missing = "a token"
+		}
+	case TypeOAuth2:
+		if u, err := url.Parse(c.TokenURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("oauth2 auth needs an absolute http or https token url, got %q", c.TokenURL)
+		}
+		if c.ClientID == "" {
+			missing = "a client id"
+		}
+	case TypeHMAC:
+		if c.KeyID == "" || c.Secret == "" {
+			missing = "a key id and a secret"
+		}
+	case TypeSigV4:
+		if c.KeyID == "" || c.Secret == "" || c.Region == "" || c.Service == "" {
+			missing = "an access key, a secret key, a region and a service"
+		}
+	default:
+		return fmt.Errorf("unsupported auth %q (expected %s, %s, %s, %s or %s)",
+			c.Type, TypeBasic, TypeBearer, TypeOAuth2, TypeHMAC, TypeSigV4)
+	}
+	if missing != "" {
+		return fmt.Errorf("%s auth needs %s", c.Type, missing)
+	}
+	return nil
+}
+
+/*
+Providers returns the function giving each virtual user its auth provider, or nil without
+authentication.  OAuth2 token requests are sent with client.  Every user gets its own OAuth2
+provider, and so its own tokens, unless Shared is set; the other providers keep no state
+and are always shared.
+*/
+func (c *Config) Providers(client *http.Client) (func() interfaces.AuthProvider, error) {
+	if err := c.Validate(); err != nil || c.Type == "" {
+		return nil, err
+	}
+	if c.Type == TypeOAuth2 && !c.Shared {
+		return func() interfaces.AuthProvider { return c.provider(client) }, nil
+	}
+	provider := c.provider(client)
+	return func() interfaces.AuthProvider { return provider }, nil
+}
+
+// provider returns a new provider of the type
+func (c *Config) provider(client *http.Client) interfaces.AuthProvider {
+	switch c.Type {
+	case TypeBasic:
+		return &Basic{Username: c.Username, Password: c.Password}
+	case TypeBearer:
+		return &Bearer{Token: c.Token}
+	case TypeOAuth2:
+		return &OAuth2{
+			TokenURL:      c.TokenURL,
+			ClientID:      c.ClientID,
+			ClientSecret:  c.ClientSecret,
+			Username:      c.Username,
+			Password:      c.Password,
+			Scopes:        c.Scopes,
+			RefreshBefore: c.RefreshBefore,
+			Client:        client,
+		}
+	case TypeHMAC:
+		return &HMAC{KeyID: c.KeyID, Secret: c.Secret}
+	default:
+		return &SigV4{AccessKey: c.KeyID, SecretKey: c.Secret, SessionToken: c.SessionToken,
+			Region: c.Region, Service: c.Service}
+	}
+}
@@ -0,0 +1,68 @@
+// Package auth authenticates the requests of a load test against protected APIs: static
+// Basic and Bearer credentials, OAuth2 access tokens fetched with the client credentials or
+// password grant and renewed before they expire, and request signatures (HMAC or AWS
+// Signature Version 4) computed for every request.  Every provider implements
+// interfaces.AuthProvider.
+package auth
+
+import "time"
+
+const (
+	// TypeBasic selects HTTP Basic authentication
+	TypeBasic = "basic"
+	// TypeBearer selects a static Bearer token
+	TypeBearer = "bearer"
+	// TypeOAuth2 selects OAuth2 access tokens from a token endpoint
+	TypeOAuth2 = "oauth2"
+	// TypeHMAC selects HMAC-SHA256 request signatures
+	TypeHMAC = "hmac"
+	// TypeSigV4 selects AWS Signature Version 4 request signatures
+	TypeSigV4 = "sigv4"
+
+	// AuthorizationHeader is the header carrying the credentials of a request
+	AuthorizationHeader = "Authorization"
+	// ContentTypeHeader is the Content-Type header name
+	ContentTypeHeader = "Content-Type"
+	// DateHeader carries the time an HMAC signature was made
+	DateHeader = "Date"
+	// ContentDigestHeader carries the hex SHA-256 digest of the body an HMAC signature covers
+	ContentDigestHeader = "X-Content-SHA256"
+	// BearerPrefix starts the Authorization header of a Bearer token
+	BearerPrefix = "Bearer "
+	// HMACScheme starts the Authorization header of an HMAC signature
+	HMACScheme = "HMAC-SHA256"
+
+	// GrantClientCredentials is the OAuth2 grant of a client acting on its own behalf
+	GrantClientCredentials = "client_credentials"
+	// GrantPassword is the OAuth2 grant of a client acting with a user's password
+	GrantPassword = "password"
+	// GrantRefreshToken is the OAuth2 grant renewing an access token with a refresh token
+	GrantRefreshToken = "refresh_token"
+	// FormContentType is the content type of token requests
+	FormContentType = "application/x-www-form-urlencoded"
+	// DefaultRefreshBefore is how long before it expires an OAuth2 access token is renewed
+	DefaultRefreshBefore = 30 * time.Second
+	// MaxTokenResponseSize caps the token responses read, in bytes
+	MaxTokenResponseSize = 1 << 20
+
+	// SigV4Algorithm names the AWS Signature Version 4 algorithm
+	SigV4Algorithm = "AWS4-HMAC-SHA256"
+	// SigV4Terminator ends the credential scope of a signature
+	SigV4Terminator = "aws4_request"
+	// SigV4KeyPrefix prefixes the secret key when deriving the signing key
+	SigV4KeyPrefix = "AWS4"
+	// SigV4DateLayout formats the X-Amz-Date header
+	SigV4DateLayout = "20060102T150405Z"
+	// SigV4DayLayout formats the date of the credential scope
+	SigV4DayLayout = "20060102"
+	// AmzDateHeader carries the time of a SigV4 signature
+	AmzDateHeader = "X-Amz-Date"
+	// AmzSecurityTokenHeader carries the session token of temporary credentials
+	AmzSecurityTokenHeader = "X-Amz-Security-Token"
+	// AmzContentSHA256Header carries the payload hash S3 requires
+	AmzContentSHA256Header = "X-Amz-Content-Sha256"
+	// AmzHeaderPrefix starts the lower case names of the x-amz-* headers, which are all signed
+	AmzHeaderPrefix = "x-amz-"
+	// ServiceS3 is the S3 service, whose paths are not encoded twice
+	ServiceS3 = "s3"
+)
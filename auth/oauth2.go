@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+OAuth2 authenticates requests with access tokens from a token endpoint.  The first request
+fetches a token and the next ones reuse it until it is about to expire; it is then renewed
+with its refresh token, if the endpoint issued one, or else fetched again.  Concurrent
+requests wait for a single token request, so a provider shared by every virtual user
+fetches one token for all of them.
+
+Structure Overview
+  - TokenURL - the token endpoint
+  - ClientID / ClientSecret - the client credentials, sent with HTTP Basic authentication
+  - Username / Password - the resource owner of the password grant; the client
+    credentials grant is used without a Username
+  - Scopes - the scopes requested, none when empty
+  - RefreshBefore - how long before they expire tokens are renewed, DefaultRefreshBefore when 0
+  - Client - the client of the token requests, http.DefaultClient when nil
+*/
+type OAuth2 struct {
+	TokenURL      string
+	ClientID      string
+	ClientSecret  string
+	Username      string
+	Password      string
+	Scopes        []string
+	RefreshBefore time.Duration
+	Client        *http.Client
+
+	mu       sync.Mutex
+	token    string
+	refresh  string
+	expires  time.Time // zero for tokens without a lifetime
+	fetches  int
+	renewing *renewal // the renewal in progress, if any
+}
+
+// renewal is a token renewal that concurrent requests wait for
+type renewal struct {
+	done  chan struct{} // closed once token and err are set
+	token string
+	err   error
+}
+
+// tokenResponse is the successful response of a token endpoint
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Authenticate implements interfaces.AuthProvider; a token request is sent with the context of req
+func (o *OAuth2) Authenticate(req *http.Request) error {
+	token, err := o.current(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set(AuthorizationHeader, BearerPrefix+token)
+	return nil
+}
+
+// Fetches returns the number of token requests sent so far
+func (o *OAuth2) Fetches() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.fetches
+}
+
+// current returns a token that is not about to expire, renewing it first when needed.  The
+// token requests are sent without holding mu; concurrent callers wait for the one in progress.
+func (o *OAuth2) current(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	if o.token != "" && !o.expiring() {
+		token := o.token
+		o.mu.Unlock()
+		return token, nil
+	}
+	if r := o.renewing; r != nil {
+		o.mu.Unlock()
+		select {
+		case <-r.done:
+			return r.token, r.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	r := &renewal{done: make(chan struct{})}
+	o.renewing = r
+	refresh := o.refresh
+	o.mu.Unlock()
+
+	token, refreshFailed, err := o.renew(ctx, refresh)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if refreshFailed {
+		o.refresh = ""
+	}
+	if err == nil {
+		o.store(token)
+	}
+	r.token, r.err = o.token, err
+	o.renewing = nil
+	close(r.done)
+	return r.token, r.err
+}
+
+// expiring reports whether the token expires within RefreshBefore
+func (o *OAuth2) expiring() bool {
+	refreshBefore := o.RefreshBefore
+	if refreshBefore <= 0 {
+		refreshBefore = DefaultRefreshBefore
+	}
+	return !o.expires.IsZero() && time.Until(o.expires) < refreshBefore
+}
+
+// renew requests a new token, with the refresh token when there is one and it still works
+func (o *OAuth2) renew(ctx context.Context, refresh string) (token *tokenResponse, refreshFailed bool, err error) {
+	if refresh != "" {
+		form := url.Values{"grant_type": {GrantRefreshToken}, "refresh_token": {refresh}}
+		if token, err = o.fetch(ctx, form); err == nil {
+			return token, false, nil
+		}
+		refreshFailed = true
+	}
+	form := url.Values{"grant_type": {GrantClientCredentials}}
+	if o.Username != "" {
+		form = url.Values{"grant_type": {GrantPassword}, "username": {o.Username}, "password": {o.Password}}
+	}
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	token, err = o.fetch(ctx, form)
+	return token, refreshFailed, err
+}
+
+// fetch sends a token request with form and returns the token of the response
+func (o *OAuth2) fetch(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token url %q: %w", o.TokenURL, err)
+	}
+	req.Header.Set(ContentTypeHeader, FormContentType)
+	req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	o.mu.Lock()
+	o.fetches++
+	o.mu.Unlock()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxTokenResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token tokenResponse
+	if err = json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint %s returned no access token", o.TokenURL)
+	}
+	return &token, nil
+}
+
+// store keeps token, and its refresh token if it comes with one; mu must be held
+func (o *OAuth2) store(token *tokenResponse) {
+	o.token = token.AccessToken
+	if token.RefreshToken != "" {
+		o.refresh = token.RefreshToken
+	}
+	o.expires = time.Time{}
+	if token.ExpiresIn > 0 {
+		o.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/request"
+)
+
+// tokenServer stands in for an OAuth2 token endpoint: it checks the client credentials,
+// issues numbered tokens living lifetime seconds and records the grants it served
+type tokenServer struct {
+	*httptest.Server
+	lifetime int64
+	mu       sync.Mutex
+	grants   []string
+	issued   atomic.Int64
+}
+
+func newTokenServer(t *testing.T, lifetime int64) *tokenServer {
+	t.Helper()
+	s := &tokenServer{lifetime: lifetime}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "load-test" || secret != "s3cret" || r.Header.Get(ContentTypeHeader) != FormContentType {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		grant := r.PostFormValue("grant_type")
+		switch {
+		case grant == GrantPassword && (r.PostFormValue("username") != "alice" || r.PostFormValue("password") != "pw"),
+			grant == GrantRefreshToken && r.PostFormValue("refresh_token") != "refresh":
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.grants = append(s.grants, grant+" "+r.PostFormValue("scope"))
+		s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  fmt.Sprintf("token-%d", s.issued.Add(1)),
+			"token_type":    "bearer",
+			"expires_in":    s.lifetime,
+			"refresh_token": "refresh",
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// api is a protected endpoint answering 401 without a bearer token of the token server
+func api(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	seen := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get(AuthorizationHeader))
+		mu.Unlock()
+		if len(r.Header.Get(AuthorizationHeader)) <= len(BearerPrefix) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &seen
+}
+
+func TestOAuth2(t *testing.T) {
+	color.Green("~~ TestOAuth2 ~~")
+	server, seen := api(t)
+
+	tests := []struct {
+		name     string
+		lifetime int64
+		oauth    *OAuth2
+		grants   string
+		tokens   string
+	}{
+		{"client credentials cached", 3600, &OAuth2{Scopes: []string{"read", "write"}},
+			"[client_credentials read write]", "[Bearer token-1 Bearer token-1 Bearer token-1]"},
+		{"password grant", 3600, &OAuth2{Username: "alice", Password: "pw"},
+			"[password ]", "[Bearer token-1 Bearer token-1 Bearer token-1]"},
+		// Tokens expiring within RefreshBefore are renewed before every request
+		{"refreshed before expiry", 20, &OAuth2{},
+			"[client_credentials  refresh_token  refresh_token ]", "[Bearer token-1 Bearer token-2 Bearer token-3]"},
+		{"without a lifetime", 0, &OAuth2{RefreshBefore: time.Hour},
+			"[client_credentials ]", "[Bearer token-1 Bearer token-1 Bearer token-1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := newTokenServer(t, tt.lifetime)
+			*seen = []string{}
+			oauth := tt.oauth
+			oauth.TokenURL, oauth.ClientID, oauth.ClientSecret = tokens.URL, "load-test", "s3cret"
+			for range 3 {
+				resp := request.Fetch(request.FetchInput{BaseURL: server.URL, Auth: oauth})
+				if resp.Status != http.StatusOK {
+					t.Fatalf("status %d: %s", resp.Status, resp.URL)
+				}
+			}
+			if grants := fmt.Sprint(tokens.grants); grants != tt.grants || fmt.Sprint(*seen) != tt.tokens {
+				t.Errorf("grants %s, tokens %v", grants, *seen)
+			}
+			if oauth.Fetches() != len(tokens.grants) {
+				t.Errorf("%d fetches for %d grants", oauth.Fetches(), len(tokens.grants))
+			}
+		})
+	}
+}
+
+func TestOAuth2Failures(t *testing.T) {
+	server, _ := api(t)
+	tokens := newTokenServer(t, 3600)
+	for name, oauth := range map[string]*OAuth2{
+		"wrong client secret": {TokenURL: tokens.URL, ClientID: "load-test", ClientSecret: "guess"},
+		"wrong password": {TokenURL: tokens.URL, ClientID: "load-test", ClientSecret: "s3cret",
+			Username: "alice", Password: "guess"},
+		"no token endpoint": {TokenURL: server.URL + "/missing", ClientID: "load-test"},
+	} {
+		resp := request.Fetch(request.FetchInput{BaseURL: server.URL, Auth: oauth})
+		if resp.Status != request.HTTPStatusConnectionError || resp.Error != request.ErrorAuth {
+			t.Errorf("%s: status %d, error %q", name, resp.Status, resp.Error)
+		}
+	}
+}
+
+func TestOAuth2Context(t *testing.T) {
+	// The token endpoint answers once release is closed
+	release := make(chan struct{})
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm() // the server notices a cancelled request once the body is read
+		select {
+		case <-release:
+			_, _ = w.Write([]byte(`{"access_token":"slow","expires_in":3600}`))
+		case <-r.Context().Done():
+		}
+	}))
+	defer tokens.Close()
+	oauth := &OAuth2{TokenURL: tokens.URL, ClientID: "load-test"}
+
+	// The token request is cancelled with the request it authenticates
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://api.test/", http.NoBody)
+	if err := oauth.Authenticate(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Authenticate = %v, want a deadline error", err)
+	}
+
+	// Requests waiting for a token request do not block the provider, and get its token
+	results := make(chan string, 3)
+	for range cap(results) {
+		go func() {
+			req, _ := http.NewRequest(http.MethodGet, "http://api.test/", http.NoBody)
+			if err := oauth.Authenticate(req); err != nil {
+				t.Error(err)
+			}
+			results <- req.Header.Get(AuthorizationHeader)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	if fetches := oauth.Fetches(); fetches != 2 {
+		t.Errorf("%d token requests while one is pending, want 2", fetches)
+	}
+	close(release)
+	for range cap(results) {
+		if token := <-results; token != BearerPrefix+"slow" {
+			t.Errorf("Authorization %q", token)
+		}
+	}
+}
+
+func TestOAuth2Phases(t *testing.T) {
+	server, _ := api(t)
+	tokens := newTokenServer(t, 3600)
+	oauth := &OAuth2{TokenURL: tokens.URL, ClientID: "load-test", ClientSecret: "s3cret"}
+	client := &http.Client{Transport: &http.Transport{}}
+	defer client.CloseIdleConnections()
+
+	// The token request of a page on a reused connection is not one of its phases
+	request.Fetch(request.FetchInput{BaseURL: server.URL, Client: client})
+	resp := request.Fetch(request.FetchInput{BaseURL: server.URL, Client: client, Auth: oauth})
+	if resp.Status != http.StatusOK || oauth.Fetches() != 1 {
+		t.Fatalf("status %d after %d token requests", resp.Status, oauth.Fetches())
+	}
+	if !resp.ConnReused {
+		t.Fatal("the page connection was not reused")
+	}
+	if timings := resp.Timings; timings.DNS != 0 || timings.Connect != 0 || timings.TLS != 0 {
+		t.Errorf("timings %+v on a reused connection", timings)
+	}
+}
+
+func TestProviders(t *testing.T) {
+	tokens := newTokenServer(t, 3600)
+	server, _ := api(t)
+	config := Config{Type: TypeOAuth2, TokenURL: tokens.URL, ClientID: "load-test", ClientSecret: "s3cret"}
+
+	// Virtual users fetch their own tokens unless they share one
+	for shared, want := range map[bool]int64{false: 4, true: 1} {
+		tokens.issued.Store(0)
+		config.Shared = shared
+		providers, err := config.Providers(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func(provider interfaces.AuthProvider) {
+				defer wg.Done()
+				for range 3 {
+					request.Fetch(request.FetchInput{BaseURL: server.URL, Auth: provider})
+				}
+			}(providers())
+		}
+		wg.Wait()
+		if tokens.issued.Load() != want {
+			t.Errorf("shared %v: %d tokens issued, want %d", shared, tokens.issued.Load(), want)
+		}
+	}
+
+	if providers, err := (&Config{}).Providers(nil); providers != nil || err != nil {
+		t.Errorf("no auth: %v", err)
+	}
+	for name, invalid := range map[string]Config{
+		"unknown type":       {Type: "digest"},
+		"basic username":     {Type: TypeBasic},
+		"bearer token":       {Type: TypeBearer},
+		"oauth2 token url":   {Type: TypeOAuth2, TokenURL: "/token", ClientID: "load-test"},
+		"oauth2 client id":   {Type: TypeOAuth2, TokenURL: tokens.URL},
+		"hmac secret":        {Type: TypeHMAC, KeyID: "key"},
+		"sigv4 region":       {Type: TypeSigV4, KeyID: "AKID", Secret: "secret", Service: "execute-api"},
+		"sigv4 service only": {Type: TypeSigV4, Service: "s3"},
+	} {
+		if _, err := invalid.Providers(nil); err == nil {
+			t.Errorf("%s should be required", name)
+		}
+	}
+}
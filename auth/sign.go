@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+HMAC signs every request with HMAC-SHA256.  The signature covers, one per line, the method,
+the path and query, the host, the Date header and the hex SHA-256 digest of the body, which
+is also sent in the X-Content-SHA256 header:
+
+	Authorization: HMAC-SHA256 KeyId=<KeyID>, Signature=<base64 signature>
+*/
+type HMAC struct {
+	KeyID  string
+	Secret string
+
+	now func() time.Time // time.Now when nil
+}
+
+// Authenticate implements interfaces.AuthProvider
+func (h *HMAC) Authenticate(req *http.Request) error {
+	digest, err := bodyDigest(req)
+	if err != nil {
+		return err
+	}
+	date := clock(h.now).UTC().Format(http.TimeFormat)
+	req.Header.Set(DateHeader, date)
+	req.Header.Set(ContentDigestHeader, digest)
+
+	payload := strings.Join([]string{req.Method, req.URL.RequestURI(), host(req), date, digest}, "\n")
+	signature := base64.StdEncoding.EncodeToString(hmacSHA256([]byte(h.Secret), payload))
+	req.Header.Set(AuthorizationHeader, fmt.Sprintf("%s KeyId=%s, Signature=%s", HMACScheme, h.KeyID, signature))
+	return nil
+}
+
+/*
+SigV4 signs every request with AWS Signature Version 4, for AWS services and the APIs
+that verify the same signatures.  The host, the Content-Type and the x-amz-* headers are
+signed; S3 requests also send the hash of their body in X-Amz-Content-Sha256.
+
+Structure Overview
+  - AccessKey / SecretKey - the credentials
+  - SessionToken - the session token of temporary credentials, none when empty
+  - Region / Service - the region and service of the credential scope, e.g. us-east-1 and execute-api
+*/
+type SigV4 struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	Service      string
+
+	now func() time.Time // time.Now when nil
+}
+
+// Authenticate implements interfaces.AuthProvider
+func (s *SigV4) Authenticate(req *http.Request) error {
+	payloadHash, err := bodyDigest(req)
+	if err != nil {
+		return err
+	}
+	t := clock(s.now).UTC()
+	req.Header.Set(AmzDateHeader, t.Format(SigV4DateLayout))
+	if s.SessionToken != "" {
+		req.Header.Set(AmzSecurityTokenHeader, s.SessionToken)
+	}
+	if s.Service == ServiceS3 {
+		req.Header.Set(AmzContentSHA256Header, payloadHash)
+	}
+
+	signedHeaders, canonicalHeaders := s.canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method, s.canonicalURI(req.URL), canonicalQuery(req.URL), canonicalHeaders, signedHeaders, payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{t.Format(SigV4DayLayout), s.Region, s.Service, SigV4Terminator}, "/")
+	stringToSign := strings.Join([]string{
+		SigV4Algorithm, t.Format(SigV4DateLayout), scope, hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte(SigV4KeyPrefix + s.SecretKey)
+	for _, part := range []string{t.Format(SigV4DayLayout), s.Region, s.Service, SigV4Terminator} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set(AuthorizationHeader, fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		SigV4Algorithm, s.AccessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+	return nil
+}
+
+// canonicalURI returns the escaped path of u; every service but S3 escapes it a second time
+func (s *SigV4) canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	if s.Service == ServiceS3 {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i := range segments {
+		segments[i] = awsEscape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalHeaders returns the signed header names and the canonical header lines of req
+func (s *SigV4) canonicalHeaders(req *http.Request) (signed, canonical string) {
+	headers := map[string]string{"host": host(req)}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, AmzHeaderPrefix) {
+			trimmed := make([]string, len(values))
+			for i := range values {
+				trimmed[i] = strings.Join(strings.Fields(values[i]), " ")
+			}
+			headers[name] = strings.Join(trimmed, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines strings.Builder
+	for _, name := range names {
+		lines.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), lines.String()
+}
+
+// canonicalQuery returns the query parameters of u escaped and sorted by name, then value
+func canonicalQuery(u *url.URL) string {
+	var params []string
+	for name, values := range u.Query() {
+		for _, value := range values {
+			params = append(params, awsEscape(name)+"="+awsEscape(value))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape percent-encodes every byte of s but the unreserved characters of RFC 3986
+func awsEscape(s string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			escaped.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&escaped, "%%%02X", c)
+	}
+	return escaped.String()
+}
+
+// bodyDigest returns the hex SHA-256 digest of the body of req, read from a copy of it
+func bodyDigest(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return hexSHA256(nil), nil
+	}
+	if req.GetBody == nil {
+		return "", fmt.Errorf("cannot sign the body of %s %s", req.Method, req.URL)
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return hexSHA256(data), nil
+}
+
+// host returns the Host header of req
+func host(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// clock returns the time of now, or the current time when it is nil
+func clock(now func() time.Time) time.Time {
+	if now == nil {
+		return time.Now()
+	}
+	return now()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// hexSHA256 returns the hex SHA-256 digest of data
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/request"
+)
+
+// signedAt returns a clock stopped at the time of the AWS signature test suite
+func signedAt() time.Time {
+	return time.Date(2015, time.August, 30, 12, 36, 0, 0, time.UTC)
+}
+
+func TestSigV4(t *testing.T) {
+	color.Green("~~ TestSigV4 ~~")
+	// Requests of the AWS Signature Version 4 test suite
+	signer := &SigV4{AccessKey: "AKIDEXAMPLE", SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region: "us-east-1", Service: "service", now: signedAt}
+	scope := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/",
+			"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			"SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, http.NoBody)
+		if err := signer.Authenticate(req); err != nil {
+			t.Fatal(err)
+		}
+		if got := req.Header.Get(AuthorizationHeader); got != scope+tt.want {
+			t.Errorf("%s: Authorization %s", tt.name, got)
+		}
+		if req.Header.Get(AmzDateHeader) != "20150830T123600Z" {
+			t.Errorf("%s: X-Amz-Date %s", tt.name, req.Header.Get(AmzDateHeader))
+		}
+	}
+
+	// Temporary credentials and S3 requests sign their extra headers
+	s3 := &SigV4{AccessKey: "AKIDEXAMPLE", SecretKey: "secret", SessionToken: "session", Region: "eu-west-1",
+		Service: ServiceS3, now: signedAt}
+	req, _ := http.NewRequest(http.MethodPut, "https://bucket.s3.amazonaws.com/a%20b", strings.NewReader("data"))
+	req.Header.Set(ContentTypeHeader, "text/plain")
+	if err := s3.Authenticate(req); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(req.Header.Get(AuthorizationHeader),
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") ||
+		req.Header.Get(AmzContentSHA256Header) != hexSHA256([]byte("data")) ||
+		req.Header.Get(AmzSecurityTokenHeader) != "session" {
+		t.Errorf("S3 headers %v", req.Header)
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	color.Green("~~ TestHMAC ~~")
+	// The API recomputes the signature of every request from its own view of it
+	var verified, rejected int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		payload := strings.Join([]string{r.Method, r.RequestURI, r.Host, r.Header.Get(DateHeader),
+			hexSHA256(body)}, "\n")
+		mac := hmac.New(sha256.New, []byte("shared-secret"))
+		mac.Write([]byte(payload))
+		want := HMACScheme + " KeyId=client-1, Signature=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		if r.Header.Get(AuthorizationHeader) != want || r.Header.Get(ContentDigestHeader) != hexSHA256(body) {
+			rejected++
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		verified++
+	}))
+	defer server.Close()
+
+	signer := &HMAC{KeyID: "client-1", Secret: "shared-secret"}
+	for _, input := range []request.FetchInput{
+		{BaseURL: server.URL + "/orders?page=2", Auth: signer},
+		{BaseURL: server.URL + "/orders", Method: http.MethodPost, Body: `{"sku":42}`, Auth: signer},
+		{BaseURL: server.URL + "/orders", Auth: &HMAC{KeyID: "client-1", Secret: "guess"}},
+	} {
+		request.Fetch(input)
+	}
+	if verified != 2 || rejected != 1 {
+		t.Errorf("%d signatures verified, %d rejected", verified, rejected)
+	}
+
+	// Static credentials
+	req := httptest.NewRequest(http.MethodGet, "https://api.test/", http.NoBody)
+	_ = (&Basic{Username: "alice", Password: "pw"}).Authenticate(req)
+	if user, password, ok := req.BasicAuth(); !ok || user != "alice" || password != "pw" {
+		t.Errorf("basic credentials %q %q", user, password)
+	}
+	_ = (&Bearer{Token: "api-token"}).Authenticate(req)
+	if req.Header.Get(AuthorizationHeader) != "Bearer api-token" {
+		t.Errorf("bearer header %q", req.Header.Get(AuthorizationHeader))
+	}
+}
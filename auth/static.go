@@ -0,0 +1,26 @@
+package auth
+
+import "net/http"
+
+// Basic authenticates requests with a username and password
+type Basic struct {
+	Username string
+	Password string
+}
+
+// Authenticate implements interfaces.AuthProvider
+func (b *Basic) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(b.Username, b.Password)
+	return nil
+}
+
+// Bearer authenticates requests with a static token, such as a long-lived API token
+type Bearer struct {
+	Token string
+}
+
+// Authenticate implements interfaces.AuthProvider
+func (b *Bearer) Authenticate(req *http.Request) error {
+	req.Header.Set(AuthorizationHeader, BearerPrefix+b.Token)
+	return nil
+}
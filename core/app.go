@@ -5,14 +5,26 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/Gosayram/goperf/crawl"
+	"github.com/Gosayram/goperf/grpc"
+	"github.com/Gosayram/goperf/importer"
 	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
+	"github.com/Gosayram/goperf/perf"
+	"github.com/Gosayram/goperf/replay"
+	"github.com/Gosayram/goperf/request"
+	"github.com/Gosayram/goperf/stream"
+	"github.com/Gosayram/goperf/ws"
 )
 
 // App represents the main application
@@ -57,7 +69,30 @@ func (a *App) Run() error {
 	}
 
 	// Check for special commands
-	// TODO: Add support for -fetch, -fetchall flags
+	if config.Crawl.Enabled {
+		return a.runCrawl()
+	}
+	if config.Replay.Enabled {
+		return a.runReplay()
+	}
+	if config.Import.Enabled {
+		return a.runImport()
+	}
+	if config.WebSocket.Enabled {
+		return a.runWebSocket()
+	}
+	if config.GRPC.Enabled {
+		return a.runGRPC()
+	}
+	if config.Stream.Enabled {
+		return a.runStream()
+	}
+	if config.Test.FetchAll {
+		return a.runFetchAll()
+	}
+	if config.Test.Fetch {
+		return a.runFetch()
+	}
 
 	// Default to load testing mode
 	return a.runLoadTest()
@@ -76,58 +111,495 @@ func (a *App) runWebServer() error {
 	return nil
 }
 
-// runLoadTest performs a load test
-func (a *App) runLoadTest() error {
+// fetchInput builds the request input shared by the single fetch modes
+func (a *App) fetchInput() (request.FetchInput, error) {
+	config := a.container.Config()
+	filter, err := config.Filter.AssetFilter()
+	if err != nil {
+		return request.FetchInput{}, err
+	}
+	transport, err := config.HTTP.TransportConfig()
+	if err != nil {
+		return request.FetchInput{}, err
+	}
+	client := request.NewClient(transport)
+	providers, err := config.Auth.Providers(client)
+	if err != nil {
+		return request.FetchInput{}, err
+	}
+	input := request.FetchInput{
+		BaseURL:   config.Test.DefaultURL,
+		UserAgent: config.HTTP.UserAgent,
+		Client:    client,
+		Browser:   config.Browser.Options(),
+		Parser:    a.container.AssetParser(),
+		CSSDepth:  config.Browser.CSSDepth,
+		Filter:    filter,
+	}
+	if providers != nil {
+		input.Auth = providers()
+	}
+	return input, nil
+}
+
+// runFetch fetches the target url once and prints the response
+func (a *App) runFetch() error {
 	config := a.container.Config()
+	input, err := a.fetchInput()
+	if err != nil {
+		return err
+	}
+	input.Retdat = true
+	defer input.Client.CloseIdleConnections()
 
-	// Create test configuration
-	testConfig := &interfaces.TestConfig{
-		Target: &interfaces.Request{
-			URL:           config.Test.DefaultURL,
-			Method:        "GET",
-			Headers:       make(map[string]string),
-			UserAgent:     config.HTTP.UserAgent,
-			Timeout:       config.HTTP.Timeout,
-			ReturnContent: false,
-		},
-		Users:       config.Test.DefaultUsers,
-		Duration:    config.Test.DefaultDuration,
-		Iterations:  config.Test.Iterations,
-		OutputLevel: config.Log.Level,
+	resp := request.Fetch(input)
+	if config.Output.Format == OutputFormatJSON {
+		return a.printJSON(resp)
 	}
+	request.PrintFetchResponse(resp)
+	return nil
+}
 
-	// Get services from container
-	metrics := a.container.MetricsCollector()
-	formatter := a.container.OutputFormatter()
+// runFetchAll fetches the target url and all of its assets once and prints the page load
+func (a *App) runFetchAll() error {
+	config := a.container.Config()
+	input, err := a.fetchInput()
+	if err != nil {
+		return err
+	}
+	input.Retdat = config.Output.Format == OutputFormatJSON
+	defer input.Client.CloseIdleConnections()
+
+	resp := request.FetchAll(input)
+	switch config.Output.Format {
+	case OutputFormatJSON:
+		return a.printJSON(struct {
+			*request.FetchAllResponse
+			Waterfall []request.WaterfallEntry `json:"waterfall"`
+		}{resp, resp.Waterfall()})
+	case OutputFormatHTML:
+		return request.WriteWaterfallHTML(os.Stdout, resp)
+	default:
+		request.PrintFetchAllResponse(resp)
+		request.PrintWaterfall(resp)
+		return nil
+	}
+}
 
-	// Start test session
-	session, err := metrics.StartTest(testConfig)
+// runCrawl crawls the target site, prints the pages found and optionally saves them as load test targets
+func (a *App) runCrawl() error {
+	config := a.container.Config()
+	input, err := a.fetchInput()
+	if err != nil {
+		return err
+	}
+	defer input.Client.CloseIdleConnections()
+
+	result, err := crawl.Crawl(a.ctx, crawl.Options{
+		URL:      config.Test.DefaultURL,
+		MaxDepth: config.Crawl.MaxDepth,
+		MaxPages: config.Crawl.MaxPages,
+		Links:    config.Crawl.Links,
+		Sitemap:  config.Crawl.Sitemap,
+		Robots:   config.Crawl.Robots,
+		Workers:  config.Crawl.Workers,
+		Input:    input,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to start test: %w", err)
+		return fmt.Errorf("crawl failed: %w", err)
 	}
 
-	fmt.Printf("Starting load test: %d users for %v\n",
-		testConfig.Users, testConfig.Duration)
+	if config.Crawl.Output != "" {
+		if err = writeTargets(config.Crawl.Output, result); err != nil {
+			return err
+		}
+	}
+
+	if config.Output.Format == OutputFormatJSON {
+		return a.printJSON(result)
+	}
+	crawl.PrintResult(result)
+	return nil
+}
 
-	// TODO: Implement actual load testing logic using container services
-	// This will replace the current perf package logic
+// runReplay replays an access log against the target url and compares the replay with the recording
+func (a *App) runReplay() error {
+	config := a.container.Config()
+	input, err := a.fetchInput()
+	if err != nil {
+		return err
+	}
+	defer input.Client.CloseIdleConnections()
+
+	accessLog, err := replay.LoadAccessLog(config.Replay.Log, replay.LogOptions{
+		Format:  config.Replay.LogFormat,
+		Target:  config.Test.DefaultURL,
+		Methods: config.Replay.Methods,
+	})
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	outcomes := replay.Run(a.ctx, accessLog.Requests, replay.RunOptions{
+		Speed:   config.Replay.Speed,
+		Workers: config.Test.DefaultUsers,
+		Input:   input,
+	})
+	report := replay.NewReport(outcomes, accessLog, time.Since(start))
+
+	if config.Output.Format == OutputFormatJSON {
+		return a.printJSON(report)
+	}
+	replay.PrintReport(report)
+	return nil
+}
 
-	// For now, simulate a test
-	time.Sleep(testConfig.Duration)
+// runWebSocket load tests the WebSocket endpoint of the target url and reports the connections
+// and messages both on their own and as recorded by the metrics collector
+func (a *App) runWebSocket() error {
+	config := a.container.Config()
+	messages, err := config.WebSocket.LoadMessages()
+	if err != nil {
+		return err
+	}
+	variables, err := config.Test.LoadVariables()
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := config.HTTP.TLS.ClientConfig()
+	if err != nil {
+		return err
+	}
 
-	// Finish test and get report
-	report, err := metrics.FinishTest(session)
+	collector := a.container.MetricsCollector()
+	session, err := collector.StartTest(&interfaces.TestConfig{
+		Target:   &interfaces.Request{URL: config.Test.DefaultURL},
+		Users:    config.Test.DefaultUsers,
+		Duration: config.Test.DefaultDuration,
+	})
+	if err != nil {
+		return err
+	}
+	if config.Output.Format != OutputFormatJSON {
+		fmt.Printf("Starting WebSocket test: %d users for %v\n",
+			config.Test.DefaultUsers, config.Test.DefaultDuration)
+	}
+	result, err := ws.Run(a.ctx, ws.Options{
+		URL:       config.Test.DefaultURL,
+		Users:     config.Test.DefaultUsers,
+		Duration:  config.Test.DefaultDuration,
+		Messages:  messages,
+		Rate:      config.WebSocket.Rate,
+		IDField:   config.WebSocket.IDField,
+		Timeout:   config.WebSocket.ReplyTimeout,
+		Origin:    config.WebSocket.Origin,
+		Header:    http.Header{"User-Agent": {config.HTTP.UserAgent}},
+		TLS:       tlsConfig,
+		Variables: variables,
+		Collector: collector,
+		Session:   session,
+	})
+	if err != nil {
+		return err
+	}
+	report, err := collector.FinishTest(session)
 	if err != nil {
-		return fmt.Errorf("failed to finish test: %w", err)
+		return err
+	}
+
+	if config.Output.Format == OutputFormatJSON {
+		return a.printJSON(struct {
+			*ws.Result
+			Metrics *interfaces.TestReport `json:"metrics"`
+		}{result, report})
 	}
+	ws.PrintResult(result)
+	metrics.PrintReport(report)
+	return nil
+}
 
-	// Format and output results
-	output, err := formatter.FormatText(report)
+// runGRPC load tests the gRPC server of the target url and reports the calls by method, both on
+// their own and as recorded by the metrics collector.  Without -call it lists the methods.
+func (a *App) runGRPC() error {
+	config := a.container.Config()
+	calls, err := config.GRPC.LoadCalls()
+	if err != nil {
+		return err
+	}
+	variables, err := config.Test.LoadVariables()
+	if err != nil {
+		return err
+	}
+	header, err := config.GRPC.Header()
+	if err != nil {
+		return err
+	}
+	header.Set("User-Agent", config.HTTP.UserAgent)
+	transport, err := config.HTTP.TransportConfig()
+	if err != nil {
+		return err
+	}
+	schema, err := a.loadGRPCSchema(transport, header)
+	if err != nil {
+		return err
+	}
+	if len(calls) == 0 {
+		if config.Output.Format == OutputFormatJSON {
+			return a.printJSON(schema.MethodNames())
+		}
+		grpc.PrintMethods(schema)
+		return nil
+	}
+
+	collector := a.container.MetricsCollector()
+	session, err := collector.StartTest(&interfaces.TestConfig{
+		Target:   &interfaces.Request{URL: config.Test.DefaultURL},
+		Users:    config.Test.DefaultUsers,
+		Duration: config.Test.DefaultDuration,
+	})
+	if err != nil {
+		return err
+	}
+	if config.Output.Format != OutputFormatJSON {
+		fmt.Printf("Starting gRPC test: %d users for %v\n",
+			config.Test.DefaultUsers, config.Test.DefaultDuration)
+	}
+	result, err := grpc.Run(a.ctx, grpc.Options{
+		URL:             config.Test.DefaultURL,
+		Schema:          schema,
+		Calls:           calls,
+		Users:           config.Test.DefaultUsers,
+		Duration:        config.Test.DefaultDuration,
+		Timeout:         config.HTTP.Timeout,
+		Metadata:        header,
+		Transport:       transport,
+		SharedTransport: config.HTTP.SharedTransport,
+		Variables:       variables,
+		Collector:       collector,
+		Session:         session,
+	})
+	if err != nil {
+		return err
+	}
+	report, err := collector.FinishTest(session)
+	if err != nil {
+		return err
+	}
+
+	if config.Output.Format == OutputFormatJSON {
+		return a.printJSON(struct {
+			*grpc.Result
+			Metrics *interfaces.TestReport `json:"metrics"`
+		}{result, report})
+	}
+	grpc.PrintResult(result)
+	metrics.PrintReport(report)
+	return nil
+}
+
+// loadGRPCSchema loads the services from the -proto files, or from the server reflection
+// service of the target when there are none
+func (a *App) loadGRPCSchema(transport request.TransportConfig, header http.Header) (*grpc.Schema, error) {
+	config := a.container.Config()
+	if len(config.GRPC.ProtoFiles) > 0 {
+		return grpc.LoadProtos(config.GRPC.ProtoFiles, config.GRPC.ImportPaths)
+	}
+	client, err := grpc.NewClient(config.Test.DefaultURL, transport, header)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(a.ctx, config.HTTP.Timeout)
+	defer cancel()
+	return grpc.Reflect(ctx, client)
+}
+
+// runStream holds streams to the target url open and reports their events, both on their own
+// and as recorded by the metrics collector
+func (a *App) runStream() error {
+	config := a.container.Config()
+	header, err := config.Stream.Header()
+	if err != nil {
+		return err
+	}
+	header.Set("User-Agent", config.HTTP.UserAgent)
+	transport, err := config.HTTP.TransportConfig()
+	if err != nil {
+		return err
+	}
+
+	collector := a.container.MetricsCollector()
+	session, err := collector.StartTest(&interfaces.TestConfig{
+		Target:   &interfaces.Request{URL: config.Test.DefaultURL},
+		Users:    config.Test.DefaultUsers,
+		Duration: config.Test.DefaultDuration,
+	})
+	if err != nil {
+		return err
+	}
+	if config.Output.Format != OutputFormatJSON {
+		fmt.Printf("Starting streaming test: %d users for %v\n",
+			config.Test.DefaultUsers, config.Test.DefaultDuration)
+	}
+	result, err := stream.Run(a.ctx, stream.Options{
+		URL:             config.Test.DefaultURL,
+		Users:           config.Test.DefaultUsers,
+		Duration:        config.Test.DefaultDuration,
+		MaxConnection:   config.Stream.MaxConnection,
+		Timeout:         config.HTTP.Timeout,
+		Header:          header,
+		Transport:       transport,
+		SharedTransport: config.HTTP.SharedTransport,
+		Collector:       collector,
+		Session:         session,
+	})
+	if err != nil {
+		return err
+	}
+	report, err := collector.FinishTest(session)
+	if err != nil {
+		return err
+	}
+
+	if config.Output.Format == OutputFormatJSON {
+		return a.printJSON(struct {
+			*stream.Result
+			Metrics *interfaces.TestReport `json:"metrics"`
+		}{result, report})
+	}
+	stream.PrintResult(result)
+	metrics.PrintReport(report)
+	return nil
+}
+
+// runImport converts a curl command or an OpenAPI spec into a request file, or prints the requests
+func (a *App) runImport() error {
+	config := a.container.Config()
+	var (
+		count int
+		write func(w io.Writer) error
+	)
+	if config.Import.Source == importer.SourcePostman {
+		sequence, err := config.Import.Sequence()
+		if err != nil {
+			return fmt.Errorf("import failed: %w", err)
+		}
+		for _, warning := range sequence.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+		}
+		count = len(sequence.Requests)
+		write = func(w io.Writer) error { return importer.WriteSequence(w, sequence) }
+	} else {
+		requests, warnings, err := config.Import.Requests()
+		if err != nil {
+			return fmt.Errorf("import failed: %w", err)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+		}
+		count = len(requests)
+		write = func(w io.Writer) error { return importer.WriteRequests(w, requests) }
+	}
+	if config.Import.Output == "" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(config.Import.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create request file: %w", err)
+	}
+	if err = write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write request file: %w", err)
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d requests to %s\n", count, config.Import.Output)
+	return nil
+}
+
+// writeTargets saves the load test targets found by a crawl to path
+func writeTargets(path string, result *crawl.Result) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create targets file: %w", err)
+	}
+	if err = crawl.WriteTargets(file, result); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write targets file: %w", err)
+	}
+	return file.Close()
+}
+
+// printJSON writes v to stdout using the configured indentation
+func (a *App) printJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", a.container.Config().Output.Indentation)
 	if err != nil {
 		return fmt.Errorf("failed to format output: %w", err)
 	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// runLoadTest performs a load test
+func (a *App) runLoadTest() error {
+	config := a.container.Config()
+	filter, err := config.Filter.AssetFilter()
+	if err != nil {
+		return err
+	}
+	targets, err := config.Test.LoadTargets()
+	if err != nil {
+		return err
+	}
+	replayed, err := config.Replay.Requests()
+	if err != nil {
+		return err
+	}
+	sequence, err := config.Test.LoadSequence()
+	if err != nil {
+		return err
+	}
+	transport, err := config.HTTP.TransportConfig()
+	if err != nil {
+		return err
+	}
+	// Token requests get their own connection pool, apart from the users' requests
+	tokenClient := request.NewClient(transport)
+	defer tokenClient.CloseIdleConnections()
+	providers, err := config.Auth.Providers(tokenClient)
+	if err != nil {
+		return err
+	}
+
+	test := &perf.Init{
+		URL:             config.Test.DefaultURL,
+		Targets:         targets,
+		Sequence:        sequence,
+		Replay:          replayed,
+		Speed:           config.Replay.Speed,
+		Threads:         config.Test.DefaultUsers,
+		Seconds:         int(config.Test.DefaultDuration.Seconds()),
+		Iterations:      config.Test.Iterations,
+		Output:          config.Test.OutputInterval,
+		UserAgent:       config.HTTP.UserAgent,
+		Transport:       transport,
+		SharedTransport: config.HTTP.SharedTransport,
+		Browser:         config.Browser.Options(),
+		Cache:           config.Browser.Cache,
+		Parser:          a.container.AssetParser(),
+		CSSDepth:        config.Browser.CSSDepth,
+		Filter:          filter,
+		Auth:            providers,
+	}
+
+	fmt.Printf("Starting load test: %d users for %v\n",
+		config.Test.DefaultUsers, config.Test.DefaultDuration)
 
-	fmt.Println(output)
+	test.Basic()
+	test.Print()
 
 	return nil
 }
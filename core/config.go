@@ -3,28 +3,352 @@ package core
 import (
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/Gosayram/goperf/auth"
+	"github.com/Gosayram/goperf/grpc"
+	"github.com/Gosayram/goperf/importer"
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/perf"
+	"github.com/Gosayram/goperf/replay"
+	"github.com/Gosayram/goperf/request"
 )
 
 // Config represents the complete application configuration
 // This replaces scattered command-line flags throughout the codebase
 type Config struct {
-	HTTP   HTTPConfig   `json:"http"`
-	Test   TestConfig   `json:"test"`
-	Log    LogConfig    `json:"log"`
-	Web    WebConfig    `json:"web"`
-	Parser ParserConfig `json:"parser"`
-	Output OutputConfig `json:"output"`
+	HTTP      HTTPConfig      `json:"http"`
+	Auth      auth.Config     `json:"auth"`
+	Browser   BrowserConfig   `json:"browser"`
+	Filter    FilterConfig    `json:"filter"`
+	Crawl     CrawlConfig     `json:"crawl"`
+	Replay    ReplayConfig    `json:"replay"`
+	Import    ImportConfig    `json:"import"`
+	WebSocket WebSocketConfig `json:"websocket"`
+	GRPC      GRPCConfig      `json:"grpc"`
+	Stream    StreamConfig    `json:"stream"`
+	Test      TestConfig      `json:"test"`
+	Log       LogConfig       `json:"log"`
+	Web       WebConfig       `json:"web"`
+	Parser    ParserConfig    `json:"parser"`
+	Output    OutputConfig    `json:"output"`
 }
 
 // HTTPConfig contains HTTP client configuration
 type HTTPConfig struct {
-	Timeout        time.Duration `json:"timeout"`
-	MaxConnections int           `json:"max_connections"`
-	RetryAttempts  int           `json:"retry_attempts"`
-	UserAgent      string        `json:"user_agent"`
+	Timeout            time.Duration        `json:"timeout"`
+	MaxConnections     int                  `json:"max_connections"`
+	MaxConnsPerHost    int                  `json:"max_conns_per_host"`
+	IdleConnTimeout    time.Duration        `json:"idle_conn_timeout"`
+	DisableKeepAlives  bool                 `json:"disable_keep_alives"`
+	DisableCompression bool                 `json:"disable_compression"`
+	Protocol           string               `json:"protocol"` // "auto", "http1", "http2", "h2c"
+	SharedTransport    bool                 `json:"shared_transport"`
+	RetryAttempts      int                  `json:"retry_attempts"`
+	UserAgent          string               `json:"user_agent"`
+	TLS                request.TLSOptions   `json:"tls"`
+	Resolve            []string             `json:"resolve"` // "host:port:addr[,addr...]" overrides
+	DNSServer          string               `json:"dns_server"`
+	DNSCache           bool                 `json:"dns_cache"` // per virtual user instead of per connection
+	DNSRoundRobin      bool                 `json:"dns_round_robin"`
+	Proxy              request.ProxyOptions `json:"proxy"` // empty url: HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	Dial               request.DialOptions  `json:"dial"`  // unix socket or tcp target, source addresses
+}
+
+// ResolveOptions converts the DNS settings into request resolve options
+func (h *HTTPConfig) ResolveOptions() (request.ResolveOptions, error) {
+	overrides, err := request.ParseOverrides(h.Resolve)
+	if err != nil {
+		return request.ResolveOptions{}, err
+	}
+	opts := request.ResolveOptions{
+		Overrides:  overrides,
+		Server:     h.DNSServer,
+		Cache:      h.DNSCache,
+		RoundRobin: h.DNSRoundRobin,
+	}
+	return opts, opts.Validate()
+}
+
+// TransportConfig converts the HTTP configuration into request transport settings, loading
+// the TLS certificates
+func (h *HTTPConfig) TransportConfig() (request.TransportConfig, error) {
+	tlsConfig, err := h.TLS.ClientConfig()
+	if err != nil {
+		return request.TransportConfig{}, err
+	}
+	resolve, err := h.ResolveOptions()
+	if err != nil {
+		return request.TransportConfig{}, err
+	}
+	if err = h.Proxy.Validate(); err != nil {
+		return request.TransportConfig{}, err
+	}
+	if err = h.Dial.Validate(); err != nil {
+		return request.TransportConfig{}, err
+	}
+	return request.TransportConfig{
+		MaxIdleConns:       h.MaxConnections,
+		MaxConnsPerHost:    h.MaxConnsPerHost,
+		IdleConnTimeout:    h.IdleConnTimeout,
+		DisableKeepAlives:  h.DisableKeepAlives,
+		DisableCompression: h.DisableCompression,
+		Protocol:           h.Protocol,
+		Timeout:            h.Timeout,
+		TLS:                tlsConfig,
+		Resolve:            resolve,
+		Proxy:              h.Proxy,
+		Dial:               h.Dial,
+	}, nil
+}
+
+// BrowserConfig contains browser emulation settings used when fetching page assets
+// Cache and CSSDepth are independent of Enabled so that they can be measured with or without per-origin limits.
+type BrowserConfig struct {
+	Enabled             bool `json:"enabled"`
+	MaxConnsPerOrigin   int  `json:"max_conns_per_origin"`
+	MaxStreamsPerOrigin int  `json:"max_streams_per_origin"`
+	Cache               bool `json:"cache"`
+	CSSDepth            int  `json:"css_depth"` // levels of @import, font and url() references to follow
+}
+
+// Options converts the browser configuration into request options, or nil when disabled
+func (b *BrowserConfig) Options() *request.BrowserOptions {
+	if !b.Enabled {
+		return nil
+	}
+	return &request.BrowserOptions{
+		MaxConnsPerOrigin:   b.MaxConnsPerOrigin,
+		MaxStreamsPerOrigin: b.MaxStreamsPerOrigin,
+	}
+}
+
+// FilterConfig contains the rules selecting which page assets are fetched (see request.FilterOptions)
+type FilterConfig struct {
+	SameOrigin bool     `json:"same_origin"`
+	AllowHosts []string `json:"allow_hosts"`
+	DenyHosts  []string `json:"deny_hosts"`
+	Include    []string `json:"include"` // URL globs, or regular expressions prefixed with "re:"
+	Exclude    []string `json:"exclude"`
+	Types      []string `json:"types"`
+	SkipTypes  []string `json:"skip_types"`
+}
+
+// AssetFilter builds the request asset filter, or returns nil when no rule is set
+func (f *FilterConfig) AssetFilter() (*request.AssetFilter, error) {
+	if !f.SameOrigin && len(f.AllowHosts)+len(f.DenyHosts)+len(f.Include)+len(f.Exclude)+
+		len(f.Types)+len(f.SkipTypes) == 0 {
+		return nil, nil
+	}
+	return request.NewAssetFilter(request.FilterOptions{
+		SameOrigin: f.SameOrigin,
+		AllowHosts: f.AllowHosts,
+		DenyHosts:  f.DenyHosts,
+		Include:    f.Include,
+		Exclude:    f.Exclude,
+		Types:      f.Types,
+		SkipTypes:  f.SkipTypes,
+	})
+}
+
+// listFlag is a repeatable command line flag collecting values into a string slice.
+// Values given on the command line replace the configured ones; when split is set
+// each value may also hold several comma separated items.
+type listFlag struct {
+	values  *[]string
+	split   bool
+	changed bool
+}
+
+// String implements flag.Value
+func (l *listFlag) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+// Set implements flag.Value
+func (l *listFlag) Set(value string) error {
+	if !l.changed {
+		*l.values = nil
+		l.changed = true
+	}
+	if !l.split {
+		*l.values = append(*l.values, value)
+		return nil
+	}
+	*l.values = append(*l.values, splitList(value)...)
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// CrawlConfig contains the settings of the crawl sub-command
+type CrawlConfig struct {
+	Enabled  bool   `json:"-"` // set by "goperf crawl"
+	MaxDepth int    `json:"max_depth"`
+	MaxPages int    `json:"max_pages"`
+	Links    bool   `json:"links"`
+	Sitemap  bool   `json:"sitemap"`
+	Robots   bool   `json:"robots"`
+	Workers  int    `json:"workers"`
+	Output   string `json:"output"` // targets file; nothing is written when empty
+}
+
+// ReplayConfig contains the settings for replaying recorded traffic: a HAR file in a load test,
+// or an access log with the replay sub-command
+type ReplayConfig struct {
+	Enabled         bool     `json:"-"`     // set by "goperf replay"
+	HAR             string   `json:"har"`   // HAR file replayed by every virtual user instead of -url
+	Speed           float64  `json:"speed"` // 1 = recorded pace, 2 = twice as fast, 0 = as fast as possible
+	StripThirdParty bool     `json:"strip_third_party"`
+	StripCookies    bool     `json:"strip_cookies"`
+	Log             string   `json:"log"`        // access log replayed against -url
+	LogFormat       string   `json:"log_format"` // "auto", "combined", "json"
+	Methods         []string `json:"methods"`    // methods replayed from the access log
+}
+
+// Requests loads the requests to replay, or returns nil when no recording is configured
+func (r *ReplayConfig) Requests() ([]request.ReplayRequest, error) {
+	if r.HAR == "" {
+		return nil, nil
+	}
+	return replay.LoadHAR(r.HAR, replay.HAROptions{
+		StripThirdParty: r.StripThirdParty,
+		StripCookies:    r.StripCookies,
+	})
+}
+
+// ImportConfig contains the settings of the import sub-command: "goperf import curl '<command>'",
+// "goperf import openapi spec.yaml" or "goperf import postman collection.json"
+type ImportConfig struct {
+	Enabled     bool   `json:"-"`           // set by "goperf import"
+	Source      string `json:"-"`           // curl, openapi or postman
+	Input       string `json:"-"`           // the curl command line, the spec or the collection file
+	Output      string `json:"output"`      // request or sequence file; printed when empty
+	Server      string `json:"server"`      // replaces the server of an OpenAPI spec
+	Environment string `json:"environment"` // Postman environment file
+}
+
+// Requests converts the import input into requests, with warnings about what was not imported
+func (i *ImportConfig) Requests() ([]interfaces.Request, []string, error) {
+	switch i.Source {
+	case importer.SourceCurl:
+		req, warnings, err := importer.ParseCurl(i.Input)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []interfaces.Request{*req}, warnings, nil
+	case importer.SourceOpenAPI:
+		requests, err := importer.LoadOpenAPI(i.Input, importer.OpenAPIOptions{Server: i.Server})
+		return requests, nil, err
+	default:
+		return nil, nil, fmt.Errorf("unknown import source %q (use curl or openapi)", i.Source)
+	}
+}
+
+// Sequence converts a Postman collection into a request sequence
+func (i *ImportConfig) Sequence() (*importer.Sequence, error) {
+	return importer.LoadPostman(i.Input, importer.PostmanOptions{Environment: i.Environment})
+}
+
+// WebSocketConfig contains the settings of the ws sub-command, which load tests the WebSocket
+// endpoint of -url with -users connections for -sec seconds
+type WebSocketConfig struct {
+	Enabled      bool          `json:"-"`             // set by "goperf ws"
+	Messages     []string      `json:"messages"`      // script every user sends in order
+	MessagesFile string        `json:"messages_file"` // one message per line, sent after Messages
+	Rate         float64       `json:"rate"`          // messages per second per user; 0 waits for each reply
+	IDField      string        `json:"id_field"`      // JSON field correlating replies; echo when empty
+	ReplyTimeout time.Duration `json:"reply_timeout"` // handshake and reply timeout
+	Origin       string        `json:"origin"`        // Origin header of the handshake
+}
+
+// LoadMessages returns the message script: Messages followed by the non-empty lines of MessagesFile
+func (w *WebSocketConfig) LoadMessages() ([]string, error) {
+	messages := slices.Clone(w.Messages)
+	if w.MessagesFile == "" {
+		return messages, nil
+	}
+	data, err := os.ReadFile(w.MessagesFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			messages = append(messages, line)
+		}
+	}
+	return messages, nil
+}
+
+// GRPCConfig contains the settings of the grpc sub-command, which load tests the gRPC server
+// of -url (http:// for h2c, https:// for TLS) with -users users for -sec seconds
+type GRPCConfig struct {
+	Enabled     bool     `json:"-"`            // set by "goperf grpc"
+	Calls       []string `json:"calls"`        // "package.Service/Method [JSON request]" made in turn
+	ProtoFiles  []string `json:"proto_files"`  // service definitions; server reflection when empty
+	ImportPaths []string `json:"import_paths"` // directories searched for imported .proto files
+	Metadata    []string `json:"metadata"`     // "name=value" sent with every call
+}
+
+// LoadCalls parses the call specs
+func (g *GRPCConfig) LoadCalls() ([]grpc.Call, error) {
+	calls := make([]grpc.Call, 0, len(g.Calls))
+	for _, spec := range g.Calls {
+		call, err := grpc.ParseCall(spec)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
+
+// Header returns the metadata sent with every call
+func (g *GRPCConfig) Header() (http.Header, error) {
+	return parseHeader("metadata", g.Metadata)
+}
+
+// StreamConfig contains the settings of the stream sub-command, which holds -users requests
+// to a Server-Sent Events, chunked or long-poll endpoint open for -sec seconds
+type StreamConfig struct {
+	Enabled       bool          `json:"-"`              // set by "goperf stream"
+	MaxConnection time.Duration `json:"max_connection"` // streams are closed and opened again after this long
+	Headers       []string      `json:"headers"`        // "name=value" request headers
+}
+
+// Header returns the headers of the stream requests
+func (s *StreamConfig) Header() (http.Header, error) {
+	return parseHeader("header", s.Headers)
+}
+
+// parseHeader returns the "name=value" assignments as a header; kind names them in errors
+func parseHeader(kind string, assignments []string) (http.Header, error) {
+	header := http.Header{}
+	for _, assignment := range assignments {
+		name, value, ok := strings.Cut(assignment, perf.TargetHeaderSeparator)
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			return nil, fmt.Errorf("%s %q is not name=value", kind, assignment)
+		}
+		header.Add(name, value)
+	}
+	return header, nil
 }
 
 // TestConfig contains load testing configuration
@@ -32,9 +356,86 @@ type TestConfig struct {
 	DefaultUsers    int           `json:"default_users"`
 	DefaultDuration time.Duration `json:"default_duration"`
 	DefaultURL      string        `json:"default_url"`
+	Targets         []string      `json:"targets"`       // "[weight] [METHOD] url [Name=value ...]" specs
+	TargetsFile     string        `json:"targets_file"`  // JSON or text targets, e.g. from "goperf crawl -crawlout"
+	GraphQLFiles    []string      `json:"graphql_files"` // GraphQL requests posted to DefaultURL as targets
+	SequenceFile    string        `json:"sequence_file"` // requests sent in order, e.g. from "goperf import postman"
+	Variables       []string      `json:"variables"`     // "name=value" overrides of the sequence variables
 	OutputFile      string        `json:"output_file"`
 	Iterations      int           `json:"iterations"`
 	OutputInterval  int           `json:"output_interval"`
+	Fetch           bool          `json:"fetch"`
+	FetchAll        bool          `json:"fetch_all"`
+}
+
+// LoadVariables returns the variables set by "name=value" assignments, used by the messages
+// of the ws sub-command and the requests of the grpc one
+func (t *TestConfig) LoadVariables() (perf.Variables, error) {
+	variables := perf.Variables{}
+	for _, assignment := range t.Variables {
+		if err := variables.Set(assignment); err != nil {
+			return nil, err
+		}
+	}
+	return variables, nil
+}
+
+// LoadSequence returns the request sequence of the load test with its variables overridden,
+// or nil when no sequence file is set
+func (t *TestConfig) LoadSequence() (*perf.Sequence, error) {
+	if t.SequenceFile == "" {
+		if len(t.Variables) > 0 {
+			return nil, fmt.Errorf("variables (-var) are only used by a sequence (-sequence)")
+		}
+		return nil, nil
+	}
+	sequence, err := perf.LoadSequence(t.SequenceFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, assignment := range t.Variables {
+		if err = sequence.Variables.Set(assignment); err != nil {
+			return nil, err
+		}
+	}
+	if err = sequence.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", t.SequenceFile, err)
+	}
+	return sequence, nil
+}
+
+// LoadTargets returns the weighted targets of the load test: the ones of TargetsFile followed
+// by the Targets specs and the GraphQL requests of GraphQLFiles.  It returns nil when none is
+// set and DefaultURL alone is tested.
+func (t *TestConfig) LoadTargets() ([]perf.Target, error) {
+	var targets []perf.Target
+	if t.TargetsFile != "" {
+		loaded, err := perf.LoadTargets(t.TargetsFile)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, loaded...)
+	}
+	for _, spec := range t.Targets {
+		target, err := perf.ParseTarget(spec)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	for _, path := range t.GraphQLFiles {
+		query, err := perf.LoadGraphQL(path)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, perf.Target{URL: t.DefaultURL, GraphQL: query})
+	}
+	return targets, nil
+}
+
+// hasTargets reports whether weighted targets replace DefaultURL
+func (t *TestConfig) hasTargets() bool {
+	return t.TargetsFile != "" || len(t.Targets) > 0 || len(t.GraphQLFiles) > 0
 }
 
 // LogConfig contains logging configuration
@@ -54,7 +455,7 @@ type WebConfig struct {
 
 // ParserConfig contains asset parsing configuration
 type ParserConfig struct {
-	Method     string `json:"method"` // "regex", "dom", "hybrid"
+	Method     string `json:"method"` // "regex", "dom", "mixed" ("hybrid" is an alias)
 	Concurrent bool   `json:"concurrent"`
 	RegexLimit int    `json:"regex_limit"`
 }
@@ -100,12 +501,38 @@ func LoadConfig() (*Config, error) {
 
 // DefaultConfig returns configuration with default values
 func DefaultConfig() *Config {
+	browser := request.DefaultBrowserOptions()
 	return &Config{
 		HTTP: HTTPConfig{
-			Timeout:        DefaultHTTPTimeout,
-			MaxConnections: DefaultMaxConnections,
-			RetryAttempts:  DefaultRetryAttempts,
-			UserAgent:      DefaultUserAgent,
+			Timeout:         DefaultHTTPTimeout,
+			MaxConnections:  DefaultMaxConnections,
+			MaxConnsPerHost: DefaultMaxConnsPerHost,
+			IdleConnTimeout: DefaultIdleConnTimeout,
+			Protocol:        DefaultProtocol,
+			RetryAttempts:   DefaultRetryAttempts,
+			UserAgent:       DefaultUserAgent,
+		},
+		Browser: BrowserConfig{
+			Enabled:             false,
+			MaxConnsPerOrigin:   browser.MaxConnsPerOrigin,
+			MaxStreamsPerOrigin: browser.MaxStreamsPerOrigin,
+			CSSDepth:            DefaultCSSDepth,
+		},
+		Crawl: CrawlConfig{
+			MaxDepth: DefaultCrawlDepth,
+			MaxPages: DefaultCrawlPages,
+			Links:    true,
+			Sitemap:  true,
+			Robots:   true,
+			Workers:  DefaultCrawlWorkers,
+		},
+		Replay: ReplayConfig{
+			Speed:     DefaultReplaySpeed,
+			LogFormat: DefaultLogReplayFormat,
+			Methods:   []string{http.MethodGet, http.MethodHead},
+		},
+		WebSocket: WebSocketConfig{
+			ReplyTimeout: DefaultWebSocketReplyTimeout,
 		},
 		Test: TestConfig{
 			DefaultUsers:    DefaultUsers,
@@ -162,10 +589,370 @@ func (c *Config) LoadFromEnv() error {
 		}
 	}
 
+	if perHost := os.Getenv("GOPERF_HTTP_MAX_CONNS_PER_HOST"); perHost != "" {
+		if n, err := strconv.Atoi(perHost); err == nil {
+			c.HTTP.MaxConnsPerHost = n
+		}
+	}
+
+	if idle := os.Getenv("GOPERF_HTTP_IDLE_TIMEOUT"); idle != "" {
+		if d, err := time.ParseDuration(idle); err == nil {
+			c.HTTP.IdleConnTimeout = d
+		}
+	}
+
+	if keepAlive := os.Getenv("GOPERF_HTTP_KEEP_ALIVE"); keepAlive != "" {
+		if b, err := strconv.ParseBool(keepAlive); err == nil {
+			c.HTTP.DisableKeepAlives = !b
+		}
+	}
+
+	if compression := os.Getenv("GOPERF_HTTP_COMPRESSION"); compression != "" {
+		if b, err := strconv.ParseBool(compression); err == nil {
+			c.HTTP.DisableCompression = !b
+		}
+	}
+
+	if protocol := os.Getenv("GOPERF_HTTP_PROTOCOL"); protocol != "" {
+		c.HTTP.Protocol = protocol
+	}
+
+	if shared := os.Getenv("GOPERF_HTTP_SHARED_TRANSPORT"); shared != "" {
+		if b, err := strconv.ParseBool(shared); err == nil {
+			c.HTTP.SharedTransport = b
+		}
+	}
+
 	if userAgent := os.Getenv("GOPERF_USER_AGENT"); userAgent != "" {
 		c.HTTP.UserAgent = userAgent
 	}
 
+	// DNS configuration
+	if resolve := os.Getenv("GOPERF_DNS_RESOLVE"); resolve != "" {
+		c.HTTP.Resolve = strings.Fields(resolve)
+	}
+
+	if server := os.Getenv("GOPERF_DNS_SERVER"); server != "" {
+		c.HTTP.DNSServer = server
+	}
+
+	if cache := os.Getenv("GOPERF_DNS_CACHE"); cache != "" {
+		if b, err := strconv.ParseBool(cache); err == nil {
+			c.HTTP.DNSCache = b
+		}
+	}
+
+	if roundRobin := os.Getenv("GOPERF_DNS_ROUND_ROBIN"); roundRobin != "" {
+		if b, err := strconv.ParseBool(roundRobin); err == nil {
+			c.HTTP.DNSRoundRobin = b
+		}
+	}
+
+	// Proxy configuration, replacing the standard proxy environment variables
+	if proxy := os.Getenv("GOPERF_PROXY"); proxy != "" {
+		c.HTTP.Proxy.URL = proxy
+	}
+
+	if noProxy := os.Getenv("GOPERF_NO_PROXY"); noProxy != "" {
+		c.HTTP.Proxy.NoProxy = noProxy
+	}
+
+	// Connection endpoints
+	if target := os.Getenv("GOPERF_DIAL_TARGET"); target != "" {
+		c.HTTP.Dial.Target = target
+	}
+
+	if sources := os.Getenv("GOPERF_SOURCE_ADDRS"); sources != "" {
+		c.HTTP.Dial.Sources = splitList(sources)
+	}
+
+	// TLS configuration
+	if cert := os.Getenv("GOPERF_TLS_CERT"); cert != "" {
+		c.HTTP.TLS.CertFile = cert
+	}
+
+	if key := os.Getenv("GOPERF_TLS_KEY"); key != "" {
+		c.HTTP.TLS.KeyFile = key
+	}
+
+	if ca := os.Getenv("GOPERF_TLS_CA"); ca != "" {
+		c.HTTP.TLS.CAFile = ca
+	}
+
+	if insecure := os.Getenv("GOPERF_TLS_INSECURE"); insecure != "" {
+		if b, err := strconv.ParseBool(insecure); err == nil {
+			c.HTTP.TLS.Insecure = b
+		}
+	}
+
+	if minVersion := os.Getenv("GOPERF_TLS_MIN_VERSION"); minVersion != "" {
+		c.HTTP.TLS.MinVersion = minVersion
+	}
+
+	if maxVersion := os.Getenv("GOPERF_TLS_MAX_VERSION"); maxVersion != "" {
+		c.HTTP.TLS.MaxVersion = maxVersion
+	}
+
+	if ciphers := os.Getenv("GOPERF_TLS_CIPHERS"); ciphers != "" {
+		c.HTTP.TLS.CipherSuites = splitList(ciphers)
+	}
+
+	if serverName := os.Getenv("GOPERF_TLS_SERVER_NAME"); serverName != "" {
+		c.HTTP.TLS.ServerName = serverName
+	}
+
+	if resumption := os.Getenv("GOPERF_TLS_SESSION_RESUMPTION"); resumption != "" {
+		if b, err := strconv.ParseBool(resumption); err == nil {
+			c.HTTP.TLS.SessionResumption = b
+		}
+	}
+
+	// Auth configuration
+	if authType := os.Getenv("GOPERF_AUTH"); authType != "" {
+		c.Auth.Type = authType
+	}
+
+	if user := os.Getenv("GOPERF_AUTH_USER"); user != "" {
+		c.Auth.Username = user
+	}
+
+	if password := os.Getenv("GOPERF_AUTH_PASSWORD"); password != "" {
+		c.Auth.Password = password
+	}
+
+	if token := os.Getenv("GOPERF_AUTH_TOKEN"); token != "" {
+		c.Auth.Token = token
+	}
+
+	if tokenURL := os.Getenv("GOPERF_OAUTH_TOKEN_URL"); tokenURL != "" {
+		c.Auth.TokenURL = tokenURL
+	}
+
+	if clientID := os.Getenv("GOPERF_OAUTH_CLIENT_ID"); clientID != "" {
+		c.Auth.ClientID = clientID
+	}
+
+	if clientSecret := os.Getenv("GOPERF_OAUTH_CLIENT_SECRET"); clientSecret != "" {
+		c.Auth.ClientSecret = clientSecret
+	}
+
+	if scopes := os.Getenv("GOPERF_OAUTH_SCOPES"); scopes != "" {
+		c.Auth.Scopes = splitList(scopes)
+	}
+
+	if refreshBefore := os.Getenv("GOPERF_OAUTH_REFRESH_BEFORE"); refreshBefore != "" {
+		if d, err := time.ParseDuration(refreshBefore); err == nil {
+			c.Auth.RefreshBefore = d
+		}
+	}
+
+	if shared := os.Getenv("GOPERF_OAUTH_SHARED"); shared != "" {
+		if b, err := strconv.ParseBool(shared); err == nil {
+			c.Auth.Shared = b
+		}
+	}
+
+	if key := os.Getenv("GOPERF_SIGN_KEY"); key != "" {
+		c.Auth.KeyID = key
+	}
+
+	if secret := os.Getenv("GOPERF_SIGN_SECRET"); secret != "" {
+		c.Auth.Secret = secret
+	}
+
+	if sessionToken := os.Getenv("GOPERF_SIGN_SESSION_TOKEN"); sessionToken != "" {
+		c.Auth.SessionToken = sessionToken
+	}
+
+	if region := os.Getenv("GOPERF_SIGN_REGION"); region != "" {
+		c.Auth.Region = region
+	}
+
+	if service := os.Getenv("GOPERF_SIGN_SERVICE"); service != "" {
+		c.Auth.Service = service
+	}
+
+	// Browser configuration
+	if browser := os.Getenv("GOPERF_BROWSER"); browser != "" {
+		if b, err := strconv.ParseBool(browser); err == nil {
+			c.Browser.Enabled = b
+		}
+	}
+
+	if cache := os.Getenv("GOPERF_BROWSER_CACHE"); cache != "" {
+		if b, err := strconv.ParseBool(cache); err == nil {
+			c.Browser.Cache = b
+		}
+	}
+
+	if perOrigin := os.Getenv("GOPERF_BROWSER_MAX_CONNS_PER_ORIGIN"); perOrigin != "" {
+		if n, err := strconv.Atoi(perOrigin); err == nil {
+			c.Browser.MaxConnsPerOrigin = n
+		}
+	}
+
+	if depth := os.Getenv("GOPERF_BROWSER_CSS_DEPTH"); depth != "" {
+		if n, err := strconv.Atoi(depth); err == nil {
+			c.Browser.CSSDepth = n
+		}
+	}
+
+	// Asset filter configuration
+	if sameOrigin := os.Getenv("GOPERF_FILTER_SAME_ORIGIN"); sameOrigin != "" {
+		if b, err := strconv.ParseBool(sameOrigin); err == nil {
+			c.Filter.SameOrigin = b
+		}
+	}
+
+	for env, list := range map[string]*[]string{
+		"GOPERF_FILTER_ALLOW_HOSTS": &c.Filter.AllowHosts,
+		"GOPERF_FILTER_DENY_HOSTS":  &c.Filter.DenyHosts,
+		"GOPERF_FILTER_TYPES":       &c.Filter.Types,
+		"GOPERF_FILTER_SKIP_TYPES":  &c.Filter.SkipTypes,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*list = splitList(value)
+		}
+	}
+
+	// Patterns may contain commas, so they are separated by whitespace
+	if include := os.Getenv("GOPERF_FILTER_INCLUDE"); include != "" {
+		c.Filter.Include = strings.Fields(include)
+	}
+
+	if exclude := os.Getenv("GOPERF_FILTER_EXCLUDE"); exclude != "" {
+		c.Filter.Exclude = strings.Fields(exclude)
+	}
+
+	// Crawl configuration
+	if depth := os.Getenv("GOPERF_CRAWL_MAX_DEPTH"); depth != "" {
+		if n, err := strconv.Atoi(depth); err == nil {
+			c.Crawl.MaxDepth = n
+		}
+	}
+
+	if pages := os.Getenv("GOPERF_CRAWL_MAX_PAGES"); pages != "" {
+		if n, err := strconv.Atoi(pages); err == nil {
+			c.Crawl.MaxPages = n
+		}
+	}
+
+	if robots := os.Getenv("GOPERF_CRAWL_ROBOTS"); robots != "" {
+		if b, err := strconv.ParseBool(robots); err == nil {
+			c.Crawl.Robots = b
+		}
+	}
+
+	if output := os.Getenv("GOPERF_CRAWL_OUTPUT"); output != "" {
+		c.Crawl.Output = output
+	}
+
+	// Replay configuration
+	if har := os.Getenv("GOPERF_REPLAY_HAR"); har != "" {
+		c.Replay.HAR = har
+	}
+
+	if speed := os.Getenv("GOPERF_REPLAY_SPEED"); speed != "" {
+		if f, err := strconv.ParseFloat(speed, 64); err == nil {
+			c.Replay.Speed = f
+		}
+	}
+
+	if thirdParty := os.Getenv("GOPERF_REPLAY_STRIP_THIRD_PARTY"); thirdParty != "" {
+		if b, err := strconv.ParseBool(thirdParty); err == nil {
+			c.Replay.StripThirdParty = b
+		}
+	}
+
+	if cookies := os.Getenv("GOPERF_REPLAY_STRIP_COOKIES"); cookies != "" {
+		if b, err := strconv.ParseBool(cookies); err == nil {
+			c.Replay.StripCookies = b
+		}
+	}
+
+	// WebSocket configuration
+	if messagesFile := os.Getenv("GOPERF_WS_MESSAGES"); messagesFile != "" {
+		c.WebSocket.MessagesFile = messagesFile
+	}
+
+	if rate := os.Getenv("GOPERF_WS_RATE"); rate != "" {
+		if f, err := strconv.ParseFloat(rate, 64); err == nil {
+			c.WebSocket.Rate = f
+		}
+	}
+
+	if idField := os.Getenv("GOPERF_WS_ID_FIELD"); idField != "" {
+		c.WebSocket.IDField = idField
+	}
+
+	if replyTimeout := os.Getenv("GOPERF_WS_REPLY_TIMEOUT"); replyTimeout != "" {
+		if d, err := time.ParseDuration(replyTimeout); err == nil {
+			c.WebSocket.ReplyTimeout = d
+		}
+	}
+
+	if origin := os.Getenv("GOPERF_WS_ORIGIN"); origin != "" {
+		c.WebSocket.Origin = origin
+	}
+
+	// gRPC configuration
+	if protoFiles := os.Getenv("GOPERF_GRPC_PROTO"); protoFiles != "" {
+		c.GRPC.ProtoFiles = splitList(protoFiles)
+	}
+
+	if importPaths := os.Getenv("GOPERF_GRPC_IMPORT_PATH"); importPaths != "" {
+		c.GRPC.ImportPaths = splitList(importPaths)
+	}
+
+	// Streaming configuration
+	if maxConnection := os.Getenv("GOPERF_STREAM_MAX_CONNECTION"); maxConnection != "" {
+		if d, err := time.ParseDuration(maxConnection); err == nil {
+			c.Stream.MaxConnection = d
+		}
+	}
+
+	// Import configuration
+	if environment := os.Getenv("GOPERF_IMPORT_ENVIRONMENT"); environment != "" {
+		c.Import.Environment = environment
+	}
+
+	if output := os.Getenv("GOPERF_IMPORT_OUTPUT"); output != "" {
+		c.Import.Output = output
+	}
+
+	if server := os.Getenv("GOPERF_IMPORT_SERVER"); server != "" {
+		c.Import.Server = server
+	}
+
+	if accessLog := os.Getenv("GOPERF_REPLAY_LOG"); accessLog != "" {
+		c.Replay.Log = accessLog
+	}
+
+	if logFormat := os.Getenv("GOPERF_REPLAY_LOG_FORMAT"); logFormat != "" {
+		c.Replay.LogFormat = logFormat
+	}
+
+	if methods := os.Getenv("GOPERF_REPLAY_METHODS"); methods != "" {
+		c.Replay.Methods = splitList(strings.ToUpper(methods))
+	}
+
+	// Parser configuration
+	if method := os.Getenv("GOPERF_PARSER_METHOD"); method != "" {
+		c.Parser.Method = method
+	}
+
+	if concurrent := os.Getenv("GOPERF_PARSER_CONCURRENT"); concurrent != "" {
+		if b, err := strconv.ParseBool(concurrent); err == nil {
+			c.Parser.Concurrent = b
+		}
+	}
+
+	if limit := os.Getenv("GOPERF_PARSER_REGEX_LIMIT"); limit != "" {
+		if n, err := strconv.Atoi(limit); err == nil {
+			c.Parser.RegexLimit = n
+		}
+	}
+
 	// Test configuration
 	if users := os.Getenv("GOPERF_DEFAULT_USERS"); users != "" {
 		if n, err := strconv.Atoi(users); err == nil {
@@ -177,6 +964,18 @@ func (c *Config) LoadFromEnv() error {
 		c.Test.DefaultURL = url
 	}
 
+	if targets := os.Getenv("GOPERF_TARGETS_FILE"); targets != "" {
+		c.Test.TargetsFile = targets
+	}
+
+	if graphQL := os.Getenv("GOPERF_GRAPHQL"); graphQL != "" {
+		c.Test.GraphQLFiles = splitList(graphQL)
+	}
+
+	if sequence := os.Getenv("GOPERF_SEQUENCE"); sequence != "" {
+		c.Test.SequenceFile = sequence
+	}
+
 	// Web configuration
 	if port := os.Getenv("GOPERF_WEB_PORT"); port != "" {
 		if n, err := strconv.Atoi(port); err == nil {
@@ -197,27 +996,323 @@ func (c *Config) LoadFromFlags() error {
 	// Define flags
 	users := flag.Int("users", c.Test.DefaultUsers, "Number of concurrent users/connections")
 	url := flag.String("url", c.Test.DefaultURL, "URL to test")
+	flag.Var(&listFlag{values: &c.Test.Targets}, "target",
+		"Weighted target \"[weight] [METHOD] url [Name=value ...]\" replacing -url (repeatable); "+
+			"weights are positive and default to 1")
+	targetsFile := flag.String("targets", c.Test.TargetsFile,
+		"File of weighted targets replacing -url: JSON (as written by crawl -crawlout) or one target per line")
+	flag.Var(&listFlag{values: &c.Test.GraphQLFiles, split: true}, "graphql",
+		"GraphQL request files (query document or JSON request) posted to -url as targets (comma separated)")
+	sequenceFile := flag.String("sequence", c.Test.SequenceFile,
+		"Sequence file (as written by import postman) whose requests every user sends in order")
+	flag.Var(&listFlag{values: &c.Test.Variables}, "var", "Sequence or ws message variable \"name=value\" (repeatable)")
 	seconds := flag.Int("sec", int(c.Test.DefaultDuration.Seconds()), "Test duration in seconds")
 	web := flag.Bool("web", c.Web.Enabled, "Run as a webserver")
 	port := flag.Int("port", c.Web.Port, "Web server port")
 	userAgent := flag.String("useragent", c.HTTP.UserAgent, "User agent string")
 	outputFile := flag.String("output", c.Test.OutputFile, "Output file path")
+	fetch := flag.Bool("fetch", c.Test.Fetch, "Fetch the url once and print the response")
+	fetchAll := flag.Bool("fetchall", c.Test.FetchAll, "Fetch the url and all of its assets once and print a waterfall")
+	format := flag.String("format", c.Output.Format, "Output format for -fetch and -fetchall: text, json or html")
+	printJSON := flag.Bool("printjson", false, "Shorthand for -format json")
+	timeout := flag.Duration("timeout", c.HTTP.Timeout, "Request timeout")
+	maxConns := flag.Int("maxconns", c.HTTP.MaxConnections, "Maximum idle connections kept per connection pool")
+	maxConnsPerHost := flag.Int("maxconnsperhost", c.HTTP.MaxConnsPerHost,
+		"Maximum connections per host for each connection pool (0 means unlimited)")
+	idleTimeout := flag.Duration("idletimeout", c.HTTP.IdleConnTimeout, "How long idle connections stay in the pool")
+	noKeepAlive := flag.Bool("nokeepalive", c.HTTP.DisableKeepAlives, "Open a new connection for every request")
+	noCompression := flag.Bool("nocompression", c.HTTP.DisableCompression, "Do not request gzip compressed responses")
+	protocol := flag.String("protocol", c.HTTP.Protocol,
+		"HTTP protocol: auto, http1, http2 or h2c (HTTP/2 over cleartext with prior knowledge)")
+	sharedTransport := flag.Bool("sharedtransport", c.HTTP.SharedTransport,
+		"Share one connection pool between all users instead of one pool per user")
+	flag.Var(&listFlag{values: &c.HTTP.Resolve}, "resolve",
+		"Dial addr instead of resolving host:port, \"host:port:addr[,addr...]\" like curl --resolve (repeatable)")
+	dnsServer := flag.String("dns", c.HTTP.DNSServer, "DNS server \"ip[:port]\" queried instead of the system resolver")
+	dnsCache := flag.Bool("dnscache", c.HTTP.DNSCache,
+		"Resolve each host once per user instead of for every new connection")
+	dnsRoundRobin := flag.Bool("dnsroundrobin", c.HTTP.DNSRoundRobin,
+		"Spread new connections over all the addresses of a host")
+	proxy := flag.String("proxy", c.HTTP.Proxy.URL,
+		"Proxy url: http://, https://, socks5:// or socks5h://[user:password@]host:port, or direct "+
+			"(default: HTTP_PROXY and HTTPS_PROXY)")
+	noProxy := flag.String("noproxy", c.HTTP.Proxy.NoProxy,
+		"Hosts, domains, IPs and CIDR ranges reached without the proxy, comma separated (default: NO_PROXY)")
+	dialTarget := flag.String("dial", c.HTTP.Dial.Target,
+		"unix:///path or tcp://host:port dialed for every connection instead of the url's host")
+	flag.Var(&listFlag{values: &c.HTTP.Dial.Sources, split: true}, "source",
+		"Local IPs or network interfaces connections are bound to in turn, comma separated")
+	certFile := flag.String("cert", c.HTTP.TLS.CertFile, "PEM client certificate presented to servers asking for one")
+	keyFile := flag.String("key", c.HTTP.TLS.KeyFile, "PEM key of the -cert client certificate")
+	caFile := flag.String("cacert", c.HTTP.TLS.CAFile, "PEM CA bundle trusted in addition to the system roots")
+	insecure := flag.Bool("insecure", c.HTTP.TLS.Insecure, "Skip TLS certificate verification")
+	tlsMin := flag.String("tlsmin", c.HTTP.TLS.MinVersion, "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	tlsMax := flag.String("tlsmax", c.HTTP.TLS.MaxVersion, "Maximum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	flag.Var(&listFlag{values: &c.HTTP.TLS.CipherSuites, split: true}, "ciphers",
+		"TLS 1.0-1.2 cipher suites by name, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (comma separated)")
+	serverName := flag.String("servername", c.HTTP.TLS.ServerName,
+		"Server name sent as SNI and verified instead of the url's host")
+	tlsResume := flag.Bool("tlsresume", c.HTTP.TLS.SessionResumption,
+		"Resume TLS sessions on new connections instead of a full handshake each time")
+	authType := flag.String("auth", c.Auth.Type, "Authenticate requests with basic, bearer, oauth2, hmac or sigv4")
+	authUser := flag.String("authuser", c.Auth.Username, "basic username, or oauth2 password grant username")
+	authPassword := flag.String("authpassword", c.Auth.Password, "Password of -authuser")
+	authToken := flag.String("authtoken", c.Auth.Token, "bearer token")
+	tokenURL := flag.String("tokenurl", c.Auth.TokenURL, "oauth2 token endpoint")
+	clientID := flag.String("clientid", c.Auth.ClientID, "oauth2 client id")
+	clientSecret := flag.String("clientsecret", c.Auth.ClientSecret, "oauth2 client secret")
+	flag.Var(&listFlag{values: &c.Auth.Scopes, split: true}, "scopes", "oauth2 scopes requested, comma separated")
+	tokenRefresh := flag.Duration("tokenrefresh", c.Auth.RefreshBefore,
+		"Renew oauth2 tokens this long before they expire (0 means 30s)")
+	tokenShared := flag.Bool("tokenshared", c.Auth.Shared, "Share one oauth2 token between all users")
+	signKey := flag.String("signkey", c.Auth.KeyID, "hmac key id, or sigv4 access key")
+	signSecret := flag.String("signsecret", c.Auth.Secret, "hmac secret, or sigv4 secret key")
+	signRegion := flag.String("signregion", c.Auth.Region, "sigv4 region, e.g. us-east-1")
+	signService := flag.String("signservice", c.Auth.Service, "sigv4 service, e.g. execute-api")
+	browser := flag.Bool("browser", c.Browser.Enabled, "Emulate browser per-origin limits when fetching assets")
+	maxConnsPerOrigin := flag.Int("maxconnsperorigin", c.Browser.MaxConnsPerOrigin,
+		"Concurrent asset fetches per HTTP/1.x origin in browser mode")
+	maxStreamsPerOrigin := flag.Int("maxstreamsperorigin", c.Browser.MaxStreamsPerOrigin,
+		"Concurrent asset fetches per HTTP/2 origin in browser mode")
+	cache := flag.Bool("cache", c.Browser.Cache, "Give each user an HTTP cache to measure first-view vs repeat-view loads")
+	cssDepth := flag.Int("cssdepth", c.Browser.CSSDepth,
+		"Levels of stylesheet @imports, fonts and url() references to fetch (0 disables)")
+	sameOrigin := flag.Bool("sameorigin", c.Filter.SameOrigin, "Only fetch assets served from the page's origin")
+	flag.Var(&listFlag{values: &c.Filter.AllowHosts, split: true}, "allowhost",
+		"Only fetch assets from these hosts and their subdomains (comma separated, repeatable)")
+	flag.Var(&listFlag{values: &c.Filter.DenyHosts, split: true}, "denyhost",
+		"Never fetch assets from these hosts and their subdomains (comma separated, repeatable)")
+	flag.Var(&listFlag{values: &c.Filter.Include}, "include",
+		"Only fetch assets whose URL matches this glob, or regex prefixed with re: (repeatable)")
+	flag.Var(&listFlag{values: &c.Filter.Exclude}, "exclude",
+		"Never fetch assets whose URL matches this glob, or regex prefixed with re: (repeatable)")
+	flag.Var(&listFlag{values: &c.Filter.Types, split: true}, "types",
+		"Only fetch these asset types: js, css, img, font, media, icon, document, other (comma separated)")
+	flag.Var(&listFlag{values: &c.Filter.SkipTypes, split: true}, "skiptypes",
+		"Never fetch these asset types (comma separated)")
+	maxDepth := flag.Int("maxdepth", c.Crawl.MaxDepth, "crawl: links to follow away from the start page")
+	maxPages := flag.Int("maxpages", c.Crawl.MaxPages, "crawl: maximum number of pages to load")
+	links := flag.Bool("links", c.Crawl.Links, "crawl: follow same-site <a href> links")
+	sitemap := flag.Bool("sitemap", c.Crawl.Sitemap, "crawl: read the sitemaps from robots.txt or /sitemap.xml")
+	robots := flag.Bool("robots", c.Crawl.Robots, "crawl: obey robots.txt")
+	crawlWorkers := flag.Int("crawlworkers", c.Crawl.Workers, "crawl: pages loaded concurrently")
+	crawlOutput := flag.String("crawlout", c.Crawl.Output, "crawl: write the discovered load test targets to this file")
+	har := flag.String("har", c.Replay.HAR, "Replay the requests of this HAR file instead of loading -url")
+	speed := flag.Float64("speed", c.Replay.Speed,
+		"Replay pace: 1 = recorded timing, 2 = twice as fast, 0 = as fast as possible")
+	stripThirdParty := flag.Bool("stripthirdparty", c.Replay.StripThirdParty,
+		"Do not replay requests to hosts outside the site of the first recorded request")
+	stripCookies := flag.Bool("stripcookies", c.Replay.StripCookies, "Do not replay recorded Cookie headers")
+	accessLog := flag.String("log", c.Replay.Log, "replay: access log whose requests are sent to -url")
+	logFormat := flag.String("logformat", c.Replay.LogFormat, "replay: access log format: auto, combined or json")
+	importOutput := flag.String("importout", c.Import.Output, "import: write the requests to this request file")
+	server := flag.String("server", c.Import.Server, "import: send the OpenAPI requests to this server")
+	environment := flag.String("env", c.Import.Environment, "import: Postman environment file of the collection")
+	flag.Var(&listFlag{values: &c.WebSocket.Messages}, "message",
+		"ws: message every user sends, in order; {{id}} is a unique message id (repeatable)")
+	messagesFile := flag.String("messages", c.WebSocket.MessagesFile, "ws: file of messages, one per line")
+	rate := flag.Float64("rate", c.WebSocket.Rate,
+		"ws: messages per second per user; 0 sends the next message once the previous one is answered")
+	idField := flag.String("idfield", c.WebSocket.IDField,
+		"ws: JSON field matching replies to messages; replies are matched as echoes when empty")
+	replyTimeout := flag.Duration("replytimeout", c.WebSocket.ReplyTimeout, "ws: handshake and reply timeout")
+	origin := flag.String("origin", c.WebSocket.Origin, "ws: Origin header of the handshake (default the url's host)")
+	flag.Var(&listFlag{values: &c.GRPC.Calls}, "call",
+		`grpc: call every user makes in turn, "package.Service/Method {JSON request}" (repeatable)`)
+	flag.Var(&listFlag{values: &c.GRPC.ProtoFiles, split: true}, "proto",
+		"grpc: .proto files of the services (comma separated); server reflection when not set")
+	flag.Var(&listFlag{values: &c.GRPC.ImportPaths, split: true}, "importpath",
+		"grpc: directories searched for imported .proto files (comma separated)")
+	flag.Var(&listFlag{values: &c.GRPC.Metadata}, "metadata",
+		"grpc: name=value metadata sent with every call (repeatable)")
+	maxStream := flag.Duration("maxstream", c.Stream.MaxConnection,
+		"stream: close and reopen every stream after this long (0 holds it until the server ends it)")
+	flag.Var(&listFlag{values: &c.Stream.Headers}, "header", "stream: name=value request header (repeatable)")
+	flag.Var(&listFlag{values: &c.Replay.Methods, split: true}, "methods",
+		"replay: request methods to replay from the access log (comma separated)")
+	parser := flag.String("parser", c.Parser.Method, "Asset parsing method: regex, dom or mixed")
+	parserConcurrent := flag.Bool("parserconcurrent", c.Parser.Concurrent,
+		"Extract each asset class in its own goroutine")
+	regexLimit := flag.Int("regexlimit", c.Parser.RegexLimit,
+		"Maximum regex matches per asset pattern (negative means unlimited)")
 
-	// Parse flags
-	flag.Parse()
+	// Parse flags, after the sub-command if there is one
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case CrawlCommand:
+			c.Crawl.Enabled = true
+			args = args[1:]
+		case ReplayCommand:
+			c.Replay.Enabled = true
+			args = args[1:]
+		case WebSocketCommand:
+			c.WebSocket.Enabled = true
+			args = args[1:]
+		case GRPCCommand:
+			c.GRPC.Enabled = true
+			args = args[1:]
+		case StreamCommand:
+			c.Stream.Enabled = true
+			args = args[1:]
+		case ImportCommand:
+			// goperf import <source> <input> [flags]
+			c.Import.Enabled = true
+			args = args[1:]
+			for _, field := range []*string{&c.Import.Source, &c.Import.Input} {
+				if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+					*field, args = args[0], args[1:]
+				}
+			}
+		}
+	}
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return err
+	}
 
 	// Apply flag values
 	c.Test.DefaultUsers = *users
 	c.Test.DefaultURL = *url
+	c.Test.TargetsFile = *targetsFile
+	c.Test.SequenceFile = *sequenceFile
 	c.Test.DefaultDuration = time.Duration(*seconds) * time.Second
 	c.Web.Enabled = *web
 	c.Web.Port = *port
 	c.HTTP.UserAgent = *userAgent
 	c.Test.OutputFile = *outputFile
+	c.Test.Fetch = *fetch
+	c.Test.FetchAll = *fetchAll
+	c.Output.Format = *format
+	if *printJSON {
+		c.Output.Format = OutputFormatJSON
+	}
+	c.HTTP.Timeout = *timeout
+	c.HTTP.MaxConnections = *maxConns
+	c.HTTP.MaxConnsPerHost = *maxConnsPerHost
+	c.HTTP.IdleConnTimeout = *idleTimeout
+	c.HTTP.DisableKeepAlives = *noKeepAlive
+	c.HTTP.DisableCompression = *noCompression
+	c.HTTP.Protocol = *protocol
+	c.HTTP.SharedTransport = *sharedTransport
+	c.HTTP.DNSServer = *dnsServer
+	c.HTTP.DNSCache = *dnsCache
+	c.HTTP.DNSRoundRobin = *dnsRoundRobin
+	c.HTTP.Proxy.URL = *proxy
+	c.HTTP.Proxy.NoProxy = *noProxy
+	c.HTTP.Dial.Target = *dialTarget
+	c.HTTP.TLS.CertFile = *certFile
+	c.HTTP.TLS.KeyFile = *keyFile
+	c.HTTP.TLS.CAFile = *caFile
+	c.HTTP.TLS.Insecure = *insecure
+	c.HTTP.TLS.MinVersion = *tlsMin
+	c.HTTP.TLS.MaxVersion = *tlsMax
+	c.HTTP.TLS.ServerName = *serverName
+	c.HTTP.TLS.SessionResumption = *tlsResume
+	c.Auth.Type = *authType
+	c.Auth.Username = *authUser
+	c.Auth.Password = *authPassword
+	c.Auth.Token = *authToken
+	c.Auth.TokenURL = *tokenURL
+	c.Auth.ClientID = *clientID
+	c.Auth.ClientSecret = *clientSecret
+	c.Auth.RefreshBefore = *tokenRefresh
+	c.Auth.Shared = *tokenShared
+	c.Auth.KeyID = *signKey
+	c.Auth.Secret = *signSecret
+	c.Auth.Region = *signRegion
+	c.Auth.Service = *signService
+	c.Browser.Enabled = *browser
+	c.Browser.MaxConnsPerOrigin = *maxConnsPerOrigin
+	c.Browser.MaxStreamsPerOrigin = *maxStreamsPerOrigin
+	c.Browser.Cache = *cache
+	c.Browser.CSSDepth = *cssDepth
+	c.Filter.SameOrigin = *sameOrigin
+	c.Crawl.MaxDepth = *maxDepth
+	c.Crawl.MaxPages = *maxPages
+	c.Crawl.Links = *links
+	c.Crawl.Sitemap = *sitemap
+	c.Crawl.Robots = *robots
+	c.Crawl.Workers = *crawlWorkers
+	c.Crawl.Output = *crawlOutput
+	c.Replay.HAR = *har
+	c.Replay.Speed = *speed
+	c.Replay.StripThirdParty = *stripThirdParty
+	c.Replay.StripCookies = *stripCookies
+	c.Replay.Log = *accessLog
+	c.Import.Output = *importOutput
+	c.Import.Server = *server
+	c.Import.Environment = *environment
+	c.Replay.LogFormat = *logFormat
+	c.WebSocket.MessagesFile = *messagesFile
+	c.WebSocket.Rate = *rate
+	c.WebSocket.IDField = *idField
+	c.WebSocket.ReplyTimeout = *replyTimeout
+	c.WebSocket.Origin = *origin
+	c.Stream.MaxConnection = *maxStream
+	for i, method := range c.Replay.Methods {
+		c.Replay.Methods[i] = strings.ToUpper(method)
+	}
+	c.Parser.Method = *parser
+	c.Parser.Concurrent = *parserConcurrent
+	c.Parser.RegexLimit = *regexLimit
 
 	return nil
 }
 
+// validateWebSocket checks the settings of the ws sub-command
+func (c *Config) validateWebSocket() error {
+	if !strings.HasPrefix(c.Test.DefaultURL, "ws://") && !strings.HasPrefix(c.Test.DefaultURL, "wss://") {
+		return fmt.Errorf("ws needs a ws:// or wss:// url, got %q", c.Test.DefaultURL)
+	}
+	if c.WebSocket.Rate < 0 {
+		return fmt.Errorf("ws message rate must not be negative")
+	}
+	if c.WebSocket.ReplyTimeout <= 0 {
+		return fmt.Errorf("ws reply timeout must be positive")
+	}
+	if c.Test.SequenceFile != "" || c.Test.hasTargets() || c.Replay.HAR != "" {
+		return fmt.Errorf("ws cannot be combined with weighted targets, a sequence or a HAR replay")
+	}
+	if _, err := c.WebSocket.LoadMessages(); err != nil {
+		return err
+	}
+	_, err := c.Test.LoadVariables()
+	return err
+}
+
+// validateStream checks the settings of the stream sub-command
+func (c *Config) validateStream() error {
+	if !strings.HasPrefix(c.Test.DefaultURL, "http://") && !strings.HasPrefix(c.Test.DefaultURL, "https://") {
+		return fmt.Errorf("stream needs an http:// or https:// url, got %q", c.Test.DefaultURL)
+	}
+	if c.Stream.MaxConnection < 0 {
+		return fmt.Errorf("the maximum stream duration must not be negative")
+	}
+	if c.Test.SequenceFile != "" || c.Test.hasTargets() || c.Replay.HAR != "" {
+		return fmt.Errorf("stream cannot be combined with weighted targets, a sequence or a HAR replay")
+	}
+	_, err := c.Stream.Header()
+	return err
+}
+
+// validateGRPC checks the settings of the grpc sub-command
+func (c *Config) validateGRPC() error {
+	if !strings.HasPrefix(c.Test.DefaultURL, "http://") && !strings.HasPrefix(c.Test.DefaultURL, "https://") {
+		return fmt.Errorf("grpc needs an http:// (h2c) or https:// url, got %q", c.Test.DefaultURL)
+	}
+	if c.Test.SequenceFile != "" || c.Test.hasTargets() || c.Replay.HAR != "" {
+		return fmt.Errorf("grpc cannot be combined with weighted targets, a sequence or a HAR replay")
+	}
+	if _, err := c.GRPC.LoadCalls(); err != nil {
+		return err
+	}
+	if _, err := c.GRPC.Header(); err != nil {
+		return err
+	}
+	_, err := c.Test.LoadVariables()
+	return err
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.HTTP.Timeout <= 0 {
@@ -228,10 +1323,137 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max connections must be positive")
 	}
 
+	if c.HTTP.MaxConnsPerHost < 0 {
+		return fmt.Errorf("max connections per host must not be negative")
+	}
+
+	if c.HTTP.IdleConnTimeout < 0 {
+		return fmt.Errorf("idle connection timeout must not be negative")
+	}
+
+	if err := request.ValidateProtocol(c.HTTP.Protocol); err != nil {
+		return err
+	}
+
+	if _, err := c.HTTP.TLS.ClientConfig(); err != nil {
+		return err
+	}
+
+	if _, err := c.HTTP.ResolveOptions(); err != nil {
+		return err
+	}
+
+	if err := c.HTTP.Proxy.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.HTTP.Dial.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Auth.Validate(); err != nil {
+		return err
+	}
+
+	if c.Browser.MaxConnsPerOrigin <= 0 || c.Browser.MaxStreamsPerOrigin <= 0 {
+		return fmt.Errorf("browser per-origin limits must be positive")
+	}
+
+	if c.Browser.CSSDepth < 0 {
+		return fmt.Errorf("css depth must not be negative")
+	}
+
+	if _, err := c.Filter.AssetFilter(); err != nil {
+		return err
+	}
+
+	if c.Crawl.MaxDepth < 0 || c.Crawl.MaxPages <= 0 || c.Crawl.Workers <= 0 {
+		return fmt.Errorf("crawl depth must not be negative and crawl pages and workers must be positive")
+	}
+
+	if c.Crawl.Enabled && !c.Crawl.Links && !c.Crawl.Sitemap {
+		return fmt.Errorf("crawl needs links, a sitemap or both to discover pages")
+	}
+
+	if _, err := interfaces.ParseParsingMethod(c.Parser.Method); err != nil {
+		return err
+	}
+
+	if c.Parser.RegexLimit == 0 {
+		return fmt.Errorf("regex limit must not be zero (use a negative value for unlimited)")
+	}
+
+	if _, err := c.Test.LoadTargets(); err != nil {
+		return err
+	}
+
+	if c.Replay.Speed < 0 {
+		return fmt.Errorf("replay speed must not be negative")
+	}
+
+	if c.Replay.HAR != "" && c.Test.hasTargets() {
+		return fmt.Errorf("a HAR replay and weighted targets cannot be combined")
+	}
+
+	switch {
+	case c.WebSocket.Enabled:
+		if err := c.validateWebSocket(); err != nil {
+			return err
+		}
+	case c.GRPC.Enabled:
+		if err := c.validateGRPC(); err != nil {
+			return err
+		}
+	case c.Stream.Enabled:
+		if err := c.validateStream(); err != nil {
+			return err
+		}
+	default:
+		if _, err := c.Test.LoadSequence(); err != nil {
+			return err
+		}
+	}
+
+	if c.Test.SequenceFile != "" && (c.Replay.HAR != "" || c.Test.hasTargets()) {
+		return fmt.Errorf("a sequence cannot be combined with weighted targets or a HAR replay")
+	}
+
+	if _, err := c.Replay.Requests(); err != nil {
+		return err
+	}
+
+	if c.Replay.Enabled && c.Replay.Log == "" {
+		return fmt.Errorf("replay needs an access log (-log)")
+	}
+
+	if c.Import.Enabled {
+		switch c.Import.Source {
+		case importer.SourceCurl, importer.SourceOpenAPI, importer.SourcePostman:
+		default:
+			return fmt.Errorf("import needs a source: goperf import curl '<command>', " +
+				"goperf import openapi <spec> or goperf import postman <collection>")
+		}
+		if c.Import.Input == "" {
+			return fmt.Errorf("import %s needs its input", c.Import.Source)
+		}
+	}
+
+	switch c.Replay.LogFormat {
+	case replay.LogFormatAuto, replay.LogFormatCombined, replay.LogFormatJSON:
+	default:
+		return fmt.Errorf("invalid access log format: %s (use auto, combined or json)", c.Replay.LogFormat)
+	}
+
 	if c.Test.DefaultUsers <= 0 {
 		return fmt.Errorf("default users must be positive")
 	}
 
+	switch c.Output.Format {
+	case OutputFormatText, OutputFormatJSON, OutputFormatHTML, OutputFormatCSV:
+	default:
+		return fmt.Errorf("unsupported output format %q", c.Output.Format)
+	}
+
 	if c.Web.Port < MinPortNumber || c.Web.Port > MaxPortNumber {
 		return fmt.Errorf("web port must be between %d and %d", MinPortNumber, MaxPortNumber)
 	}
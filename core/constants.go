@@ -9,6 +9,38 @@ const (
 	DefaultMaxConnections = 100 // Default maximum HTTP connections
 	// DefaultRetryAttempts specifies the default number of retry attempts for failed requests
 	DefaultRetryAttempts = 3 // Default number of retry attempts
+	// DefaultMaxConnsPerHost specifies the default per-host connection cap (0 means unlimited)
+	DefaultMaxConnsPerHost = 0 // Default per-host connection limit
+	// DefaultIdleConnTimeout specifies how long idle keep-alive connections stay pooled
+	DefaultIdleConnTimeout = 90 * time.Second // Default idle connection timeout
+	// DefaultProtocol specifies the default HTTP protocol negotiation mode
+	DefaultProtocol = "auto" // Default HTTP protocol selection
+	// DefaultCSSDepth specifies how many levels of stylesheet sub-resources FetchAll follows
+	DefaultCSSDepth = 3 // Stylesheet, its @imports and theirs
+	// DefaultCrawlDepth specifies how many links away from the start page the crawler goes
+	DefaultCrawlDepth = 2 // Start page, the pages it links and theirs
+	// DefaultCrawlPages specifies the maximum number of pages a crawl loads
+	DefaultCrawlPages = 50 // Default crawl page limit
+	// DefaultCrawlWorkers specifies how many pages the crawler loads at once
+	DefaultCrawlWorkers = 4 // Polite default crawl concurrency
+	// CrawlCommand specifies the sub-command that runs the site crawler
+	CrawlCommand = "crawl"
+	// DefaultReplaySpeed specifies the pace of replayed traffic relative to the recording
+	DefaultReplaySpeed = 1.0 // Recorded timing
+	// DefaultLogReplayFormat specifies how the format of a replayed access log is chosen
+	DefaultLogReplayFormat = "auto" // Detected from the first line
+	// ReplayCommand specifies the sub-command that replays an access log
+	ReplayCommand = "replay"
+	// ImportCommand specifies the sub-command that converts curl commands and OpenAPI specs into request files
+	ImportCommand = "import"
+	// WebSocketCommand specifies the sub-command that load tests a WebSocket endpoint
+	WebSocketCommand = "ws"
+	// GRPCCommand specifies the sub-command that load tests a gRPC server
+	GRPCCommand = "grpc"
+	// StreamCommand specifies the sub-command that load tests a Server-Sent Events, chunked or long-poll endpoint
+	StreamCommand = "stream"
+	// DefaultWebSocketReplyTimeout specifies how long a WebSocket handshake or reply may take
+	DefaultWebSocketReplyTimeout = 5 * time.Second
 	// DefaultUserAgent specifies the default User-Agent header for HTTP requests
 	DefaultUserAgent = "goperf" // Default User-Agent header
 
@@ -33,7 +65,7 @@ const (
 	MaxPortNumber = 65535 // Maximum valid port number
 
 	// DefaultRegexLimit specifies the default limit for regex matches during parsing
-	DefaultRegexLimit = -10 // Default regex match limit (-1 means unlimited)
+	DefaultRegexLimit = -1 // Default regex match limit per pattern (negative means unlimited)
 
 	// MockHTTPStatusOK represents a successful HTTP status code for mock responses
 	MockHTTPStatusOK = 200 // Mock HTTP 200 status
@@ -43,20 +75,10 @@ const (
 	MockResponseSize = 100 // Mock response size in bytes
 	// MockLatency specifies the mock latency in milliseconds for testing
 	MockLatency = 10 // Mock latency in milliseconds
-	// MockTotalRequests specifies the mock total requests count for testing
-	MockTotalRequests = 25 // Mock total requests count
-	// MockSuccessRequests specifies the mock successful requests count for testing
-	MockSuccessRequests = 25 // Mock successful requests count
-	// MockAvgLatency specifies the mock average latency in milliseconds for testing
-	MockAvgLatency = 242 // Mock average latency in milliseconds
 	// MockMinLatency specifies the minimum latency for mock responses
 	MockMinLatency = 180 // Mock minimum latency in milliseconds
 	// MockMaxLatency specifies the maximum latency for mock responses
 	MockMaxLatency = 350 // Mock maximum latency in milliseconds
-	// MockThroughput specifies the mock throughput in requests per second for testing
-	MockThroughput = 12.5 // Mock throughput (requests per second)
-	// MockTotalBytes specifies the mock total bytes transferred for testing
-	MockTotalBytes = 2500 // Mock total bytes transferred
 	// MockSmallAssetSize specifies the mock small asset size for testing
 	MockSmallAssetSize = 30 // Mock small asset size
 	// MockMediumAssetSize specifies the mock medium asset size for testing
@@ -79,7 +101,15 @@ const (
 	// DefaultParsingMethod specifies the default HTML parsing method
 	DefaultParsingMethod = "dom" // Default HTML parsing method
 	// DefaultOutputFormat specifies the default output format for results
-	DefaultOutputFormat = "text" // Default output format
+	DefaultOutputFormat = OutputFormatText // Default output format
+	// OutputFormatText selects colored, human readable output
+	OutputFormatText = "text"
+	// OutputFormatJSON selects indented JSON output
+	OutputFormatJSON = "json"
+	// OutputFormatHTML selects a standalone HTML report
+	OutputFormatHTML = "html"
+	// OutputFormatCSV selects comma separated output
+	OutputFormatCSV = "csv"
 	// DefaultIndentation specifies the default JSON indentation string
 	DefaultIndentation = "    " // Default JSON indentation
 	// DefaultAPIPath specifies the default API path for web server mode
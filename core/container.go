@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Gosayram/goperf/httputils"
 	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
 )
 
 // Container manages all application dependencies
@@ -32,17 +34,17 @@ func NewContainer(config *Config) *Container {
 
 // initServices initializes all services with their dependencies
 func (c *Container) initServices() {
-	// Initialize with mock implementations for demonstration
+	// The HTTP client and formatter are still mocks
 	// TODO: These will be replaced with actual implementations later
 
 	// Initialize HTTP client
 	c.httpClient = newMockHTTPClient()
 
 	// Initialize asset parser
-	c.assetParser = newMockAssetParser()
+	c.assetParser = newAssetParser(&c.config.Parser)
 
 	// Initialize metrics collector
-	c.metrics = newMockMetricsCollector()
+	c.metrics = metrics.NewCollector()
 
 	// Initialize output formatter
 	c.formatter = newMockOutputFormatter()
@@ -53,12 +55,11 @@ func newMockHTTPClient() interfaces.HTTPClient {
 	return &mockHTTPClient{}
 }
 
-func newMockAssetParser() interfaces.AssetParser {
-	return &mockAssetParser{}
-}
-
-func newMockMetricsCollector() interfaces.MetricsCollector {
-	return &mockMetricsCollector{}
+// newAssetParser creates the asset parser described by cfg.
+// The method has already been checked by Config.Validate.
+func newAssetParser(cfg *ParserConfig) interfaces.AssetParser {
+	method, _ := interfaces.ParseParsingMethod(cfg.Method)
+	return httputils.NewAssetParser(method, cfg.Concurrent, cfg.RegexLimit)
 }
 
 func newMockOutputFormatter() interfaces.OutputFormatter {
@@ -89,54 +90,6 @@ func (c *mockHTTPClient) SetTimeout(_ time.Duration) {}
 func (c *mockHTTPClient) SetUserAgent(_ string)      {}
 func (c *mockHTTPClient) SetMaxConnections(_ int)    {}
 
-type mockAssetParser struct{}
-
-func (p *mockAssetParser) ParseAssets(_ string) (*interfaces.Assets, error) {
-	return &interfaces.Assets{Total: 0}, nil
-}
-func (p *mockAssetParser) ParseJS(_ string) ([]string, error)          { return []string{}, nil }
-func (p *mockAssetParser) ParseCSS(_ string) ([]string, error)         { return []string{}, nil }
-func (p *mockAssetParser) ParseImages(_ string) ([]string, error)      { return []string{}, nil }
-func (p *mockAssetParser) SetParsingMethod(_ interfaces.ParsingMethod) {}
-
-type mockMetricsCollector struct{}
-
-func (m *mockMetricsCollector) StartTest(config *interfaces.TestConfig) (*interfaces.TestSession, error) {
-	return &interfaces.TestSession{
-		ID:      "mock-session",
-		Config:  config,
-		Started: time.Now(),
-		Status:  interfaces.SessionStatusRunning,
-	}, nil
-}
-
-func (m *mockMetricsCollector) RecordRequest(_ *interfaces.TestSession, _ *interfaces.RequestResult) error {
-	return nil
-}
-
-func (m *mockMetricsCollector) GetStats(_ *interfaces.TestSession) (*interfaces.Statistics, error) {
-	return &interfaces.Statistics{
-		TotalRequests:   MockTotalRequests,
-		SuccessRequests: MockSuccessRequests,
-		AvgLatency:      MockAvgLatency * time.Millisecond,
-		Throughput:      MockThroughput,
-		TotalBytes:      MockTotalBytes,
-	}, nil
-}
-
-func (m *mockMetricsCollector) FinishTest(session *interfaces.TestSession) (*interfaces.TestReport, error) {
-	stats, _ := m.GetStats(session)
-	return &interfaces.TestReport{
-		Session:     session,
-		Stats:       stats,
-		Started:     session.Started,
-		Finished:    time.Now(),
-		ElapsedTime: time.Since(session.Started),
-	}, nil
-}
-
-func (m *mockMetricsCollector) Reset(_ *interfaces.TestSession) error { return nil }
-
 type mockOutputFormatter struct{}
 
 func (f *mockOutputFormatter) FormatJSON(_ interface{}) ([]byte, error) {
@@ -0,0 +1,44 @@
+// Package crawl discovers the pages of a site by following same-site links and reading
+// its sitemaps, within depth and page limits and the rules of its robots.txt. Every page
+// is loaded with its assets so the resulting URL list carries per-page weight and timing,
+// ready to be used as the target set of a weighted load test.
+package crawl
+
+const (
+	// RobotsPath specifies where a site publishes its robots.txt
+	RobotsPath = "/robots.txt"
+	// SitemapPath specifies the conventional sitemap location used when robots.txt lists none
+	SitemapPath = "/sitemap.xml"
+	// MaxSitemaps caps how many sitemap files, including nested sitemap indexes, are read
+	MaxSitemaps = 50 // Large sites split their sitemap, but not endlessly
+
+	// WWWPrefix is ignored when deciding whether a link stays on the same site
+	WWWPrefix = "www."
+	// HTMLContentType specifies the media type of pages whose links are followed
+	HTMLContentType = "text/html"
+	// XHTMLContentType specifies the XHTML media type, also treated as a page
+	XHTMLContentType = "application/xhtml+xml"
+	// ContentTypeHeader specifies the HTTP Content-Type header name
+	ContentTypeHeader = "Content-Type"
+
+	// JSONIndent specifies the indentation of the targets file
+	JSONIndent = "  " // 2 spaces for JSON formatting
+
+	// SourceStart marks the page the crawl started from
+	SourceStart = "start"
+	// SourceLink marks a page found through a link
+	SourceLink = "link"
+	// SourceSitemap marks a page listed in a sitemap
+	SourceSitemap = "sitemap"
+
+	// RobotsWildcardAgent specifies the robots.txt group that applies to every crawler
+	RobotsWildcardAgent = "*"
+	// RobotsUserAgentField specifies the robots.txt line starting a group
+	RobotsUserAgentField = "user-agent"
+	// RobotsAllowField specifies the robots.txt allow rule
+	RobotsAllowField = "allow"
+	// RobotsDisallowField specifies the robots.txt disallow rule
+	RobotsDisallowField = "disallow"
+	// RobotsSitemapField specifies the robots.txt sitemap line
+	RobotsSitemapField = "sitemap"
+)
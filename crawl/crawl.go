@@ -0,0 +1,355 @@
+package crawl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/httputils"
+	"github.com/Gosayram/goperf/request"
+)
+
+/*
+Options configures a crawl.
+
+Structure Overview
+  - URL - the page to start from; only pages on its site are crawled
+  - MaxDepth - how many links away from the start page or a sitemap entry to go
+  - MaxPages - the maximum number of pages to load
+  - Links - follow <a href> and <area href> links
+  - Sitemap - read the sitemaps listed in robots.txt, or /sitemap.xml
+  - Robots - obey robots.txt
+  - Workers - how many pages are loaded concurrently
+  - Input - the request settings for every page load (client, user agent, asset filters...)
+
+A link is on the same site when its host equals the start page's host, ignoring a
+leading "www.", over either http or https.
+*/
+type Options struct {
+	URL      string
+	MaxDepth int
+	MaxPages int
+	Links    bool
+	Sitemap  bool
+	Robots   bool
+	Workers  int
+	Input    request.FetchInput
+}
+
+/*
+Page is one crawled page.  Its JSON form doubles as a load test target.
+
+Structure Overview
+  - URL - the page as it was linked
+  - Weight - how many crawled pages link to it (at least 1), so popular pages get more traffic
+  - Depth - links followed from the start page or a sitemap entry
+  - Source - how the page was found: start, link or sitemap
+  - Status, ContentType - the response of the page itself
+  - Bytes, Requests - page weight: the document and all of its assets
+  - Time - how long the page and its assets took to load
+*/
+type Page struct {
+	URL         string        `json:"url"`
+	Weight      int           `json:"weight"`
+	Depth       int           `json:"depth"`
+	Source      string        `json:"source"`
+	Status      int           `json:"status"`
+	ContentType string        `json:"contentType,omitempty"`
+	Bytes       int           `json:"bytes"`
+	Requests    int           `json:"requests"`
+	Time        time.Duration `json:"time"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// Result is the outcome of a crawl
+type Result struct {
+	StartURL   string        `json:"startUrl"`
+	Pages      []Page        `json:"pages"`
+	Sitemaps   []string      `json:"sitemaps,omitempty"`
+	Disallowed []string      `json:"disallowed,omitempty"`
+	Time       time.Duration `json:"time"`
+}
+
+// queued is a page waiting to be loaded
+type queued struct {
+	url    string
+	depth  int
+	source string
+}
+
+// crawler holds the state of a single crawl
+type crawler struct {
+	opts    Options
+	site    string
+	robots  *Robots
+	seen    map[string]bool
+	inbound map[string]int
+	result  *Result
+}
+
+// Crawl discovers and loads the pages of the site at opts.URL
+func Crawl(ctx context.Context, opts Options) (*Result, error) {
+	start, err := url.Parse(opts.URL)
+	if err != nil || (start.Scheme != request.HTTPScheme && start.Scheme != request.HTTPSScheme) || start.Host == "" {
+		return nil, fmt.Errorf("crawl needs an absolute http or https url, got %q", opts.URL)
+	}
+	began := time.Now()
+	c := &crawler{
+		opts:    opts,
+		site:    siteOf(start),
+		robots:  AllowAll(),
+		seen:    make(map[string]bool),
+		inbound: make(map[string]int),
+		result:  &Result{StartURL: opts.URL},
+	}
+	if opts.Robots {
+		if c.robots, err = c.loadRobots(start); err != nil {
+			return nil, err
+		}
+	}
+
+	level := c.enqueue(nil, opts.URL, 0, SourceStart)
+	if opts.Sitemap {
+		for _, page := range c.readSitemaps(start) {
+			level = c.enqueue(level, page, 0, SourceSitemap)
+		}
+	}
+
+	for len(level) > 0 && len(c.result.Pages) < opts.MaxPages && ctx.Err() == nil {
+		level = level[:min(len(level), opts.MaxPages-len(c.result.Pages))]
+		var next []queued
+		for _, loaded := range c.loadLevel(ctx, level) {
+			c.result.Pages = append(c.result.Pages, loaded.page)
+			for _, link := range loaded.links {
+				c.inbound[link]++
+				if loaded.page.Depth < opts.MaxDepth {
+					next = c.enqueue(next, link, loaded.page.Depth+1, SourceLink)
+				}
+			}
+		}
+		level = next
+	}
+
+	for i := range c.result.Pages {
+		c.result.Pages[i].Weight = max(c.inbound[c.result.Pages[i].URL], 1)
+	}
+	c.result.Time = time.Since(began)
+	return c.result, ctx.Err()
+}
+
+// enqueue appends rawURL to level unless it was seen, is on another site or robots.txt disallows it
+func (c *crawler) enqueue(level []queued, rawURL string, depth int, source string) []queued {
+	if c.seen[rawURL] {
+		return level
+	}
+	c.seen[rawURL] = true
+	u, err := url.Parse(rawURL)
+	if err != nil || siteOf(u) != c.site {
+		return level
+	}
+	if !c.robots.Allowed(rawURL) {
+		c.result.Disallowed = append(c.result.Disallowed, rawURL)
+		return level
+	}
+	return append(level, queued{url: rawURL, depth: depth, source: source})
+}
+
+// loadedPage is a loaded page and the same-site links found on it
+type loadedPage struct {
+	page  Page
+	links []string
+}
+
+// loadLevel loads the pages of one crawl level with opts.Workers workers, keeping their order
+func (c *crawler) loadLevel(ctx context.Context, level []queued) []loadedPage {
+	loaded := make([]loadedPage, len(level))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(c.opts.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				loaded[i] = c.load(level[i])
+			}
+		}()
+	}
+	for i := range level {
+		if ctx.Err() != nil {
+			loaded = loaded[:i]
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return loaded
+}
+
+// load fetches a page with its assets and extracts its links
+func (c *crawler) load(q queued) loadedPage {
+	input := c.opts.Input
+	input.BaseURL = q.url
+	input.Retdat = true
+	resp := request.FetchAll(input)
+
+	base := resp.BaseURL
+	page := Page{
+		URL:         q.url,
+		Depth:       q.depth,
+		Source:      q.source,
+		Status:      base.Status,
+		ContentType: mediaType(base.Headers),
+		Bytes:       resp.TotalBytes,
+		Requests:    resp.TotalRequests,
+		Time:        resp.TotalTime,
+		Error:       base.Error,
+	}
+	if !page.IsHTML() || !c.opts.Links {
+		return loadedPage{page: page}
+	}
+
+	resolver, err := request.NewAssetResolver(base.FinalURL(), httputils.GetBaseHref(resp.Body))
+	if err != nil {
+		return loadedPage{page: page}
+	}
+	return loadedPage{page: page, links: resolver.Resolve(httputils.GetLinks(resp.Body))}
+}
+
+// loadRobots fetches and parses the site's robots.txt. A missing file allows everything;
+// an unreachable one stops the crawl, as RFC 9309 asks crawlers to assume a full disallow.
+func (c *crawler) loadRobots(start *url.URL) (*Robots, error) {
+	robotsURL := start.ResolveReference(&url.URL{Path: RobotsPath}).String()
+	input := c.opts.Input
+	input.BaseURL = robotsURL
+	input.Retdat = true
+	resp := request.Fetch(input)
+	switch {
+	case resp.Status >= http.StatusOK && resp.Status < http.StatusMultipleChoices:
+		return ParseRobots(resp.Body, input.UserAgent), nil
+	case resp.Status >= http.StatusBadRequest && resp.Status < http.StatusInternalServerError:
+		return AllowAll(), nil
+	default:
+		return nil, fmt.Errorf("%s is unreachable (status %d), so the whole site counts as disallowed; "+
+			"crawl without robots.txt to ignore it", robotsURL, resp.Status)
+	}
+}
+
+// readSitemaps returns the same-site page URLs of the site's sitemaps, following sitemap indexes
+func (c *crawler) readSitemaps(start *url.URL) []string {
+	queue := c.robots.Sitemaps
+	if len(queue) == 0 {
+		queue = []string{start.ResolveReference(&url.URL{Path: SitemapPath}).String()}
+	}
+	read := make(map[string]bool)
+	var pages []string
+	for len(queue) > 0 && len(read) < MaxSitemaps {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if read[sitemapURL] {
+			continue
+		}
+		read[sitemapURL] = true
+
+		input := c.opts.Input
+		input.BaseURL = sitemapURL
+		input.Retdat = true
+		resp := request.Fetch(input)
+		if resp.Status < http.StatusOK || resp.Status >= http.StatusMultipleChoices {
+			continue
+		}
+		found, nested, err := httputils.ParseSitemap([]byte(resp.Body))
+		if err != nil {
+			continue
+		}
+		c.result.Sitemaps = append(c.result.Sitemaps, sitemapURL)
+		pages = append(pages, found...)
+		queue = append(queue, nested...)
+	}
+	return pages
+}
+
+// IsHTML reports whether the page loaded successfully as an HTML document
+func (p *Page) IsHTML() bool {
+	return p.Error == "" && p.Status >= http.StatusOK && p.Status < http.StatusMultipleChoices &&
+		(p.ContentType == HTMLContentType || p.ContentType == XHTMLContentType)
+}
+
+// Targets returns the pages usable as load test targets: the ones that loaded as HTML
+func (r *Result) Targets() []Page {
+	targets := []Page{}
+	for _, page := range r.Pages {
+		if page.IsHTML() {
+			targets = append(targets, page)
+		}
+	}
+	return targets
+}
+
+// WriteTargets writes the load test targets of the crawl as a JSON array
+func WriteTargets(w io.Writer, r *Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", JSONIndent)
+	return enc.Encode(r.Targets())
+}
+
+// PrintResult prints the crawled pages and a summary to stdout
+func PrintResult(r *Result) {
+	yellow := color.New(color.FgHiYellow, color.Underline).SprintfFunc()
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	grey := color.New(color.FgHiBlack).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+	red := color.New(color.FgRed).SprintfFunc()
+
+	color.Red("Crawled Pages")
+	fmt.Printf(" - %-14s %-15s %-15s %-17s %-17s %-22s %s\n", yellow("Depth"), yellow("Status"), yellow("Weight"),
+		yellow("Bytes"), yellow("Requests"), yellow("Time"), yellow("Url"))
+	for i, page := range r.Pages {
+		paint := white
+		if i%2 != 0 {
+			paint = grey
+		}
+		status := paint(strconv.Itoa(page.Status))
+		if !page.IsHTML() {
+			status = red(strconv.Itoa(page.Status))
+		}
+		fmt.Printf(" - %-12s %-13s %-13s %-15s %-15s %-20s %s\n", paint(strconv.Itoa(page.Depth)), status,
+			paint(strconv.Itoa(page.Weight)), paint(strconv.Itoa(page.Bytes)), paint(strconv.Itoa(page.Requests)),
+			paint(page.Time.String()), paint(page.URL))
+	}
+
+	color.Red("Crawl Summary")
+	fmt.Printf(" - %-34s %s\n", yel("Start Url"), r.StartURL)
+	fmt.Printf(" - %-34s %d (%d usable as targets)\n", yel("Pages"), len(r.Pages), len(r.Targets()))
+	fmt.Printf(" - %-34s %d\n", yel("Sitemaps Read"), len(r.Sitemaps))
+	fmt.Printf(" - %-34s %d\n", yel("Disallowed by robots.txt"), len(r.Disallowed))
+	fmt.Printf(" - %-34s %s\n", yel("Total Time"), r.Time)
+}
+
+// siteOf returns the host name of u without a leading www.
+func siteOf(u *url.URL) string {
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), WWWPrefix)
+}
+
+// mediaType returns the media type of the Content-Type header, without parameters
+func mediaType(headers map[string][]string) string {
+	values := http.Header(headers).Values(ContentTypeHeader)
+	if len(values) == 0 {
+		return ""
+	}
+	mt, _, err := mime.ParseMediaType(values[0])
+	if err != nil {
+		return ""
+	}
+	return mt
+}
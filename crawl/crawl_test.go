@@ -0,0 +1,146 @@
+package crawl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/request"
+)
+
+// testSite serves a small site: the home page links two sections, one of which links
+// an article; a page only listed in the sitemap; a page hidden by robots.txt; and
+// links that must not be followed.
+func testSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	pages := map[string]string{
+		"/": `<a href="/a">A</a> <a href="b">B</a> <a href="/a#top">A again</a>
+<a href="/private/x">private</a> <a href="https://other.example/">off site</a>
+<a href="/c" rel="nofollow">no follow</a> <a href="mailto:me@example.com">mail</a>
+<img src="/logo.png">`,
+		"/a":               `<a href="/">home</a> <a href="/a/article">article</a>`,
+		"/b":               `<a href="/">home</a> <a href="/a">A</a>`,
+		"/a/article":       `<a href="/">home</a> <a href="/deep">deep</a>`,
+		"/deep":            `deep`,
+		"/c":               `nofollow target`,
+		"/only-in-sitemap": `<a href="/">home</a>`,
+	}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private/\nSitemap: " + server.URL + "/sitemap.xml\n"))
+		case "/sitemap.xml":
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>` + server.URL + `/only-in-sitemap</loc></url>
+<url><loc>https://other.example/elsewhere</loc></url>
+</urlset>`))
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png"))
+		default:
+			body, ok := pages[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(body))
+		}
+	}))
+	return server
+}
+
+func crawlPaths(result *Result, base string) []string {
+	paths := []string{}
+	for _, page := range result.Pages {
+		paths = append(paths, strings.TrimPrefix(page.URL, base))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestCrawl(t *testing.T) {
+	color.Green("~~ TestCrawl ~~")
+	server := testSite(t)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"links only", Options{MaxDepth: 1, MaxPages: 100, Links: true}, []string{"/", "/a", "/b", "/private/x"}},
+		{"robots", Options{MaxDepth: 1, MaxPages: 100, Links: true, Robots: true}, []string{"/", "/a", "/b"}},
+		{"depth", Options{MaxDepth: 3, MaxPages: 100, Links: true, Robots: true},
+			[]string{"/", "/a", "/a/article", "/b", "/deep"}},
+		{"sitemap only", Options{MaxPages: 100, Sitemap: true, Robots: true}, []string{"/", "/only-in-sitemap"}},
+		{"page limit", Options{MaxDepth: 3, MaxPages: 2, Links: true}, []string{"/", "/a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.URL = server.URL + "/"
+			tt.opts.Workers = 2
+			tt.opts.Input = request.FetchInput{UserAgent: "goperf"}
+			result, err := Crawl(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := crawlPaths(result, server.URL); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("crawled %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawlPageStats(t *testing.T) {
+	server := testSite(t)
+	defer server.Close()
+
+	result, err := Crawl(context.Background(), Options{
+		URL: server.URL + "/", MaxDepth: 2, MaxPages: 100, Links: true, Robots: true, Workers: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := map[string]Page{}
+	for _, page := range result.Pages {
+		pages[strings.TrimPrefix(page.URL, server.URL)] = page
+	}
+
+	home := pages["/"]
+	if home.Source != SourceStart || home.Depth != 0 || home.Requests != 2 || home.Bytes == 0 || home.Time <= 0 {
+		t.Errorf("home page = %+v, want start page with its logo loaded", home)
+	}
+	// Linked from /a, /b and /a/article
+	if home.Weight != 3 {
+		t.Errorf("home weight = %d, want 3", home.Weight)
+	}
+	// Linked from / and /b, the duplicate #top link counts once
+	if a := pages["/a"]; a.Weight != 2 || a.Depth != 1 || a.Source != SourceLink {
+		t.Errorf("/a = %+v, want weight 2 at depth 1", a)
+	}
+	if article := pages["/a/article"]; article.Weight != 1 || article.Depth != 2 {
+		t.Errorf("/a/article = %+v, want weight 1 at depth 2", article)
+	}
+	if len(result.Disallowed) != 1 || !strings.HasSuffix(result.Disallowed[0], "/private/x") {
+		t.Errorf("Disallowed = %v, want /private/x", result.Disallowed)
+	}
+	if len(result.Targets()) != len(result.Pages) {
+		t.Errorf("every crawled page is HTML, got %d targets for %d pages", len(result.Targets()), len(result.Pages))
+	}
+}
+
+func TestCrawlInvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"", "example.com", "ftp://example.com/"} {
+		if _, err := Crawl(context.Background(), Options{URL: rawURL, MaxPages: 1, Links: true}); err == nil {
+			t.Errorf("Crawl(%q) should fail", rawURL)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package crawl
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+/*
+Robots holds the robots.txt rules that apply to one crawler, following RFC 9309:
+  - the groups whose user-agent occurs in the crawler's User-Agent apply, or the * groups if none does
+  - the longest matching allow or disallow rule decides and allow wins a tie
+  - * in a rule matches any run of characters and a trailing $ anchors the end of the URL
+
+Sitemaps lists the sitemap URLs of the file, which are independent of the groups.
+*/
+type Robots struct {
+	rules    []robotsRule
+	Sitemaps []string
+}
+
+// robotsRule is a compiled allow or disallow line
+type robotsRule struct {
+	allow  bool
+	length int
+	re     *regexp.Regexp
+}
+
+// robotsGroup is a set of user-agents and the rules that follow them
+type robotsGroup struct {
+	agents []string
+	rules  []robotsRule
+}
+
+// AllowAll returns rules that allow every URL, used when a site has no robots.txt
+func AllowAll() *Robots {
+	return &Robots{}
+}
+
+// ParseRobots parses the robots.txt in body for a crawler sending userAgent
+func ParseRobots(body, userAgent string) *Robots {
+	robots := &Robots{}
+	var groups []*robotsGroup
+	var current *robotsGroup
+	inAgents := false
+	for _, line := range strings.Split(body, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case RobotsUserAgentField:
+			if !inAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+		case RobotsAllowField, RobotsDisallowField:
+			inAgents = false
+			if current != nil && value != "" {
+				current.rules = append(current.rules, newRobotsRule(field == RobotsAllowField, value))
+			}
+		case RobotsSitemapField:
+			if value != "" {
+				robots.Sitemaps = append(robots.Sitemaps, value)
+			}
+		}
+	}
+
+	agent := strings.ToLower(userAgent)
+	for _, wildcard := range []bool{false, true} {
+		matched := false
+		for _, group := range groups {
+			if group.matches(agent, wildcard) {
+				robots.rules = append(robots.rules, group.rules...)
+				matched = true
+			}
+		}
+		if matched {
+			break
+		}
+	}
+	return robots
+}
+
+// matches reports whether the group names agent, or only when wildcard is set, whether it is a * group
+func (g *robotsGroup) matches(agent string, wildcard bool) bool {
+	for _, name := range g.agents {
+		if wildcard && name == RobotsWildcardAgent {
+			return true
+		}
+		if !wildcard && name != RobotsWildcardAgent && name != "" && strings.Contains(agent, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// newRobotsRule compiles a robots.txt path pattern
+func newRobotsRule(allow bool, pattern string) robotsRule {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	if strings.HasSuffix(expr, `\$`) {
+		expr = strings.TrimSuffix(expr, `\$`) + "$"
+	}
+	return robotsRule{allow: allow, length: len(pattern), re: regexp.MustCompile("^" + expr)}
+}
+
+// Allowed reports whether the crawler may fetch rawURL
+func (r *Robots) Allowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		if rule.length > longest || rule.length == longest && rule.allow {
+			allowed, longest = rule.allow, rule.length
+		}
+	}
+	return allowed
+}
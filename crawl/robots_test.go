@@ -0,0 +1,71 @@
+package crawl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+const testRobots = `# comment
+User-agent: Googlebot
+Disallow: /
+
+User-agent: goperf
+User-agent: otherbot
+Disallow: /private/
+Allow: /private/public-*
+Disallow: /*.pdf$
+Disallow: /search?q=
+
+User-agent: *
+Disallow: /admin   # trailing comment
+
+Sitemap: https://example.com/sitemap_index.xml
+`
+
+func TestRobotsAllowed(t *testing.T) {
+	color.Green("~~ TestRobotsAllowed ~~")
+	tests := []struct {
+		name  string
+		agent string
+		url   string
+		want  bool
+	}{
+		{"no rule", "goperf/1.0", "https://example.com/", true},
+		{"disallowed dir", "goperf/1.0", "https://example.com/private/a.html", false},
+		{"longer allow wins", "goperf/1.0", "https://example.com/private/public-a.html", true},
+		{"end anchor", "goperf/1.0", "https://example.com/docs/a.pdf", false},
+		{"end anchor no match", "goperf/1.0", "https://example.com/docs/a.pdf?x=1", true},
+		{"query", "goperf/1.0", "https://example.com/search?q=go", false},
+		{"agent match ignores case", "Mozilla/5.0 (compatible; GoPerf)", "https://example.com/private/", false},
+		{"specific group replaces wildcard", "goperf", "https://example.com/admin", true},
+		{"wildcard group", "curl/8.0", "https://example.com/admin/users", false},
+		{"wildcard group allows the rest", "curl/8.0", "https://example.com/private/", true},
+		{"disallow all", "Googlebot/2.1", "https://example.com/anything", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			robots := ParseRobots(testRobots, tt.agent)
+			if got := robots.Allowed(tt.url); got != tt.want {
+				t.Errorf("Allowed(%s) for %q = %t, want %t", tt.url, tt.agent, got, tt.want)
+			}
+		})
+	}
+
+	robots := ParseRobots(testRobots, "goperf")
+	if want := []string{"https://example.com/sitemap_index.xml"}; !reflect.DeepEqual(robots.Sitemaps, want) {
+		t.Errorf("Sitemaps = %v, want %v", robots.Sitemaps, want)
+	}
+}
+
+func TestRobotsEmptyDisallow(t *testing.T) {
+	// An empty Disallow in the crawler's own group allows everything, even when * is stricter
+	robots := ParseRobots("User-agent: goperf\nDisallow:\n\nUser-agent: *\nDisallow: /\n", "goperf")
+	if !robots.Allowed("https://example.com/page") {
+		t.Error("empty Disallow should allow every url")
+	}
+	if !AllowAll().Allowed("https://example.com/page") {
+		t.Error("AllowAll should allow every url")
+	}
+}
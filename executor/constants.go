@@ -0,0 +1,4 @@
+// Package executor holds what the executors of the long-lived protocols (WebSocket, streaming
+// and gRPC) share: running their virtual users for the duration of a test, recording results
+// in the metrics collector and summarizing the measurements of every user.
+package executor
@@ -0,0 +1,87 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
+)
+
+/*
+Run runs users virtual users, at least one, for duration or until ctx is cancelled.  Every
+user runs user with its number and its own stats, and must return once its context is done;
+Run returns the stats of every user once they all have, and how long the test took.
+*/
+func Run[S any](ctx context.Context, users int, duration time.Duration,
+	user func(ctx context.Context, i int, stats *S)) ([]S, time.Duration, error) {
+	if duration <= 0 {
+		return nil, 0, fmt.Errorf("the test duration must be positive")
+	}
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	start := time.Now()
+	stats := make([]S, max(users, 1))
+	var wg sync.WaitGroup
+	for i := range stats {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user(ctx, i, &stats[i])
+		}()
+	}
+	wg.Wait()
+	return stats, time.Since(start), nil
+}
+
+// Recorder passes the results of a test on to a metrics collector
+type Recorder struct {
+	Collector interfaces.MetricsCollector
+	Session   *interfaces.TestSession
+}
+
+// Record records result in the session of the collector, if there is one
+func (r Recorder) Record(result *interfaces.RequestResult) {
+	if r.Collector != nil && r.Session != nil {
+		_ = r.Collector.RecordRequest(r.Session, result)
+	}
+}
+
+// Summary is the part every executor's result starts with
+type Summary struct {
+	URL       string        `json:"url"`
+	Users     int           `json:"users"`
+	Duration  time.Duration `json:"duration"`
+	LastError string        `json:"lastError,omitempty"`
+}
+
+// NewSummary returns the summary of a test of url by users that took elapsed, with the last
+// error any of them met
+func NewSummary[S any](url string, users []S, elapsed time.Duration, lastError func(*S) string) Summary {
+	summary := Summary{URL: url, Users: len(users), Duration: elapsed}
+	for i := range users {
+		if err := lastError(&users[i]); err != "" {
+			summary.LastError = err
+		}
+	}
+	return summary
+}
+
+// PerSecond returns the rate of n events over the test, 0 when it took no time
+func (s Summary) PerSecond(n int) float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(n) / s.Duration.Seconds()
+}
+
+// Latency returns the latency of the durations measured by every user
+func Latency[S any](users []S, durations func(*S) []time.Duration) metrics.Latency {
+	var merged []time.Duration
+	for i := range users {
+		merged = append(merged, durations(&users[i])...)
+	}
+	return metrics.Summarize(merged)
+}
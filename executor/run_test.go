@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
+)
+
+// userStats are the measurements of a test user
+type userStats struct {
+	id        int
+	waits     []time.Duration
+	lastError string
+}
+
+func TestRun(t *testing.T) {
+	color.Green("~~ TestRun ~~")
+	users, elapsed, err := Run(context.Background(), 3, 50*time.Millisecond,
+		func(ctx context.Context, i int, stats *userStats) {
+			stats.id = i
+			start := time.Now()
+			<-ctx.Done()
+			stats.waits = append(stats.waits, time.Since(start))
+			if i == 2 {
+				stats.lastError = "user 2 failed"
+			}
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 || users[2].id != 2 || elapsed < 50*time.Millisecond {
+		t.Fatalf("Run = %+v after %s", users, elapsed)
+	}
+
+	summary := NewSummary("http://test", users, 2*time.Second, func(u *userStats) string { return u.lastError })
+	if summary.Users != 3 || summary.LastError != "user 2 failed" || summary.PerSecond(10) != 5 {
+		t.Errorf("summary %+v", summary)
+	}
+	if (Summary{}).PerSecond(10) != 0 {
+		t.Error("a test that took no time has no rate")
+	}
+	latency := Latency(users, func(u *userStats) []time.Duration { return u.waits })
+	if latency.Count != 3 || latency.P50 < 40*time.Millisecond {
+		t.Errorf("latency %+v", latency)
+	}
+
+	// A cancelled context ends the test at once; a test has at least one user
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	users, _, err = Run(ctx, 0, time.Hour, func(ctx context.Context, _ int, _ *userStats) { <-ctx.Done() })
+	if err != nil || len(users) != 1 {
+		t.Errorf("Run with no users = %d users, %v", len(users), err)
+	}
+	if _, _, err = Run(ctx, 1, 0, func(context.Context, int, *userStats) {}); err == nil {
+		t.Error("Run should fail without a duration")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	collector := metrics.NewCollector()
+	session, _ := collector.StartTest(&interfaces.TestConfig{Target: &interfaces.Request{URL: "http://test"}})
+	result := &interfaces.RequestResult{URL: "http://test", Duration: time.Millisecond, Timestamp: time.Now(),
+		ErrorMessage: "refused"}
+
+	// Without a collector or a session nothing is recorded
+	Recorder{}.Record(result)
+	Recorder{Collector: collector}.Record(result)
+	Recorder{Collector: collector, Session: session}.Record(result)
+	report, err := collector.FinishTest(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Stats.TotalRequests != 1 || report.Stats.FailedRequests != 1 {
+		t.Errorf("recorded %+v", report.Stats)
+	}
+}
@@ -0,0 +1,161 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/encoding/gzip" // accept gzip-compressed responses
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Gosayram/goperf/request"
+)
+
+// codeNames are the names of the status codes, as gRPC tools print them
+var codeNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND",
+	"ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION", "ABORTED",
+	"OUT_OF_RANGE", "UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// Status is the outcome of a call
+type Status struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message,omitempty"`
+}
+
+// CodeName returns the name of the status code, e.g. "NOT_FOUND"
+func (s Status) CodeName() string {
+	if int(s.Code) < len(codeNames) {
+		return codeNames[s.Code]
+	}
+	return "CODE_" + strconv.Itoa(int(s.Code))
+}
+
+// Err returns the status as an error, or nil when the call succeeded
+func (s Status) Err() error {
+	if s.Code == codes.OK {
+		return nil
+	}
+	if s.Message == "" {
+		return errors.New(s.CodeName())
+	}
+	return fmt.Errorf("%s: %s", s.CodeName(), s.Message)
+}
+
+// Client calls the methods of one server
+type Client struct {
+	conn     *grpc.ClientConn
+	metadata metadata.MD
+}
+
+/*
+NewClient returns a client of the server at target, an http:// url for HTTP/2 over
+cleartext (h2c) or an https:// one for HTTP/2 over TLS.  Connections follow the TLS, Dial,
+Resolve and Proxy options of transport; header holds the metadata sent with every call,
+its User-Agent replacing the one of the client.  The connection is opened by the first call.
+*/
+func NewClient(target string, transport request.TransportConfig, header http.Header) (*Client, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid gRPC server url %q (expected http://host:port or https://host:port)", target)
+	}
+	var creds credentials.TransportCredentials
+	switch u.Scheme {
+	case request.HTTPScheme:
+		creds = insecure.NewCredentials()
+	case request.HTTPSScheme:
+		config := &tls.Config{}
+		if transport.TLS != nil {
+			config = transport.TLS.Clone()
+		}
+		creds = credentials.NewTLS(config)
+	default:
+		return nil, fmt.Errorf("invalid gRPC server url %q (expected http://host:port or https://host:port)", target)
+	}
+
+	options := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(request.NewDialer(transport, u.Scheme)),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(MaxMessageSize)),
+	}
+	md := metadata.MD{}
+	for name, values := range header {
+		if http.CanonicalHeaderKey(name) == UserAgentHeader {
+			options = append(options, grpc.WithUserAgent(header.Get(name)))
+			continue
+		}
+		md.Append(name, values...)
+	}
+	// The passthrough resolver hands the host to the dialer, which resolves it
+	conn, err := grpc.NewClient("passthrough:///"+u.Host, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, metadata: md}, nil
+}
+
+// Close closes the connection of the client
+func (c *Client) Close() {
+	_ = c.conn.Close()
+}
+
+/*
+Call calls method with the given request messages, which are all sent before the server
+answers, and passes every response message to receive.  The outcome of the call is its
+status; failures to connect or to read the response map to UNAVAILABLE, DEADLINE_EXCEEDED
+or another code like in every gRPC client.
+*/
+func (c *Client) Call(ctx context.Context, method *Method, requests []proto.Message,
+	receive func(proto.Message)) Status {
+	ctx = metadata.NewOutgoingContext(ctx, c.metadata)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	desc := &grpc.StreamDesc{ServerStreams: method.ServerStreaming, ClientStreams: method.ClientStreaming}
+	stream, err := c.conn.NewStream(ctx, desc, method.Path)
+	if err != nil {
+		return errorStatus(err)
+	}
+	for _, message := range requests {
+		if err = stream.SendMsg(message); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	// A failed send is followed by the status of the call
+	for {
+		response := method.Response()
+		if err = stream.RecvMsg(response); err != nil {
+			break
+		}
+		if receive != nil {
+			receive(response)
+		}
+		if !method.ServerStreaming {
+			return Status{Code: codes.OK}
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		return Status{Code: codes.OK}
+	}
+	return errorStatus(err)
+}
+
+// errorStatus returns the status of a call that failed with err
+func errorStatus(err error) Status {
+	s := status.Convert(err)
+	return Status{Code: s.Code(), Message: s.Message()}
+}
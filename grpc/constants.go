@@ -0,0 +1,31 @@
+/*
+Package grpc load tests gRPC services without generated code.  Service definitions come from
+.proto files or from the server reflection service; requests are written as JSON and sent with
+grpc-go over HTTP/2 (h2c for http:// urls, TLS for https:// ones).
+
+Unary and server-streaming methods are supported.  Messages use the canonical protobuf JSON
+mapping, so well-known types take their JSON form, e.g. "2023-11-14T22:13:20Z" for a
+google.protobuf.Timestamp.  Responses may be gzip-compressed.
+*/
+package grpc
+
+import "time"
+
+const (
+	// UserAgentHeader is the metadata replacing the user agent of the client
+	UserAgentHeader = "User-Agent"
+	// MaxMessageSize bounds the size of a received message
+	MaxMessageSize = 64 << 20 // 64 MiB
+
+	// ReflectionPath is the method of the server reflection service
+	ReflectionPath = "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+	// ReflectionAlphaPath is the method of the server reflection service before it was stable
+	ReflectionAlphaPath = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+	// ReflectionService prefixes the reflection services, which are not listed as methods to test
+	ReflectionService = "grpc.reflection."
+
+	// ResultType marks the calls recorded in the metrics collector
+	ResultType = "grpc"
+	// DefaultTimeout is the deadline of a call when Options.Timeout is not set
+	DefaultTimeout = 10 * time.Second
+)
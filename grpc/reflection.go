@@ -0,0 +1,152 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+/*
+Reflect loads the services of the server, and every file they import, from its server
+reflection service (grpc.reflection.v1, or v1alpha for older servers).  The reflection
+service itself is left out, and a file the server cannot provide is an error.
+*/
+func Reflect(ctx context.Context, client *Client) (*Schema, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r, err := openReflection(ctx, client, ReflectionPath)
+	var listed *reflectionpb.ServerReflectionResponse
+	if err == nil {
+		listed, err = r.ask(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		})
+	}
+	// The two versions of the service share their messages
+	if errorStatus(err).Code == codes.Unimplemented {
+		if r, err = openReflection(ctx, client, ReflectionAlphaPath); err == nil {
+			listed, err = r.ask(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+			})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("server reflection failed: %w", err)
+	}
+
+	files := map[string]*descriptorpb.FileDescriptorProto{}
+	for _, service := range listed.GetListServicesResponse().GetService() {
+		if strings.HasPrefix(service.GetName(), ReflectionService) {
+			continue
+		}
+		if err = r.files(files, &reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: service.GetName(),
+			},
+		}); err != nil {
+			return nil, fmt.Errorf("server reflection failed: %w", err)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("server reflection lists no services")
+	}
+
+	// Servers may leave out the files they already sent on the stream or well-known ones
+	for missing := missingImports(files); len(missing) > 0; missing = missingImports(files) {
+		for _, name := range missing {
+			if err = r.files(files, &reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+			}); err != nil {
+				return nil, fmt.Errorf("server reflection failed for %s: %w", name, err)
+			}
+			if files[name] == nil {
+				return nil, fmt.Errorf("server reflection did not send %s", name)
+			}
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, file := range files {
+		set.File = append(set.File, file)
+	}
+	registry, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file descriptors: %w", err)
+	}
+	return newSchema(registry), nil
+}
+
+// reflectionStream is a stream to the server reflection service
+type reflectionStream struct {
+	stream grpc.ClientStream
+}
+
+// openReflection opens a stream to the reflection service at path
+func openReflection(ctx context.Context, client *Client, path string) (*reflectionStream, error) {
+	desc := &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}
+	stream, err := client.conn.NewStream(ctx, desc, path)
+	if err != nil {
+		return nil, err
+	}
+	return &reflectionStream{stream: stream}, nil
+}
+
+// ask sends req and returns the response of the server; an error response is an error
+func (r *reflectionStream) ask(
+	req *reflectionpb.ServerReflectionRequest) (*reflectionpb.ServerReflectionResponse, error) {
+	if err := r.stream.SendMsg(req); err != nil {
+		// The status of the stream tells why the send failed
+		response := &reflectionpb.ServerReflectionResponse{}
+		if err = r.stream.RecvMsg(response); err == nil {
+			err = errors.New("reflection stream closed")
+		}
+		return nil, err
+	}
+	response := &reflectionpb.ServerReflectionResponse{}
+	if err := r.stream.RecvMsg(response); err != nil {
+		return nil, err
+	}
+	if failure := response.GetErrorResponse(); failure != nil {
+		code := Status{Code: codes.Code(failure.GetErrorCode())}.CodeName()
+		return nil, fmt.Errorf("%s (%s)", failure.GetErrorMessage(), code)
+	}
+	return response, nil
+}
+
+// files asks for the files of req and adds them to files by name
+func (r *reflectionStream) files(files map[string]*descriptorpb.FileDescriptorProto,
+	req *reflectionpb.ServerReflectionRequest) error {
+	response, err := r.ask(req)
+	if err != nil {
+		return err
+	}
+	for _, data := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		file := &descriptorpb.FileDescriptorProto{}
+		if err = proto.Unmarshal(data, file); err != nil {
+			return fmt.Errorf("invalid file descriptor: %w", err)
+		}
+		files[file.GetName()] = file
+	}
+	return nil
+}
+
+// missingImports returns the files imported by files that are not among them
+func missingImports(files map[string]*descriptorpb.FileDescriptorProto) []string {
+	var missing []string
+	for _, file := range files {
+		for _, dependency := range file.GetDependency() {
+			if files[dependency] == nil && !slices.Contains(missing, dependency) {
+				missing = append(missing, dependency)
+			}
+		}
+	}
+	return missing
+}
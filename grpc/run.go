@@ -0,0 +1,322 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gnulnx/color"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Gosayram/goperf/executor"
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
+	"github.com/Gosayram/goperf/perf"
+	"github.com/Gosayram/goperf/request"
+)
+
+// Call is a method called by the virtual users with its JSON request
+type Call struct {
+	Method string `json:"method"`
+	Data   string `json:"data,omitempty"`
+}
+
+// ParseCall parses a "package.Service/Method [JSON request]" call spec
+func ParseCall(spec string) (Call, error) {
+	method, data, _ := strings.Cut(strings.TrimSpace(spec), " ")
+	if method == "" {
+		return Call{}, fmt.Errorf("empty gRPC call")
+	}
+	return Call{Method: method, Data: strings.TrimSpace(data)}, nil
+}
+
+/*
+Options configures a gRPC load test.
+
+Structure Overview
+  - URL - the server: http://host:port for h2c, https://host:port for TLS
+  - Schema - the services, from LoadProtos or Reflect
+  - Calls - the calls every user makes in turn; the {{name}} references of their JSON
+    requests are expanded from Variables for every call
+  - Users, Duration - how many users call the server and for how long
+  - Timeout - the deadline of every call (DefaultTimeout when 0)
+  - Metadata - sent with every call
+  - Transport, SharedTransport - connection pooling, one pool per user unless shared
+  - Collector, Session - when set every call is also recorded there
+*/
+type Options struct {
+	URL             string
+	Schema          *Schema
+	Calls           []Call
+	Users           int
+	Duration        time.Duration
+	Timeout         time.Duration
+	Metadata        http.Header
+	Transport       request.TransportConfig
+	SharedTransport bool
+	Variables       perf.Variables
+	Collector       interfaces.MetricsCollector
+	Session         *interfaces.TestSession
+}
+
+/*
+MethodResult summarizes the calls of one method.
+
+Structure Overview
+  - Calls, Failed - calls made and calls whose status was not OK
+  - Messages - response messages received, more than Calls for server-streaming methods
+  - Latency - from sending the request to receiving the status
+  - Codes - calls by status code name, e.g. {"OK": 120, "UNAVAILABLE": 3}
+*/
+type MethodResult struct {
+	Method        string          `json:"method"`
+	Calls         int             `json:"calls"`
+	Failed        int             `json:"failed"`
+	Messages      int             `json:"messages"`
+	Latency       metrics.Latency `json:"latency"`
+	Codes         map[string]int  `json:"codes"`
+	BytesSent     int             `json:"bytesSent"`
+	BytesReceived int             `json:"bytesReceived"`
+	LastError     string          `json:"lastError,omitempty"`
+}
+
+// Result summarizes a gRPC load test; LastError is the last failure of any method
+type Result struct {
+	executor.Summary
+	Calls          int            `json:"calls"`
+	Failed         int            `json:"failed"`
+	CallsPerSecond float64        `json:"callsPerSecond"`
+	Methods        []MethodResult `json:"methods"`
+}
+
+// userStats are the calls of one user, by method
+type userStats struct {
+	methods   map[string]*methodStats
+	lastError string
+}
+
+// methodStats are the calls of one method by one user
+type methodStats struct {
+	durations     []time.Duration
+	codes         map[string]int
+	failed        int
+	messages      int
+	bytesSent     int
+	bytesReceived int
+	lastError     string
+}
+
+// plannedCall is a call with its resolved method
+type plannedCall struct {
+	Call
+	method *Method
+}
+
+// Run makes opts.Calls with opts.Users users for opts.Duration, or until ctx is cancelled,
+// and returns their results by method.  Calls in flight at the end are completed.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	calls, err := plan(&opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Variables == nil {
+		opts.Variables = perf.Variables{}
+	}
+	shared, err := NewClient(opts.URL, opts.Transport, opts.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	defer shared.Close()
+
+	// Every user starts with a different call and, unless the transport is shared, a client of its own;
+	// a user whose client cannot be created reports why and calls on the shared one
+	recorder := executor.Recorder{Collector: opts.Collector, Session: opts.Session}
+	user := func(ctx context.Context, i int, stats *userStats) {
+		client := shared
+		if !opts.SharedTransport && i > 0 {
+			if own, err := NewClient(opts.URL, opts.Transport, opts.Metadata); err != nil {
+				stats.lastError = err.Error()
+			} else {
+				client = own
+				defer client.Close()
+			}
+		}
+		stats.methods = map[string]*methodStats{}
+		for seq := i; ctx.Err() == nil; seq++ {
+			recorder.Record(call(ctx, client, &opts, &calls[seq%len(calls)], stats))
+		}
+	}
+	users, elapsed, err := executor.Run(ctx, opts.Users, opts.Duration, user)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(&opts, calls, users, elapsed), nil
+}
+
+// plan checks the options and resolves the method of every call, checking that its
+// request encodes
+func plan(opts *Options) ([]plannedCall, error) {
+	if opts.Schema == nil || len(opts.Calls) == 0 {
+		return nil, fmt.Errorf("a gRPC test needs a schema and at least one call")
+	}
+	calls := make([]plannedCall, 0, len(opts.Calls))
+	for _, c := range opts.Calls {
+		method, err := opts.Schema.Method(c.Method)
+		if err != nil {
+			return nil, err
+		}
+		if method.ClientStreaming {
+			return nil, fmt.Errorf("%s is a client-streaming method; only unary and server-streaming ones are supported",
+				method.Name)
+		}
+		if _, err = method.Request(opts.Variables.Expand(c.Data)); err != nil {
+			return nil, fmt.Errorf("request of %s: %w", method.Name, err)
+		}
+		calls = append(calls, plannedCall{Call: c, method: method})
+	}
+	return calls, nil
+}
+
+// call makes one call, adds its outcome to the stats of its method and returns it
+func call(ctx context.Context, client *Client, opts *Options, c *plannedCall,
+	user *userStats) *interfaces.RequestResult {
+	stats := user.methods[c.method.Name]
+	if stats == nil {
+		stats = &methodStats{codes: map[string]int{}}
+		user.methods[c.method.Name] = stats
+	}
+
+	start := time.Now()
+	var status Status
+	sent, messages, received := 0, 0, 0
+	message, err := c.method.Request(opts.Variables.Expand(c.Data))
+	if err != nil {
+		status = Status{Code: codes.InvalidArgument, Message: err.Error()}
+	} else {
+		sent = proto.Size(message)
+		callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), opts.Timeout)
+		status = client.Call(callCtx, c.method, []proto.Message{message}, func(response proto.Message) {
+			messages++
+			received += proto.Size(response)
+		})
+		cancel()
+	}
+	elapsed := time.Since(start)
+
+	stats.durations = append(stats.durations, elapsed)
+	stats.codes[status.CodeName()]++
+	stats.messages += messages
+	stats.bytesSent += sent
+	stats.bytesReceived += received
+	result := &interfaces.RequestResult{
+		URL: c.method.Name, Type: ResultType, StatusCode: int(status.Code), Duration: elapsed,
+		Size: received, Success: status.Code == codes.OK, Timestamp: start,
+	}
+	if err = status.Err(); err != nil {
+		stats.failed++
+		stats.lastError = err.Error()
+		user.lastError = stats.lastError
+		result.ErrorMessage = stats.lastError
+	}
+	return result
+}
+
+// newResult merges the stats of every user by method, in the order of the calls
+func newResult(opts *Options, calls []plannedCall, users []userStats, elapsed time.Duration) *Result {
+	result := &Result{Summary: executor.NewSummary(opts.URL, users, elapsed, func(u *userStats) string {
+		return u.lastError
+	})}
+	for _, c := range calls {
+		if slices.ContainsFunc(result.Methods, func(m MethodResult) bool { return m.Method == c.method.Name }) {
+			continue
+		}
+		method := MethodResult{Method: c.method.Name, Codes: map[string]int{}}
+		for _, user := range users {
+			stats := user.methods[c.method.Name]
+			if stats == nil {
+				continue
+			}
+			for code, n := range stats.codes {
+				method.Codes[code] += n
+			}
+			method.Failed += stats.failed
+			method.Messages += stats.messages
+			method.BytesSent += stats.bytesSent
+			method.BytesReceived += stats.bytesReceived
+			if stats.lastError != "" {
+				method.LastError = stats.lastError
+			}
+		}
+		method.Latency = executor.Latency(users, func(u *userStats) []time.Duration {
+			if stats := u.methods[c.method.Name]; stats != nil {
+				return stats.durations
+			}
+			return nil
+		})
+		method.Calls = method.Latency.Count
+		result.Calls += method.Calls
+		result.Failed += method.Failed
+		result.Methods = append(result.Methods, method)
+	}
+	result.CallsPerSecond = result.PerSecond(result.Calls)
+	return result
+}
+
+// PrintResult prints the results of a gRPC load test to stdout
+func PrintResult(result *Result) {
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	yellow := color.New(color.FgHiYellow, color.Underline).SprintfFunc()
+	grey := color.New(color.FgHiBlack).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("gRPC Results")
+	fmt.Printf(" - %-34s %s\n", yel("Url"), white(result.URL))
+	fmt.Printf(" - %-34s %s\n", yel("Users / Duration"), white("%d / %s", result.Users,
+		result.Duration.Round(time.Millisecond)))
+	fmt.Printf(" - %-34s %s\n", yel("Calls"), white("%d (%d failed), %.1f per second", result.Calls, result.Failed,
+		result.CallsPerSecond))
+	fmt.Printf(" - %-20s %-20s %-44s %-30s %s\n", yellow("Calls"), yellow("Messages"),
+		yellow("Latency (avg / p50 / p95 / p99)"), yellow("Status"), yellow("Method"))
+	for i, method := range result.Methods {
+		paint := white
+		if i%2 == 0 {
+			paint = grey
+		}
+		codes, _ := json.Marshal(method.Codes)
+		fmt.Printf(" - %-18s %-18s %-42s %-28s %s\n", paint("%d", method.Calls), paint("%d", method.Messages),
+			paint("%s / %s / %s / %s", method.Latency.Avg, method.Latency.P50, method.Latency.P95, method.Latency.P99),
+			paint(string(codes)), paint(method.Method))
+	}
+	for _, method := range result.Methods {
+		if method.LastError != "" {
+			fmt.Printf(" - %-34s %s\n", yel("Last Error"), white("%s: %s", method.Method, method.LastError))
+		}
+	}
+}
+
+// PrintMethods prints the methods of a schema to stdout, with their request and response types
+func PrintMethods(schema *Schema) {
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("gRPC Methods")
+	for _, name := range schema.MethodNames() {
+		method, _ := schema.Method(name)
+		input, output := string(method.Input.FullName()), string(method.Output.FullName())
+		if method.ClientStreaming {
+			input = "stream " + input
+		}
+		if method.ServerStreaming {
+			output = "stream " + output
+		}
+		fmt.Printf(" - %-45s %s\n", yel(name), white("(%s) returns (%s)", input, output))
+	}
+}
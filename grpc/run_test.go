@@ -0,0 +1,303 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
+	"github.com/Gosayram/goperf/request"
+)
+
+// shopServer serves the Shop service of shopProto over h2c with grpc-go, gzip-compressing its
+// responses, and the v1alpha server reflection service
+func shopServer(t *testing.T) string {
+	t.Helper()
+	schema := loadShop(t)
+	getItem, _ := schema.Method("test.shop.Shop/GetItem")
+	listItems, _ := schema.Method("test.shop.Shop/ListItems")
+
+	// compress makes the server send compressed responses to the call of ctx
+	compress := func(ctx context.Context) {
+		if err := grpc.SetSendCompressor(ctx, "gzip"); err != nil {
+			t.Error(err)
+		}
+	}
+	service := &grpc.ServiceDesc{
+		ServiceName: "test.shop.Shop",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "GetItem",
+			Handler: func(_ any, ctx context.Context, decode func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := dynamicpb.NewMessage(getItem.Input)
+				if err := decode(req); err != nil {
+					return nil, err
+				}
+				compress(ctx)
+				md, _ := metadata.FromIncomingContext(ctx)
+				id := req.Get(getItem.Input.Fields().ByName("id")).String()
+				switch {
+				case !slices.Equal(md.Get("x-tenant"), []string{"acme"}):
+					return nil, status.Error(codes.Unauthenticated, "no tenant")
+				case id == "missing":
+					return nil, status.Error(codes.NotFound, "no item 100% missing")
+				case id == "slow":
+					select {
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					case <-time.After(200 * time.Millisecond):
+					}
+				}
+				item := getItem.Response()
+				err := protojson.Unmarshal(fmt.Appendf(nil,
+					`{"id":%q,"kind":"BOOK","title":"Item","updated":"2023-11-14T22:13:20Z"}`, id), item)
+				return item, err
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "ListItems",
+			ServerStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				req := dynamicpb.NewMessage(listItems.Input)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				compress(stream.Context())
+				limit := req.Get(listItems.Input.Fields().ByName("limit")).Int()
+				for i := range limit {
+					item := listItems.Response()
+					if err := protojson.Unmarshal(fmt.Appendf(nil, `{"id":"%d","sizes":[%d]}`, i, i), item); err != nil {
+						return err
+					}
+					if err := stream.SendMsg(item); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}},
+	}
+
+	server := grpc.NewServer()
+	server.RegisterService(service, struct{}{})
+	reflectionpb.RegisterServerReflectionServer(server,
+		reflection.NewServer(reflection.ServerOptions{Services: server, DescriptorResolver: schema.Files}))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return "http://" + listener.Addr().String()
+}
+
+// newTestClient returns a client of target sending the tenant metadata
+func newTestClient(t *testing.T, target string) *Client {
+	t.Helper()
+	client, err := NewClient(target, request.DefaultTransportConfig(), http.Header{"X-Tenant": {"acme"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestReflect(t *testing.T) {
+	color.Green("~~ TestReflect ~~")
+	client := newTestClient(t, shopServer(t))
+	schema, err := Reflect(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := loadShop(t)
+	if !reflect.DeepEqual(schema.MethodNames(), want.MethodNames()) {
+		t.Errorf("MethodNames = %v, want %v", schema.MethodNames(), want.MethodNames())
+	}
+	for _, name := range want.MethodNames() {
+		got, _ := schema.Method(name)
+		method, _ := want.Method(name)
+		if got == nil || got.ServerStreaming != method.ServerStreaming ||
+			!proto.Equal(protodesc.ToDescriptorProto(got.Input), protodesc.ToDescriptorProto(method.Input)) ||
+			!proto.Equal(protodesc.ToDescriptorProto(got.Output), protodesc.ToDescriptorProto(method.Output)) {
+			t.Errorf("reflected method %s = %+v, want %+v", name, got, method)
+		}
+	}
+	for _, path := range []string{"common/money.proto", "google/protobuf/timestamp.proto"} {
+		if _, err = schema.Files.FindFileByPath(path); err != nil {
+			t.Errorf("reflected files: %v", err)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	target := shopServer(t)
+	client := newTestClient(t, target)
+	schema := loadShop(t)
+	getItem, _ := schema.Method("test.shop.Shop/GetItem")
+	listItems, _ := schema.Method("test.shop.Shop/ListItems")
+
+	call := func(client *Client, method *Method, data string, timeout time.Duration) (Status, []string) {
+		message, err := method.Request(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		var responses []string
+		status := client.Call(ctx, method, []proto.Message{message}, func(response proto.Message) {
+			text, err := method.JSON(response)
+			if err != nil {
+				t.Error(err)
+			}
+			responses = append(responses, text)
+		})
+		return status, responses
+	}
+
+	// The responses are gzip-compressed; well-known types come back in their JSON form
+	status, responses := call(client, getItem, `{"id":"42"}`, time.Second)
+	if status.Err() != nil || len(responses) != 1 ||
+		responses[0] != `{"id":"42","kind":"BOOK","title":"Item","updated":"2023-11-14T22:13:20Z"}` {
+		t.Errorf("GetItem = %+v, %v", status, responses)
+	}
+	status, responses = call(client, listItems, `{"limit":3}`, time.Second)
+	if status.Err() != nil || !slices.Equal(responses, []string{`{"id":"0","sizes":[0]}`,
+		`{"id":"1","sizes":[1]}`, `{"id":"2","sizes":[2]}`}) {
+		t.Errorf("ListItems = %+v, %v", status, responses)
+	}
+
+	unauthenticated, _ := NewClient(target, request.DefaultTransportConfig(), nil)
+	defer unauthenticated.Close()
+	closed := httptest.NewServer(nil)
+	closed.Close()
+	unavailable, _ := NewClient(closed.URL, request.DefaultTransportConfig(), nil)
+	defer unavailable.Close()
+	unknown := *getItem
+	unknown.Path = "/test.shop.Shop/Unknown"
+
+	for _, tt := range []struct {
+		client  *Client
+		method  *Method
+		data    string
+		timeout time.Duration
+		want    Status
+	}{
+		{client, getItem, `{"id":"missing"}`, time.Second, Status{codes.NotFound, "no item 100% missing"}},
+		{unauthenticated, getItem, `{}`, time.Second, Status{codes.Unauthenticated, "no tenant"}},
+		{client, &unknown, `{}`, time.Second, Status{Code: codes.Unimplemented}},
+		{client, getItem, `{"id":"slow"}`, 50 * time.Millisecond, Status{Code: codes.DeadlineExceeded}},
+		{unavailable, getItem, `{}`, time.Second, Status{Code: codes.Unavailable}},
+	} {
+		status, _ := call(tt.client, tt.method, tt.data, tt.timeout)
+		if status.Code != tt.want.Code || (tt.want.Message != "" && status.Message != tt.want.Message) {
+			t.Errorf("%s %s = %+v (%s), want %+v", tt.method.Path, tt.data, status, status.CodeName(), tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	target := shopServer(t)
+	collector := metrics.NewCollector()
+	session, _ := collector.StartTest(&interfaces.TestConfig{Target: &interfaces.Request{URL: target}})
+
+	result, err := Run(context.Background(), Options{
+		URL:    target,
+		Schema: loadShop(t),
+		Calls: []Call{
+			{Method: "test.shop.Shop/GetItem", Data: `{"id":"{{$randomInt}}"}`},
+			{Method: "test.shop.Shop.ListItems", Data: `{"limit":{{limit}}}`},
+			{Method: "test.shop.Shop/GetItem", Data: `{"id":"missing"}`},
+		},
+		Users:     3,
+		Duration:  300 * time.Millisecond,
+		Metadata:  http.Header{"X-Tenant": {"acme"}},
+		Transport: request.DefaultTransportConfig(),
+		Variables: map[string]string{"limit": "3"},
+		Collector: collector,
+		Session:   session,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Methods) != 2 || result.Methods[0].Method != "test.shop.Shop/GetItem" ||
+		result.Methods[1].Method != "test.shop.Shop/ListItems" {
+		t.Fatalf("methods %s", jsonString(result.Methods))
+	}
+	get, list := result.Methods[0], result.Methods[1]
+	if get.Calls < 30 || get.Codes["OK"] == 0 || get.Codes["NOT_FOUND"] == 0 ||
+		get.Failed != get.Codes["NOT_FOUND"] || get.Messages != get.Codes["OK"] || get.LastError == "" {
+		t.Errorf("GetItem %s", jsonString(get))
+	}
+	if list.Calls == 0 || list.Messages != 3*list.Calls || list.Failed != 0 || list.Latency.P99 <= 0 {
+		t.Errorf("ListItems %s", jsonString(list))
+	}
+	if result.Calls != get.Calls+list.Calls || result.Failed != get.Failed || result.CallsPerSecond <= 0 {
+		t.Errorf("result %+v", result)
+	}
+
+	report, err := collector.FinishTest(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.AssetStats) != 2 || report.AssetStats[0].Count != get.Calls ||
+		report.AssetStats[1].Count != list.Calls || report.Stats.FailedRequests != result.Failed {
+		data, _ := json.Marshal(report)
+		t.Errorf("collected %s", data)
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	schema := loadShop(t)
+	getItem := []Call{{Method: "test.shop.Shop/GetItem"}}
+	for name, opts := range map[string]Options{
+		"no calls":         {URL: "http://localhost:1", Schema: schema, Duration: time.Second},
+		"unknown method":   {URL: "http://localhost:1", Calls: []Call{{Method: "x.Y/Z"}}},
+		"client streaming": {URL: "http://localhost:1", Calls: []Call{{Method: "test.shop.Shop/Upload"}}},
+		"invalid request":  {URL: "http://localhost:1", Calls: []Call{{Method: "test.shop.Shop/GetItem", Data: `{"id":`}}},
+		"no duration":      {URL: "http://localhost:1", Schema: schema, Calls: getItem},
+		"invalid url":      {URL: "grpc://localhost:1", Calls: getItem},
+	} {
+		if opts.Schema == nil && opts.Calls != nil {
+			opts.Schema = schema
+		}
+		if opts.Duration == 0 && name != "no duration" {
+			opts.Duration = time.Second
+		}
+		if _, err := Run(context.Background(), opts); err == nil {
+			t.Errorf("Run with %s should fail", name)
+		}
+	}
+
+	for spec, want := range map[string]Call{
+		"test.shop.Shop/GetItem":                {Method: "test.shop.Shop/GetItem"},
+		` test.shop.Shop/GetItem  {"id": "1"} `: {Method: "test.shop.Shop/GetItem", Data: `{"id": "1"}`},
+	} {
+		if got, err := ParseCall(spec); err != nil || got != want {
+			t.Errorf("ParseCall(%q) = %+v, %v", spec, got, err)
+		}
+	}
+	if _, err := ParseCall(" "); err == nil {
+		t.Error("ParseCall should fail for an empty spec")
+	}
+}
@@ -0,0 +1,197 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+/*
+Schema holds the files of a set of services and the types they use.
+
+Structure Overview
+  - Files - the file descriptors, with every file they import
+  - Types - the message types of Files, used to write and read messages of any of them,
+    e.g. inside a google.protobuf.Any
+*/
+type Schema struct {
+	Files *protoregistry.Files
+	Types *dynamicpb.Types
+
+	methods map[string]*Method // by full name
+}
+
+// Method is a method of a service
+type Method struct {
+	Name            string // full name, e.g. "shop.v1.Orders/Get"
+	Path            string // HTTP/2 path, e.g. "/shop.v1.Orders/Get"
+	Input, Output   protoreflect.MessageDescriptor
+	ClientStreaming bool
+	ServerStreaming bool
+
+	types *dynamicpb.Types
+}
+
+/*
+LoadProtos compiles .proto files and the files they import into a schema.  Imports are
+looked up in importPaths, then in the directories of files; the google/protobuf imports
+are built in.  A file is known to the files importing it by its path in the first import
+path holding it, or else by its base name.
+*/
+func LoadProtos(files, importPaths []string) (*Schema, error) {
+	paths := slices.Clone(importPaths)
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			return nil, fmt.Errorf("failed to read proto file: %w", err)
+		}
+		name, ok := importName(file, importPaths)
+		if !ok {
+			name = filepath.Base(file)
+			if dir := filepath.Dir(file); !slices.Contains(paths, dir) {
+				paths = append(paths, dir)
+			}
+		}
+		names = append(names, name)
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: paths}),
+	}
+	compiled, err := compiler.Compile(context.Background(), names...)
+	if err != nil {
+		return nil, err
+	}
+	registry := &protoregistry.Files{}
+	for _, file := range compiled {
+		if err = register(registry, file); err != nil {
+			return nil, err
+		}
+	}
+	return newSchema(registry), nil
+}
+
+// importName returns the path of file relative to the first of importPaths holding it
+func importName(file string, importPaths []string) (string, bool) {
+	for _, dir := range importPaths {
+		rel, err := filepath.Rel(dir, file)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel), true
+		}
+	}
+	return "", false
+}
+
+// register adds file and the files it imports to registry, once each
+func register(registry *protoregistry.Files, file protoreflect.FileDescriptor) error {
+	if _, err := registry.FindFileByPath(file.Path()); err == nil {
+		return nil
+	}
+	imports := file.Imports()
+	for i := range imports.Len() {
+		if err := register(registry, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return registry.RegisterFile(file)
+}
+
+// newSchema returns the schema of the files of registry; the reflection service is left out
+func newSchema(registry *protoregistry.Files) *Schema {
+	schema := &Schema{Files: registry, Types: dynamicpb.NewTypes(registry), methods: map[string]*Method{}}
+	registry.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		services := file.Services()
+		for i := range services.Len() {
+			service := services.Get(i)
+			if strings.HasPrefix(string(service.FullName()), ReflectionService) {
+				continue
+			}
+			methods := service.Methods()
+			for j := range methods.Len() {
+				m := methods.Get(j)
+				name := string(service.FullName()) + "/" + string(m.Name())
+				schema.methods[name] = &Method{
+					Name:            name,
+					Path:            "/" + name,
+					Input:           m.Input(),
+					Output:          m.Output(),
+					ClientStreaming: m.IsStreamingClient(),
+					ServerStreaming: m.IsStreamingServer(),
+					types:           schema.Types,
+				}
+			}
+		}
+		return true
+	})
+	return schema
+}
+
+// Method returns a method from its full name: "pkg.Service/Method", "/pkg.Service/Method"
+// or "pkg.Service.Method"
+func (s *Schema) Method(name string) (*Method, error) {
+	name = strings.TrimPrefix(name, "/")
+	service, method, ok := strings.Cut(name, "/")
+	if !ok {
+		dot := strings.LastIndex(name, ".")
+		if dot < 0 {
+			return nil, fmt.Errorf("invalid method %q (expected package.Service/Method)", name)
+		}
+		service, method = name[:dot], name[dot+1:]
+	}
+	if m, ok := s.methods[service+"/"+method]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown method %s/%s", service, method)
+}
+
+// MethodNames returns the full names of the methods of every service, sorted
+func (s *Schema) MethodNames() []string {
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Request returns the request message written as JSON in text, which may be empty.  The JSON
+// is the canonical protobuf mapping: well-known types take their JSON form, e.g. an RFC 3339
+// string for a google.protobuf.Timestamp, and fields their JSON or .proto names.
+func (m *Method) Request(text string) (*dynamicpb.Message, error) {
+	message := dynamicpb.NewMessage(m.Input)
+	if strings.TrimSpace(text) == "" {
+		return message, nil
+	}
+	if err := (protojson.UnmarshalOptions{Resolver: m.types}).Unmarshal([]byte(text), message); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", m.Input.FullName(), err)
+	}
+	return message, nil
+}
+
+// Response returns an empty response message
+func (m *Method) Response() *dynamicpb.Message {
+	return dynamicpb.NewMessage(m.Output)
+}
+
+// JSON returns the canonical JSON form of a message of the schema, without spaces
+func (m *Method) JSON(message proto.Message) (string, error) {
+	data, err := (protojson.MarshalOptions{Resolver: m.types}).Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	var compact bytes.Buffer
+	err = json.Compact(&compact, data)
+	return compact.String(), err
+}
@@ -0,0 +1,256 @@
+package grpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gnulnx/color"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// shopProto is a service definition using most of the .proto syntax
+const shopProto = `
+// Shop service for the tests
+syntax = "proto3";
+
+package test.shop;
+
+import "google/protobuf/any.proto";
+import "google/protobuf/timestamp.proto";
+import public "common/money.proto";
+
+option go_package = "example.com/shop";
+
+service Shop {
+  option deprecated = true;
+  rpc GetItem (GetItemRequest) returns (Item);
+  rpc ListItems(ListItemsRequest) returns (stream Item) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+  rpc Upload(stream Item) returns (common.Money);
+}
+
+message GetItemRequest {
+  string id = 1;
+}
+
+message ListItemsRequest {
+  int32 limit = 1;
+  repeated Item.Kind kinds = 2;
+}
+
+message Item {
+  enum Kind {
+    KIND_UNSPECIFIED = 0;
+    BOOK = 1;
+    GAME = 2 [deprecated = true];
+  }
+  message Review { sint32 stars = 1; string text = 2; }
+
+  string id = 1;
+  Kind kind = 2;
+  common.Money price = 3;
+  repeated int32 sizes = 4;
+  repeated int32 legacy = 5 [packed = false];
+  map<string, int64> stock = 6;
+  repeated Review reviews = 7;
+  oneof label {
+    string title = 8;
+    bytes code = 9 [json_name = "barcode"];
+  }
+  google.protobuf.Timestamp updated = 10;
+  uint64 views = 11;
+  double rating = 12;
+  fixed32 shelf = 13;
+  reserved 14, 15;
+  optional bool hidden = 16;
+  google.protobuf.Any extra = 17;
+}
+`
+
+// moneyProto is imported by shopProto from an import path
+const moneyProto = `
+syntax = "proto3";
+package common;
+message Money { string currency = 1; sfixed64 units = 2; float ratio = 3; }
+`
+
+// loadShop writes the test protos and loads them
+func loadShop(t *testing.T) *Schema {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "include", "common"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"shop.proto": shopProto, "include/common/money.proto": moneyProto}
+	for name, source := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schema, err := LoadProtos([]string{filepath.Join(dir, "shop.proto")}, []string{filepath.Join(dir, "include")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestLoadProtos(t *testing.T) {
+	color.Green("~~ TestLoadProtos ~~")
+	schema := loadShop(t)
+
+	if got, want := schema.MethodNames(), []string{
+		"test.shop.Shop/GetItem", "test.shop.Shop/ListItems", "test.shop.Shop/Upload",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MethodNames = %v, want %v", got, want)
+	}
+	for _, name := range []string{"test.shop.Shop/ListItems", "/test.shop.Shop/ListItems", "test.shop.Shop.ListItems"} {
+		method, err := schema.Method(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if method.Path != "/test.shop.Shop/ListItems" || !method.ServerStreaming || method.ClientStreaming ||
+			method.Input.FullName() != "test.shop.ListItemsRequest" || method.Output.FullName() != "test.shop.Item" {
+			t.Errorf("Method(%s) = %+v", name, method)
+		}
+	}
+	upload, _ := schema.Method("test.shop.Shop/Upload")
+	if !upload.ClientStreaming || upload.Output.FullName() != "common.Money" {
+		t.Errorf("Upload = %+v", upload)
+	}
+	if _, err := schema.Method("test.shop.Shop/Missing"); err == nil {
+		t.Error("Method should fail for an unknown method")
+	}
+
+	// Imports are known by their path in the import path
+	if _, err := schema.Files.FindFileByPath("common/money.proto"); err != nil {
+		t.Error(err)
+	}
+	fields := upload.Input.Fields()
+	if barcode := fields.ByJSONName("barcode"); barcode == nil || barcode.Number() != 9 {
+		t.Errorf("barcode = %v", barcode)
+	}
+	if !fields.ByName("sizes").IsPacked() || fields.ByName("legacy").IsPacked() || !fields.ByName("stock").IsMap() {
+		t.Error("sizes should be packed, legacy not and stock should be a map")
+	}
+	if game := fields.ByName("kind").Enum().Values().ByName("GAME"); game == nil || game.Number() != 2 {
+		t.Errorf("GAME = %v", game)
+	}
+}
+
+func TestLoadProtosErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, source := range map[string]string{
+		"import.proto":    `syntax = "proto3"; import "other/b.proto"; message A { other.B b = 1; }`,
+		"missing.proto":   `syntax = "proto3"; message A { other.B b = 1; }`,
+		"invalid.proto":   `syntax = "proto3"; message A { string a = ; }`,
+		"duplicate.proto": `syntax = "proto3"; message A { string a = 1; string b = 1; }`,
+		"unclosed.proto":  `syntax = "proto3"; message A { string a = 1;`,
+		"comment.proto":   `syntax = "proto3"; /* message A {}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(source), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadProtos([]string{path}, nil); err == nil {
+			t.Errorf("LoadProtos(%s) should fail", name)
+		}
+	}
+	if _, err := LoadProtos([]string{filepath.Join(dir, "none.proto")}, nil); err == nil {
+		t.Error("LoadProtos should fail for a missing file")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	schema := loadShop(t)
+	tests := []struct {
+		name   string
+		method string // the input of the method is the message
+		input  string
+		wire   string // hex
+		output string // canonical JSON, the input when empty
+	}{
+		{"string", "test.shop.Shop/GetItem", `{"id":"abc"}`, "0a03616263", ""},
+		{"empty", "test.shop.Shop/GetItem", `{}`, "", ""},
+		{"packed and enum names", "test.shop.Shop/ListItems", `{"limit":-1,"kinds":["BOOK",2]}`,
+			"08ffffffffffffffffff01" + "12020102", `{"limit":-1,"kinds":["BOOK","GAME"]}`},
+		{"proto names and strings for numbers", "test.shop.Shop/Upload",
+			`{"sizes":["1",2],"legacy":[3,4],"views":"18446744073709551615","shelf":7}`,
+			"22020102" + "2803" + "2804" + "58ffffffffffffffffff01" + "6d07000000",
+			`{"sizes":[1,2],"legacy":[3,4],"views":"18446744073709551615","shelf":7}`},
+		{"nested, repeated messages, maps and bytes", "test.shop.Shop/Upload",
+			`{"price":{"currency":"EUR","units":"-5","ratio":0.5},"stock":{"a":"1","b":"2"},` +
+				`"reviews":[{"stars":-2,"text":"ok"},{"stars":3}],"barcode":"AQI=","rating":1.5}`,
+			"1a13" + "0a03455552" + "11fbffffffffffffff" + "1d0000003f" +
+				"3205" + "0a0161" + "1001" + "3205" + "0a0162" + "1002" +
+				"3a06" + "0803" + "12026f6b" + "3a02" + "0806" +
+				"61000000000000f83f" + "4a020102", ""}, // oneof fields come last
+		{"well-known types and special floats", "test.shop.Shop/Upload",
+			`{"updated":"2023-11-14T22:13:20.000000005Z","rating":"NaN","hidden":true}`,
+			"520808" + "80e2cfaa06" + "1005" + "61" + "010000000000f87f" + "8001" + "01", ""},
+		{"any", "test.shop.Shop/Upload", `{"extra":{"@type":"type.googleapis.com/common.Money","currency":"EUR"}}`,
+			"8a0129" + "0a20" + hex.EncodeToString([]byte("type.googleapis.com/common.Money")) + "1205" + "0a03455552",
+			""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, err := schema.Method(tt.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			message, err := method.Request(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wire, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(wire); got != tt.wire {
+				t.Errorf("encoded %s, want %s", got, tt.wire)
+			}
+			decoded := dynamicpb.NewMessage(method.Input)
+			if err = proto.Unmarshal(wire, decoded); err != nil {
+				t.Fatal(err)
+			}
+			got, err := method.JSON(decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.output
+			if want == "" {
+				want = tt.input
+			}
+			if got != want {
+				t.Errorf("JSON = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	upload, _ := loadShop(t).Method("test.shop.Shop/Upload")
+	for _, input := range []string{
+		`{"unknown":1}`, `{"id":1}`, `{"kind":"TOY"}`, `{"sizes":1}`, `{"sizes":[1.5]}`,
+		`{"views":-1}`, `{"barcode":"%%"}`, `{"stock":[1]}`, `{"price":"EUR"}`, `{"hidden":"maybe"}`, `[1]`,
+		`{"updated":{"seconds":"1700000000"}}`, `{"extra":{"@type":"type.googleapis.com/other.B"}}`,
+	} {
+		if _, err := upload.Request(input); err == nil {
+			t.Errorf("Request(%s) should fail", input)
+		}
+	}
+	if message, err := upload.Request(" "); err != nil || proto.Size(message) != 0 {
+		t.Errorf("Request of an empty text = %v, %v", message, err)
+	}
+}
+
+// jsonString returns the JSON form of v
+func jsonString(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,304 @@
+package httputils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+// assetSet collects classified asset urls, each category in document order and
+// without duplicates. It also tracks the state needed to pick a single resource
+// out of <picture> and <video>/<audio> groups, like a browser does.
+type assetSet struct {
+	js, css, img, fonts, media, icons, documents, other []string
+
+	seen        map[*[]string]map[string]bool
+	pictureDone bool // a <source> of the current <picture> was chosen
+	mediaDone   bool // the current <video>/<audio> already has its resource
+}
+
+func newAssetSet() *assetSet {
+	return &assetSet{seen: make(map[*[]string]map[string]bool)}
+}
+
+// add appends url to list unless it is empty or already present
+func (s *assetSet) add(list *[]string, url string) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return
+	}
+	if s.seen[list] == nil {
+		s.seen[list] = make(map[string]bool)
+	}
+	if s.seen[list][url] {
+		return
+	}
+	s.seen[list][url] = true
+	*list = append(*list, url)
+}
+
+// element classifies one HTML element. attrs holds its attributes keyed by lower-case
+// name and parent is the name of the enclosing picture, video or audio element, if any.
+func (s *assetSet) element(tag string, attrs map[string]string, parent string) {
+	switch tag {
+	case ScriptTag:
+		if _, legacy := attrs[NoModuleAttribute]; !legacy {
+			s.add(&s.js, attrs[SrcAttribute])
+		}
+	case LinkTag:
+		s.link(attrs)
+	case ImageTag:
+		if parent == PictureTag && s.pictureDone {
+			return
+		}
+		s.add(&s.img, pickImage(attrs[SrcsetAttribute], attrs[SrcAttribute]))
+	case PictureTag:
+		s.pictureDone = false
+	case SourceTag:
+		s.source(attrs, parent)
+	case VideoTag, AudioTag:
+		s.add(&s.img, attrs[PosterAttribute])
+		s.add(&s.media, attrs[SrcAttribute])
+		s.mediaDone = attrs[SrcAttribute] != ""
+	case IframeTag, FrameTag:
+		s.add(&s.documents, attrs[SrcAttribute])
+	case EmbedTag:
+		s.add(&s.other, attrs[SrcAttribute])
+	case ObjectTag:
+		s.add(&s.other, attrs[DataAttribute])
+	}
+}
+
+// source handles <source>: the first one of a <picture> replaces its <img> and the
+// first one of a <video>/<audio> without a src is the media resource
+func (s *assetSet) source(attrs map[string]string, parent string) {
+	switch parent {
+	case PictureTag:
+		if !s.pictureDone && attrs[SrcsetAttribute] != "" {
+			s.add(&s.img, pickSrcset(attrs[SrcsetAttribute]))
+			s.pictureDone = true
+		}
+	case VideoTag, AudioTag:
+		if !s.mediaDone && attrs[SrcAttribute] != "" {
+			s.add(&s.media, attrs[SrcAttribute])
+			s.mediaDone = true
+		}
+	}
+}
+
+// link classifies <link> by its rel tokens. Only relations a browser fetches during
+// page load count: canonical, alternate, prefetch, preconnect and friends are ignored.
+func (s *assetSet) link(attrs map[string]string) {
+	href := attrs[HrefAttribute]
+	rels := strings.Fields(strings.ToLower(attrs[RelAttribute]))
+	has := func(want ...string) bool {
+		for _, rel := range rels {
+			for _, w := range want {
+				if rel == w {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	switch {
+	case has("stylesheet"):
+		s.add(&s.css, href)
+	case has("preload"):
+		s.preload(strings.ToLower(strings.TrimSpace(attrs[AsAttribute])), href, attrs[ImageSrcsetAttribute])
+	case has("modulepreload"):
+		s.add(&s.js, href)
+	case has("icon", "apple-touch-icon", "apple-touch-icon-precomposed", "mask-icon"):
+		s.add(&s.icons, href)
+	case has("manifest"):
+		s.add(&s.other, href)
+	}
+}
+
+// preload classifies <link rel=preload> by its as attribute. Preloads without
+// a valid as are ignored by browsers.
+func (s *assetSet) preload(as, href, imageSrcset string) {
+	switch as {
+	case "script", "worker", "sharedworker", "serviceworker":
+		s.add(&s.js, href)
+	case "style":
+		s.add(&s.css, href)
+	case "image":
+		s.add(&s.img, pickImage(imageSrcset, href))
+	case "font":
+		s.add(&s.fonts, href)
+	case "audio", "video", "track":
+		s.add(&s.media, href)
+	case "document", "iframe":
+		s.add(&s.documents, href)
+	case "fetch", "object", "embed", "manifest":
+		s.add(&s.other, href)
+	}
+}
+
+// merge appends the urls of other to s
+func (s *assetSet) merge(other *assetSet) {
+	for _, pair := range []struct{ dst, src *[]string }{
+		{&s.js, &other.js}, {&s.css, &other.css}, {&s.img, &other.img}, {&s.fonts, &other.fonts},
+		{&s.media, &other.media}, {&s.icons, &other.icons}, {&s.documents, &other.documents},
+		{&s.other, &other.other},
+	} {
+		for _, url := range *pair.src {
+			s.add(pair.dst, url)
+		}
+	}
+}
+
+// assets converts the set to interfaces.Assets
+func (s *assetSet) assets() *interfaces.Assets {
+	nonNil := func(list []string) []string {
+		if list == nil {
+			return []string{}
+		}
+		return list
+	}
+	assets := &interfaces.Assets{
+		JavaScript: nonNil(s.js),
+		CSS:        nonNil(s.css),
+		Images:     nonNil(s.img),
+		Fonts:      nonNil(s.fonts),
+		Media:      nonNil(s.media),
+		Icons:      nonNil(s.icons),
+		Documents:  nonNil(s.documents),
+		Other:      nonNil(s.other),
+	}
+	assets.Total = len(s.js) + len(s.css) + len(s.img) + len(s.fonts) +
+		len(s.media) + len(s.icons) + len(s.documents) + len(s.other)
+	return assets
+}
+
+// pickImage returns the srcset candidate a browser would load, or src when there is none
+func pickImage(srcset, src string) string {
+	if picked := pickSrcset(srcset); picked != "" {
+		return picked
+	}
+	return src
+}
+
+/*
+pickSrcset chooses one candidate from a srcset attribute the way a desktop browser
+with an EmulatedViewportWidth wide viewport and EmulatedPixelDensity would:
+width descriptors pick the smallest image at least as wide as the viewport (or the
+widest one), density descriptors pick the smallest density covering the display
+(or the densest one). A candidate without descriptor counts as 1x.
+*/
+func pickSrcset(srcset string) string {
+	var best string
+	var bestValue float64
+	widthMode := false
+	for _, candidate := range splitSrcset(srcset) {
+		url, value, isWidth := candidate.url, 1.0, false
+		if len(candidate.descriptors) > 0 {
+			descriptor := strings.ToLower(candidate.descriptors[0])
+			number, err := strconv.ParseFloat(descriptor[:len(descriptor)-1], 64)
+			if err != nil || number <= 0 {
+				continue
+			}
+			switch {
+			case strings.HasSuffix(descriptor, WidthDescriptor):
+				value, isWidth = number, true
+			case strings.HasSuffix(descriptor, DensityDescriptor):
+				value = number
+			default:
+				continue
+			}
+		}
+		if best != "" && isWidth != widthMode {
+			continue // a valid srcset does not mix width and density descriptors
+		}
+		target := EmulatedPixelDensity
+		if isWidth {
+			target = EmulatedViewportWidth * EmulatedPixelDensity
+		}
+		if best == "" || better(value, bestValue, target) {
+			best, bestValue, widthMode = url, value, isWidth
+		}
+	}
+	return best
+}
+
+// srcsetCandidate is an image candidate of a srcset attribute
+type srcsetCandidate struct {
+	url         string
+	descriptors []string
+}
+
+/*
+splitSrcset splits a srcset attribute into its candidates as the HTML standard does: a URL
+runs up to the next whitespace, so it may hold commas (/w_400,h_300/a.jpg), and only
+ends its candidate when it also ends with one.  Otherwise the candidate's descriptors
+follow, up to the next comma outside parentheses.
+*/
+func splitSrcset(srcset string) []srcsetCandidate {
+	var candidates []srcsetCandidate
+	isSpace := func(c byte) bool { return strings.IndexByte(SrcsetWhitespace, c) >= 0 }
+	for pos := 0; pos < len(srcset); {
+		for pos < len(srcset) && (isSpace(srcset[pos]) || srcset[pos] == ',') {
+			pos++
+		}
+		start := pos
+		for pos < len(srcset) && !isSpace(srcset[pos]) {
+			pos++
+		}
+		if start == pos {
+			break
+		}
+		candidate := srcsetCandidate{url: strings.TrimRight(srcset[start:pos], ",")}
+		if len(candidate.url) < pos-start {
+			// A URL ending with a comma has no descriptors
+			if candidate.url != "" {
+				candidates = append(candidates, candidate)
+			}
+			continue
+		}
+
+		var descriptor strings.Builder
+		inParens := false
+		flush := func() {
+			if descriptor.Len() > 0 {
+				candidate.descriptors = append(candidate.descriptors, descriptor.String())
+				descriptor.Reset()
+			}
+		}
+		for ; pos < len(srcset); pos++ {
+			c := srcset[pos]
+			if inParens {
+				inParens = c != ')'
+				descriptor.WriteByte(c)
+			} else if c == ',' {
+				pos++
+				break
+			} else if isSpace(c) {
+				flush()
+			} else {
+				inParens = c == '('
+				descriptor.WriteByte(c)
+			}
+		}
+		flush()
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// better reports whether candidate value fits target more closely than current:
+// the smallest value at or above target wins, otherwise the largest value
+func better(value, current, target float64) bool {
+	switch {
+	case value >= target && current >= target:
+		return value < current
+	case value >= target:
+		return true
+	case current >= target:
+		return false
+	default:
+		return value > current
+	}
+}
@@ -6,8 +6,8 @@ package httputils
 const (
 	// AssetTypesCount specifies the number of different asset types (JS, CSS, images)
 	AssetTypesCount = 3 // Number of asset types processed
-	// RegexMatchLimit specifies the limit for regex matches during asset extraction
-	RegexMatchLimit = -10 // Unlimited matches for regex processing
+	// RegexMatchLimit specifies the default limit for regex matches per pattern during asset extraction
+	RegexMatchLimit = -1 // Negative means unlimited matches
 
 	// HTTPProtocolLength specifies the length of HTTP protocol prefix in URLs
 	HTTPProtocolLength = 4 // Length of "http" string
@@ -18,17 +18,105 @@ const (
 	ImageTag = "img" // HTML img tag
 	// LinkTag specifies the HTML link tag name for CSS and other linked assets
 	LinkTag = "link" // HTML link tag
+	// BaseTag specifies the HTML base tag name that overrides the document base URL
+	BaseTag = "base" // HTML base tag
+	// PictureTag specifies the HTML picture tag whose first <source> replaces its <img>
+	PictureTag = "picture" // HTML picture tag
+	// SourceTag specifies the HTML source tag used inside picture, video and audio
+	SourceTag = "source" // HTML source tag
+	// VideoTag specifies the HTML video tag
+	VideoTag = "video" // HTML video tag
+	// AudioTag specifies the HTML audio tag
+	AudioTag = "audio" // HTML audio tag
+	// IframeTag specifies the HTML iframe tag
+	IframeTag = "iframe" // HTML iframe tag
+	// FrameTag specifies the legacy HTML frame tag
+	FrameTag = "frame" // HTML frame tag
+	// EmbedTag specifies the HTML embed tag
+	EmbedTag = "embed" // HTML embed tag
+	// ObjectTag specifies the HTML object tag
+	ObjectTag = "object" // HTML object tag
+	// LinkSelector selects the hyperlinks of a page
+	LinkSelector = "a[href], area[href]"
+	// NoFollowRel marks hyperlinks that crawlers should not follow
+	NoFollowRel = "nofollow" // rel="nofollow"
+	// GzipMagic is the leading bytes of gzip compressed data, e.g. sitemap.xml.gz
+	GzipMagic = "\x1f\x8b"
+	// AssetSelector selects every element that can make the browser fetch an asset, in document order
+	AssetSelector = "script, link, img, picture, source, video, audio, iframe, frame, embed, object"
+
 	// SrcAttribute specifies the HTML src attribute name for asset URLs
 	SrcAttribute = "src" // HTML src attribute
 	// HrefAttribute specifies the HTML href attribute name for link URLs
 	HrefAttribute = "href" // HTML href attribute
+	// SrcsetAttribute specifies the responsive image candidate list attribute
+	SrcsetAttribute = "srcset" // HTML srcset attribute
+	// ImageSrcsetAttribute specifies the srcset of <link rel=preload as=image>
+	ImageSrcsetAttribute = "imagesrcset" // HTML imagesrcset attribute
+	// PosterAttribute specifies the video poster image attribute
+	PosterAttribute = "poster" // HTML poster attribute
+	// DataAttribute specifies the <object> resource attribute
+	DataAttribute = "data" // HTML data attribute
+	// RelAttribute specifies the link relation attribute
+	RelAttribute = "rel" // HTML rel attribute
+	// AsAttribute specifies the destination of a preload link
+	AsAttribute = "as" // HTML as attribute
+	// StyleAttribute specifies the inline style attribute
+	StyleAttribute = "style" // HTML style attribute
+	// NoModuleAttribute marks legacy scripts that module-aware browsers do not fetch
+	NoModuleAttribute = "nomodule" // HTML nomodule attribute
+
+	// EmulatedViewportWidth is the viewport width, in CSS pixels, used to pick srcset candidates
+	EmulatedViewportWidth = 1366 // Common desktop viewport width
+	// EmulatedPixelDensity is the device pixel ratio used to pick srcset candidates
+	EmulatedPixelDensity = 1.0 // Standard density display
+	// WidthDescriptor is the srcset suffix of width descriptors (480w)
+	WidthDescriptor = "w"
+	// DensityDescriptor is the srcset suffix of pixel density descriptors (2x)
+	DensityDescriptor = "x"
+	// SrcsetWhitespace holds the ASCII whitespace separating srcset URLs and descriptors
+	SrcsetWhitespace = " \t\n\f\r"
+
+	// The patterns below only match inside a single tag ([^>]), so attributes spread
+	// over several lines are found and a match never runs on into the next tag.
+	// Requiring whitespace before src/href keeps data-src and similar attributes out,
+	// and each quote style has its own group so "it's.png" is not cut at the apostrophe.
 
 	// ScriptSrcPattern specifies the regex pattern for extracting script source URLs
-	ScriptSrcPattern = `<script.*?src=["'\''](.*?)["'\''].*?>`
+	ScriptSrcPattern = `(?i)<script[^>]*?\ssrc\s*=\s*(?:"([^"]*)"|'([^']*)')`
 	// LinkHrefPattern specifies the regex pattern for extracting link href URLs
-	LinkHrefPattern = `<link.*?href=["'\''](.*?)["'\''].*?>`
+	LinkHrefPattern = `(?i)<link[^>]*?\shref\s*=\s*(?:"([^"]*)"|'([^']*)')`
 	// ImageSrcPattern specifies the regex pattern for extracting img src URLs
-	ImageSrcPattern = `<img.*?src=["'\''](.*?)["'\''].*?>`
+	ImageSrcPattern = `(?i)<img[^>]*?\ssrc\s*=\s*(?:"([^"]*)"|'([^']*)')`
 	// BackgroundImagePattern specifies the regex pattern for extracting background image URLs from CSS
-	BackgroundImagePattern = `background-image\s*:\s*url\(["'\'']?(.*?)["'\'']?\)`
+	BackgroundImagePattern = `(?i)background(?:-image)?\s*:[^;{}"'<>]*?url\(\s*(?:"([^"]*)"|'([^']*)'|([^)"'\s]*))\s*\)`
+	// CSSURLPattern specifies the regex pattern for any url() reference in CSS
+	CSSURLPattern = `(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)"'\s]*))\s*\)`
+	// StyleAttributePattern specifies the regex pattern for inline style attributes
+	StyleAttributePattern = `(?i)\sstyle\s*=\s*(?:"([^"]*)"|'([^']*)')`
+	// AssetTagPattern matches the start tags in AssetSelector and the end tags of the containers
+	// (picture, video, audio) whose <source> children depend on them. Group 1 is the tag
+	// name, prefixed with / for end tags, and group 2 the raw attributes.
+	AssetTagPattern = `(?i)<(/(?:picture|video|audio)|script|link|img|picture|source|video|audio|` +
+		`iframe|frame|embed|object)\b([^>]*)>`
+	// AttributePattern matches one name=value attribute in quoted or unquoted form
+	AttributePattern = `([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+))`
+
+	// CSSCommentPattern matches a CSS comment
+	CSSCommentPattern = `(?s)/\*.*?\*/`
+	// CSSImportPattern matches an @import rule in its url() or string form
+	CSSImportPattern = `(?i)@import\s+(?:url\(\s*(?:"([^"]*)"|'([^']*)'|([^)"'\s]*))\s*\)|"([^"]*)"|'([^']*)')[^;]*;?`
+	// FontFacePattern matches an @font-face rule; group 1 is its declaration block
+	FontFacePattern = `(?i)@font-face\s*\{([^}]*)\}`
+	// FontSrcPattern matches the src descriptor of an @font-face block; group 1 is its value
+	FontSrcPattern = `(?i)(?:^|[;{])\s*src\s*:([^;]*)`
+	// FontSourcePattern matches one url() source of a font src list with its optional
+	// format() hint in group 4
+	FontSourcePattern = `(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)"'\s]*))\s*\)` +
+		`(?:\s*format\(\s*["']?([^"')]*)["']?\s*\))?`
+
+	// FontFormatEOT is the format() hint of Embedded OpenType fonts, which only IE loads
+	FontFormatEOT = "embedded-opentype"
+	// FontFormatSVG is the format() hint of SVG fonts, which current browsers do not load
+	FontFormatSVG = "svg"
 )
@@ -0,0 +1,67 @@
+package httputils
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+var (
+	cssCommentRegex = regexp.MustCompile(CSSCommentPattern)
+	cssImportRegex  = regexp.MustCompile(CSSImportPattern)
+	fontFaceRegex   = regexp.MustCompile(FontFacePattern)
+	fontSrcRegex    = regexp.MustCompile(FontSrcPattern)
+	fontSourceRegex = regexp.MustCompile(FontSourcePattern)
+)
+
+/*
+ParseStylesheet returns the sub-resources a browser may request for a stylesheet:
+  - CSS - the targets of @import rules
+  - Fonts - one source per @font-face rule, the first format the browser supports
+  - Images - every other url() reference, e.g. backgrounds, cursors and list markers
+
+Urls are returned as written; they are relative to the stylesheet, not the page.
+Images are reported whether or not a rule matches an element of the page, and
+fragment-only references like url(#clip) are skipped.
+*/
+func ParseStylesheet(css string) *interfaces.Assets {
+	set := newAssetSet()
+	css = cssCommentRegex.ReplaceAllString(css, " ")
+
+	for _, url := range findAll(cssImportRegex, css, RegexMatchLimit) {
+		set.add(&set.css, url)
+	}
+	css = cssImportRegex.ReplaceAllString(css, " ")
+
+	for _, block := range findAll(fontFaceRegex, css, RegexMatchLimit) {
+		set.add(&set.fonts, pickFontSource(block))
+	}
+	css = fontFaceRegex.ReplaceAllString(css, " ")
+
+	for _, url := range findAll(cssURLRegex, css, RegexMatchLimit) {
+		if !strings.HasPrefix(url, "#") {
+			set.add(&set.img, url)
+		}
+	}
+	return set.assets()
+}
+
+// pickFontSource returns the font a browser downloads for an @font-face block: the last
+// src descriptor wins and within it the first url() in a format the browser can load
+func pickFontSource(block string) string {
+	srcs := findAll(fontSrcRegex, block, RegexMatchLimit)
+	if len(srcs) == 0 {
+		return ""
+	}
+	for _, match := range fontSourceRegex.FindAllStringSubmatch(srcs[len(srcs)-1], RegexMatchLimit) {
+		switch strings.ToLower(strings.TrimSpace(match[4])) {
+		case FontFormatEOT, FontFormatSVG:
+			continue
+		}
+		if url := firstGroup(match[1:4]); url != "" {
+			return url
+		}
+	}
+	return ""
+}
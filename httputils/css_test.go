@@ -0,0 +1,70 @@
+package httputils
+
+import (
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+const testStylesheet = `
+@charset "utf-8";
+@import url("reset.css");
+@import 'theme.css' screen and (min-width: 600px);
+@import url(print.css) print;
+/* @import "commented.css"; background: url(commented.png) */
+
+@font-face {
+	font-family: "Legacy";
+	src: url("fonts/legacy.eot");
+	src: url("fonts/legacy.eot?#iefix") format("embedded-opentype"),
+	     url("fonts/legacy.svg#legacy") format("svg"),
+	     url("fonts/legacy.woff2") format("woff2"),
+	     url("fonts/legacy.woff") format("woff");
+}
+@font-face {
+	font-family: Inter;
+	src: local("Inter"), url(../fonts/inter.woff2) format('woff2');
+}
+@font-face { font-family: Unused; font-display: swap; }
+
+.hero { background: #000 url('../img/hero.jpg') no-repeat; }
+.hero { background-image: url(../img/hero.jpg); }
+.cursor { cursor: url("/img/pointer.cur"), auto; }
+ul { list-style-image: url(data:image/png;base64,AAAA); }
+.clip { clip-path: url(#clip); }
+`
+
+func TestParseStylesheet(t *testing.T) {
+	color.Green("~~ TestParseStylesheet ~~")
+	assets := ParseStylesheet(testStylesheet)
+
+	testEquality(assets.CSS, []string{"reset.css", "theme.css", "print.css"}, t)
+	testEquality(assets.Fonts, []string{"fonts/legacy.woff2", "../fonts/inter.woff2"}, t)
+	testEquality(assets.Images, []string{"../img/hero.jpg", "/img/pointer.cur", "data:image/png;base64,AAAA"}, t)
+	if assets.Total != 8 {
+		t.Errorf("Total = %d, want 8", assets.Total)
+	}
+}
+
+func TestPickFontSource(t *testing.T) {
+	tests := []struct {
+		name  string
+		block string
+		want  string
+	}{
+		{"no src", `font-family: A;`, ""},
+		{"single", `src: url(a.woff2);`, "a.woff2"},
+		{"local only", `src: local("A");`, ""},
+		{"last src wins", `src: url(a.eot); src: url(a.woff);`, "a.woff"},
+		{"unsupported formats skipped", `src: url(a.eot) format("embedded-opentype"), url(a.ttf) format("truetype")`,
+			"a.ttf"},
+		{"font-family is not src", `font-family: src; src: url('b.woff')`, "b.woff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickFontSource(tt.block); got != tt.want {
+				t.Errorf("pickFontSource(%q) = %q, want %q", tt.block, got, tt.want)
+			}
+		})
+	}
+}
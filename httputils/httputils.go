@@ -1,11 +1,17 @@
 package httputils
 
 import (
-	"log"
-	"regexp"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+var (
+	sequentialRegexParser = NewAssetParser(interfaces.ParsingMethodRegex, false, RegexMatchLimit)
+	concurrentRegexParser = NewAssetParser(interfaces.ParsingMethodRegex, true, RegexMatchLimit)
+	domParser             = NewAssetParser(interfaces.ParsingMethodDOM, false, RegexMatchLimit)
 )
 
 /*
@@ -15,128 +21,92 @@ The method runs lineearly.
 In benchmark you will see that ParseAllAssets is generally faster and GetAssets is faster still
 */
 func ParseAllAssetsSequential(body string) (js, img, css []string) {
-	jsfiles := GetJS(body)
-	cssfiles := GetCSS(body)
-	imgfiles := GetIMG(body)
-	return jsfiles, imgfiles, cssfiles
+	assets, _ := sequentialRegexParser.ParseAssets(body)
+	return assets.JavaScript, assets.Images, assets.CSS
 }
 
 /*
 GetAssets takes a string of test from an http.Response.Body and returns the
-urls for the page <script>, <link>, and <img> tags.
-It makes use of the goquery library and is currently the fastest method
+urls of the page's scripts, images and stylesheets.
+It makes use of the goquery library and is currently the fastest method.
+If the document can't be parsed it falls back to ParseAllAssets.
+Use GetAllAssets for fonts, media, icons, frames and other assets as well.
 */
 func GetAssets(body string) (js, img, css []string) {
-	utfBody := strings.NewReader(body)
-	doc, err := goquery.NewDocumentFromReader(utfBody)
-	if err != nil {
-		log.Println("Unable to parse document with goquery.  Make sure it is utf8")
-		return ParseAllAssets(body)
-	}
-	goroutine := false
-	jsfiles := []string{}
-	imgfiles := []string{}
-	cssfiles := []string{}
-
-	if goroutine {
-		c1 := make(chan []string)
-		c2 := make(chan []string)
-		c3 := make(chan []string)
+	assets, _ := domParser.ParseAssets(body)
+	return assets.JavaScript, assets.Images, assets.CSS
+}
 
-		go func() { c1 <- getAttr(doc, ScriptTag, SrcAttribute) }()
-		go func() { c2 <- getAttr(doc, ImageTag, SrcAttribute) }()
-		go func() { c3 <- getAttr(doc, LinkTag, HrefAttribute) }()
+// GetAllAssets returns every asset of the page classified by type, using the DOM parser
+func GetAllAssets(body string) *interfaces.Assets {
+	assets, _ := domParser.ParseAssets(body)
+	return assets
+}
 
-		for i := 0; i < AssetTypesCount; i++ {
-			select {
-			case jsfiles = <-c1:
-			case imgfiles = <-c2:
-			case cssfiles = <-c3:
-			}
-		}
-	} else {
-		jsfiles = getAttr(doc, ScriptTag, SrcAttribute)
-		imgfiles = getAttr(doc, ImageTag, SrcAttribute)
-		cssfiles = getAttr(doc, LinkTag, HrefAttribute)
+/*
+GetBaseHref returns the href of the document's first <base> element, or an empty
+string if there is none. Relative asset URLs resolve against it instead of the page URL.
+*/
+func GetBaseHref(body string) string {
+	// Avoid a second DOM parse for the common case of pages without <base>
+	if !strings.Contains(body, "<base") && !strings.Contains(body, "<BASE") {
+		return ""
 	}
-
-	return jsfiles, imgfiles, cssfiles
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	href, _ := doc.Find(BaseTag).First().Attr(HrefAttribute)
+	return href
 }
 
 /*
-geAttr takes a *goquery.Document a html tag and attr
-and returns a list of those attributes
+GetLinks returns the href of every <a> and <area> element of the page in document
+order, leaving out links marked rel="nofollow". The hrefs are returned as written.
 */
-func getAttr(doc *goquery.Document, tag, attr string) []string {
-	files := []string{}
-	doc.Find(tag).Each(func(_ int, s *goquery.Selection) {
-		value, exists := s.Attr(attr)
-		if exists {
-			files = append(files, value)
+func GetLinks(body string) []string {
+	doc, err := newDocument(body)
+	if err != nil {
+		return []string{}
+	}
+	links := []string{}
+	doc.Find(LinkSelector).Each(func(_ int, s *goquery.Selection) {
+		rel, _ := s.Attr(RelAttribute)
+		for _, token := range strings.Fields(strings.ToLower(rel)) {
+			if token == NoFollowRel {
+				return
+			}
+		}
+		if href, _ := s.Attr(HrefAttribute); strings.TrimSpace(href) != "" {
+			links = append(links, strings.TrimSpace(href))
 		}
 	})
-	return files
+	return links
 }
 
 /*
 ParseAllAssets takes string of text (typically from a http.Response.Body)
 and return the urls for the page <script> <link> and <img> tag.
-The method uses separate go routines for each asset class.
+The method runs the tag and style scans in separate go routines.
 It is faster than ParseAllAssetsSequentially, but still slower than GetAssets
 */
 func ParseAllAssets(body string) (js, img, css []string) {
-	// make some channels
-	c1 := make(chan []string)
-	c2 := make(chan []string)
-	c3 := make(chan []string)
-
-	// kick off our anonymous go routines.
-	go func() { c1 <- GetJS(body) }()
-	go func() { c2 <- GetIMG(body) }()
-	go func() { c3 <- GetCSS(body) }()
-
-	// collect our results
-	jsfiles := []string{}
-	imgfiles := []string{}
-	cssfiles := []string{}
-
-	for i := 0; i < AssetTypesCount; i++ {
-		select {
-		case jsfiles = <-c1:
-		case imgfiles = <-c2:
-		case cssfiles = <-c3:
-		}
-	}
-
-	return jsfiles, imgfiles, cssfiles
+	assets, _ := concurrentRegexParser.ParseAssets(body)
+	return assets.JavaScript, assets.Images, assets.CSS
 }
 
 // GetJS uses regex to parse a body of text and return the script src attributes
 func GetJS(body string) []string {
-	return runregex(ScriptSrcPattern, body)
+	return sequentialRegexParser.runregex(scriptSrcRegex, body)
 }
 
 // GetCSS uses regex to parse a body of text and return the <link> href attributes
 func GetCSS(body string) []string {
-	return runregex(LinkHrefPattern, body)
+	return sequentialRegexParser.runregex(linkHrefRegex, body)
 }
 
 // GetIMG uses regex to parse a body of text and return the <img> src attributes
 func GetIMG(body string) []string {
-	backgroundimgs := runregex(BackgroundImagePattern, body)
-	imgs := runregex(ImageSrcPattern, body)
-	imgs = append(imgs, backgroundimgs...)
-	return imgs
-}
-
-// Take a regex expression that returns the matched object
-// and return an array of the matched text
-func runregex(expr, body string) []string {
-	r, _ := regexp.Compile(expr)
-	match := r.FindAllStringSubmatch(body, RegexMatchLimit)
-	files := make([]string, 0)
-	for j := 0; j < len(match); j++ {
-		files = append(files, match[j][1])
-	}
-	return files
+	imgs := sequentialRegexParser.runregex(imageSrcRegex, body)
+	return append(imgs, sequentialRegexParser.runregex(backgroundImageRegex, body)...)
 }
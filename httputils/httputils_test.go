@@ -2,6 +2,7 @@ package httputils
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/gnulnx/color"
@@ -68,11 +69,9 @@ func TestParseAllAssets(t *testing.T) {
 	}
 	testEquality(imgfiles, testData, t)
 
-	//Test css Results
+	//Test css Results (the manifest and favicon links are not stylesheets)
 	testData = []string{
-		`/media/manifest.webmanifest`,
 		`/static/vendor/icomoon/style.css`,
-		`/media/favicon_94S_icon.ico`,
 		`/static/vendor/bootstrap/bootstrap.min.css`,
 		`/static/tcart/css/styles.min.css`,
 	}
@@ -99,3 +98,13 @@ func BenchmarkGetAssets(b *testing.B) {
 		GetAssets(body)
 	}
 }
+
+func TestGetLinks(t *testing.T) {
+	color.Green("~~ TestGetLinks ~~")
+	body := `<a href="/a">A</a><a name="anchor">no href</a><a href=" b.html ">B</a>
+<a href="/c" rel="external NOFOLLOW">C</a><map><area href="/d" alt="D"></map><a href="">empty</a>`
+	links := GetLinks(body)
+	if strings.Join(links, " ") != "/a b.html /d" {
+		t.Errorf("GetLinks = %q, want [/a b.html /d]", links)
+	}
+}
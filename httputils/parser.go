@@ -0,0 +1,272 @@
+package httputils
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+var (
+	scriptSrcRegex       = regexp.MustCompile(ScriptSrcPattern)
+	linkHrefRegex        = regexp.MustCompile(LinkHrefPattern)
+	imageSrcRegex        = regexp.MustCompile(ImageSrcPattern)
+	backgroundImageRegex = regexp.MustCompile(BackgroundImagePattern)
+	cssURLRegex          = regexp.MustCompile(CSSURLPattern)
+	styleAttributeRegex  = regexp.MustCompile(StyleAttributePattern)
+	assetTagRegex        = regexp.MustCompile(AssetTagPattern)
+	attributeRegex       = regexp.MustCompile(AttributePattern)
+)
+
+/*
+AssetParser is the interfaces.AssetParser implementation backed by this package.
+
+Every method classifies assets the way a browser requests them: <link> by its rel
+(and as for preloads), one candidate out of srcset and <picture>, video posters and
+sources, iframes and url() references in inline style attributes.
+
+Parsing methods
+  - ParsingMethodRegex - regular expressions only; cheapest, but blind to markup structure
+  - ParsingMethodDOM - goquery over the parsed document; falls back to regex if the
+    document cannot be parsed
+  - ParsingMethodMixed - DOM plus a regex scan of the whole body for background images,
+    which also finds the ones declared in <style> blocks
+
+When concurrent is set the independent scans run in their own goroutines.
+regexLimit caps the matches per pattern; a negative value means unlimited.
+An AssetParser is safe for use by multiple goroutines.
+*/
+type AssetParser struct {
+	method     atomic.Int32
+	concurrent bool
+	regexLimit int
+}
+
+// NewAssetParser creates a parser using method
+func NewAssetParser(method interfaces.ParsingMethod, concurrent bool, regexLimit int) *AssetParser {
+	p := &AssetParser{concurrent: concurrent, regexLimit: regexLimit}
+	p.method.Store(int32(method))
+	return p
+}
+
+// ParsingMethod returns the method currently in use
+func (p *AssetParser) ParsingMethod() interfaces.ParsingMethod {
+	return interfaces.ParsingMethod(p.method.Load())
+}
+
+// SetParsingMethod implements interfaces.AssetParser
+func (p *AssetParser) SetParsingMethod(method interfaces.ParsingMethod) {
+	p.method.Store(int32(method))
+}
+
+// ParseAssets implements interfaces.AssetParser
+func (p *AssetParser) ParseAssets(body string) (*interfaces.Assets, error) {
+	set, err := p.assets(body)
+	if err != nil {
+		return nil, err
+	}
+	return set.assets(), nil
+}
+
+// ParseJS implements interfaces.AssetParser.
+// The whole page is classified, so scripts preloaded with <link> are included.
+func (p *AssetParser) ParseJS(body string) ([]string, error) {
+	assets, err := p.ParseAssets(body)
+	if err != nil {
+		return nil, err
+	}
+	return assets.JavaScript, nil
+}
+
+// ParseCSS implements interfaces.AssetParser.
+// Only stylesheets are returned; icons, manifests and other links are not CSS.
+func (p *AssetParser) ParseCSS(body string) ([]string, error) {
+	assets, err := p.ParseAssets(body)
+	if err != nil {
+		return nil, err
+	}
+	return assets.CSS, nil
+}
+
+// ParseImages implements interfaces.AssetParser
+func (p *AssetParser) ParseImages(body string) ([]string, error) {
+	assets, err := p.ParseAssets(body)
+	if err != nil {
+		return nil, err
+	}
+	return assets.Images, nil
+}
+
+// assets classifies every asset of body with the configured method
+func (p *AssetParser) assets(body string) (*assetSet, error) {
+	method := p.ParsingMethod()
+	switch method {
+	case interfaces.ParsingMethodRegex:
+		return p.run(p.regexElements(body), p.regexStyles(body)), nil
+	case interfaces.ParsingMethodDOM, interfaces.ParsingMethodMixed:
+	default:
+		return nil, fmt.Errorf("unsupported parsing method %s", method)
+	}
+
+	doc, err := newDocument(body)
+	if err != nil {
+		log.Printf("Unable to parse document with goquery, falling back to regex: %v", err)
+		return p.run(p.regexElements(body), p.regexStyles(body)), nil
+	}
+	tasks := []func(*assetSet){domElements(doc), domStyles(doc)}
+	if method == interfaces.ParsingMethodMixed {
+		tasks = append(tasks, p.regexBackgrounds(body))
+	}
+	return p.run(tasks...), nil
+}
+
+// run executes the scans, each into its own set, and merges the results in order.
+// The scans run in their own goroutines when the parser is concurrent.
+func (p *AssetParser) run(tasks ...func(*assetSet)) *assetSet {
+	sets := make([]*assetSet, len(tasks))
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		sets[i] = newAssetSet()
+		if !p.concurrent {
+			task(sets[i])
+			continue
+		}
+		wg.Add(1)
+		go func(task func(*assetSet), set *assetSet) {
+			defer wg.Done()
+			task(set)
+		}(task, sets[i])
+	}
+	wg.Wait()
+
+	for _, set := range sets[1:] {
+		sets[0].merge(set)
+	}
+	return sets[0]
+}
+
+// domElements classifies the asset elements of doc in document order
+func domElements(doc *goquery.Document) func(*assetSet) {
+	return func(set *assetSet) {
+		doc.Find(AssetSelector).Each(func(_ int, s *goquery.Selection) {
+			node := s.Get(0)
+			attrs := make(map[string]string, len(node.Attr))
+			for _, attr := range node.Attr {
+				attrs[attr.Key] = attr.Val
+			}
+			set.element(node.Data, attrs, goquery.NodeName(s.Parent()))
+		})
+	}
+}
+
+// domStyles collects the url() references of inline style attributes as images
+func domStyles(doc *goquery.Document) func(*assetSet) {
+	return func(set *assetSet) {
+		doc.Find("[" + StyleAttribute + "]").Each(func(_ int, s *goquery.Selection) {
+			style, _ := s.Attr(StyleAttribute)
+			for _, url := range findAll(cssURLRegex, style, -1) {
+				set.add(&set.img, url)
+			}
+		})
+	}
+}
+
+// regexElements classifies asset tags found with regular expressions. The enclosing
+// picture, video or audio element is tracked through its start and end tags.
+func (p *AssetParser) regexElements(body string) func(*assetSet) {
+	return func(set *assetSet) {
+		container := ""
+		for _, match := range assetTagRegex.FindAllStringSubmatch(body, p.regexLimit) {
+			tag := strings.ToLower(match[1])
+			if closing, ok := strings.CutPrefix(tag, "/"); ok {
+				if closing == container {
+					container = ""
+				}
+				continue
+			}
+			set.element(tag, parseAttributes(match[2]), container)
+			switch tag {
+			case PictureTag, VideoTag, AudioTag:
+				container = tag
+			}
+		}
+	}
+}
+
+// regexStyles collects url() references of inline style attributes and background
+// images anywhere in body as images
+func (p *AssetParser) regexStyles(body string) func(*assetSet) {
+	backgrounds := p.regexBackgrounds(body)
+	return func(set *assetSet) {
+		for _, style := range findAll(styleAttributeRegex, body, p.regexLimit) {
+			for _, url := range findAll(cssURLRegex, html.UnescapeString(style), -1) {
+				set.add(&set.img, url)
+			}
+		}
+		backgrounds(set)
+	}
+}
+
+// regexBackgrounds collects background images declared anywhere in body
+func (p *AssetParser) regexBackgrounds(body string) func(*assetSet) {
+	return func(set *assetSet) {
+		for _, url := range findAll(backgroundImageRegex, body, p.regexLimit) {
+			// Inside style attributes quotes may still be escaped as &quot;
+			set.add(&set.img, strings.Trim(html.UnescapeString(url), `"'`))
+		}
+	}
+}
+
+// parseAttributes splits the raw attributes of a start tag into a map keyed by
+// lower-case name. Attributes without a value, like nomodule, map to "".
+func parseAttributes(raw string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range attributeRegex.FindAllStringSubmatch(raw, -1) {
+		attrs[strings.ToLower(match[1])] = html.UnescapeString(firstGroup(match[2:]))
+	}
+	for _, name := range strings.Fields(attributeRegex.ReplaceAllString(raw, " ")) {
+		name = strings.ToLower(strings.Trim(name, "/"))
+		if _, ok := attrs[name]; !ok && name != "" {
+			attrs[name] = ""
+		}
+	}
+	return attrs
+}
+
+// runregex returns the first non-empty capture group of every match of r in body
+func (p *AssetParser) runregex(r *regexp.Regexp, body string) []string {
+	return findAll(r, body, p.regexLimit)
+}
+
+// findAll returns the first non-empty capture group of up to limit matches of r in s
+func findAll(r *regexp.Regexp, s string, limit int) []string {
+	files := make([]string, 0)
+	for _, match := range r.FindAllStringSubmatch(s, limit) {
+		if group := firstGroup(match[1:]); group != "" {
+			files = append(files, group)
+		}
+	}
+	return files
+}
+
+// firstGroup returns the first non-empty capture group
+func firstGroup(groups []string) string {
+	for _, group := range groups {
+		if group != "" {
+			return group
+		}
+	}
+	return ""
+}
+
+// newDocument parses body into a goquery document
+func newDocument(body string) (*goquery.Document, error) {
+	return goquery.NewDocumentFromReader(strings.NewReader(body))
+}
@@ -0,0 +1,287 @@
+package httputils
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+var (
+	expectedJS = []string{
+		`/static/tcart/js/test1.min.js`,
+		`/static/tcart/js/bundle_kldsf2334.min.js`,
+	}
+	expectedIMG = []string{
+		`/media//teaquinox_header_2.svg`,
+		`/media/cart.svg`,
+		`/media/banners/1-12-2018/SnowyTea_50percent.jpg`,
+		`/media/product_11/Shou_Mei_M.jpeg`,
+		`/media/product_36/Turmeric_Chai_M.jpeg`,
+		`/media/product_45/Luian_Gua_Pian_M.jpeg`,
+		`/media/product_58/NEB_new_m.jpg`,
+		`/media/product_71/Black_Dragon_Pearls_M.jpg`,
+		`/media/product_None/Moroccan_Mint_M.jpg`,
+		`/static/tcart/img/stripe_badges/outline_dark/powered_by_stripe.png`,
+	}
+	expectedBackground = `/media/banners/1-12-2018/SnowyTea_lowres2.jpg`
+	expectedCSS        = []string{
+		`/static/vendor/icomoon/style.css`,
+		`/static/vendor/bootstrap/bootstrap.min.css`,
+		`/static/tcart/css/styles.min.css`,
+	}
+	expectedIcons = []string{`/media/favicon_94S_icon.ico`}
+	expectedOther = []string{`/media/manifest.webmanifest`}
+)
+
+var parsingMethods = []interfaces.ParsingMethod{
+	interfaces.ParsingMethodRegex,
+	interfaces.ParsingMethodDOM,
+	interfaces.ParsingMethodMixed,
+}
+
+func TestAssetParserMethods(t *testing.T) {
+	color.Green("~~ TestAssetParserMethods ~~")
+	body := getTestBody()
+	for _, method := range parsingMethods {
+		for _, concurrent := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/concurrent=%t", method, concurrent), func(t *testing.T) {
+				parser := NewAssetParser(method, concurrent, RegexMatchLimit)
+				assets, err := parser.ParseAssets(body)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				imgs := append(append([]string{}, expectedIMG...), expectedBackground)
+				testEquality(assets.JavaScript, expectedJS, t)
+				testEquality(assets.Images, imgs, t)
+				testEquality(assets.CSS, expectedCSS, t)
+				testEquality(assets.Icons, expectedIcons, t)
+				testEquality(assets.Other, expectedOther, t)
+				want := len(expectedJS) + len(imgs) + len(expectedCSS) + len(expectedIcons) + len(expectedOther)
+				if assets.Total != want {
+					t.Errorf("Total = %d, want %d", assets.Total, want)
+				}
+			})
+		}
+	}
+}
+
+func TestAssetParserSingleClass(t *testing.T) {
+	body := getTestBody()
+	for _, method := range parsingMethods {
+		t.Run(method.String(), func(t *testing.T) {
+			parser := NewAssetParser(method, false, RegexMatchLimit)
+			assets, err := parser.ParseAssets(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			js, _ := parser.ParseJS(body)
+			css, _ := parser.ParseCSS(body)
+			imgs, _ := parser.ParseImages(body)
+			if !reflect.DeepEqual(js, assets.JavaScript) {
+				t.Errorf("ParseJS = %v, want %v", js, assets.JavaScript)
+			}
+			if !reflect.DeepEqual(css, assets.CSS) {
+				t.Errorf("ParseCSS = %v, want %v", css, assets.CSS)
+			}
+			if !reflect.DeepEqual(imgs, assets.Images) {
+				t.Errorf("ParseImages = %v, want %v", imgs, assets.Images)
+			}
+		})
+	}
+}
+
+func TestAssetParserRegexEdgeCases(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"multi-line tag", "<img class=\"a\"\n  alt=\"b\"\n  src=\"/a.png\">", []string{"/a.png"}},
+		{"single quotes", `<img src='/a.png'>`, []string{"/a.png"}},
+		{"apostrophe in value", `<img src="/it's.png">`, []string{"/it's.png"}},
+		{"upper case", `<IMG SRC="/a.png">`, []string{"/a.png"}},
+		{"data-src ignored", `<img data-src="/lazy.png" src="/a.png">`, []string{"/a.png"}},
+		{"no src", `<img alt="x"><p src="/not-an-img.png">`, []string{}},
+		{"background image", `<div style="background-image: url( '/bg.png' )">`, []string{"/bg.png"}},
+	}
+
+	parser := NewAssetParser(interfaces.ParsingMethodRegex, false, RegexMatchLimit)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseImages(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseImages(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssetParserScriptDoesNotSpanTags(t *testing.T) {
+	body := `<script>var a = 1</script><img src="/a.png"><script src="/b.js"></script>`
+	js, err := NewAssetParser(interfaces.ParsingMethodRegex, false, RegexMatchLimit).ParseJS(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(js, []string{"/b.js"}) {
+		t.Errorf("ParseJS = %q, want [/b.js]", js)
+	}
+}
+
+func TestAssetParserRegexLimit(t *testing.T) {
+	body := `<script src="/1.js"></script><script src="/2.js"></script><script src="/3.js"></script>`
+	for _, tt := range []struct {
+		limit int
+		want  int
+	}{{1, 1}, {2, 2}, {10, 3}, {-1, 3}} {
+		js, _ := NewAssetParser(interfaces.ParsingMethodRegex, false, tt.limit).ParseJS(body)
+		if len(js) != tt.want {
+			t.Errorf("limit %d returned %d scripts, want %d", tt.limit, len(js), tt.want)
+		}
+	}
+}
+
+func TestAssetParserSetParsingMethod(t *testing.T) {
+	// Only the mixed method looks inside <style> blocks
+	body := `<style>.hero { background: #fff url("/hero.png") no-repeat; }</style><img src="/a.png">`
+	parser := NewAssetParser(interfaces.ParsingMethodDOM, false, RegexMatchLimit)
+	domImgs, _ := parser.ParseImages(body)
+
+	parser.SetParsingMethod(interfaces.ParsingMethodMixed)
+	if parser.ParsingMethod() != interfaces.ParsingMethodMixed {
+		t.Fatalf("ParsingMethod = %s, want mixed", parser.ParsingMethod())
+	}
+	mixedImgs, _ := parser.ParseImages(body)
+	if len(mixedImgs) != len(domImgs)+1 {
+		t.Errorf("mixed found %d images, want %d", len(mixedImgs), len(domImgs)+1)
+	}
+
+	parser.SetParsingMethod(interfaces.ParsingMethod(42))
+	if _, err := parser.ParseAssets(body); err == nil {
+		t.Error("ParseAssets with an unknown method should fail")
+	}
+}
+
+/*
+Benchmarks comparing the parsing methods on a small hand written page (basic)
+and on a real production page (full), sequentially and with one goroutine per scan.
+
+	go test ./httputils -bench AssetParser -benchmem
+*/
+func BenchmarkAssetParser(b *testing.B) {
+	pages := []struct {
+		name string
+		file string
+	}{
+		{"basic", "test_data/test_basic.html"},
+		{"full", "test_data/test.html"},
+	}
+	for _, page := range pages {
+		bodyBytes, err := os.ReadFile(page.file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		body := string(bodyBytes)
+		for _, method := range parsingMethods {
+			for _, concurrent := range []bool{false, true} {
+				name := fmt.Sprintf("%s/%s/concurrent=%t", page.name, method, concurrent)
+				parser := NewAssetParser(method, concurrent, RegexMatchLimit)
+				b.Run(name, func(b *testing.B) {
+					b.SetBytes(int64(len(body)))
+					for i := 0; i < b.N; i++ {
+						if _, err := parser.ParseAssets(body); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestAssetParserClassification(t *testing.T) {
+	color.Green("~~ TestAssetParserClassification ~~")
+	bodyBytes, err := os.ReadFile("test_data/test_rich.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(bodyBytes)
+
+	images := []string{
+		`/img/hero-large.jpg`,
+		`/img/logo.png`,
+		`/img/photo-1600.jpg`,
+		`/img/badge.png`,
+		`/img/art.avif`,
+		`/img/plain-picture.jpg`,
+		`/img/poster.jpg`,
+		`/img/inline-bg.jpg`,
+		`/img/bullet.gif`,
+	}
+	for _, method := range parsingMethods {
+		t.Run(method.String(), func(t *testing.T) {
+			assets, err := NewAssetParser(method, true, RegexMatchLimit).ParseAssets(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantImages := images
+			if method != interfaces.ParsingMethodDOM {
+				// The <style> block is only scanned by regex
+				wantImages = append(append([]string{}, images...), `/img/banner.png`)
+			}
+			testEquality(assets.JavaScript,
+				[]string{`/js/preloaded.js`, `/js/module-dep.js`, `/js/app.mjs`, `/js/vendor.js`}, t)
+			testEquality(assets.CSS, []string{`/css/site.css`, `/css/contrast.css`, `/css/critical.css`}, t)
+			testEquality(assets.Fonts, []string{`/fonts/inter.woff2`}, t)
+			testEquality(assets.Images, wantImages, t)
+			testEquality(assets.Media, []string{`/media/clip.webm`, `/media/direct.mp4`, `/media/sound.ogg`}, t)
+			testEquality(assets.Icons, []string{`/favicon-32.png`, `/favicon.ico`, `/apple-touch-icon.png`}, t)
+			testEquality(assets.Documents, []string{`/embed/widget.html`}, t)
+			testEquality(assets.Other,
+				[]string{`/api/config.json`, `/site.webmanifest`, `/media/animation.swf`, `/docs/manual.pdf`}, t)
+		})
+	}
+}
+
+func TestPickSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		want   string
+	}{
+		{"empty", "", ""},
+		{"single", "/a.jpg", "/a.jpg"},
+		{"density 1x", "/a.jpg 1x, /a@2x.jpg 2x", "/a.jpg"},
+		{"density unordered", "/a@2x.jpg 2x, /a.jpg 1x", "/a.jpg"},
+		{"density only high", "/a@2x.jpg 2x, /a@3x.jpg 3x", "/a@2x.jpg"},
+		{"density only low", "/a-half.jpg 0.5x, /a-small.jpg 0.75x", "/a-small.jpg"},
+		{"implicit 1x", "/a.jpg, /a@2x.jpg 2x", "/a.jpg"},
+		{"width covers viewport", "/s.jpg 400w, /m.jpg 1400w, /l.jpg 2800w", "/m.jpg"},
+		{"width all smaller", "/s.jpg 400w, /m.jpg 800w", "/m.jpg"},
+		{"no spaces after comma", "/s.jpg 400w,/l.jpg 2000w", "/l.jpg"},
+		{"invalid descriptor skipped", "/bad.jpg 10q, /a.jpg 1x", "/a.jpg"},
+		{"mixed descriptors", "/s.jpg 400w, /a@2x.jpg 2x, /l.jpg 1600w", "/l.jpg"},
+		{"commas in urls", "/w_400,h_300/a.jpg 400w, /w_1600,h_1200/a.jpg 1600w", "/w_1600,h_1200/a.jpg"},
+		{"comma in a url without descriptor", "/c_fill,w_400/a.jpg", "/c_fill,w_400/a.jpg"},
+		{"url ending with a comma", "/b.jpg 2x, /a.jpg, /c.jpg 3x", "/a.jpg"},
+		{"comma without space is part of the url", "/a.jpg,/b.jpg 2x", "/a.jpg,/b.jpg"},
+		{"comma after descriptor only", "/a.jpg 1x,/b.jpg 2x,", "/a.jpg"},
+		{"newlines", "\n  /a@2x.jpg 2x,\n  /a.jpg\t1x\n", "/a.jpg"},
+		{"parentheses keep commas", "/a.jpg 1x (a, b), /b.jpg 2x", "/a.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickSrcset(tt.srcset); got != tt.want {
+				t.Errorf("pickSrcset(%q) = %q, want %q", tt.srcset, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package httputils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// sitemapDocument covers both a <urlset> sitemap and a <sitemapindex>
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+/*
+ParseSitemap parses a sitemaps.org sitemap. A <urlset> returns the page URLs it lists
+and a <sitemapindex> the URLs of the sitemaps it points to. Gzip compressed sitemaps
+(sitemap.xml.gz served without Content-Encoding) are decompressed first.
+*/
+func ParseSitemap(body []byte) (pages, sitemaps []string, err error) {
+	if bytes.HasPrefix(body, []byte(GzipMagic)) {
+		zr, zerr := gzip.NewReader(bytes.NewReader(body))
+		if zerr != nil {
+			return nil, nil, fmt.Errorf("invalid gzip sitemap: %w", zerr)
+		}
+		defer zr.Close()
+		if body, err = io.ReadAll(zr); err != nil {
+			return nil, nil, fmt.Errorf("invalid gzip sitemap: %w", err)
+		}
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid sitemap: %w", err)
+	}
+	pages, sitemaps = []string{}, []string{}
+	for _, u := range doc.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			pages = append(pages, loc)
+		}
+	}
+	for _, s := range doc.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+	return pages, sitemaps, nil
+}
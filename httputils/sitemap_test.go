@@ -0,0 +1,56 @@
+package httputils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+func TestParseSitemap(t *testing.T) {
+	color.Green("~~ TestParseSitemap ~~")
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc><priority>1.0</priority></url>
+  <url><loc>
+    https://example.com/about
+  </loc></url>
+  <url><lastmod>2024-01-01</lastmod></url>
+</urlset>`
+	index := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/sitemap-posts.xml.gz</loc></sitemap>
+</sitemapindex>`
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, _ = zw.Write([]byte(urlset))
+	_ = zw.Close()
+
+	tests := []struct {
+		name         string
+		body         []byte
+		wantPages    []string
+		wantSitemaps []string
+	}{
+		{"urlset", []byte(urlset), []string{"https://example.com/", "https://example.com/about"}, []string{}},
+		{"gzip", gz.Bytes(), []string{"https://example.com/", "https://example.com/about"}, []string{}},
+		{"index", []byte(index), []string{}, []string{"https://example.com/sitemap-posts.xml.gz"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, sitemaps, err := ParseSitemap(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(pages, tt.wantPages) || !reflect.DeepEqual(sitemaps, tt.wantSitemaps) {
+				t.Errorf("ParseSitemap = %q, %q, want %q, %q", pages, sitemaps, tt.wantPages, tt.wantSitemaps)
+			}
+		})
+	}
+
+	if _, _, err := ParseSitemap([]byte("<html><body>not found</body>")); err == nil {
+		t.Error("ParseSitemap should fail on an HTML page")
+	}
+}
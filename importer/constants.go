@@ -0,0 +1,87 @@
+// Package importer converts request definitions from other tools, such as curl command
+// lines and OpenAPI specs, into goperf request files: JSON arrays of interfaces.Request
+// that "goperf -targets" loads like any other targets file.  Postman collections become
+// sequence files instead, whose requests "goperf -sequence" sends in order.
+package importer
+
+const (
+	// SourceCurl selects a curl command line as the import source
+	SourceCurl = "curl"
+	// SourceOpenAPI selects an OpenAPI 3 or Swagger 2 spec, in YAML or JSON, as the import source
+	SourceOpenAPI = "openapi"
+	// SourcePostman selects a Postman v2.1 collection as the import source
+	SourcePostman = "postman"
+
+	// DefaultScheme is the scheme curl assumes for a url without one
+	DefaultScheme = "http://"
+	// SchemeSeparator separates the scheme of a url from the rest
+	SchemeSeparator = "://"
+	// DataFilePrefix marks a curl -d value that names a file to read the body from
+	DataFilePrefix = "@"
+	// DataSeparator joins the values of repeated curl -d options
+	DataSeparator = "&"
+	// HeaderSeparator separates the name and value of a curl -H header
+	HeaderSeparator = ":"
+	// ShortFlagLength is the length of a bare short curl option such as -H
+	ShortFlagLength = 2
+
+	// AuthorizationHeader carries the credentials of curl -u
+	AuthorizationHeader = "Authorization"
+	// BasicAuthPrefix starts a Basic Authorization header value
+	BasicAuthPrefix = "Basic "
+	// BearerAuthPrefix starts a Bearer Authorization header value
+	BearerAuthPrefix = "Bearer "
+	// ContentTypeHeader names the media type of a request body
+	ContentTypeHeader = "Content-Type"
+	// AcceptHeader names the media types a request accepts
+	AcceptHeader = "Accept"
+	// UserAgentHeader carries the curl -A user agent
+	UserAgentHeader = "User-Agent"
+	// RefererHeader carries the curl -e referer
+	RefererHeader = "Referer"
+	// FormContentType is the media type of url-encoded form bodies, which curl -d sends by default
+	FormContentType = "application/x-www-form-urlencoded"
+	// JSONContentType is the media type of JSON bodies
+	JSONContentType = "application/json"
+	// JSONMediaSuffix marks structured JSON media types such as application/problem+json
+	JSONMediaSuffix = "+json"
+
+	// PlaceholderFormat writes a path parameter that has no example as a {{name}} placeholder
+	PlaceholderFormat = "{{%s}}"
+	// MaxSchemaDepth limits how deep example bodies are built from nested or recursive schemas
+	MaxSchemaDepth = 8
+	// MaxVariableDepth limits how many times Postman variables referring to other variables are expanded
+	MaxVariableDepth = 10
+	// RefPrefix starts the local references of a spec, e.g. #/components/schemas/Pet
+	RefPrefix = "#/"
+	// JSONIndent is the indentation of written request files
+	JSONIndent = "  "
+)
+
+// exampleStrings are the example values of string schemas by format
+var exampleStrings = map[string]string{
+	"date":      "2026-01-01",
+	"date-time": "2026-01-01T00:00:00Z",
+	"email":     "user@example.com",
+	"uuid":      "00000000-0000-0000-0000-000000000000",
+	"uri":       "https://example.com/",
+	"url":       "https://example.com/",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"password":  "password",
+	"byte":      "ZXhhbXBsZQ==",
+	"":          "string",
+}
+
+// rawContentTypes are the Content-Type headers of Postman raw bodies by language
+var rawContentTypes = map[string]string{
+	"json":       JSONContentType,
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"text":       "text/plain",
+	"javascript": "application/javascript",
+}
+
+// operationMethods lists the operations of an OpenAPI path item in the order they are imported
+var operationMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "TRACE"}
@@ -0,0 +1,463 @@
+package importer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+// curlCommand collects the options of a curl command line
+type curlCommand struct {
+	req    interfaces.Request
+	method string   // -X, which wins over the method implied by the other options
+	data   []string // -d values, joined with & into the body or, with -G, the query
+	get    bool     // -G
+	head   bool     // -I
+
+	warnings []string // options a request file cannot hold
+}
+
+// curlOption is a supported curl option; options with a nil apply are accepted and ignored
+type curlOption struct {
+	value bool // the option takes a value
+	apply func(c *curlCommand, value string) error
+}
+
+// curlOptions maps the supported curl options, by their short name when they have one
+var curlOptions = map[string]curlOption{
+	"-X":                {true, (*curlCommand).setMethod},
+	"-H":                {true, (*curlCommand).addHeader},
+	"-d":                {true, (*curlCommand).addData},
+	"--data-raw":        {true, (*curlCommand).addRawData},
+	"--data-binary":     {true, (*curlCommand).addBinaryData},
+	"--data-urlencode":  {true, (*curlCommand).addURLEncoded},
+	"--json":            {true, (*curlCommand).addJSON},
+	"-u":                {true, (*curlCommand).setUser},
+	"-b":                {true, (*curlCommand).setCookies},
+	"-A":                {true, (*curlCommand).setUserAgent},
+	"-e":                {true, (*curlCommand).setReferer},
+	"-m":                {true, (*curlCommand).setTimeout},
+	"--url":             {true, (*curlCommand).setURL},
+	"-k":                {false, (*curlCommand).setInsecure},
+	"-G":                {false, (*curlCommand).setGet},
+	"-I":                {false, (*curlCommand).setHead},
+	"-o":                {true, nil}, // output, connection and retry options do not change the request
+	"-w":                {true, nil},
+	"-D":                {true, nil},
+	"-c":                {true, nil},
+	"--connect-timeout": {true, nil},
+	"--retry":           {true, nil},
+	"--max-redirs":      {true, nil},
+	"-s":                {false, nil},
+	"-S":                {false, nil},
+	"-v":                {false, nil},
+	"-i":                {false, nil},
+	"-L":                {false, nil},
+	"-f":                {false, nil},
+	"-g":                {false, nil},
+	"-N":                {false, nil},
+	"--compressed":      {false, nil},
+	"--http1.1":         {false, nil},
+	"--http2":           {false, nil},
+}
+
+// curlAliases maps long curl options to the short name they are listed under in curlOptions
+var curlAliases = map[string]string{
+	"--request": "-X", "--header": "-H", "--data": "-d", "--data-ascii": "-d", "--user": "-u",
+	"--cookie": "-b", "--user-agent": "-A", "--referer": "-e", "--max-time": "-m", "--insecure": "-k",
+	"--get": "-G", "--head": "-I", "--output": "-o", "--write-out": "-w", "--dump-header": "-D",
+	"--cookie-jar": "-c", "--silent": "-s", "--show-error": "-S", "--verbose": "-v", "--include": "-i",
+	"--location": "-L", "--fail": "-f", "--globoff": "-g", "--no-buffer": "-N",
+}
+
+/*
+ParseCurl translates a curl command line, such as one copied from a browser's network
+panel, into a request.  It understands the method (-X, -I, -G), headers (-H, -A, -e),
+bodies (-d and its --data-* variants, --json), credentials (-u, sent as a Basic
+Authorization header) and cookies (-b name=value); output and connection options are
+ignored and any other option is an error.
+
+Like curl, a body makes the request a POST sent as a url-encoded form unless the
+method or Content-Type is given, and a url without a scheme uses http.
+
+The warnings list the options a request file cannot hold: -k and -m apply to every
+request of a test, with the -insecure and -timeout flags.
+*/
+func ParseCurl(command string) (*interfaces.Request, []string, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(args) > 0 && (args[0] == SourceCurl || strings.HasSuffix(args[0], "/"+SourceCurl)) {
+		args = args[1:]
+	}
+
+	c := &curlCommand{req: interfaces.Request{Headers: map[string]string{}}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if err = c.setURL(arg); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		name, value, hasValue := arg, "", false
+		if !strings.HasPrefix(arg, "--") && len(arg) > ShortFlagLength {
+			name, value, hasValue = arg[:ShortFlagLength], arg[ShortFlagLength:], true
+		}
+		if alias, ok := curlAliases[name]; ok {
+			name = alias
+		}
+		option, ok := curlOptions[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported curl option %s", name)
+		}
+		if hasValue && !option.value {
+			// A cluster of short options such as -sSL: the rest is read as the next argument
+			args = slices.Insert(args, i+1, "-"+value)
+			hasValue = false
+		}
+		if option.value && !hasValue {
+			if i++; i == len(args) {
+				return nil, nil, fmt.Errorf("curl option %s needs a value", name)
+			}
+			value = args[i]
+		}
+		if option.apply == nil {
+			continue
+		}
+		if err = option.apply(c, value); err != nil {
+			return nil, nil, err
+		}
+	}
+	req, err := c.request()
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, c.warnings, nil
+}
+
+// request builds the request from the collected options
+func (c *curlCommand) request() (*interfaces.Request, error) {
+	req := c.req
+	if req.URL == "" {
+		return nil, fmt.Errorf("the curl command has no url")
+	}
+	if !strings.Contains(req.URL, SchemeSeparator) {
+		req.URL = DefaultScheme + req.URL
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q in the curl command", req.URL)
+	}
+
+	data := strings.Join(c.data, DataSeparator)
+	switch {
+	case c.get && len(c.data) > 0:
+		if u.RawQuery != "" {
+			u.RawQuery += DataSeparator
+		}
+		u.RawQuery += data
+		req.URL = u.String()
+	case len(c.data) > 0:
+		req.Body = data
+		if headerValue(req.Headers, ContentTypeHeader) == "" {
+			req.Headers[ContentTypeHeader] = FormContentType
+		}
+	}
+
+	switch {
+	case c.method != "":
+		req.Method = c.method
+	case c.head:
+		req.Method = http.MethodHead
+	case req.Body != "":
+		req.Method = http.MethodPost
+	default:
+		req.Method = http.MethodGet
+	}
+	req.Name = req.Method + " " + u.Path
+	if len(req.Headers) == 0 {
+		req.Headers = nil
+	}
+	return &req, nil
+}
+
+func (c *curlCommand) setURL(value string) error {
+	if c.req.URL != "" {
+		return fmt.Errorf("the curl command has more than one url: %s and %s", c.req.URL, value)
+	}
+	c.req.URL = value
+	return nil
+}
+
+func (c *curlCommand) setMethod(value string) error {
+	c.method = strings.ToUpper(value)
+	return nil
+}
+
+// addHeader adds a "Name: value" header; "Name:" removes a header set earlier, as in curl
+func (c *curlCommand) addHeader(value string) error {
+	name, content, ok := strings.Cut(value, HeaderSeparator)
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("curl header %q is not Name: value", value)
+	}
+	deleteHeader(c.req.Headers, name)
+	if content = strings.TrimSpace(content); content != "" {
+		c.req.Headers[name] = content
+	}
+	return nil
+}
+
+// addData adds a -d value, reading @file and dropping its line breaks as curl does
+func (c *curlCommand) addData(value string) error {
+	if !strings.HasPrefix(value, DataFilePrefix) {
+		c.data = append(c.data, value)
+		return nil
+	}
+	data, err := readDataFile(value)
+	if err != nil {
+		return err
+	}
+	c.data = append(c.data, strings.NewReplacer("\r", "", "\n", "").Replace(data))
+	return nil
+}
+
+// addRawData adds a --data-raw value, where @ has no special meaning
+func (c *curlCommand) addRawData(value string) error {
+	c.data = append(c.data, value)
+	return nil
+}
+
+// addBinaryData adds a --data-binary value, reading @file as is
+func (c *curlCommand) addBinaryData(value string) error {
+	if !strings.HasPrefix(value, DataFilePrefix) {
+		c.data = append(c.data, value)
+		return nil
+	}
+	data, err := readDataFile(value)
+	if err != nil {
+		return err
+	}
+	c.data = append(c.data, data)
+	return nil
+}
+
+// addURLEncoded adds a --data-urlencode value: content, =content or name=content
+func (c *curlCommand) addURLEncoded(value string) error {
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		name, content = "", value
+	}
+	encoded := url.QueryEscape(content)
+	if name != "" {
+		encoded = name + "=" + encoded
+	}
+	c.data = append(c.data, encoded)
+	return nil
+}
+
+// addJSON adds a --json body, which also sets the JSON Content-Type and Accept headers
+func (c *curlCommand) addJSON(value string) error {
+	if err := c.addBinaryData(value); err != nil {
+		return err
+	}
+	for _, name := range []string{ContentTypeHeader, AcceptHeader} {
+		if headerValue(c.req.Headers, name) == "" {
+			c.req.Headers[name] = JSONContentType
+		}
+	}
+	return nil
+}
+
+// setUser turns -u user:password into a Basic Authorization header
+func (c *curlCommand) setUser(value string) error {
+	deleteHeader(c.req.Headers, AuthorizationHeader)
+	c.req.Headers[AuthorizationHeader] = BasicAuthPrefix + base64.StdEncoding.EncodeToString([]byte(value))
+	return nil
+}
+
+// setCookies sets -b name=value cookies; a cookie file cannot be imported
+func (c *curlCommand) setCookies(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("curl cookie file %q cannot be imported, pass the cookies as name=value", value)
+	}
+	if c.req.Cookies != "" {
+		c.req.Cookies += "; "
+	}
+	c.req.Cookies += value
+	return nil
+}
+
+func (c *curlCommand) setUserAgent(value string) error {
+	deleteHeader(c.req.Headers, UserAgentHeader)
+	c.req.Headers[UserAgentHeader] = value
+	return nil
+}
+
+func (c *curlCommand) setReferer(value string) error {
+	deleteHeader(c.req.Headers, RefererHeader)
+	c.req.Headers[RefererHeader] = value
+	return nil
+}
+
+// setTimeout checks -m, in seconds; targets have no timeout of their own
+func (c *curlCommand) setTimeout(value string) error {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return fmt.Errorf("invalid curl timeout %q", value)
+	}
+	c.warnings = append(c.warnings, fmt.Sprintf("-m %s is not imported: run the test with -timeout %s",
+		value, time.Duration(seconds*float64(time.Second))))
+	return nil
+}
+
+// setInsecure notes -k, which targets cannot hold
+func (c *curlCommand) setInsecure(string) error {
+	c.warnings = append(c.warnings, "-k is not imported: run the test with -insecure")
+	return nil
+}
+
+func (c *curlCommand) setGet(string) error {
+	c.get = true
+	return nil
+}
+
+func (c *curlCommand) setHead(string) error {
+	c.head = true
+	return nil
+}
+
+// readDataFile reads the file named by a @file option value
+func readDataFile(value string) (string, error) {
+	data, err := os.ReadFile(strings.TrimPrefix(value, DataFilePrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to read curl data file: %w", err)
+	}
+	return string(data), nil
+}
+
+// headerValue returns the value of the header name in headers, whatever its case
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// deleteHeader removes the header name from headers, whatever its case
+func deleteHeader(headers map[string]string, name string) {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			delete(headers, key)
+		}
+	}
+}
+
+/*
+splitCommand splits a shell command line into its arguments the way a POSIX shell
+would for the quoting found in copied curl commands: 'single', "double" with
+backslash escapes, $'ANSI-C' quotes and backslash-newline continuations.
+*/
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	for i := 0; i < len(command); i++ {
+		ch := command[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+			continue
+		case ch == '\\':
+			if i++; i == len(command) {
+				return nil, fmt.Errorf("the command ends with a backslash")
+			}
+			if command[i] == '\n' {
+				// Line continuation
+				continue
+			}
+			current.WriteByte(command[i])
+		case ch == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ' quote in the command")
+			}
+			current.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+		case ch == '"':
+			n, err := readDoubleQuoted(command[i+1:], &current)
+			if err != nil {
+				return nil, err
+			}
+			i += n
+		case ch == '$' && i+1 < len(command) && command[i+1] == '\'':
+			n, err := readANSIQuoted(command[i+2:], &current)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+		default:
+			current.WriteByte(ch)
+		}
+		inArg = true
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// readDoubleQuoted copies a "double quoted" string into b and returns its length including the closing quote
+func readDoubleQuoted(s string, b *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			return i + 1, nil
+		case s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0:
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			i++
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return 0, fmt.Errorf("unterminated \" quote in the command")
+}
+
+// readANSIQuoted copies a $'ANSI-C quoted' string into b and returns its length including the closing quote
+func readANSIQuoted(s string, b *strings.Builder) (int, error) {
+	escapes := map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '\\': '\\', '\'': '\'', '"': '"'}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			return i + 1, nil
+		case s[i] == '\\' && i+1 < len(s):
+			if escaped, ok := escapes[s[i+1]]; ok {
+				b.WriteByte(escaped)
+			} else {
+				b.WriteString(s[i : i+2])
+			}
+			i++
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return 0, fmt.Errorf("unterminated $' quote in the command")
+}
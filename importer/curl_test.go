@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+func TestParseCurl(t *testing.T) {
+	color.Green("~~ TestParseCurl ~~")
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "body.txt")
+	if err := os.WriteFile(dataFile, []byte("a=1\nb=2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		command  string
+		want     interfaces.Request
+		warnings []string
+	}{
+		{
+			name:    "plain get",
+			command: "curl example.com/health",
+			want:    interfaces.Request{URL: "http://example.com/health", Method: "GET", Name: "GET /health"},
+		},
+		{
+			name: "copied from a browser",
+			command: `curl 'https://api.example.com/v1/items?page=2' \
+  -H 'accept: application/json' \
+  -H 'x-trace: a b' \
+  -b 'session=abc; theme=dark' \
+  --data-raw $'{"name":"it\'s"}' \
+  --compressed`,
+			want: interfaces.Request{
+				URL:    "https://api.example.com/v1/items?page=2",
+				Method: "POST",
+				Headers: map[string]string{
+					"accept": "application/json", "x-trace": "a b", "Content-Type": "application/x-www-form-urlencoded",
+				},
+				Cookies: "session=abc; theme=dark",
+				Body:    `{"name":"it's"}`,
+				Name:    "POST /v1/items",
+			},
+		},
+		{
+			name: "method, user, insecure and timeout",
+			command: `curl -sSLk -XPUT -u admin:s3cret -m 2.5 -H "Content-Type: application/json" ` +
+				`-d "{\"on\":true}" https://a.test/x`,
+			want: interfaces.Request{
+				URL:    "https://a.test/x",
+				Method: "PUT",
+				Headers: map[string]string{
+					"Content-Type": "application/json", "Authorization": "Basic YWRtaW46czNjcmV0",
+				},
+				Body: `{"on":true}`,
+				Name: "PUT /x",
+			},
+			warnings: []string{"-k is not imported: run the test with -insecure",
+				"-m 2.5 is not imported: run the test with -timeout 2.5s"},
+		},
+		{
+			name:    "get with data, file and urlencode",
+			command: "curl --get https://a.test/search?x=0 -d @" + dataFile + " --data-urlencode 'q=a b&c'",
+			want: interfaces.Request{
+				URL:    "https://a.test/search?x=0&a=1b=2&q=a+b%26c",
+				Method: "GET",
+				Name:   "GET /search",
+			},
+		},
+		{
+			name:    "json and header removal",
+			command: `curl --json '{"a":1}' -A goperf -H 'Accept:' --url https://a.test/api`,
+			want: interfaces.Request{
+				URL:     "https://a.test/api",
+				Method:  "POST",
+				Headers: map[string]string{"Content-Type": "application/json", "User-Agent": "goperf"},
+				Body:    `{"a":1}`,
+				Name:    "POST /api",
+			},
+		},
+		{
+			name:    "head",
+			command: "curl -I https://a.test/",
+			want:    interfaces.Request{URL: "https://a.test/", Method: "HEAD", Name: "HEAD /"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ParseCurl(tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseCurl()\n got %+v\nwant %+v", *got, tt.want)
+			}
+			if !slices.Equal(warnings, tt.warnings) {
+				t.Errorf("warnings %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestParseCurlInvalid(t *testing.T) {
+	for _, command := range []string{
+		"curl",
+		"curl -H",
+		"curl --proxy http://p:3128 https://a.test/",
+		"curl 'https://a.test/",
+		"curl -b cookies.txt https://a.test/",
+		"curl https://a.test/ https://b.test/",
+		"curl -H 'no colon' https://a.test/",
+	} {
+		if _, _, err := ParseCurl(command); err == nil {
+			t.Errorf("ParseCurl(%q) should fail", command)
+		}
+	}
+}
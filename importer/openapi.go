@@ -0,0 +1,564 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+// pathParameter matches the {name} parameters of a path template
+var pathParameter = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// openAPISpec is the part of an OpenAPI 3 or Swagger 2 spec an import needs
+type openAPISpec struct {
+	OpenAPI string `yaml:"openapi"`
+	Swagger string `yaml:"swagger"`
+	Servers []struct {
+		URL       string `yaml:"url"`
+		Variables map[string]struct {
+			Default string `yaml:"default"`
+		} `yaml:"variables"`
+	} `yaml:"servers"`
+	Host       string               `yaml:"host"`
+	BasePath   string               `yaml:"basePath"`
+	Schemes    []string             `yaml:"schemes"`
+	Paths      map[string]*pathItem `yaml:"paths"`
+	Components struct {
+		Schemas       map[string]*schema      `yaml:"schemas"`
+		Parameters    map[string]*parameter   `yaml:"parameters"`
+		RequestBodies map[string]*requestBody `yaml:"requestBodies"`
+	} `yaml:"components"`
+	Definitions map[string]*schema    `yaml:"definitions"`
+	Parameters  map[string]*parameter `yaml:"parameters"`
+}
+
+// pathItem holds the operations of one path
+type pathItem struct {
+	Parameters []*parameter `yaml:"parameters"`
+	Get        *operation   `yaml:"get"`
+	Post       *operation   `yaml:"post"`
+	Put        *operation   `yaml:"put"`
+	Patch      *operation   `yaml:"patch"`
+	Delete     *operation   `yaml:"delete"`
+	Head       *operation   `yaml:"head"`
+	Options    *operation   `yaml:"options"`
+	Trace      *operation   `yaml:"trace"`
+}
+
+// operation is one method of a path
+type operation struct {
+	OperationID string       `yaml:"operationId"`
+	Parameters  []*parameter `yaml:"parameters"`
+	RequestBody *requestBody `yaml:"requestBody"`
+}
+
+// parameter is an operation parameter; Swagger 2 describes non-body parameters with inline schema fields
+type parameter struct {
+	Ref      string              `yaml:"$ref"`
+	Name     string              `yaml:"name"`
+	In       string              `yaml:"in"`
+	Required bool                `yaml:"required"`
+	Example  any                 `yaml:"example"`
+	Examples map[string]*example `yaml:"examples"`
+	Schema   *schema             `yaml:"schema"`
+	Type     any                 `yaml:"type"`
+	Format   string              `yaml:"format"`
+	Default  any                 `yaml:"default"`
+	Enum     []any               `yaml:"enum"`
+	Items    *schema             `yaml:"items"`
+}
+
+// requestBody is an OpenAPI 3 request body
+type requestBody struct {
+	Ref     string                `yaml:"$ref"`
+	Content map[string]*mediaType `yaml:"content"`
+}
+
+// mediaType is one representation of a request body
+type mediaType struct {
+	Schema   *schema             `yaml:"schema"`
+	Example  any                 `yaml:"example"`
+	Examples map[string]*example `yaml:"examples"`
+}
+
+// example is a named example
+type example struct {
+	Value any `yaml:"value"`
+}
+
+// schema is the part of a JSON schema that examples are built from
+type schema struct {
+	Ref        string             `yaml:"$ref"`
+	Type       any                `yaml:"type"` // a string, or a list of them in OpenAPI 3.1
+	Format     string             `yaml:"format"`
+	Example    any                `yaml:"example"`
+	Examples   []any              `yaml:"examples"`
+	Default    any                `yaml:"default"`
+	Const      any                `yaml:"const"`
+	Enum       []any              `yaml:"enum"`
+	Properties map[string]*schema `yaml:"properties"`
+	Items      *schema            `yaml:"items"`
+	AllOf      []*schema          `yaml:"allOf"`
+	OneOf      []*schema          `yaml:"oneOf"`
+	AnyOf      []*schema          `yaml:"anyOf"`
+}
+
+/*
+OpenAPIOptions configures an OpenAPI import.
+
+Structure Overview
+  - Server - the scheme and host the requests are sent to instead of the spec's server,
+    keeping the spec's base path unless Server has a path of its own; required when the
+    spec's server is relative
+*/
+type OpenAPIOptions struct {
+	Server string
+}
+
+// LoadOpenAPI reads the spec at path and converts it with ParseOpenAPI
+func LoadOpenAPI(path string, opts OpenAPIOptions) ([]interfaces.Request, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI spec: %w", err)
+	}
+	requests, err := ParseOpenAPI(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return requests, nil
+}
+
+/*
+ParseOpenAPI generates one request for every operation of an OpenAPI 3 or Swagger 2
+spec, in YAML or JSON, ordered by path and method.
+
+Path parameters take the example, default or first enum value of the spec and are
+left as {{name}} placeholders when it has none.  Required query, header and cookie
+parameters, and request bodies, are filled in from the spec's examples or, failing
+that, built from their schemas.  JSON bodies are preferred over form bodies; an
+operation that only accepts other media types, such as file uploads, gets no body.
+*/
+func ParseOpenAPI(data []byte, opts OpenAPIOptions) ([]interfaces.Request, error) {
+	var spec openAPISpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+	if spec.OpenAPI == "" && spec.Swagger == "" {
+		return nil, fmt.Errorf("not an OpenAPI spec: it has neither an openapi nor a swagger version")
+	}
+	server, err := spec.server(opts.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	requests := []interfaces.Request{}
+	for _, path := range paths {
+		item := spec.Paths[path]
+		if item == nil {
+			continue
+		}
+		operations := item.operations()
+		for _, method := range operationMethods {
+			if op := operations[method]; op != nil {
+				requests = append(requests, spec.request(server, path, method, item, op))
+			}
+		}
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("the spec has no operations")
+	}
+	return requests, nil
+}
+
+// server returns the base url of the requests: the spec's first server, moved to override if set
+func (s *openAPISpec) server(override string) (string, error) {
+	base := ""
+	switch {
+	case len(s.Servers) > 0:
+		base = s.Servers[0].URL
+		for name, variable := range s.Servers[0].Variables {
+			base = strings.ReplaceAll(base, "{"+name+"}", variable.Default)
+		}
+	case s.Host != "":
+		scheme := "https"
+		if len(s.Schemes) > 0 {
+			scheme = s.Schemes[0]
+		}
+		base = scheme + SchemeSeparator + s.Host + s.BasePath
+	default:
+		base = s.BasePath
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid server url %q in the spec", base)
+	}
+	if override != "" {
+		target, overrideErr := url.Parse(override)
+		if overrideErr != nil || target.Host == "" {
+			return "", fmt.Errorf("server must be an absolute url, got %q", override)
+		}
+		if target.Path == "" || target.Path == "/" {
+			target.Path = u.Path
+		}
+		u = target
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("the spec has no absolute server url (%q), pass the server to send requests to", base)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// operations returns the operations of the path item by method
+func (p *pathItem) operations() map[string]*operation {
+	return map[string]*operation{
+		"GET": p.Get, "POST": p.Post, "PUT": p.Put, "PATCH": p.Patch,
+		"DELETE": p.Delete, "HEAD": p.Head, "OPTIONS": p.Options, "TRACE": p.Trace,
+	}
+}
+
+// operationRequest collects the parts of the request of one operation
+type operationRequest struct {
+	path    string
+	query   url.Values
+	headers map[string]string
+	cookies []string
+	form    url.Values
+	body    string
+}
+
+// request builds the request of one operation
+func (s *openAPISpec) request(server, path, method string, item *pathItem, op *operation) interfaces.Request {
+	// Every path parameter starts as a placeholder, including undeclared ones
+	placeholders := pathParameter.ReplaceAllStringFunc(path, func(param string) string {
+		return fmt.Sprintf(PlaceholderFormat, strings.Trim(param, "{}"))
+	})
+	r := &operationRequest{path: placeholders, query: url.Values{}, form: url.Values{}, headers: map[string]string{}}
+	for _, p := range s.parameters(item, op) {
+		s.addParameter(r, p)
+	}
+	if len(r.form) > 0 && r.body == "" {
+		r.body = r.form.Encode()
+		r.headers[ContentTypeHeader] = FormContentType
+	}
+	if body := s.resolveBody(op.RequestBody); body != nil {
+		if contentType := preferredMediaType(body.Content); contentType != "" {
+			r.body = s.body(contentType, body.Content[contentType])
+			r.headers[ContentTypeHeader] = contentType
+		}
+	}
+
+	req := interfaces.Request{
+		URL:     server + r.path,
+		Method:  method,
+		Cookies: strings.Join(r.cookies, "; "),
+		Name:    op.OperationID,
+		Body:    r.body,
+	}
+	if req.Name == "" {
+		req.Name = method + " " + path
+	}
+	if len(r.query) > 0 {
+		req.URL += "?" + r.query.Encode()
+	}
+	if len(r.headers) > 0 {
+		req.Headers = r.headers
+	}
+	return req
+}
+
+// addParameter fills in a parameter: path parameters always, the others when they are required
+func (s *openAPISpec) addParameter(r *operationRequest, p *parameter) {
+	switch {
+	case p.In == "path":
+		if v := p.explicitExample(); v != nil {
+			r.path = strings.ReplaceAll(r.path, fmt.Sprintf(PlaceholderFormat, p.Name), url.PathEscape(scalar(v)))
+		}
+	case p.In == "body":
+		// Swagger 2 bodies
+		r.body = s.body(JSONContentType, &mediaType{Schema: p.Schema, Example: p.Example})
+		r.headers[ContentTypeHeader] = JSONContentType
+	case !p.Required:
+	case p.In == "query":
+		r.query.Set(p.Name, scalar(s.parameterExample(p)))
+	case p.In == "header":
+		r.headers[p.Name] = scalar(s.parameterExample(p))
+	case p.In == "cookie":
+		r.cookies = append(r.cookies, p.Name+"="+scalar(s.parameterExample(p)))
+	case p.In == "formData":
+		r.form.Set(p.Name, scalar(s.parameterExample(p)))
+	}
+}
+
+// parameters returns the resolved parameters of an operation, which override the path item's ones
+func (s *openAPISpec) parameters(item *pathItem, op *operation) []*parameter {
+	var params []*parameter
+	index := map[string]int{}
+	for _, p := range append(append([]*parameter{}, item.Parameters...), op.Parameters...) {
+		if p = s.resolveParameter(p); p == nil {
+			continue
+		}
+		key := p.In + " " + p.Name
+		if i, ok := index[key]; ok {
+			params[i] = p
+			continue
+		}
+		index[key] = len(params)
+		params = append(params, p)
+	}
+	return params
+}
+
+// explicitExample returns the example, default or first enum value the spec gives a parameter, or nil
+func (p *parameter) explicitExample() any {
+	if p.Example != nil {
+		return p.Example
+	}
+	for _, name := range sortedKeys(p.Examples) {
+		if p.Examples[name] != nil && p.Examples[name].Value != nil {
+			return p.Examples[name].Value
+		}
+	}
+	if p.Schema != nil {
+		if v := explicitValue(p.Schema); v != nil {
+			return v
+		}
+	}
+	return explicitValue(&schema{Default: p.Default, Enum: p.Enum})
+}
+
+// parameterExample returns an example value of a parameter, built from its schema when the spec has none
+func (s *openAPISpec) parameterExample(p *parameter) any {
+	if v := p.explicitExample(); v != nil {
+		return v
+	}
+	if p.Schema != nil {
+		return s.example(p.Schema, 0)
+	}
+	return s.example(&schema{Type: p.Type, Format: p.Format, Items: p.Items}, 0)
+}
+
+// preferredMediaType picks the body representation to send: JSON, then a form, then any with an example
+func preferredMediaType(content map[string]*mediaType) string {
+	types := sortedKeys(content)
+	for _, t := range types {
+		if t == JSONContentType || strings.HasSuffix(t, JSONMediaSuffix) {
+			return t
+		}
+	}
+	for _, t := range types {
+		if t == FormContentType {
+			return t
+		}
+	}
+	for _, t := range types {
+		if m := content[t]; m != nil && m.Example != nil {
+			return t
+		}
+	}
+	return ""
+}
+
+// body encodes the example of a media type as contentType
+func (s *openAPISpec) body(contentType string, m *mediaType) string {
+	if m == nil {
+		return ""
+	}
+	value := m.Example
+	for _, name := range sortedKeys(m.Examples) {
+		if value == nil && m.Examples[name] != nil {
+			value = m.Examples[name].Value
+		}
+	}
+	if value == nil {
+		value = s.example(m.Schema, 0)
+	}
+
+	if text, ok := value.(string); ok && contentType != JSONContentType && !strings.HasSuffix(contentType,
+		JSONMediaSuffix) {
+		return text
+	}
+	if contentType == FormContentType {
+		form := url.Values{}
+		if fields, ok := value.(map[string]any); ok {
+			for name, field := range fields {
+				form.Set(name, scalar(field))
+			}
+		}
+		return form.Encode()
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// example builds an example value from a schema
+func (s *openAPISpec) example(sc *schema, depth int) any {
+	sc = s.resolveSchema(sc)
+	if sc == nil || depth > MaxSchemaDepth {
+		return nil
+	}
+	if v := explicitValue(sc); v != nil {
+		return v
+	}
+	switch {
+	case len(sc.AllOf) > 0:
+		merged := map[string]any{}
+		for _, part := range sc.AllOf {
+			if fields, ok := s.example(part, depth+1).(map[string]any); ok {
+				maps.Copy(merged, fields)
+			}
+		}
+		return merged
+	case len(sc.OneOf) > 0:
+		return s.example(sc.OneOf[0], depth+1)
+	case len(sc.AnyOf) > 0:
+		return s.example(sc.AnyOf[0], depth+1)
+	}
+
+	switch schemaType(sc) {
+	case "object":
+		fields := map[string]any{}
+		for name, property := range sc.Properties {
+			if value := s.example(property, depth+1); value != nil {
+				fields[name] = value
+			}
+		}
+		return fields
+	case "array":
+		if item := s.example(sc.Items, depth+1); item != nil {
+			return []any{item}
+		}
+		return []any{}
+	case "integer", "number":
+		return 1
+	case "boolean":
+		return true
+	case "string":
+		if value, ok := exampleStrings[sc.Format]; ok {
+			return value
+		}
+		return exampleStrings[""]
+	default:
+		return nil
+	}
+}
+
+// explicitValue returns the example, default, const or first enum value of a schema, or nil
+func explicitValue(sc *schema) any {
+	switch {
+	case sc.Example != nil:
+		return sc.Example
+	case len(sc.Examples) > 0:
+		return sc.Examples[0]
+	case sc.Default != nil:
+		return sc.Default
+	case sc.Const != nil:
+		return sc.Const
+	case len(sc.Enum) > 0:
+		return sc.Enum[0]
+	default:
+		return nil
+	}
+}
+
+// schemaType returns the type of a schema: its first non-null type, or object when it has properties
+func schemaType(sc *schema) string {
+	switch t := sc.Type.(type) {
+	case string:
+		return t
+	case []any:
+		for _, item := range t {
+			if name, ok := item.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	if len(sc.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+// resolveSchema follows the $ref of a schema
+func (s *openAPISpec) resolveSchema(sc *schema) *schema {
+	for seen := 0; sc != nil && sc.Ref != "" && seen <= MaxSchemaDepth; seen++ {
+		name := refName(sc.Ref)
+		if next, ok := s.Components.Schemas[name]; ok {
+			sc = next
+		} else {
+			sc = s.Definitions[name]
+		}
+	}
+	return sc
+}
+
+// resolveParameter follows the $ref of a parameter
+func (s *openAPISpec) resolveParameter(p *parameter) *parameter {
+	if p == nil || p.Ref == "" {
+		return p
+	}
+	name := refName(p.Ref)
+	if resolved, ok := s.Components.Parameters[name]; ok {
+		return resolved
+	}
+	return s.Parameters[name]
+}
+
+// resolveBody follows the $ref of a request body
+func (s *openAPISpec) resolveBody(body *requestBody) *requestBody {
+	if body == nil || body.Ref == "" {
+		return body
+	}
+	return s.Components.RequestBodies[refName(body.Ref)]
+}
+
+// refName returns the name a local reference such as #/components/schemas/Pet points to
+func refName(ref string) string {
+	if !strings.HasPrefix(ref, RefPrefix) {
+		return ""
+	}
+	name := ref[strings.LastIndex(ref, "/")+1:]
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+}
+
+// scalar formats an example value for a url, header or form field
+func scalar(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any, map[string]any:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
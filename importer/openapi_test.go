@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+const testOpenAPI = `
+openapi: 3.0.3
+servers:
+  - url: https://{region}.api.example.com/v2
+    variables:
+      region: {default: eu}
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: integer, default: 20}}
+        - {name: cursor, in: query, schema: {type: string}}
+        - {name: X-Tenant, in: header, required: true, schema: {type: string, format: uuid}}
+    post:
+      operationId: createPet
+      parameters:
+        - $ref: '#/components/parameters/Session'
+      requestBody:
+        $ref: '#/components/requestBodies/NewPet'
+  /pets/{petId}:
+    parameters:
+      - {name: petId, in: path, required: true, schema: {type: integer}}
+    get:
+      operationId: getPet
+    delete:
+      parameters:
+        - {name: petId, in: path, required: true, example: 7}
+  /pets/{petId}/photo:
+    put:
+      operationId: uploadPhoto
+      requestBody:
+        content:
+          multipart/form-data:
+            schema: {type: object}
+  /login:
+    post:
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            schema:
+              type: object
+              properties:
+                user: {type: string, format: email}
+                remember: {type: boolean}
+components:
+  parameters:
+    Session: {name: session, in: cookie, required: true, schema: {type: string, example: abc}}
+  requestBodies:
+    NewPet:
+      content:
+        text/plain:
+          example: ignored
+        application/json:
+          schema: {$ref: '#/components/schemas/Pet'}
+  schemas:
+    Pet:
+      allOf:
+        - {$ref: '#/components/schemas/Named'}
+        - type: object
+          properties:
+            tags: {type: array, items: {type: string, enum: [cute, fluffy]}}
+            born: {type: string, format: date}
+            owner: {$ref: '#/components/schemas/Pet'}
+    Named:
+      type: object
+      properties:
+        name: {type: string, example: Rex}
+        age: {type: [integer, 'null']}
+`
+
+func TestParseOpenAPI(t *testing.T) {
+	color.Green("~~ TestParseOpenAPI ~~")
+	requests, err := ParseOpenAPI([]byte(testOpenAPI), OpenAPIOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := "https://eu.api.example.com/v2"
+	want := []interfaces.Request{
+		{Method: "POST", URL: server + "/login", Name: "POST /login", Body: "remember=true&user=user%40example.com",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}},
+		{Method: "GET", URL: server + "/pets?limit=20", Name: "listPets",
+			Headers: map[string]string{"X-Tenant": "00000000-0000-0000-0000-000000000000"}},
+		{Method: "POST", URL: server + "/pets", Name: "createPet", Cookies: "session=abc",
+			Headers: map[string]string{"Content-Type": "application/json"}},
+		{Method: "GET", URL: server + "/pets/{{petId}}", Name: "getPet"},
+		{Method: "DELETE", URL: server + "/pets/7", Name: "DELETE /pets/{petId}"},
+		{Method: "PUT", URL: server + "/pets/{{petId}}/photo", Name: "uploadPhoto"},
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got %d requests, want %d: %+v", len(requests), len(want), requests)
+	}
+	for i := range want {
+		got := requests[i]
+		body := got.Body
+		if got.Name == "createPet" {
+			// Checked below: the recursive owner makes the body deep
+			got.Body = ""
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("request %d = %+v, want %+v", i, requests[i], want[i])
+		}
+		if got.Name != "createPet" {
+			continue
+		}
+		var pet map[string]any
+		if err = json.Unmarshal([]byte(body), &pet); err != nil {
+			t.Fatalf("createPet body %q: %v", body, err)
+		}
+		if pet["name"] != "Rex" || pet["age"] != float64(1) || pet["born"] != "2026-01-01" ||
+			!reflect.DeepEqual(pet["tags"], []any{"cute"}) || pet["owner"] == nil {
+			t.Errorf("createPet body = %s", body)
+		}
+	}
+}
+
+func TestParseSwagger(t *testing.T) {
+	spec := `{"swagger": "2.0", "host": "petstore.example.com", "basePath": "/v1", "schemes": ["http"],
+	"paths": {"/store/order": {"post": {"parameters": [
+		{"in": "body", "name": "order", "schema": {"$ref": "#/definitions/Order"}},
+		{"in": "query", "name": "dryRun", "type": "boolean", "required": true}]}},
+	"/user/{name}": {"put": {"parameters": [
+		{"in": "path", "name": "name", "type": "string", "required": true},
+		{"in": "formData", "name": "email", "type": "string", "required": true}]}}},
+	"definitions": {"Order": {"properties": {"quantity": {"type": "integer", "example": 3}}}}}`
+
+	requests, err := ParseOpenAPI([]byte(spec), OpenAPIOptions{Server: "http://localhost:8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interfaces.Request{
+		{Method: "POST", URL: "http://localhost:8080/v1/store/order?dryRun=true", Name: "POST /store/order",
+			Body: `{"quantity":3}`, Headers: map[string]string{"Content-Type": "application/json"}},
+		{Method: "PUT", URL: "http://localhost:8080/v1/user/{{name}}", Name: "PUT /user/{name}",
+			Body: "email=string", Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}},
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("ParseOpenAPI()\n got %+v\nwant %+v", requests, want)
+	}
+}
+
+func TestParseOpenAPIInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		spec, server string
+	}{
+		"not yaml":        {"paths: [", ""},
+		"not a spec":      {"name: goperf", ""},
+		"relative server": {"openapi: 3.1.0\nservers: [{url: /api}]\npaths: {/a: {get: {}}}", ""},
+		"bad server":      {"openapi: 3.1.0\npaths: {/a: {get: {}}}", "localhost"},
+		"no operations":   {"openapi: 3.1.0\nservers: [{url: 'https://a.test'}]\npaths: {}", ""},
+	} {
+		if _, err := ParseOpenAPI([]byte(tc.spec), OpenAPIOptions{Server: tc.server}); err == nil {
+			t.Errorf("%s: ParseOpenAPI should fail", name)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+// variableRef matches a {{name}} variable reference, which is kept as is in urls and form bodies
+var variableRef = regexp.MustCompile(`\{\{[^{}]+\}\}`)
+
+// postmanCollection is the part of a Postman v2.1 collection an import needs
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     *postmanAuth      `json:"auth"`
+	Variable []postmanVariable `json:"variable"`
+}
+
+// postmanItem is a folder, when it has items, or a request
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+	Auth    *postmanAuth    `json:"auth"` // folder auth; requests carry theirs in Request
+	Event   []any           `json:"event"`
+}
+
+// postmanRequest is a request; Postman also allows a bare url string
+type postmanRequest struct {
+	Method string        `json:"method"`
+	Header []postmanPair `json:"header"`
+	Body   *postmanBody  `json:"body"`
+	URL    postmanURL    `json:"url"`
+	Auth   *postmanAuth  `json:"auth"`
+}
+
+// postmanPair is a header, query parameter or form field
+type postmanPair struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// postmanBody is a request body in one of Postman's modes
+type postmanBody struct {
+	Mode       string        `json:"mode"`
+	Raw        string        `json:"raw"`
+	URLEncoded []postmanPair `json:"urlencoded"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+	Disabled bool `json:"disabled"`
+}
+
+// postmanURL is a request url; Postman also allows a bare string
+type postmanURL struct {
+	Raw      string        `json:"raw"`
+	Protocol string        `json:"protocol"`
+	Host     postmanList   `json:"host"`
+	Port     string        `json:"port"`
+	Path     postmanList   `json:"path"`
+	Query    []postmanPair `json:"query"`
+	Variable []postmanPair `json:"variable"` // values of :name path segments
+}
+
+// postmanList is a list of url parts, which Postman also writes as a single string
+type postmanList []string
+
+// postmanAuth is the auth of a collection, folder or request
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []postmanAuthAttr `json:"bearer"`
+	Basic  []postmanAuthAttr `json:"basic"`
+	APIKey []postmanAuthAttr `json:"apikey"`
+}
+
+// postmanAuthAttr is one setting of an auth
+type postmanAuthAttr struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+// postmanVariable is a collection variable or an environment value
+type postmanVariable struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Disabled bool   `json:"disabled"`
+	Enabled  *bool  `json:"enabled"` // environments use enabled instead of disabled
+}
+
+// postmanEnvironment is a Postman environment file
+type postmanEnvironment struct {
+	Name   string            `json:"name"`
+	Values []postmanVariable `json:"values"`
+}
+
+// UnmarshalJSON accepts a request object or a bare url string
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &r.URL.Raw)
+	}
+	type plain postmanRequest
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// UnmarshalJSON accepts a url object or a bare url string
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &u.Raw)
+	}
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+// UnmarshalJSON accepts a list of strings or a single string
+func (l *postmanList) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*l = postmanList{s}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+/*
+PostmanOptions configures a Postman import.
+
+Structure Overview
+  - Environment - a Postman environment file whose enabled values override the collection variables
+*/
+type PostmanOptions struct {
+	Environment string
+}
+
+// LoadPostman reads the collection at path, and the environment file of opts, and converts them with ParsePostman
+func LoadPostman(path string, opts PostmanOptions) (*Sequence, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Postman collection: %w", err)
+	}
+	var environment []byte
+	if opts.Environment != "" {
+		if environment, err = os.ReadFile(opts.Environment); err != nil {
+			return nil, fmt.Errorf("failed to read Postman environment: %w", err)
+		}
+	}
+	sequence, err := ParsePostman(data, environment)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sequence, nil
+}
+
+/*
+ParsePostman converts a Postman v2.1 collection into a request sequence: the requests of
+its folders in collection order, named "Folder / Request".  The collection variables,
+overridden by the enabled values of environment when it is not empty, become the
+sequence variables, and {{name}} references are kept for goperf to expand.
+
+Bearer, Basic and API key auth is resolved through folders and "inherit" into headers
+or query parameters; Basic credentials are encoded with the variables known at import
+time.  Raw, url-encoded and GraphQL bodies are imported.  Other auth types, form-data
+and file bodies, and scripts cannot be imported and are reported as warnings.
+*/
+func ParsePostman(collection, environment []byte) (*Sequence, error) {
+	var c postmanCollection
+	if err := json.Unmarshal(collection, &c); err != nil {
+		return nil, fmt.Errorf("invalid Postman collection: %w", err)
+	}
+	if strings.Contains(c.Info.Schema, "/v1.") {
+		return nil, fmt.Errorf("postman v1 collections are not supported, export the collection as v2.1")
+	}
+
+	sequence := &Sequence{Name: c.Info.Name, Variables: map[string]string{}}
+	for _, v := range c.Variable {
+		if !v.Disabled {
+			sequence.Variables[v.Key] = scalar(v.Value)
+		}
+	}
+	if len(environment) > 0 {
+		var env postmanEnvironment
+		if err := json.Unmarshal(environment, &env); err != nil {
+			return nil, fmt.Errorf("invalid Postman environment: %w", err)
+		}
+		for _, v := range env.Values {
+			if v.Enabled == nil || *v.Enabled {
+				sequence.Variables[v.Key] = scalar(v.Value)
+			}
+		}
+	}
+
+	scripts := sequence.addItems(c.Item, "", c.Auth)
+	if len(sequence.Requests) == 0 {
+		return nil, fmt.Errorf("the collection has no requests")
+	}
+	if scripts > 0 {
+		sequence.Warnings = append(sequence.Warnings,
+			fmt.Sprintf("%d pre-request or test scripts are not run, set the variables they set with -var", scripts))
+	}
+	if len(sequence.Variables) == 0 {
+		sequence.Variables = nil
+	}
+	return sequence, nil
+}
+
+// addItems adds the requests of items, depth first, and returns the number of items with scripts
+func (s *Sequence) addItems(items []postmanItem, folder string, auth *postmanAuth) int {
+	scripts := 0
+	for i := range items {
+		item := &items[i]
+		if len(item.Event) > 0 {
+			scripts++
+		}
+		name := item.Name
+		if folder != "" {
+			name = folder + " / " + name
+		}
+		if item.Request == nil {
+			scripts += s.addItems(item.Item, name, item.Auth.inherit(auth))
+			continue
+		}
+		s.Requests = append(s.Requests, s.request(name, item.Request, item.Request.Auth.inherit(auth)))
+	}
+	return scripts
+}
+
+// inherit returns the auth that applies: a's own one, or parent when a is missing or inherits
+func (a *postmanAuth) inherit(parent *postmanAuth) *postmanAuth {
+	if a == nil || a.Type == "inherit" {
+		return parent
+	}
+	return a
+}
+
+// request converts one Postman request
+func (s *Sequence) request(name string, r *postmanRequest, auth *postmanAuth) interfaces.Request {
+	req := interfaces.Request{Name: name, Method: strings.ToUpper(r.Method), URL: r.URL.String()}
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	headers := map[string]string{}
+	for _, h := range r.Header {
+		if !h.Disabled && h.Key != "" {
+			headers[h.Key] = h.Value
+		}
+	}
+	if r.Body != nil && !r.Body.Disabled {
+		s.body(&req, headers, r.Body)
+	}
+	s.auth(&req, headers, auth)
+	if len(headers) > 0 {
+		req.Headers = headers
+	}
+	return req
+}
+
+// body sets the request body and its Content-Type
+func (s *Sequence) body(req *interfaces.Request, headers map[string]string, body *postmanBody) {
+	contentType := ""
+	switch body.Mode {
+	case "raw":
+		req.Body = body.Raw
+		contentType = rawContentTypes[body.Options.Raw.Language]
+	case "urlencoded":
+		fields := make([]string, 0, len(body.URLEncoded))
+		for _, field := range body.URLEncoded {
+			if !field.Disabled {
+				fields = append(fields, escapeForm(field.Key)+"="+escapeForm(field.Value))
+			}
+		}
+		req.Body = strings.Join(fields, DataSeparator)
+		contentType = FormContentType
+	case "graphql":
+		if body.GraphQL == nil {
+			return
+		}
+		query := map[string]any{"query": body.GraphQL.Query}
+		var variables any
+		if json.Unmarshal([]byte(body.GraphQL.Variables), &variables) == nil && variables != nil {
+			query["variables"] = variables
+		}
+		encoded, _ := json.Marshal(query)
+		req.Body = string(encoded)
+		contentType = JSONContentType
+	case "":
+	default:
+		s.Warnings = append(s.Warnings, fmt.Sprintf("%s: %s bodies are not imported", req.Name, body.Mode))
+	}
+	if contentType != "" && headerValue(headers, ContentTypeHeader) == "" {
+		headers[ContentTypeHeader] = contentType
+	}
+}
+
+// auth adds the credentials of auth to the request unless it sets its own Authorization header
+func (s *Sequence) auth(req *interfaces.Request, headers map[string]string, auth *postmanAuth) {
+	if auth == nil || auth.Type == "noauth" || headerValue(headers, AuthorizationHeader) != "" {
+		return
+	}
+	switch auth.Type {
+	case "bearer":
+		headers[AuthorizationHeader] = BearerAuthPrefix + authAttr(auth.Bearer, "token")
+	case "basic":
+		credentials := s.expand(authAttr(auth.Basic, "username")) + ":" + s.expand(authAttr(auth.Basic, "password"))
+		headers[AuthorizationHeader] = BasicAuthPrefix + base64.StdEncoding.EncodeToString([]byte(credentials))
+	case "apikey":
+		key, value := authAttr(auth.APIKey, "key"), authAttr(auth.APIKey, "value")
+		if authAttr(auth.APIKey, "in") != "query" {
+			headers[key] = value
+			return
+		}
+		separator := "?"
+		if strings.Contains(req.URL, "?") {
+			separator = DataSeparator
+		}
+		req.URL += separator + escapeForm(key) + "=" + escapeForm(value)
+	default:
+		s.Warnings = append(s.Warnings, fmt.Sprintf("%s: %s auth is not imported", req.Name, auth.Type))
+	}
+}
+
+// authAttr returns the value of the auth setting key
+func authAttr(attrs []postmanAuthAttr, key string) string {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return scalar(attr.Value)
+		}
+	}
+	return ""
+}
+
+// expand replaces the variable references of s with the sequence variables, for values encoded at import time
+func (s *Sequence) expand(value string) string {
+	for range MaxVariableDepth {
+		expanded := variableRef.ReplaceAllStringFunc(value, func(ref string) string {
+			if v, ok := s.Variables[strings.TrimSpace(ref[2:len(ref)-2])]; ok {
+				return v
+			}
+			return ref
+		})
+		if expanded == value {
+			break
+		}
+		value = expanded
+	}
+	return value
+}
+
+// String returns the url, from its raw form when there is one, with :name path segments filled in
+func (u *postmanURL) String() string {
+	raw := u.Raw
+	if raw == "" {
+		raw = strings.Join(u.Host, ".")
+		if u.Protocol != "" {
+			raw = u.Protocol + SchemeSeparator + raw
+		}
+		if u.Port != "" {
+			raw += ":" + u.Port
+		}
+		if len(u.Path) > 0 {
+			raw += "/" + strings.Join(u.Path, "/")
+		}
+		query := make([]string, 0, len(u.Query))
+		for _, q := range u.Query {
+			if !q.Disabled {
+				query = append(query, escapeForm(q.Key)+"="+escapeForm(q.Value))
+			}
+		}
+		if len(query) > 0 {
+			raw += "?" + strings.Join(query, DataSeparator)
+		}
+	}
+	for _, v := range u.Variable {
+		raw = pathVariable(v.Key).ReplaceAllString(raw, "/"+strings.ReplaceAll(v.Value, "$", "$$")+"$1")
+	}
+	if !strings.Contains(raw, SchemeSeparator) && !strings.HasPrefix(raw, "{{") {
+		raw = DefaultScheme + raw
+	}
+	return raw
+}
+
+// pathVariable matches the /:name segment of a url
+func pathVariable(name string) *regexp.Regexp {
+	return regexp.MustCompile(`/:` + regexp.QuoteMeta(name) + `([/?#]|$)`)
+}
+
+// escapeForm url-encodes a form or query value, keeping its variable references intact
+func escapeForm(value string) string {
+	var b strings.Builder
+	last := 0
+	for _, ref := range variableRef.FindAllStringIndex(value, -1) {
+		b.WriteString(url.QueryEscape(value[last:ref[0]]))
+		b.WriteString(value[ref[0]:ref[1]])
+		last = ref[1]
+	}
+	b.WriteString(url.QueryEscape(value[last:]))
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+const testPostman = `{
+  "info": {"name": "Shop", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+  "variable": [
+    {"key": "baseUrl", "value": "https://shop.test"},
+    {"key": "user", "value": "ann"},
+    {"key": "old", "value": "x", "disabled": true}
+  ],
+  "item": [
+    {"name": "Login", "event": [{"listen": "test"}], "request": {
+      "method": "POST",
+      "url": "{{baseUrl}}/login",
+      "header": [{"key": "X-Debug", "value": "1", "disabled": true}],
+      "body": {"mode": "urlencoded", "urlencoded": [
+        {"key": "user", "value": "{{user}}"}, {"key": "pass", "value": "a&b"}, {"key": "skip", "disabled": true}
+      ]},
+      "auth": {"type": "noauth"}
+    }},
+    {"name": "Orders", "auth": {"type": "basic", "basic": [
+      {"key": "username", "value": "{{user}}"}, {"key": "password", "value": "pw"}
+    ]}, "item": [
+      {"name": "Get", "request": {"method": "get", "url": {
+        "raw": "{{baseUrl}}/orders/:id?full=1", "variable": [{"key": "id", "value": "42"}]
+      }, "auth": {"type": "inherit"}}},
+      {"name": "Create", "request": {"method": "POST", "url": {
+        "protocol": "https", "host": ["api", "shop", "test"], "path": ["orders"],
+        "query": [{"key": "dry run", "value": "{{dry}}"}]
+      }, "body": {"mode": "raw", "raw": "{\"sku\": \"{{sku}}\"}", "options": {"raw": {"language": "json"}}},
+      "auth": {"type": "apikey", "apikey": [
+        {"key": "key", "value": "api_key"}, {"key": "value", "value": "{{apiKey}}"}, {"key": "in", "value": "query"}
+      ]}}}
+    ]},
+    {"name": "Search", "request": {"method": "POST", "url": "shop.test/graphql",
+      "header": [{"key": "Authorization", "value": "Token abc"}],
+      "body": {"mode": "graphql", "graphql": {"query": "{ items }", "variables": "{\"n\": 2}"}}}},
+    {"name": "Health", "request": "{{baseUrl}}/health"},
+    {"name": "Upload", "request": {"method": "PUT", "url": "{{baseUrl}}/files",
+      "body": {"mode": "formdata"}, "auth": {"type": "oauth2"}}}
+  ]
+}`
+
+func TestParsePostman(t *testing.T) {
+	color.Green("~~ TestParsePostman ~~")
+	environment := `{"name": "staging", "values": [
+		{"key": "baseUrl", "value": "https://staging.shop.test", "enabled": true},
+		{"key": "user", "value": "bob"},
+		{"key": "token", "value": "t0k", "enabled": false}
+	]}`
+	sequence, err := ParsePostman([]byte(testPostman), []byte(environment))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sequence.Name != "Shop" {
+		t.Errorf("Name = %q, want Shop", sequence.Name)
+	}
+	wantVariables := map[string]string{"baseUrl": "https://staging.shop.test", "user": "bob"}
+	if !reflect.DeepEqual(sequence.Variables, wantVariables) {
+		t.Errorf("Variables = %v, want %v", sequence.Variables, wantVariables)
+	}
+	want := []interfaces.Request{
+		{Name: "Login", Method: "POST", URL: "{{baseUrl}}/login", Body: "user={{user}}&pass=a%26b",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}},
+		{Name: "Orders / Get", Method: "GET", URL: "{{baseUrl}}/orders/42?full=1",
+			Headers: map[string]string{"Authorization": "Basic Ym9iOnB3"}},
+		{Name: "Orders / Create", Method: "POST", URL: "https://api.shop.test/orders?dry+run={{dry}}&api_key={{apiKey}}",
+			Body: `{"sku": "{{sku}}"}`, Headers: map[string]string{"Content-Type": "application/json"}},
+		{Name: "Search", Method: "POST", URL: "http://shop.test/graphql", Body: `{"query":"{ items }","variables":{"n":2}}`,
+			Headers: map[string]string{"Authorization": "Token abc", "Content-Type": "application/json"}},
+		{Name: "Health", Method: "GET", URL: "{{baseUrl}}/health",
+			Headers: map[string]string{"Authorization": "Bearer {{token}}"}},
+		{Name: "Upload", Method: "PUT", URL: "{{baseUrl}}/files"},
+	}
+	if !reflect.DeepEqual(sequence.Requests, want) {
+		t.Errorf("Requests\n got %+v\nwant %+v", sequence.Requests, want)
+	}
+	wantWarnings := []string{
+		"Upload: formdata bodies are not imported",
+		"Upload: oauth2 auth is not imported",
+		"1 pre-request or test scripts are not run, set the variables they set with -var",
+	}
+	if !reflect.DeepEqual(sequence.Warnings, wantWarnings) {
+		t.Errorf("Warnings = %q, want %q", sequence.Warnings, wantWarnings)
+	}
+}
+
+func TestParsePostmanInvalid(t *testing.T) {
+	v1 := `{"info": {"schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"},
+		"item": [{"request": "https://a.test/"}]}`
+	for name, tc := range map[string]struct {
+		collection, environment string
+	}{
+		"not json":            {"{", ""},
+		"v1":                  {v1, ""},
+		"no requests":         {`{"info": {"name": "empty"}, "item": [{"name": "folder", "item": []}]}`, ""},
+		"invalid environment": {`{"item": [{"request": "https://a.test/"}]}`, "["},
+	} {
+		if _, err := ParsePostman([]byte(tc.collection), []byte(tc.environment)); err == nil {
+			t.Errorf("%s: ParsePostman should fail", name)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package importer
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+// WriteRequests writes requests as a request file: a JSON array that "goperf -targets" can load
+func WriteRequests(w io.Writer, requests []interfaces.Request) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", JSONIndent)
+	return enc.Encode(requests)
+}
+
+/*
+Sequence is an ordered request set with the values of the {{name}} variables its
+requests refer to.  Written with WriteSequence it is a sequence file that
+"goperf -sequence" sends in order, expanding the variables for every request.
+Warnings lists what could not be imported.
+*/
+type Sequence struct {
+	Name      string               `json:"name,omitempty"`
+	Variables map[string]string    `json:"variables,omitempty"`
+	Requests  []interfaces.Request `json:"requests"`
+	Warnings  []string             `json:"-"`
+}
+
+// WriteSequence writes a sequence file
+func WriteSequence(w io.Writer, s *Sequence) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", JSONIndent)
+	return enc.Encode(s)
+}
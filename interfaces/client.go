@@ -5,6 +5,7 @@ package interfaces
 
 import (
 	"context"
+	"net/http"
 	"time"
 )
 
@@ -28,6 +29,14 @@ type HTTPClient interface {
 	SetMaxConnections(maxConns int)
 }
 
+// AuthProvider authenticates requests, replacing credentials hard-coded into headers.
+// Implementations are safe for concurrent use, so one provider can serve every virtual user.
+type AuthProvider interface {
+	// Authenticate adds the credentials of req, such as an Authorization header or a
+	// signature, just before it is sent
+	Authenticate(req *http.Request) error
+}
+
 // Request represents a unified HTTP request structure
 // This replaces FetchInput and other similar structs
 type Request struct {
@@ -38,6 +47,9 @@ type Request struct {
 	UserAgent     string            `json:"user_agent"`
 	Timeout       time.Duration     `json:"timeout"`
 	ReturnContent bool              `json:"return_content"`
+	Name          string            `json:"name,omitempty"` // label of the request in a request file
+	Body          string            `json:"body,omitempty"` // request body, sent as is
+	Auth          AuthProvider      `json:"-"`              // authenticates the request, none when nil
 }
 
 // Response represents a unified HTTP response structure
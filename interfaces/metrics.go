@@ -55,9 +55,11 @@ const (
 	SessionStatusFailed
 )
 
-// RequestResult represents the result of a single request
+// RequestResult represents the result of a single request.
+// Type tells apart the kinds of requests of a test, e.g. "connect" and "message".
 type RequestResult struct {
 	URL          string        `json:"url"`
+	Type         string        `json:"type,omitempty"`
 	StatusCode   int           `json:"status_code"`
 	Duration     time.Duration `json:"duration"`
 	Size         int           `json:"size"`
@@ -74,6 +76,9 @@ type Statistics struct {
 	AvgLatency      time.Duration `json:"avg_latency"`
 	MinLatency      time.Duration `json:"min_latency"`
 	MaxLatency      time.Duration `json:"max_latency"`
+	P50Latency      time.Duration `json:"p50_latency"`
+	P95Latency      time.Duration `json:"p95_latency"`
+	P99Latency      time.Duration `json:"p99_latency"`
 	Throughput      float64       `json:"throughput"` // requests per second
 	TotalBytes      int           `json:"total_bytes"`
 }
@@ -95,6 +100,7 @@ type AssetStats struct {
 	Type        string        `json:"type"` // "js", "css", "img"
 	Count       int           `json:"count"`
 	AvgLatency  time.Duration `json:"avg_latency"`
+	P95Latency  time.Duration `json:"p95_latency"`
 	SuccessRate float64       `json:"success_rate"`
 }
 
@@ -1,5 +1,7 @@
 package interfaces
 
+import "fmt"
+
 // AssetParser defines the contract for extracting assets from HTML content
 // This replaces the current httputils package direct function calls
 type AssetParser interface {
@@ -31,11 +33,17 @@ const (
 	ParsingMethodMixed
 )
 
-// Assets represents all extracted assets from a page
+// Assets represents all extracted assets from a page, classified the way a browser
+// would request them (for example <link rel=preload as=font> is a font, not CSS)
 type Assets struct {
 	JavaScript []string `json:"javascript"`
 	CSS        []string `json:"css"`
 	Images     []string `json:"images"`
+	Fonts      []string `json:"fonts"`
+	Media      []string `json:"media"`     // audio, video and their sources
+	Icons      []string `json:"icons"`     // favicons and touch icons
+	Documents  []string `json:"documents"` // iframes and frames
+	Other      []string `json:"other"`     // manifests, embeds, objects and fetch preloads
 	Total      int      `json:"total"`
 }
 
@@ -52,3 +60,18 @@ func (p ParsingMethod) String() string {
 		return "unknown"
 	}
 }
+
+// ParseParsingMethod converts a configuration value ("regex", "dom", "mixed" or its
+// alias "hybrid") into a ParsingMethod
+func ParseParsingMethod(method string) (ParsingMethod, error) {
+	switch method {
+	case "regex":
+		return ParsingMethodRegex, nil
+	case "dom":
+		return ParsingMethodDOM, nil
+	case "mixed", "hybrid":
+		return ParsingMethodMixed, nil
+	default:
+		return ParsingMethodDOM, fmt.Errorf("unsupported parsing method %q", method)
+	}
+}
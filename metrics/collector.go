@@ -0,0 +1,241 @@
+package metrics
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+// sessionIDs numbers the sessions of every collector of the process
+var sessionIDs atomic.Int64
+
+/*
+Collector is the in-memory interfaces.MetricsCollector.  It keeps every result of
+a session so that latency percentiles are exact; RecordRequest may be called from
+any number of goroutines.
+*/
+type Collector struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionResults
+}
+
+// sessionResults are the results recorded for one session
+type sessionResults struct {
+	session *interfaces.TestSession
+	results []interfaces.RequestResult
+}
+
+// NewCollector returns an empty collector
+func NewCollector() *Collector {
+	return &Collector{sessions: map[string]*sessionResults{}}
+}
+
+// StartTest implements interfaces.MetricsCollector
+func (c *Collector) StartTest(config *interfaces.TestConfig) (*interfaces.TestSession, error) {
+	session := &interfaces.TestSession{
+		ID:      fmt.Sprintf(SessionIDFormat, sessionIDs.Add(1)),
+		Config:  config,
+		Started: time.Now(),
+		Status:  interfaces.SessionStatusRunning,
+	}
+	c.mu.Lock()
+	c.sessions[session.ID] = &sessionResults{session: session}
+	c.mu.Unlock()
+	return session, nil
+}
+
+// RecordRequest implements interfaces.MetricsCollector
+func (c *Collector) RecordRequest(session *interfaces.TestSession, result *interfaces.RequestResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, err := c.lookup(session)
+	if err != nil {
+		return err
+	}
+	s.results = append(s.results, *result)
+	return nil
+}
+
+// GetStats implements interfaces.MetricsCollector
+func (c *Collector) GetStats(session *interfaces.TestSession) (*interfaces.Statistics, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, err := c.lookup(session)
+	if err != nil {
+		return nil, err
+	}
+	return statistics(s.results, time.Since(session.Started)), nil
+}
+
+// FinishTest implements interfaces.MetricsCollector.  The report lists the results
+// of every type and url, in that order, in AssetStats.
+func (c *Collector) FinishTest(session *interfaces.TestSession) (*interfaces.TestReport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, err := c.lookup(session)
+	if err != nil {
+		return nil, err
+	}
+	finished := time.Now()
+	session.Status = interfaces.SessionStatusCompleted
+	return &interfaces.TestReport{
+		Session:     session,
+		Stats:       statistics(s.results, finished.Sub(session.Started)),
+		AssetStats:  groupStats(s.results),
+		Started:     session.Started,
+		Finished:    finished,
+		ElapsedTime: finished.Sub(session.Started),
+	}, nil
+}
+
+// Reset implements interfaces.MetricsCollector
+func (c *Collector) Reset(session *interfaces.TestSession) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, err := c.lookup(session)
+	if err != nil {
+		return err
+	}
+	s.results = nil
+	session.Started = time.Now()
+	session.Status = interfaces.SessionStatusCreated
+	return nil
+}
+
+// lookup returns the results of session; c.mu must be held
+func (c *Collector) lookup(session *interfaces.TestSession) (*sessionResults, error) {
+	if session == nil {
+		return nil, fmt.Errorf("no test session")
+	}
+	s, ok := c.sessions[session.ID]
+	if !ok {
+		return nil, fmt.Errorf("unknown test session %q", session.ID)
+	}
+	return s, nil
+}
+
+// statistics summarizes results collected over elapsed
+func statistics(results []interfaces.RequestResult, elapsed time.Duration) *interfaces.Statistics {
+	stats := &interfaces.Statistics{TotalRequests: len(results)}
+	durations := make([]time.Duration, 0, len(results))
+	for i := range results {
+		if results[i].Success {
+			stats.SuccessRequests++
+		} else {
+			stats.FailedRequests++
+		}
+		stats.TotalBytes += results[i].Size
+		durations = append(durations, results[i].Duration)
+	}
+	latency := Summarize(durations)
+	stats.AvgLatency, stats.MinLatency, stats.MaxLatency = latency.Avg, latency.Min, latency.Max
+	stats.P50Latency, stats.P95Latency, stats.P99Latency = latency.P50, latency.P95, latency.P99
+	if elapsed > 0 {
+		stats.Throughput = float64(len(results)) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// groupStats summarizes results by type and url
+func groupStats(results []interfaces.RequestResult) []*interfaces.AssetStats {
+	type key struct{ kind, url string }
+	groups := map[key][]interfaces.RequestResult{}
+	for i := range results {
+		k := key{results[i].Type, results[i].URL}
+		groups[k] = append(groups[k], results[i])
+	}
+	keys := make([]key, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].kind != keys[b].kind {
+			return keys[a].kind < keys[b].kind
+		}
+		return keys[a].url < keys[b].url
+	})
+
+	stats := make([]*interfaces.AssetStats, 0, len(keys))
+	for _, k := range keys {
+		group := statistics(groups[k], 0)
+		stats = append(stats, &interfaces.AssetStats{
+			URL:         k.url,
+			Type:        k.kind,
+			Count:       group.TotalRequests,
+			AvgLatency:  group.AvgLatency,
+			P95Latency:  group.P95Latency,
+			SuccessRate: float64(group.SuccessRequests) / float64(group.TotalRequests),
+		})
+	}
+	return stats
+}
+
+// Latency summarizes a set of latencies
+type Latency struct {
+	Count int           `json:"count"`
+	Avg   time.Duration `json:"avg"`
+	Min   time.Duration `json:"min"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// Summarize returns the average, extremes and percentiles of values, which it leaves untouched
+func Summarize(values []time.Duration) Latency {
+	if len(values) == 0 {
+		return Latency{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	var total time.Duration
+	for _, v := range sorted {
+		total += v
+	}
+	percentile := func(p int) time.Duration {
+		return sorted[max((len(sorted)*p+PercentBase-1)/PercentBase-1, 0)]
+	}
+	return Latency{
+		Count: len(sorted),
+		Avg:   total / time.Duration(len(sorted)),
+		Min:   sorted[0],
+		P50:   percentile(Percentile50),
+		P95:   percentile(Percentile95),
+		P99:   percentile(Percentile99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// PrintReport prints the statistics of a test report and its per type and url breakdown to stdout
+func PrintReport(report *interfaces.TestReport) {
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	yellow := color.New(color.FgHiYellow, color.Underline).SprintfFunc()
+	grey := color.New(color.FgHiBlack).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+	stats := report.Stats
+
+	color.Red("Collected Metrics")
+	fmt.Printf(" - %-34s %s\n", yel("Requests"), white("%d (%d failed) in %s", stats.TotalRequests,
+		stats.FailedRequests, report.ElapsedTime.Round(time.Millisecond)))
+	fmt.Printf(" - %-34s %s\n", yel("Throughput"), white("%.1f per second", stats.Throughput))
+	fmt.Printf(" - %-34s %s\n", yel("Latency (avg / p50 / p95 / p99)"), white("%s / %s / %s / %s",
+		stats.AvgLatency, stats.P50Latency, stats.P95Latency, stats.P99Latency))
+	fmt.Printf(" - %-20s %-22s %-22s %-22s %s\n", yellow("Count"), yellow("Avg"), yellow("P95"),
+		yellow("Success"), yellow("Type / Url"))
+	for i, group := range report.AssetStats {
+		paint := white
+		if i%2 == 0 {
+			paint = grey
+		}
+		fmt.Printf(" - %-18s %-20s %-20s %-20s %s\n", paint("%d", group.Count), paint(group.AvgLatency.String()),
+			paint(group.P95Latency.String()), paint("%.1f%%", group.SuccessRate*PercentBase),
+			paint(group.Type+" "+group.URL))
+	}
+}
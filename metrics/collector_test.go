@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+func TestSummarize(t *testing.T) {
+	color.Green("~~ TestSummarize ~~")
+	values := make([]time.Duration, 0, 100)
+	for i := 100; i > 0; i-- {
+		values = append(values, time.Duration(i)*time.Millisecond)
+	}
+	want := Latency{
+		Count: 100,
+		Avg:   50500 * time.Microsecond,
+		Min:   time.Millisecond,
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+		Max:   100 * time.Millisecond,
+	}
+	if got := Summarize(values); got != want {
+		t.Errorf("Summarize = %+v, want %+v", got, want)
+	}
+	if values[0] != 100*time.Millisecond {
+		t.Error("Summarize reordered its input")
+	}
+	if got := Summarize([]time.Duration{time.Second}); got.P50 != time.Second || got.P99 != time.Second {
+		t.Errorf("Summarize(1s) = %+v", got)
+	}
+	if got := Summarize(nil); got != (Latency{}) {
+		t.Errorf("Summarize(nil) = %+v", got)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	collector := NewCollector()
+	session, err := collector.StartTest(&interfaces.TestConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range []interfaces.RequestResult{
+		{URL: "/b", Type: "get", Duration: 30 * time.Millisecond, Size: 10, Success: true},
+		{URL: "/a", Type: "get", Duration: 10 * time.Millisecond, Size: 20, Success: true},
+		{URL: "/a", Type: "get", Duration: 20 * time.Millisecond, Size: 30},
+		{URL: "/a", Type: "connect", Duration: 40 * time.Millisecond, Success: true},
+	} {
+		if err = collector.RecordRequest(session, &result); err != nil {
+			t.Fatalf("RecordRequest(%d): %s", i, err)
+		}
+	}
+
+	stats, err := collector.GetStats(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalRequests != 4 || stats.FailedRequests != 1 || stats.TotalBytes != 60 ||
+		stats.AvgLatency != 25*time.Millisecond || stats.P50Latency != 20*time.Millisecond ||
+		stats.MaxLatency != 40*time.Millisecond || stats.Throughput <= 0 {
+		t.Errorf("GetStats = %+v", stats)
+	}
+
+	report, err := collector.FinishTest(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interfaces.AssetStats{
+		{URL: "/a", Type: "connect", Count: 1, AvgLatency: 40 * time.Millisecond, P95Latency: 40 * time.Millisecond,
+			SuccessRate: 1},
+		{URL: "/a", Type: "get", Count: 2, AvgLatency: 15 * time.Millisecond, P95Latency: 20 * time.Millisecond,
+			SuccessRate: 0.5},
+		{URL: "/b", Type: "get", Count: 1, AvgLatency: 30 * time.Millisecond, P95Latency: 30 * time.Millisecond,
+			SuccessRate: 1},
+	}
+	if len(report.AssetStats) != len(want) {
+		t.Fatalf("%d asset stats, want %d", len(report.AssetStats), len(want))
+	}
+	for i := range want {
+		if *report.AssetStats[i] != want[i] {
+			t.Errorf("AssetStats[%d] = %+v, want %+v", i, *report.AssetStats[i], want[i])
+		}
+	}
+	if session.Status != interfaces.SessionStatusCompleted || report.ElapsedTime <= 0 {
+		t.Errorf("session %s, elapsed %s", session.Status, report.ElapsedTime)
+	}
+
+	if err = collector.Reset(session); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ = collector.GetStats(session); stats.TotalRequests != 0 {
+		t.Errorf("%d requests after Reset", stats.TotalRequests)
+	}
+}
+
+func TestCollectorUnknownSession(t *testing.T) {
+	collector := NewCollector()
+	other, _ := NewCollector().StartTest(&interfaces.TestConfig{})
+	if err := collector.RecordRequest(other, &interfaces.RequestResult{}); err == nil {
+		t.Error("RecordRequest should reject a session of another collector")
+	}
+	if _, err := collector.GetStats(nil); err == nil {
+		t.Error("GetStats should reject a nil session")
+	}
+	if _, err := collector.FinishTest(other); err == nil {
+		t.Error("FinishTest should reject a session of another collector")
+	}
+}
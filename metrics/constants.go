@@ -0,0 +1,16 @@
+// Package metrics collects the results of load test requests through interfaces.MetricsCollector
+// and summarizes them into statistics, latency percentiles and per-target reports.
+package metrics
+
+const (
+	// SessionIDFormat names test sessions after a sequence number
+	SessionIDFormat = "test-%d"
+	// PercentBase specifies the base of percentile ranks
+	PercentBase = 100
+	// Percentile50 is the median
+	Percentile50 = 50
+	// Percentile95 is the 95th percentile
+	Percentile95 = 95
+	// Percentile99 is the 99th percentile
+	Percentile99 = 99
+)
@@ -16,9 +16,25 @@ const (
 	// DefaultJSONIndent specifies the default indentation for JSON formatting
 	DefaultJSONIndent = "  " // 2 spaces for JSON formatting
 
-	// AssetTypesCount specifies the number of different asset types processed
-	AssetTypesCount = 3 // JS, CSS, IMG
-
 	// FloatFormatChar specifies the format character for float conversion
 	FloatFormatChar = 'g' // Float format character for strconv
+
+	// DefaultTargetWeight specifies the weight of a target that does not set one
+	DefaultTargetWeight = 1
+	// TargetHeaderSeparator separates the name and value of a header in a target spec
+	TargetHeaderSeparator = "="
+	// TargetCommentPrefix starts a comment line in a text targets file
+	TargetCommentPrefix = "#"
+	// JSONArrayPrefix identifies a JSON targets file
+	JSONArrayPrefix = "["
+	// GraphQLAnonymousOperation names the operation of a GraphQL document that names none
+	GraphQLAnonymousOperation = "anonymous"
+	// GraphQLContentType is the content type of GraphQL requests
+	GraphQLContentType = "application/json"
+	// ContentTypeHeader specifies the HTTP Content-Type header name
+	ContentTypeHeader = "Content-Type"
+	// MaxVariableDepth limits how many times variables referring to other variables are expanded
+	MaxVariableDepth = 10
+	// MaxRandomInt specifies the largest value of the {{$randomInt}} variable
+	MaxRandomInt = 1000
 )
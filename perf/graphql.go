@@ -0,0 +1,114 @@
+package perf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// operationDefinition matches the first named operation of a GraphQL document
+var operationDefinition = regexp.MustCompile(`(?:^|[\s}])(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// graphQLComment matches a comment of a GraphQL document
+var graphQLComment = regexp.MustCompile(`#[^\n]*`)
+
+/*
+GraphQL is the request of a target sending a GraphQL operation.  It is posted as JSON to
+the url of the target, and the target's results are also reported under the name of the
+operation, so operations sharing one endpoint are told apart.
+
+Structure Overview
+  - Query - the query document
+  - Variables - the variables of the operation; their string values may hold {{name}} references
+  - OperationName - the operation of the document to run, needed when it defines several
+*/
+type GraphQL struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+/*
+LoadGraphQL reads a GraphQL request file: either a JSON request with query, variables
+and operationName fields, or a bare query document such as a .graphql file.  A document
+in the shorthand form, e.g. "{ me { name } }", is not JSON and is read as a query.
+*/
+func LoadGraphQL(path string) (*GraphQL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GraphQL file: %w", err)
+	}
+	query := &GraphQL{Query: string(data)}
+	if json.Valid(data) {
+		query = &GraphQL{}
+		if err = json.Unmarshal(data, query); err != nil {
+			return nil, fmt.Errorf("failed to parse GraphQL file %s: %w", path, err)
+		}
+	}
+	if err = query.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return query, nil
+}
+
+// Validate checks that the request has a query
+func (g *GraphQL) Validate() error {
+	if strings.TrimSpace(g.Query) == "" {
+		return fmt.Errorf("GraphQL request has no query")
+	}
+	return nil
+}
+
+// Operation returns the name results are reported under: OperationName, else the name of
+// the first operation of the document, else GraphQLAnonymousOperation
+func (g *GraphQL) Operation() string {
+	if g.OperationName != "" {
+		return g.OperationName
+	}
+	if match := operationDefinition.FindStringSubmatch(graphQLComment.ReplaceAllString(g.Query, "")); match != nil {
+		return match[1]
+	}
+	return GraphQLAnonymousOperation
+}
+
+// expand returns a copy of the request with the variable references of its query and of the
+// string values of its variables expanded.  They are expanded before the request is encoded,
+// so values holding quotes, backslashes or newlines are escaped in the JSON body.
+func (g *GraphQL) expand(vars Variables) *GraphQL {
+	expanded := *g
+	expanded.Query = vars.Expand(g.Query)
+	if g.Variables != nil {
+		expanded.Variables = expandValue(g.Variables, vars).(map[string]any)
+	}
+	return &expanded
+}
+
+// expandValue expands the strings of a decoded JSON value, recursing into objects and arrays
+func expandValue(value any, vars Variables) any {
+	switch v := value.(type) {
+	case string:
+		return vars.Expand(v)
+	case map[string]any:
+		expanded := make(map[string]any, len(v))
+		for key, item := range v {
+			expanded[key] = expandValue(item, vars)
+		}
+		return expanded
+	case []any:
+		expanded := make([]any, len(v))
+		for i, item := range v {
+			expanded[i] = expandValue(item, vars)
+		}
+		return expanded
+	default:
+		return value
+	}
+}
+
+// body returns the JSON request body
+func (g *GraphQL) body() string {
+	data, _ := json.Marshal(g)
+	return string(data)
+}
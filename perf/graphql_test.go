@@ -0,0 +1,135 @@
+package perf
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+func TestLoadGraphQL(t *testing.T) {
+	color.Green("~~ TestLoadGraphQL ~~")
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name, content, operation string
+		variables                int
+	}{
+		{"user.graphql", "# query Commented\nquery GetUser($id: ID!) { user(id: $id) { name } }", "GetUser", 0},
+		{"anonymous.graphql", "{ me { name } }", GraphQLAnonymousOperation, 0},
+		{"mutation.graphql", "fragment F on User { name }\nmutation Rename { rename { ...F } }", "Rename", 0},
+		{"request.json", `{"query": "query A { a } query B { b }", "operationName": "B", "variables": {"id": 1}}`, "B", 1},
+	}
+	for _, tt := range tests {
+		query, err := LoadGraphQL(write(tt.name, tt.content))
+		if err != nil {
+			t.Fatalf("LoadGraphQL(%s): %v", tt.name, err)
+		}
+		if got := query.Operation(); got != tt.operation || len(query.Variables) != tt.variables {
+			t.Errorf("LoadGraphQL(%s) = operation %q with %v, want %q", tt.name, got, query.Variables, tt.operation)
+		}
+	}
+
+	for _, content := range []string{"  \n", `{"variables": {}}`, `["query"]`} {
+		if _, err := LoadGraphQL(write("invalid.graphql", content)); err == nil {
+			t.Errorf("LoadGraphQL(%q) should fail", content)
+		}
+	}
+	if _, err := LoadGraphQL(filepath.Join(dir, "missing.graphql")); err == nil {
+		t.Error("LoadGraphQL(missing.graphql) should fail")
+	}
+}
+
+func TestBasicGraphQL(t *testing.T) {
+	var expanded atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GraphQL
+		if r.Method != http.MethodPost || r.Header.Get(ContentTypeHeader) != GraphQLContentType ||
+			json.NewDecoder(r.Body).Decode(&req) != nil {
+			// The request opening the session of every user
+			http.Error(w, "not a GraphQL request", http.StatusBadRequest)
+			return
+		}
+		if id, _ := req.Variables["id"].(string); regexp.MustCompile(`^\d+$`).MatchString(id) {
+			expanded.Add(1)
+		}
+		w.Header().Set(ContentTypeHeader, GraphQLContentType)
+		if req.OperationName == "Broken" {
+			_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "boom"}, {"message": "again"}]}`))
+			return
+		}
+		// The script tag must not be fetched as an asset
+		_, _ = w.Write([]byte(`{"data": {"user": {"bio": "<script src=\"/app.js\"></script>"}}}`))
+	}))
+	defer server.Close()
+
+	getUser := "query GetUser($id: ID!) { user(id: $id) { bio } }"
+	test := &Init{
+		Threads: 2,
+		Seconds: 1,
+		Targets: []Target{
+			{URL: server.URL, GraphQL: &GraphQL{Query: getUser, Variables: map[string]any{"id": "{{$randomInt}}"}}},
+			{URL: server.URL, GraphQL: &GraphQL{Query: getUser, Variables: map[string]any{"id": "1"}}},
+			{URL: server.URL, GraphQL: &GraphQL{Query: "mutation Broken { break }", OperationName: "Broken"}},
+		},
+	}
+	results := test.Basic()
+
+	// Targets sending the same operation to the same url are reported together
+	if len(results.Targets) != 2 || results.Targets[0].Operation != "GetUser" || results.Targets[0].Method != "POST" ||
+		results.Targets[1].Operation != "Broken" {
+		t.Fatalf("%d targets, the first %s %s", len(results.Targets), results.Targets[0].Method,
+			results.Targets[0].Operation)
+	}
+	operations := buildOperationResults(results.Operations)
+	if len(operations) != 2 {
+		t.Fatalf("operations = %+v", operations)
+	}
+	get, broken := operations[0], operations[1]
+	if get.Name != "GetUser" || get.Numreqs != results.Targets[0].Base.NumRequests ||
+		get.Failed != 0 || get.Status["200"] != get.Numreqs || int64(get.Numreqs) != expanded.Load() {
+		t.Errorf("GetUser = %+v, %d expanded", get, expanded.Load())
+	}
+	if broken.Name != "Broken" || broken.Numreqs == 0 || broken.Failed != broken.Numreqs ||
+		broken.Errored != broken.Numreqs || broken.FailureRate != 1 || broken.Status["200"] != broken.Numreqs {
+		t.Errorf("Broken = %+v", broken)
+	}
+	if len(results.JSResps) != 0 {
+		t.Errorf("GraphQL responses should not be parsed for assets, got %+v", results.JSResps)
+	}
+}
+
+func TestGraphQLExpand(t *testing.T) {
+	quoted := "say \"hi\" \\o/\nbye"
+	target := Target{URL: "https://api.test/graphql", GraphQL: &GraphQL{
+		Query:     "query Find($q: String!) { find(q: $q, by: \"{{field}}\") { id } }",
+		Variables: map[string]any{"q": "{{quoted}}", "filter": map[string]any{"tags": []any{"{{quoted}}", 1.0}}},
+	}}
+	expanded := target.expand(Variables{"quoted": quoted, "field": "name"})
+
+	var body GraphQL
+	if err := json.Unmarshal([]byte(expanded.Body), &body); err != nil {
+		t.Fatalf("body %s is not JSON: %v", expanded.Body, err)
+	}
+	tags := body.Variables["filter"].(map[string]any)["tags"].([]any)
+	if body.Variables["q"] != quoted || tags[0] != quoted || tags[1] != 1.0 ||
+		body.Query != "query Find($q: String!) { find(q: $q, by: \"name\") { id } }" {
+		t.Errorf("expanded request %+v", body)
+	}
+	if target.GraphQL.Variables["q"] != "{{quoted}}" {
+		t.Errorf("the target's variables were modified: %v", target.GraphQL.Variables)
+	}
+}
@@ -4,30 +4,59 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand/v2"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/gnulnx/color"
 
+	"github.com/Gosayram/goperf/interfaces"
 	"github.com/Gosayram/goperf/request"
 )
 
 // Init represents the configuration and state for a performance test session.
 // It contains all necessary parameters for running concurrent load tests including
 // URL, number of threads, duration, and authentication details.
+// Transport controls connection pooling; unless SharedTransport is set every
+// virtual user gets its own pool, just like independent browsers.
+// Browser, when set, caps concurrent asset fetches per origin during each page load.
+// Cache gives every virtual user its own HTTP cache so repeat views behave like returning visitors.
+// Parser extracts the asset urls of each page; httputils.GetAssets is used when nil.
+// CSSDepth is how many levels of stylesheet sub-resources each page load follows.
+// Filter, when set, keeps third-party and other unwanted assets out of the test.
+// Auth, when set, gives every virtual user the provider authenticating its requests.
+// Targets, when set, replaces URL: each iteration loads one of them picked by weight
+// and the results are broken down per target as well as aggregated.
+// Sequence, when set, replaces them: each virtual user sends its requests in order, expanding
+// their {{name}} variables for every request.
+// Replay, when set, replaces all three: each iteration replays the recorded requests, such as
+// a HAR page load, at Speed times their recorded pace (0 sends them back to back).
 type Init struct {
-	URL        string
-	Threads    int
-	Seconds    int
-	Iterations int
-	Output     int
-	Index      int // Also the channel number
-	Verbose    bool
-	Results    *request.IterateReqRespAll
-	Cookies    string
-	Headers    string
-	UserAgent  string
+	URL             string
+	Targets         []Target
+	Sequence        *Sequence
+	Replay          []request.ReplayRequest
+	Speed           float64
+	Threads         int
+	Seconds         int
+	Iterations      int
+	Output          int
+	Index           int // Also the channel number
+	Verbose         bool
+	Results         *request.IterateReqRespAll
+	Cookies         string
+	Headers         string
+	UserAgent       string
+	Transport       request.TransportConfig
+	SharedTransport bool
+	Browser         *request.BrowserOptions
+	Cache           bool
+	Parser          interfaces.AssetParser
+	CSSDepth        int
+	Filter          *request.AssetFilter
+	Auth            func() interfaces.AuthProvider
 }
 
 // Basic runs the main performance test by spawning multiple goroutines
@@ -35,25 +64,39 @@ type Init struct {
 func (input *Init) Basic() request.IterateReqRespAll {
 	// Create slice of channels to hold results
 	// Fire off anonymous go routine using newly created channel
+	var shared *http.Client
+	if input.SharedTransport {
+		shared = request.NewClient(input.Transport)
+		defer shared.CloseIdleConnections()
+	}
+
 	chanslice := []chan request.IterateReqRespAll{}
 	for i := 0; i < input.Threads; i++ {
 		chanslice = append(chanslice, make(chan request.IterateReqRespAll))
 		go func(c chan request.IterateReqRespAll) {
+			client := shared
+			if client == nil {
+				client = request.NewClient(input.Transport)
+				defer client.CloseIdleConnections()
+			}
 			// Make an initial GET request to get and set cookies so we can accurately simulate a user.
 			// This effectively sets up a user session.  If this is commented out
 			// then each request will simulate a new user.
 			// TODO This should be a parameter the user can set.
-			resp1, _ := http.Get(input.URL)
-			if resp1 == nil {
-				fmt.Println("Error connecting to url: ", input.URL)
-				return
-			}
-			defer resp1.Body.Close()
-			if len(resp1.Header["Set-Cookie"]) > 0 {
-				input.Cookies = resp1.Header["Set-Cookie"][0]
+			first := input.variables().Expand(input.targets()[0].URL)
+			resp1, err := client.Get(first)
+			if err != nil {
+				// Keep iterating so the connection errors show up in the results
+				// instead of leaving the collector waiting on this channel forever.
+				fmt.Println("Error connecting to url: ", first)
+			} else {
+				resp1.Body.Close()
+				if len(resp1.Header["Set-Cookie"]) > 0 {
+					input.Cookies = resp1.Header["Set-Cookie"][0]
+				}
 			}
 			// TODO Just pass the Input in
-			c <- iterateRequest(input)
+			c <- iterateRequest(input, client)
 		}(chanslice[i])
 	}
 
@@ -67,11 +110,61 @@ func (input *Init) Basic() request.IterateReqRespAll {
 	return *input.Results
 }
 
-func iterateRequest(input *Init) request.IterateReqRespAll {
+// targets returns the targets of the test: the requests of the Sequence, Targets, or URL
+// alone.  A replay is a single target named after its first request.
+func (input *Init) targets() []Target {
+	if len(input.Replay) > 0 {
+		return []Target{{URL: input.Replay[0].URL}}
+	}
+	if input.Sequence != nil {
+		return input.Sequence.Requests
+	}
+	if len(input.Targets) > 0 {
+		return input.Targets
+	}
+	return []Target{{URL: input.URL}}
+}
+
+// targetsLabel names what the test loads: the url of its only target, the number of targets
+// or the replayed requests
+func (input *Init) targetsLabel() string {
+	if len(input.Replay) > 0 {
+		return fmt.Sprintf("%s (replay of %d requests)", input.Replay[0].URL, len(input.Replay))
+	}
+	if input.Sequence != nil {
+		return input.Sequence.label()
+	}
+	if targets := input.targets(); len(targets) > 1 {
+		return strconv.Itoa(len(targets)) + " targets"
+	}
+	return input.targets()[0].URL
+}
+
+// variables returns the variables of the sequence; without one only the dynamic variables are expanded
+func (input *Init) variables() Variables {
+	if input.Sequence == nil {
+		return nil
+	}
+	return input.Sequence.Variables
+}
+
+func iterateRequest(input *Init, client *http.Client) request.IterateReqRespAll {
 	/*
-		Continuously fetch 'url' for 'sec' second and return the results.
+		Continuously fetch the targets, picked by weight or in sequence order, for 'sec' second and return the results.
 	*/
-	url := input.URL
+	targets := input.targets()
+	picker := newTargetPicker(targets)
+	targetStats := make([]request.TargetStats, len(targets))
+	for i := range targets {
+		targetStats[i] = request.TargetStats{
+			URL:       targets[i].URL,
+			Method:    targets[i].method(),
+			Operation: targets[i].operation(),
+			Weight:    targets[i].weight(),
+			Base:      request.IterateReqResp{URL: targets[i].URL},
+		}
+	}
+	operations := newOperationStats(targets)
 	sec := input.Seconds
 	cookies := input.Cookies
 	headers := input.Headers
@@ -81,35 +174,91 @@ func iterateRequest(input *Init) request.IterateReqRespAll {
 	var elapsedTime time.Duration
 
 	resp := request.IterateReqResp{
-		URL: url,
+		URL: input.targetsLabel(),
+	}
+	assetMaps := make(map[string]map[string]*request.IterateReqResp, len(request.AssetTypes))
+	for _, kind := range request.AssetTypes {
+		assetMaps[kind] = map[string]*request.IterateReqResp{}
+	}
+
+	var cache *request.HTTPCache
+	if input.Cache {
+		cache = request.NewHTTPCache()
+	}
+	firstView, repeatView := request.ViewStats{}, request.ViewStats{}
+	protocols := request.ProtocolCounts{}
+	versions := request.TLSCounts{}
+	proxy := request.ProxyStats{}
+	var auth interfaces.AuthProvider
+	if input.Auth != nil {
+		auth = input.Auth()
 	}
-	jsMap := map[string]*request.IterateReqResp{}
-	cssMap := map[string]*request.IterateReqResp{}
-	imgMap := map[string]*request.IterateReqResp{}
 
 	var totalRespTimes int64
 	var totalLinearRespTimes int64
+	var totalQueueTimes int64
 	var count int64 // TODO for loop counter instead???
 
 	for {
-		// Fetch the url and all the js, css, and img assets
-		fetchAllResp := request.FetchAll(request.FetchInput{
-			BaseURL:   url,
+		// Fetch a target and all of its assets
+		picked := picker.pick(rand.IntN(picker.total()))
+		if input.Sequence != nil {
+			picked = int(count % int64(len(targets)))
+		}
+		target := targets[picked].expand(input.variables())
+		fetchInput := request.FetchInput{
+			BaseURL:   target.URL,
+			Method:    target.Method,
+			Header:    target.header(),
+			Body:      target.Body,
+			Auth:      auth,
 			Retdat:    false,
 			Cookies:   cookies,
 			Headers:   headers,
 			UserAgent: useragent,
-		})
+			Client:    client,
+			Browser:   input.Browser,
+			Cache:     cache,
+			Parser:    input.Parser,
+			CSSDepth:  input.CSSDepth,
+			Filter:    input.Filter,
+			GraphQL:   target.GraphQL != nil,
+		}
+		var fetchAllResp *request.FetchAllResponse
+		if len(input.Replay) > 0 {
+			fetchAllResp = request.Replay(fetchInput, input.Replay, input.Speed)
+		} else {
+			fetchAllResp = request.FetchAll(fetchInput)
+		}
+
+		// Only the first load of each user starts with an empty cache
+		if count == 0 {
+			firstView.Add(fetchAllResp)
+		} else {
+			repeatView.Add(fetchAllResp)
+		}
+		targetStats[picked].Add(fetchAllResp)
+		if name := targetStats[picked].Operation; name != "" {
+			operations[slices.IndexFunc(operations, func(o request.OperationStats) bool { return o.Name == name })].
+				Add(fetchAllResp.BaseURL)
+		}
+		protocols.Merge(fetchAllResp.Protocols)
+		versions.Merge(fetchAllResp.TLS)
+		proxy.Merge(&fetchAllResp.Proxy)
 
 		// Set base resp properties
 		resp.Status = append(resp.Status, fetchAllResp.BaseURL.Status)
 		resp.RespTimes = append(resp.RespTimes, fetchAllResp.BaseURL.Time)
 		resp.Bytes = fetchAllResp.TotalBytes
+		if fetchAllResp.BaseURL.ConnReused {
+			resp.ConnReused++
+		}
 
 		totalRespTimes += int64(fetchAllResp.TotalTime)
 		totalLinearRespTimes += int64(fetchAllResp.TotalLinearTime)
+		totalQueueTimes += int64(fetchAllResp.TotalQueueTime)
 
-		gatherAllStats(fetchAllResp, jsMap, cssMap, imgMap)
+		gatherAllStats(fetchAllResp, assetMaps)
 
 		elapsedTime = time.Since(start)
 		count++
@@ -120,34 +269,43 @@ func iterateRequest(input *Init) request.IterateReqRespAll {
 
 	avgTotalRespTimes := time.Duration(totalRespTimes / count)
 	avgTotalLinearRespTimes := time.Duration(totalLinearRespTimes / count)
-
-	// TODO Clean this up.  Perhaps some benchmark tests
-	// to see if its faster as go routines or not
-	jsResps := []request.IterateReqResp{}
-	for _, val := range jsMap {
-		jsResps = append(jsResps, *val)
-	}
-
-	cssResps := []request.IterateReqResp{}
-	for _, val := range cssMap {
-		cssResps = append(cssResps, *val)
-	}
-	imgResps := []request.IterateReqResp{}
-	for _, val := range imgMap {
-		imgResps = append(imgResps, *val)
-	}
+	avgTotalQueueTimes := time.Duration(totalQueueTimes / count)
 
 	output := request.IterateReqRespAll{
 		BaseURL:                resp,
 		AvgTotalRespTime:       avgTotalRespTimes,
 		AvgTotalLinearRespTime: avgTotalLinearRespTimes,
-		JSResps:                jsResps,
-		CSSResps:               cssResps,
-		IMGResps:               imgResps,
+		AvgTotalQueueTime:      avgTotalQueueTimes,
+		FirstView:              firstView,
+		RepeatView:             repeatView,
+		Targets:                targetStats,
+		Operations:             operations,
+		Protocols:              protocols,
+		TLS:                    versions,
+		Proxy:                  proxy,
+	}
+	for _, kind := range request.AssetTypes {
+		assetResps := []request.IterateReqResp{}
+		for _, val := range assetMaps[kind] {
+			assetResps = append(assetResps, *val)
+		}
+		*output.Resps(kind) = assetResps
 	}
 	return output
 }
 
+// newOperationStats returns empty stats for every GraphQL operation of targets, in target order
+func newOperationStats(targets []Target) []request.OperationStats {
+	var operations []request.OperationStats
+	for i := range targets {
+		name := targets[i].operation()
+		if name != "" && !slices.ContainsFunc(operations, func(o request.OperationStats) bool { return o.Name == name }) {
+			operations = append(operations, request.OperationStats{Name: name, Base: request.IterateReqResp{URL: name}})
+		}
+	}
+	return operations
+}
+
 // BaseURL represents the performance metrics for the main URL being tested.
 // It contains response times, status codes, and byte counts for the base page.
 type BaseURL struct {
@@ -155,25 +313,136 @@ type BaseURL struct {
 	Numreqs             int            `json:"num_reqs"`
 	TotBytes            int            `json:"total_bytes"`
 	AvgPageRespTime     time.Duration  `json:"avg_page_resp_time"`
+	AvgPageQueueTime    time.Duration  `json:"avg_page_queue_time"`
 	AvgTimeToFirsttByte time.Duration  `json:"avg_time_to_first_byte"`
 	Status              map[string]int `json:"status"`
+	ConnReuseRatio      float64        `json:"conn_reuse_ratio"`
+	TotalConnReuseRatio float64        `json:"total_conn_reuse_ratio"`
 }
 
-// AssetResult represents performance metrics for individual assets (JS, CSS, images).
+// AssetResult represents performance metrics for individual assets (JS, CSS, images, fonts...).
 // It tracks response times and status codes for each asset URL discovered on the page.
 type AssetResult struct {
-	URL         string         `json:"url"`
-	AvgRespTime time.Duration  `json:"avg_resp_time"`
-	Status      map[string]int `json:"status"`
+	URL            string         `json:"url"`
+	AvgRespTime    time.Duration  `json:"avg_resp_time"`
+	AvgQueueTime   time.Duration  `json:"avg_queue_time"`
+	Status         map[string]int `json:"status"`
+	ConnReuseRatio float64        `json:"conn_reuse_ratio"`
+}
+
+// ViewResult summarizes first-view or repeat-view page loads
+type ViewResult struct {
+	Pages           int           `json:"pages"`
+	AvgPageRespTime time.Duration `json:"avg_page_resp_time"`
+	AvgPageBytes    int           `json:"avg_page_bytes"`
+	Requests        int           `json:"requests"`
+	NotModified     int           `json:"not_modified"`
+	CacheHits       int           `json:"cache_hits"`
+}
+
+// TargetResult summarizes the page loads of one target of a multi-target test.
+// Share is the fraction of all page loads that went to the target.
+type TargetResult struct {
+	URL                 string         `json:"url"`
+	Method              string         `json:"method"`
+	Operation           string         `json:"operation,omitempty"`
+	Weight              int            `json:"weight"`
+	Numreqs             int            `json:"num_reqs"`
+	Share               float64        `json:"share"`
+	AvgPageRespTime     time.Duration  `json:"avg_page_resp_time"`
+	AvgPageBytes        int            `json:"avg_page_bytes"`
+	AvgTimeToFirsttByte time.Duration  `json:"avg_time_to_first_byte"`
+	Status              map[string]int `json:"status"`
+	ConnReuseRatio      float64        `json:"conn_reuse_ratio"`
+}
+
+// OperationResult summarizes the requests of one GraphQL operation, whichever targets sent it.
+// Failed counts the requests that got no response, an error status or GraphQL errors;
+// Errored counts the responses that listed GraphQL errors, usually with 200 OK.
+type OperationResult struct {
+	Name           string         `json:"name"`
+	Numreqs        int            `json:"num_reqs"`
+	AvgRespTime    time.Duration  `json:"avg_resp_time"`
+	AvgBytes       int            `json:"avg_bytes"`
+	Status         map[string]int `json:"status"`
+	Failed         int            `json:"failed"`
+	Errored        int            `json:"errored"`
+	FailureRate    float64        `json:"failure_rate"`
+	ConnReuseRatio float64        `json:"conn_reuse_ratio"`
+}
+
+// ProtocolResult summarizes the requests sent over one negotiated protocol.  StreamsPerConn
+// is above 1 when HTTP/2 multiplexes requests on a connection.
+type ProtocolResult struct {
+	Protocol       string  `json:"protocol"`
+	Requests       int     `json:"requests"`
+	Connections    int     `json:"connections"`
+	StreamsPerConn float64 `json:"streams_per_conn"`
+}
+
+// TLSResult summarizes the requests sent over one negotiated TLS version and the handshakes
+// of their connections
+type TLSResult struct {
+	Version      string        `json:"version"`
+	Requests     int           `json:"requests"`
+	Handshakes   int           `json:"handshakes"`
+	Resumed      int           `json:"resumed"`
+	AvgHandshake time.Duration `json:"avg_handshake"`
+}
+
+// ProxyResult summarizes the requests sent through a proxy.  AvgConnect and AvgTunnel are
+// the time new connections spent reaching the proxy and opening a tunnel through it, before
+// any time spent on the origin.
+type ProxyResult struct {
+	Requests    int           `json:"requests"`
+	Connections int           `json:"connections"`
+	AvgConnect  time.Duration `json:"avg_connect"`
+	AvgTunnel   time.Duration `json:"avg_tunnel"`
 }
 
 // Output represents the complete performance test results in JSON-serializable format.
 // It combines base URL metrics with detailed asset performance data.
+// Targets is only filled in when the test loads more than one target, Operations when it
+// sends GraphQL operations.
 type Output struct {
-	BaseURL    BaseURL       `json:"base_url"`
-	JSResults  []AssetResult `json:"js_assets"`
-	CSSResults []AssetResult `json:"css_assets"`
-	IMGResults []AssetResult `json:"img_assets"`
+	BaseURL         BaseURL           `json:"base_url"`
+	Targets         []TargetResult    `json:"targets,omitempty"`
+	Operations      []OperationResult `json:"operations,omitempty"`
+	Protocols       []ProtocolResult  `json:"protocols"`
+	TLS             []TLSResult       `json:"tls,omitempty"`
+	Proxy           *ProxyResult      `json:"proxy,omitempty"`
+	FirstView       ViewResult        `json:"first_view"`
+	RepeatView      ViewResult        `json:"repeat_view"`
+	JSResults       []AssetResult     `json:"js_assets"`
+	CSSResults      []AssetResult     `json:"css_assets"`
+	IMGResults      []AssetResult     `json:"img_assets"`
+	FontResults     []AssetResult     `json:"font_assets"`
+	MediaResults    []AssetResult     `json:"media_assets"`
+	IconResults     []AssetResult     `json:"icon_assets"`
+	DocumentResults []AssetResult     `json:"document_assets"`
+	OtherResults    []AssetResult     `json:"other_assets"`
+}
+
+// assetResults returns the results of asset type kind
+func (o *Output) assetResults(kind string) *[]AssetResult {
+	switch kind {
+	case request.AssetTypeJS:
+		return &o.JSResults
+	case request.AssetTypeCSS:
+		return &o.CSSResults
+	case request.AssetTypeIMG:
+		return &o.IMGResults
+	case request.AssetTypeFont:
+		return &o.FontResults
+	case request.AssetTypeMedia:
+		return &o.MediaResults
+	case request.AssetTypeIcon:
+		return &o.IconResults
+	case request.AssetTypeDocument:
+		return &o.DocumentResults
+	default:
+		return &o.OtherResults
+	}
 }
 
 // JSONAll prints all performance test data in JSON format.
@@ -202,26 +471,149 @@ func (input *Init) JSONResults() string {
 			Numreqs:             len(results.BaseURL.Status),
 			TotBytes:            results.BaseURL.Bytes,
 			AvgPageRespTime:     results.AvgTotalRespTime,
+			AvgPageQueueTime:    results.AvgTotalQueueTime,
 			AvgTimeToFirsttByte: avg,
 			Status:              statusResults,
+			ConnReuseRatio:      reuseRatio(&results.BaseURL),
+			TotalConnReuseRatio: totalReuseRatio(results),
 		},
-		JSResults:  buildAssetSlice(results.JSResps),
-		CSSResults: buildAssetSlice(results.CSSResps),
-		IMGResults: buildAssetSlice(results.IMGResps),
+		Targets:    buildTargetResults(results.Targets),
+		Operations: buildOperationResults(results.Operations),
+		Protocols:  buildProtocolResults(results.Protocols),
+		TLS:        buildTLSResults(results.TLS),
+		Proxy:      buildProxyResult(&results.Proxy),
+		FirstView:  buildViewResult(&results.FirstView),
+		RepeatView: buildViewResult(&results.RepeatView),
+	}
+	for _, kind := range request.AssetTypes {
+		*output.assetResults(kind) = buildAssetSlice(*results.Resps(kind))
 	}
 
 	outputJSON, _ := json.MarshalIndent(output, "", DefaultJSONIndent)
 	return string(outputJSON)
 }
 
+// buildTargetResults summarizes the targets of a multi-target test, or returns nil for a single target
+func buildTargetResults(targets []request.TargetStats) []TargetResult {
+	if len(targets) < 2 {
+		return nil
+	}
+	pages := 0
+	for i := range targets {
+		pages += targets[i].View.Pages
+	}
+	results := []TargetResult{}
+	for i := range targets {
+		target := &targets[i]
+		result := TargetResult{
+			URL:             target.URL,
+			Method:          target.Method,
+			Operation:       target.Operation,
+			Weight:          target.Weight,
+			Numreqs:         target.View.Pages,
+			AvgPageRespTime: target.View.AvgPageTime(),
+			AvgPageBytes:    target.View.AvgBytes(),
+			ConnReuseRatio:  reuseRatio(&target.Base),
+		}
+		if pages > 0 {
+			result.Share = float64(target.View.Pages) / float64(pages)
+		}
+		if len(target.Base.Status) > 0 {
+			result.AvgTimeToFirsttByte, result.Status = procResult(&target.Base)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// buildOperationResults summarizes the requests of each GraphQL operation
+func buildOperationResults(operations []request.OperationStats) []OperationResult {
+	var results []OperationResult
+	for i := range operations {
+		operation := &operations[i]
+		result := OperationResult{
+			Name:           operation.Name,
+			Numreqs:        len(operation.Base.Status),
+			Failed:         operation.Failed,
+			Errored:        operation.Errored,
+			ConnReuseRatio: reuseRatio(&operation.Base),
+			Status:         map[string]int{},
+		}
+		if result.Numreqs > 0 {
+			result.AvgRespTime, result.Status = procResult(&operation.Base)
+			result.AvgBytes = operation.Base.Bytes / result.Numreqs
+			result.FailureRate = float64(operation.Failed) / float64(result.Numreqs)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// buildProtocolResults summarizes the requests and connections of each protocol
+func buildProtocolResults(protocols request.ProtocolCounts) []ProtocolResult {
+	results := []ProtocolResult{}
+	for _, protocol := range protocols.Names() {
+		stats := protocols[protocol]
+		results = append(results, ProtocolResult{
+			Protocol:       protocol,
+			Requests:       stats.Requests,
+			Connections:    stats.Connections,
+			StreamsPerConn: stats.StreamsPerConn(),
+		})
+	}
+	return results
+}
+
+// buildTLSResults summarizes the requests and handshakes of each TLS version
+func buildTLSResults(versions request.TLSCounts) []TLSResult {
+	var results []TLSResult
+	for _, version := range versions.Versions() {
+		stats := versions[version]
+		results = append(results, TLSResult{
+			Version:      version,
+			Requests:     stats.Requests,
+			Handshakes:   stats.Handshakes,
+			Resumed:      stats.Resumed,
+			AvgHandshake: stats.AvgHandshake(),
+		})
+	}
+	return results
+}
+
+// buildProxyResult summarizes the proxied requests, or returns nil when there were none
+func buildProxyResult(proxy *request.ProxyStats) *ProxyResult {
+	if proxy.Requests == 0 {
+		return nil
+	}
+	return &ProxyResult{
+		Requests:    proxy.Requests,
+		Connections: proxy.Connections,
+		AvgConnect:  proxy.AvgConnect(),
+		AvgTunnel:   proxy.AvgTunnel(),
+	}
+}
+
+func buildViewResult(view *request.ViewStats) ViewResult {
+	return ViewResult{
+		Pages:           view.Pages,
+		AvgPageRespTime: view.AvgPageTime(),
+		AvgPageBytes:    view.AvgBytes(),
+		Requests:        view.Requests,
+		NotModified:     view.NotModified,
+		CacheHits:       view.CacheHits,
+	}
+}
+
 func buildAssetSlice(resps []request.IterateReqResp) []AssetResult {
 	results := []AssetResult{}
 	for _, resp := range resps {
 		avg, statusResults := procResult(&resp)
 		result := AssetResult{
-			URL:         resp.URL,
-			AvgRespTime: avg,
-			Status:      statusResults,
+			URL:            resp.URL,
+			AvgRespTime:    avg,
+			AvgQueueTime:   avgQueueTime(&resp),
+			Status:         statusResults,
+			ConnReuseRatio: reuseRatio(&resp),
 		}
 		results = append(results, result)
 	}
@@ -242,6 +634,7 @@ func (input *Init) Print() {
 	fmt.Printf(" - %-45s %-25s\n", yel("Number of Requests:"), white(strconv.Itoa(len(results.BaseURL.Status))))
 	fmt.Printf(" - %-45s %s\n", yel("Total Bytes:"), white(strconv.Itoa(results.BaseURL.Bytes)))
 	fmt.Printf(" - %-45s %s\n", yel("Avg Page Resp Time:"), white(results.AvgTotalRespTime.String()))
+	fmt.Printf(" - %-45s %s\n", yel("Avg Page Queue Time:"), white(results.AvgTotalQueueTime.String()))
 
 	// This is useful for comparing the decrease in resp time from linear to go routines
 	// decrease := float64(results.AvgTotalLinearRespTime) - float64(results.AvgTotalRespTime)
@@ -251,26 +644,179 @@ func (input *Init) Print() {
 	avg, statusResults := procResultString(&results.BaseURL)
 	fmt.Printf(" - %-45s %s\n", yel("Average Time to First Byte:"), white(avg))
 	fmt.Printf(" - %-45s %s\n", yel("Status:"), white(statusResults))
+	fmt.Printf(" - %-45s %s\n", yel("Connection Reuse (base / all):"), white("%.1f%% / %.1f%%",
+		reuseRatio(&results.BaseURL)*PercentageBase, totalReuseRatio(results)*PercentageBase))
+
+	printTargets(buildTargetResults(results.Targets))
+	printOperations(buildOperationResults(results.Operations))
+	printProtocols(buildProtocolResults(results.Protocols))
+	printTLS(buildTLSResults(results.TLS))
+	printProxy(buildProxyResult(&results.Proxy))
+
+	if input.Cache {
+		printView := func(title string, view *request.ViewStats) {
+			color.Red(title)
+			fmt.Printf(" - %-45s %s\n", yel("Pages:"), white(strconv.Itoa(view.Pages)))
+			fmt.Printf(" - %-45s %s\n", yel("Avg Page Resp Time:"), white(view.AvgPageTime().String()))
+			fmt.Printf(" - %-45s %s\n", yel("Avg Page Bytes:"), white(strconv.Itoa(view.AvgBytes())))
+			fmt.Printf(" - %-45s %s\n", yel("Network Requests:"), white(strconv.Itoa(view.Requests)))
+			fmt.Printf(" - %-45s %s\n", yel("Cache Hits / 304 Not Modified:"),
+				white("%d / %d", view.CacheHits, view.NotModified))
+		}
+		printView("First View Results", &results.FirstView)
+		printView("Repeat View Results", &results.RepeatView)
+	}
 
 	printAssets := func(title string, results []request.IterateReqResp) {
 		color.Red(title)
-		fmt.Printf(" - %-28s %-30s %-21s %-10s\n", yellow("Average"), yellow("Status"), yellow("Bytes"), yellow("Url"))
+		fmt.Printf(" - %-28s %-28s %-30s %-21s %-10s\n",
+			yellow("Average"), yellow("Avg Queued"), yellow("Status"), yellow("Bytes"), yellow("Url"))
 		for i, resp := range results {
 			avg, statusResults := procResultString(&resp)
+			paint := white
 			if i%2 == 0 {
-				fmt.Printf(" - %-26s %-28s %-19s %-10s\n",
-					grey(avg), grey(statusResults),
-					grey(strconv.Itoa(resp.Bytes)), grey(resp.URL))
-			} else {
-				fmt.Printf(" - %-26s %-28s %-19s %-10s\n",
-					white(avg), white(statusResults),
-					white(strconv.Itoa(resp.Bytes)), white(resp.URL))
+				paint = grey
 			}
+			fmt.Printf(" - %-26s %-26s %-28s %-19s %-10s\n",
+				paint(avg), paint(avgQueueTime(&resp).String()), paint(statusResults),
+				paint(strconv.Itoa(resp.Bytes)), paint(resp.URL))
+		}
+	}
+	for _, kind := range request.AssetTypes {
+		if assets := *results.Resps(kind); len(assets) > 0 {
+			printAssets(request.AssetTypeTitle(kind)+" Results", assets)
 		}
 	}
-	printAssets("JS Results", results.JSResps)
-	printAssets("CSS Results", results.CSSResps)
-	printAssets("IMG Results", results.IMGResps)
+}
+
+// printTargets prints the per-target breakdown of a multi-target test
+func printTargets(targets []TargetResult) {
+	if targets == nil {
+		return
+	}
+	yellow := color.New(color.FgHiYellow, color.Underline).SprintfFunc()
+	grey := color.New(color.FgHiBlack).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("Target Results")
+	fmt.Printf(" - %-24s %-22s %-28s %-28s %-30s %-10s\n", yellow("Share"), yellow("Weight"),
+		yellow("Avg Page Resp Time"), yellow("Avg Time to First Byte"), yellow("Status"), yellow("Url"))
+	for i, target := range targets {
+		paint := white
+		if i%2 == 0 {
+			paint = grey
+		}
+		status, _ := json.Marshal(target.Status)
+		name := target.Method + " " + target.URL
+		if target.Operation != "" {
+			name += " (" + target.Operation + ")"
+		}
+		fmt.Printf(" - %-22s %-20s %-26s %-26s %-28s %-10s\n",
+			paint("%.1f%% (%d)", target.Share*PercentageBase, target.Numreqs), paint(strconv.Itoa(target.Weight)),
+			paint(target.AvgPageRespTime.String()), paint(target.AvgTimeToFirsttByte.String()), paint(string(status)),
+			paint(name))
+	}
+}
+
+// printOperations prints the per-operation breakdown of a GraphQL test
+func printOperations(operations []OperationResult) {
+	if len(operations) == 0 {
+		return
+	}
+	yellow := color.New(color.FgHiYellow, color.Underline).SprintfFunc()
+	grey := color.New(color.FgHiBlack).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("GraphQL Operation Results")
+	fmt.Printf(" - %-22s %-28s %-22s %-34s %-30s %-10s\n", yellow("Requests"), yellow("Avg Resp Time"),
+		yellow("Avg Bytes"), yellow("Failed (GraphQL errors)"), yellow("Status"), yellow("Operation"))
+	for i, operation := range operations {
+		paint := white
+		if i%2 == 0 {
+			paint = grey
+		}
+		status, _ := json.Marshal(operation.Status)
+		fmt.Printf(" - %-20s %-26s %-20s %-32s %-28s %-10s\n",
+			paint(strconv.Itoa(operation.Numreqs)), paint(operation.AvgRespTime.String()),
+			paint(strconv.Itoa(operation.AvgBytes)), paint("%.1f%% (%d errored)", operation.FailureRate*PercentageBase,
+				operation.Errored), paint(string(status)), paint(operation.Name))
+	}
+}
+
+// printProtocols shows how many requests and connections each protocol used
+func printProtocols(protocols []ProtocolResult) {
+	if len(protocols) == 0 {
+		return
+	}
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("Protocol Results")
+	for _, protocol := range protocols {
+		fmt.Printf(" - %-45s %s\n", yel(protocol.Protocol+":"), white("%d requests on %d connections (%.1f per connection)",
+			protocol.Requests, protocol.Connections, protocol.StreamsPerConn))
+	}
+}
+
+// printTLS shows the handshakes made for each TLS version and how long they took
+func printTLS(versions []TLSResult) {
+	if len(versions) == 0 {
+		return
+	}
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("TLS Results")
+	for _, version := range versions {
+		fmt.Printf(" - %-45s %s\n", yel(version.Version+":"), white("%d requests, %d handshakes (%d resumed), %s average",
+			version.Requests, version.Handshakes, version.Resumed, version.AvgHandshake))
+	}
+}
+
+// printProxy shows how long new connections took to reach the proxy and tunnel through it
+func printProxy(proxy *ProxyResult) {
+	if proxy == nil {
+		return
+	}
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("Proxy Results")
+	fmt.Printf(" - %-45s %s\n", yel("Requests / New Connections:"), white("%d / %d", proxy.Requests, proxy.Connections))
+	fmt.Printf(" - %-45s %s\n", yel("Avg Proxy Connect / Tunnel:"), white("%s / %s", proxy.AvgConnect, proxy.AvgTunnel))
+}
+
+// avgQueueTime returns the average time requests for resp waited for a per-origin slot
+func avgQueueTime(resp *request.IterateReqResp) time.Duration {
+	if len(resp.Status) == 0 {
+		return 0
+	}
+	return resp.QueueTime / time.Duration(len(resp.Status))
+}
+
+// reuseRatio returns the fraction of requests for resp that were sent on a pooled connection
+func reuseRatio(resp *request.IterateReqResp) float64 {
+	if len(resp.Status) == 0 {
+		return 0
+	}
+	return float64(resp.ConnReused) / float64(len(resp.Status))
+}
+
+// totalReuseRatio returns the fraction of all requests (base and assets) that reused a connection
+func totalReuseRatio(results *request.IterateReqRespAll) float64 {
+	requests := len(results.BaseURL.Status)
+	reused := results.BaseURL.ConnReused
+	for _, kind := range request.AssetTypes {
+		group := *results.Resps(kind)
+		for i := range group {
+			requests += len(group[i].Status)
+			reused += group[i].ConnReused
+		}
+	}
+	if requests == 0 {
+		return 0
+	}
+	return float64(reused) / float64(requests)
 }
 
 func procResultString(resp *request.IterateReqResp) (avgTime, status string) {
@@ -306,16 +852,16 @@ func procResult(resp *request.IterateReqResp) (avgDuration time.Duration, status
 	return avgDuration, statusMap
 }
 
-func gatherAllStats(resp *request.FetchAllResponse, jsMap, cssMap, imgMap map[string]*request.IterateReqResp) {
+func gatherAllStats(resp *request.FetchAllResponse, assetMaps map[string]map[string]*request.IterateReqResp) {
 	/*
 		Gather all the asset stuff.
 		NOTE:  You benchmarked this and the 3 go routine method was way slower so you removed the method
 		BenchmarkGatherAllStatsGo-8   	  500000	      2764 ns/op
 		BenchmarkGatherAllStats-8     	 2000000	       638 ns/op
 	*/
-	gatherStats(resp.JSResponses, jsMap)
-	gatherStats(resp.CSSResponses, cssMap)
-	gatherStats(resp.IMGResponses, imgMap)
+	for _, kind := range request.AssetTypes {
+		gatherStats(*resp.Responses(kind), assetMaps[kind])
+	}
 }
 
 func gatherStats(resps []request.FetchResponse, respMap map[string]*request.IterateReqResp) {
@@ -332,5 +878,9 @@ func gatherStats(resps []request.FetchResponse, respMap map[string]*request.Iter
 		respMap[url2].Status = append(respMap[url2].Status, status)
 		respMap[url2].RespTimes = append(respMap[url2].RespTimes, respTime)
 		respMap[url2].Bytes += bytes
+		if resps[resp].ConnReused {
+			respMap[url2].ConnReused++
+		}
+		respMap[url2].QueueTime += resps[resp].QueueTime
 	}
 }
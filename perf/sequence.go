@@ -0,0 +1,129 @@
+package perf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// variableRef matches a {{name}} variable reference
+var variableRef = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// dynamicVariables generate a new value for every request, like their Postman counterparts
+var dynamicVariables = map[string]func() string{
+	"$guid":         randomUUID,
+	"$randomUUID":   randomUUID,
+	"$timestamp":    func() string { return strconv.FormatInt(time.Now().Unix(), 10) },
+	"$isoTimestamp": func() string { return time.Now().UTC().Format(time.RFC3339Nano) },
+	"$randomInt":    func() string { return strconv.Itoa(rand.IntN(MaxRandomInt + 1)) },
+}
+
+/*
+Variables holds the values of {{name}} references.  Values may refer to other variables.
+Besides the named ones, {{$guid}}, {{$randomUUID}}, {{$timestamp}}, {{$isoTimestamp}}
+and {{$randomInt}} take a new value every time they are expanded.
+*/
+type Variables map[string]string
+
+// Expand replaces the variable references in s, leaving unknown ones as they are
+func (v Variables) Expand(s string) string {
+	for range MaxVariableDepth {
+		if !strings.Contains(s, "{{") {
+			return s
+		}
+		expanded := variableRef.ReplaceAllStringFunc(s, func(ref string) string {
+			name := variableRef.FindStringSubmatch(ref)[1]
+			if value, ok := v[name]; ok {
+				return value
+			}
+			if dynamic, ok := dynamicVariables[name]; ok {
+				return dynamic()
+			}
+			return ref
+		})
+		if expanded == s {
+			return s
+		}
+		s = expanded
+	}
+	return s
+}
+
+// Set sets a variable from a "name=value" assignment
+func (v Variables) Set(assignment string) error {
+	name, value, ok := strings.Cut(assignment, TargetHeaderSeparator)
+	if name = strings.TrimSpace(name); !ok || name == "" {
+		return fmt.Errorf("variable %q is not name=value", assignment)
+	}
+	v[name] = value
+	return nil
+}
+
+// randomUUID returns a random version 4 UUID
+func randomUUID() string {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], rand.Uint64())
+	binary.LittleEndian.PutUint64(b[8:], rand.Uint64())
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+/*
+Sequence is an ordered list of requests, such as an imported Postman collection.  Every
+virtual user sends the requests one after the other and starts over after the last one;
+the {{name}} references in their url, headers, cookies and body are expanded from
+Variables for every request.
+
+The JSON form is the sequence file written by "goperf import postman".
+*/
+type Sequence struct {
+	Name      string    `json:"name,omitempty"`
+	Variables Variables `json:"variables,omitempty"`
+	Requests  []Target  `json:"requests"`
+}
+
+// LoadSequence reads the sequence file at path
+func LoadSequence(path string) (*Sequence, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sequence file: %w", err)
+	}
+	sequence := &Sequence{}
+	if err = json.Unmarshal(data, sequence); err != nil {
+		return nil, fmt.Errorf("failed to parse sequence file %s: %w", path, err)
+	}
+	if len(sequence.Requests) == 0 {
+		return nil, fmt.Errorf("sequence file %s lists no requests", path)
+	}
+	if sequence.Variables == nil {
+		sequence.Variables = Variables{}
+	}
+	return sequence, nil
+}
+
+// Validate checks every request of the sequence once its variables are expanded
+func (s *Sequence) Validate() error {
+	for i := range s.Requests {
+		request := s.Requests[i].expand(s.Variables)
+		if err := request.Validate(); err != nil {
+			return fmt.Errorf("request %d of the sequence: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// label names the sequence in reports
+func (s *Sequence) label() string {
+	name := s.Name
+	if name == "" {
+		name = "sequence"
+	}
+	return fmt.Sprintf("%s (%d requests in order)", name, len(s.Requests))
+}
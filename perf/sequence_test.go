@@ -0,0 +1,138 @@
+package perf
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+func TestVariablesExpand(t *testing.T) {
+	color.Green("~~ TestVariablesExpand ~~")
+	vars := Variables{"host": "{{scheme}}://a.test", "scheme": "https", "id": "7"}
+	tests := []struct {
+		in, want string
+	}{
+		{"{{host}}/items/{{ id }}", "https://a.test/items/7"},
+		{"{{unknown}}/x", "{{unknown}}/x"},
+		{"no references", "no references"},
+	}
+	for _, tt := range tests {
+		if got := vars.Expand(tt.in); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if got := vars.Expand("{{$guid}}"); !uuid.MatchString(got) {
+		t.Errorf("{{$guid}} = %q, want a version 4 UUID", got)
+	}
+	if a, b := vars.Expand("{{$randomUUID}}"), vars.Expand("{{$randomUUID}}"); a == b {
+		t.Errorf("{{$randomUUID}} repeated %q", a)
+	}
+	if got := vars.Expand("{{$timestamp}}"); !regexp.MustCompile(`^\d{10,}$`).MatchString(got) {
+		t.Errorf("{{$timestamp}} = %q", got)
+	}
+
+	if err := vars.Set("id=8=9"); err != nil || vars["id"] != "8=9" {
+		t.Errorf("Set(id=8=9) = %v, id = %q", err, vars["id"])
+	}
+	if err := vars.Set("=1"); err == nil {
+		t.Error("Set(=1) should fail")
+	}
+}
+
+func TestLoadSequence(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write("seq.json", `{"name": "Shop", "requests": [{"url": "{{base}}/a"}, {"url": "{{base}}/b"}]}`)
+	sequence, err := LoadSequence(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sequence.Variables == nil || len(sequence.Requests) != 2 {
+		t.Fatalf("LoadSequence() = %+v", sequence)
+	}
+	if sequence.label() != "Shop (2 requests in order)" {
+		t.Errorf("label() = %q", sequence.label())
+	}
+	// {{base}} is unknown until it is set
+	if err = sequence.Validate(); err == nil {
+		t.Error("Validate() should fail with an unset base url")
+	}
+	if err = sequence.Variables.Set("base=http://localhost"); err != nil {
+		t.Fatal(err)
+	}
+	if err = sequence.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	for _, content := range []string{`{"requests": []}`, `[{"url": "http://a.test/"}]`} {
+		if _, err = LoadSequence(write("bad.json", content)); err == nil {
+			t.Errorf("LoadSequence(%s) should fail", content)
+		}
+	}
+	if _, err = LoadSequence(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadSequence(missing.json) should fail")
+	}
+}
+
+func TestBasicSequence(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.RequestURI()+" "+string(body)+r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	test := &Init{
+		Threads: 1,
+		Seconds: 1,
+		Sequence: &Sequence{
+			Variables: Variables{"base": server.URL, "user": "ann", "token": "t0k"},
+			Requests: []Target{
+				{URL: "{{base}}/login", Method: http.MethodPost, Body: "user={{user}}"},
+				{URL: "{{base}}/orders?user={{user}}", Headers: map[string]string{"Authorization": "Bearer {{token}}"}},
+				{URL: "{{base}}/logout", Method: http.MethodPost},
+			},
+		},
+	}
+	results := test.Basic()
+
+	want := []string{"POST /login user=ann", "GET /orders?user=ann Bearer t0k", "POST /logout "}
+	mu.Lock()
+	defer mu.Unlock()
+	// The first request opens the session of the user
+	if len(requests) < len(want)+1 || requests[0] != "GET /login " {
+		t.Fatalf("requests = %q", requests)
+	}
+	for i, got := range requests[1:] {
+		if got != want[i%len(want)] {
+			t.Fatalf("request %d = %q, want %q", i+1, got, want[i%len(want)])
+		}
+	}
+	if !strings.HasSuffix(results.BaseURL.URL, "(3 requests in order)") || len(results.Targets) != 3 {
+		t.Errorf("BaseURL.URL = %q with %d targets", results.BaseURL.URL, len(results.Targets))
+	}
+}
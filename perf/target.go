@@ -0,0 +1,265 @@
+package perf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Gosayram/goperf/request"
+)
+
+/*
+Target is one page of a load test.  Every iteration a virtual user picks a target,
+with a probability proportional to its weight, and loads it with all of its assets.
+
+Structure Overview
+  - URL - the page to load
+  - Weight - the relative share of iterations, positive; 1 when left out
+  - Method - the request method of the page, GET when empty
+  - Headers - extra request headers of the page
+  - Cookies - a Cookie header value sent instead of the virtual user's cookies
+  - Body - the request body of the page
+  - GraphQL - a GraphQL operation posted as the body instead; the method defaults to POST
+
+The JSON form matches the targets file written by "goperf crawl -crawlout" and the
+request files written by "goperf import"; fields other than these are ignored.
+*/
+type Target struct {
+	URL     string            `json:"url"`
+	Weight  int               `json:"weight,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies string            `json:"cookies,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	GraphQL *GraphQL          `json:"graphql,omitempty"`
+}
+
+/*
+ParseTarget parses a target given on the command line:
+
+	[weight] [METHOD] url [Name=value ...]
+
+For instance "3 https://example.com/" or "1 POST https://example.com/api Accept=application/json".
+A weight of 0 is rejected rather than read as the default weight 1.
+*/
+func ParseTarget(spec string) (Target, error) {
+	fields := strings.Fields(spec)
+	target := Target{}
+	if len(fields) > 0 {
+		if weight, err := strconv.Atoi(fields[0]); err == nil {
+			if weight == 0 {
+				return Target{}, fmt.Errorf("target %q has weight 0 (leave the weight out for %d)",
+					spec, DefaultTargetWeight)
+			}
+			target.Weight = weight
+			fields = fields[1:]
+		}
+	}
+	if len(fields) > 0 && isMethod(fields[0]) {
+		target.Method = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return Target{}, fmt.Errorf("target %q has no url", spec)
+	}
+	target.URL = fields[0]
+	for _, header := range fields[1:] {
+		name, value, ok := strings.Cut(header, TargetHeaderSeparator)
+		if !ok || name == "" {
+			return Target{}, fmt.Errorf("target %q: header %q is not Name=value", spec, header)
+		}
+		if target.Headers == nil {
+			target.Headers = map[string]string{}
+		}
+		target.Headers[name] = value
+	}
+	return target, target.Validate()
+}
+
+/*
+LoadTargets reads the targets file at path.  It is either a JSON array of targets,
+such as the one "goperf crawl -crawlout" writes, or a text file with one ParseTarget
+spec per line where blank lines and lines starting with # are ignored.
+*/
+func LoadTargets(path string) ([]Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets file: %w", err)
+	}
+
+	var targets []Target
+	if content := strings.TrimSpace(string(data)); strings.HasPrefix(content, JSONArrayPrefix) {
+		if err = json.Unmarshal(data, &targets); err != nil {
+			return nil, fmt.Errorf("failed to parse targets file %s: %w", path, err)
+		}
+		for i := range targets {
+			if err = targets[i].Validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	} else {
+		for n, line := range strings.Split(content, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, TargetCommentPrefix) {
+				continue
+			}
+			target, lineErr := ParseTarget(line)
+			if lineErr != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n+1, lineErr)
+			}
+			targets = append(targets, target)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("targets file %s lists no targets", path)
+	}
+	return targets, nil
+}
+
+// UnmarshalJSON rejects a weight of 0, which only a missing weight stands for
+func (t *Target) UnmarshalJSON(data []byte) error {
+	type plain Target
+	weighted := struct {
+		*plain
+		Weight *int `json:"weight"`
+	}{plain: (*plain)(t)}
+	if err := json.Unmarshal(data, &weighted); err != nil {
+		return err
+	}
+	if weighted.Weight != nil {
+		if *weighted.Weight == 0 {
+			return fmt.Errorf("target %s has weight 0 (leave the weight out for %d)", t.URL, DefaultTargetWeight)
+		}
+		t.Weight = *weighted.Weight
+	}
+	return nil
+}
+
+// Validate checks that the target has an absolute http(s) url, a valid method and no negative weight
+func (t *Target) Validate() error {
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != request.HTTPScheme && u.Scheme != request.HTTPSScheme) || u.Host == "" {
+		return fmt.Errorf("target url must be an absolute http or https url, got %q", t.URL)
+	}
+	if t.Weight < 0 {
+		return fmt.Errorf("target %s has a negative weight", t.URL)
+	}
+	if t.Method != "" && !isMethod(t.Method) {
+		return fmt.Errorf("target %s has an invalid method %q", t.URL, t.Method)
+	}
+	if t.GraphQL != nil {
+		if err := t.GraphQL.Validate(); err != nil {
+			return fmt.Errorf("target %s: %w", t.URL, err)
+		}
+	}
+	return nil
+}
+
+// weight returns the weight of the target, counting an unset weight as 1
+func (t *Target) weight() int {
+	return max(t.Weight, DefaultTargetWeight)
+}
+
+// method returns the request method of the target
+func (t *Target) method() string {
+	switch {
+	case t.Method != "":
+		return t.Method
+	case t.GraphQL != nil:
+		return http.MethodPost
+	default:
+		return http.MethodGet
+	}
+}
+
+// operation returns the GraphQL operation of the target, or "" when it sends none
+func (t *Target) operation() string {
+	if t.GraphQL == nil {
+		return ""
+	}
+	return t.GraphQL.Operation()
+}
+
+// header returns the extra headers and cookies of the target as an http.Header.
+// GraphQL requests are sent as JSON unless the headers set another content type.
+func (t *Target) header() http.Header {
+	if len(t.Headers) == 0 && t.Cookies == "" && t.GraphQL == nil {
+		return nil
+	}
+	header := make(http.Header, len(t.Headers)+2)
+	if t.GraphQL != nil {
+		header.Set(ContentTypeHeader, GraphQLContentType)
+	}
+	for name, value := range t.Headers {
+		header.Set(name, value)
+	}
+	if t.Cookies != "" {
+		header.Set(request.CookieHeader, t.Cookies)
+	}
+	return header
+}
+
+// expand returns a copy of the target with the variable references of its url, headers,
+// cookies and body expanded; the body of a GraphQL target is its JSON request
+func (t *Target) expand(vars Variables) Target {
+	expanded := *t
+	expanded.URL = vars.Expand(t.URL)
+	expanded.Cookies = vars.Expand(t.Cookies)
+	expanded.Body = vars.Expand(t.Body)
+	if t.GraphQL != nil {
+		expanded.Method = t.method()
+		expanded.Body = t.GraphQL.expand(vars).body()
+	}
+	if len(t.Headers) > 0 {
+		expanded.Headers = make(map[string]string, len(t.Headers))
+		for name, value := range t.Headers {
+			expanded.Headers[name] = vars.Expand(value)
+		}
+	}
+	return expanded
+}
+
+// isMethod reports whether s looks like an HTTP method: an upper case token
+func isMethod(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// targetPicker chooses targets in proportion to their weights
+type targetPicker struct {
+	cumulative []int
+}
+
+// newTargetPicker builds a picker over targets
+func newTargetPicker(targets []Target) *targetPicker {
+	picker := &targetPicker{cumulative: make([]int, len(targets))}
+	total := 0
+	for i := range targets {
+		total += targets[i].weight()
+		picker.cumulative[i] = total
+	}
+	return picker
+}
+
+// total returns the sum of the target weights
+func (p *targetPicker) total() int {
+	return p.cumulative[len(p.cumulative)-1]
+}
+
+// pick returns the index of the target that n, a number in [0, total), falls on
+func (p *targetPicker) pick(n int) int {
+	return sort.SearchInts(p.cumulative, n+1)
+}
@@ -0,0 +1,169 @@
+package perf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+func TestParseTarget(t *testing.T) {
+	color.Green("~~ TestParseTarget ~~")
+	tests := []struct {
+		spec string
+		want Target
+	}{
+		{"https://example.com/", Target{URL: "https://example.com/"}},
+		{"3 https://example.com/a", Target{URL: "https://example.com/a", Weight: 3}},
+		{"POST https://example.com/api", Target{URL: "https://example.com/api", Method: "POST"}},
+		{" 2  PUT  https://example.com/api Accept=application/json X-Token=a=b ", Target{
+			URL: "https://example.com/api", Weight: 2, Method: "PUT",
+			Headers: map[string]string{"Accept": "application/json", "X-Token": "a=b"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := ParseTarget(tt.spec)
+		if err != nil {
+			t.Errorf("ParseTarget(%q): %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTarget(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "3", "3 GET", "example.com/", "-1 https://example.com/", "0 https://example.com/",
+		"get https://example.com/", "https://example.com/ Accept"} {
+		if _, err := ParseTarget(spec); err == nil {
+			t.Errorf("ParseTarget(%q) should fail", spec)
+		}
+	}
+}
+
+func TestLoadTargets(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// The targets file written by the crawl sub-command
+	crawled := write("crawl.json", `[
+  {"url": "https://example.com/", "weight": 3, "depth": 0, "source": "start", "status": 200},
+  {"url": "https://example.com/a", "weight": 1, "depth": 1, "source": "link", "status": 200}
+]`)
+	// A request file written by the import sub-command
+	imported := write("requests.json", `[
+  {"url": "https://example.com/login", "method": "POST", "headers": {"Content-Type": "application/json"},
+   "cookies": "a=1", "user_agent": "", "timeout": 0, "return_content": false, "name": "login", "body": "{}"}
+]`)
+	text := write("targets.txt", `# home page
+3 https://example.com/
+
+POST https://example.com/api Accept=application/json
+`)
+
+	tests := []struct {
+		path string
+		want []Target
+	}{
+		{crawled, []Target{{URL: "https://example.com/", Weight: 3}, {URL: "https://example.com/a", Weight: 1}}},
+		{imported, []Target{{URL: "https://example.com/login", Method: "POST",
+			Headers: map[string]string{"Content-Type": "application/json"}, Cookies: "a=1", Body: "{}"}}},
+		{text, []Target{{URL: "https://example.com/", Weight: 3}, {URL: "https://example.com/api", Method: "POST",
+			Headers: map[string]string{"Accept": "application/json"}}}},
+	}
+	for _, tt := range tests {
+		got, err := LoadTargets(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LoadTargets(%s) = %+v, want %+v", filepath.Base(tt.path), got, tt.want)
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(dir, "missing.json"),
+		write("empty.txt", "# nothing\n"),
+		write("bad.json", `[{"url": "https://example.com/", "weight": -2}]`),
+		write("zero.json", `[{"url": "https://example.com/", "weight": 0}]`),
+		write("bad.txt", "https://example.com/\nnot-a-url\n"),
+	} {
+		if _, err := LoadTargets(path); err == nil {
+			t.Errorf("LoadTargets(%s) should fail", filepath.Base(path))
+		}
+	}
+}
+
+func TestTargetPicker(t *testing.T) {
+	picker := newTargetPicker([]Target{{Weight: 2}, {Weight: 0}, {Weight: 3}})
+	if picker.total() != 6 {
+		t.Fatalf("total = %d, want 6", picker.total())
+	}
+	// An unset weight counts as 1
+	want := []int{0, 0, 1, 2, 2, 2}
+	for n, index := range want {
+		if got := picker.pick(n); got != index {
+			t.Errorf("pick(%d) = %d, want %d", n, got, index)
+		}
+	}
+}
+
+func TestBasicTargets(t *testing.T) {
+	var posts, headers atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			posts.Add(1)
+			if r.Header.Get("X-Test") == "yes" {
+				headers.Add(1)
+			}
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	test := &Init{
+		Threads: 2,
+		Seconds: 1,
+		Targets: []Target{
+			{URL: server.URL + "/home", Weight: 4},
+			{URL: server.URL + "/api", Weight: 1, Method: http.MethodPost, Headers: map[string]string{"X-Test": "yes"}},
+		},
+	}
+	results := test.Basic()
+
+	if len(results.Targets) != 2 {
+		t.Fatalf("got %d targets, want 2", len(results.Targets))
+	}
+	home, api := results.Targets[0], results.Targets[1]
+	if home.Method != http.MethodGet || api.Method != http.MethodPost || api.Weight != 1 {
+		t.Errorf("targets = %+v, %+v", home, api)
+	}
+	if home.View.Pages+api.View.Pages != len(results.BaseURL.Status) {
+		t.Errorf("%d + %d target page loads, want %d in total", home.View.Pages, api.View.Pages,
+			len(results.BaseURL.Status))
+	}
+	if int64(api.View.Pages) != posts.Load() || headers.Load() != posts.Load() {
+		t.Errorf("api loaded %d times, server saw %d POSTs, %d with the header", api.View.Pages, posts.Load(),
+			headers.Load())
+	}
+	// Weighted 4:1, the home page gets most of the traffic
+	if home.View.Pages <= api.View.Pages {
+		t.Errorf("home loaded %d times, api %d times", home.View.Pages, api.View.Pages)
+	}
+	if results.BaseURL.URL != "2 targets" {
+		t.Errorf("BaseURL.URL = %q, want 2 targets", results.BaseURL.URL)
+	}
+	if got := buildTargetResults(results.Targets); len(got) != 2 || got[0].Share+got[1].Share < 0.99 {
+		t.Errorf("target results = %+v", got)
+	}
+}
@@ -0,0 +1,313 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gosayram/goperf/request"
+)
+
+// combinedLine matches the Nginx/Apache combined log format, optionally followed by more fields:
+// remote logname user [time] "request" status bytes "referer" "user-agent" ...
+var combinedLine = regexp.MustCompile(
+	`^\S+ \S+ \S+ \[([^\]]+)\] "([^"]*)" (\d{3}) \S+(?: "([^"]*)" "([^"]*)")?(.*)$`)
+
+/*
+LogOptions selects how an access log is read.
+
+Structure Overview
+  - Format - combined, json or auto (detected from the first line)
+  - Target - the scheme and host every request is sent to instead of the logged one
+  - Methods - the request methods to replay; the others are counted as skipped.  Logs hold no
+    bodies, so only GET and HEAD are replayed by default.
+*/
+type LogOptions struct {
+	Format  string
+	Target  string
+	Methods []string
+}
+
+// AccessLog is a parsed access log ready to replay
+type AccessLog struct {
+	Requests []request.ReplayRequest `json:"requests"`
+	Skipped  int                     `json:"skipped"` // lines with a method that is not replayed
+	Invalid  int                     `json:"invalid"` // lines that could not be parsed
+	Span     time.Duration           `json:"span"`    // time between the first and the last request
+}
+
+// logLine is one parsed access log line
+type logLine struct {
+	at        time.Time
+	method    string
+	target    string // path and query, or an absolute url
+	url       string // the url the request is sent to
+	status    int
+	latency   time.Duration
+	userAgent string
+	referer   string
+}
+
+// LoadAccessLog reads the access log at path and converts it with ParseAccessLog
+func LoadAccessLog(path string, opts LogOptions) (*AccessLog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access log: %w", err)
+	}
+	defer file.Close()
+	parsed, err := ParseAccessLog(file, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return parsed, nil
+}
+
+/*
+ParseAccessLog converts an access log into a replayable request sequence ordered by time.
+
+Combined format lines may end with the request latency: a number with a decimal point is
+read as seconds (Nginx $request_time), a whole number as microseconds (Apache %D).  As
+combined timestamps only have second resolution, requests logged in the same second are
+spread evenly across it.
+
+JSON lines are objects with the keys of a typical Nginx/Apache JSON log_format:
+  - time, timestamp, @timestamp or time_iso8601 (RFC 3339), time_local (combined layout) or msec
+    (epoch seconds with milliseconds)
+  - method or request_method, and uri, request_uri, path or url; or request ("GET /path HTTP/1.1")
+  - status, request_time or duration (seconds), http_user_agent or user_agent, http_referer or referer
+
+Every request is sent to opts.Target, keeping the logged path and query.
+*/
+func ParseAccessLog(r io.Reader, opts LogOptions) (*AccessLog, error) {
+	target, err := url.Parse(opts.Target)
+	if err != nil || (target.Scheme != request.HTTPScheme && target.Scheme != request.HTTPSScheme) ||
+		target.Host == "" {
+		return nil, fmt.Errorf("replay target must be an absolute http or https url, got %q", opts.Target)
+	}
+	methods := opts.Methods
+	if len(methods) == 0 {
+		methods = DefaultReplayMethods
+	}
+
+	format := opts.Format
+	if format != "" && format != LogFormatAuto && format != LogFormatCombined && format != LogFormatJSON {
+		return nil, fmt.Errorf("unsupported access log format %q (use combined, json or auto)", format)
+	}
+	parsed := &AccessLog{}
+	var lines []logLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, LogLineBuffer), MaxLogLineSize)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if format == "" || format == LogFormatAuto {
+			format = detectFormat(text)
+		}
+		line, ok := parseLine(format, text)
+		switch {
+		case !ok:
+			parsed.Invalid++
+		case !slices.Contains(methods, line.method):
+			parsed.Skipped++
+		case !line.resolve(target):
+			parsed.Invalid++
+		default:
+			lines = append(lines, line)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read access log: %w", err)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("the access log has no requests to replay (%d skipped, %d invalid)",
+			parsed.Skipped, parsed.Invalid)
+	}
+
+	sort.SliceStable(lines, func(a, b int) bool { return lines[a].at.Before(lines[b].at) })
+	if format == LogFormatCombined {
+		spreadSeconds(lines)
+	}
+	first := lines[0].at
+	for i := range lines {
+		parsed.Requests = append(parsed.Requests, lines[i].replayRequest(first))
+	}
+	parsed.Span = lines[len(lines)-1].at.Sub(first)
+	return parsed, nil
+}
+
+// detectFormat guesses the format of a log from one of its lines
+func detectFormat(text string) string {
+	if strings.HasPrefix(text, "{") {
+		return LogFormatJSON
+	}
+	return LogFormatCombined
+}
+
+// parseLine parses one line of a log in format
+func parseLine(format, text string) (logLine, bool) {
+	switch format {
+	case LogFormatCombined:
+		return parseCombined(text)
+	case LogFormatJSON:
+		return parseJSON(text)
+	default:
+		return logLine{}, false
+	}
+}
+
+// parseCombined parses a combined format line
+func parseCombined(text string) (logLine, bool) {
+	m := combinedLine.FindStringSubmatch(text)
+	if m == nil {
+		return logLine{}, false
+	}
+	at, err := time.Parse(CombinedTimeLayout, m[1])
+	if err != nil {
+		return logLine{}, false
+	}
+	method, target, ok := splitRequest(m[2])
+	if !ok {
+		return logLine{}, false
+	}
+	status, _ := strconv.Atoi(m[3])
+	line := logLine{at: at, method: method, target: target, status: status, referer: m[4], userAgent: m[5]}
+	if fields := strings.Fields(m[6]); len(fields) > 0 {
+		line.latency = parseLatency(fields[len(fields)-1])
+	}
+	return line, true
+}
+
+// parseLatency reads a logged latency: seconds when it has a decimal point, microseconds otherwise
+func parseLatency(field string) time.Duration {
+	if strings.Contains(field, ".") {
+		seconds, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	micros, err := strconv.ParseInt(field, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(micros) * time.Microsecond
+}
+
+// splitRequest splits a request line such as "GET /path HTTP/1.1" into its method and target
+func splitRequest(requestLine string) (method, target string, ok bool) {
+	fields := strings.Fields(requestLine)
+	if len(fields) < 2 || (!strings.HasPrefix(fields[1], "/") && !strings.Contains(fields[1], "://")) {
+		return "", "", false
+	}
+	return strings.ToUpper(fields[0]), fields[1], true
+}
+
+// parseJSON parses a JSON log line
+func parseJSON(text string) (logLine, bool) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(text), &fields); err != nil {
+		return logLine{}, false
+	}
+	str := func(keys ...string) string {
+		for _, key := range keys {
+			if value, ok := fields[key]; ok {
+				switch v := value.(type) {
+				case string:
+					return v
+				case float64:
+					return strconv.FormatFloat(v, 'f', -1, 64)
+				}
+			}
+		}
+		return ""
+	}
+
+	line := logLine{
+		method:    strings.ToUpper(str("method", "request_method")),
+		target:    str("uri", "request_uri", "path", "url"),
+		userAgent: str("http_user_agent", "user_agent"),
+		referer:   str("http_referer", "referer"),
+	}
+	if line.method == "" || line.target == "" {
+		var ok bool
+		if line.method, line.target, ok = splitRequest(str("request")); !ok {
+			return logLine{}, false
+		}
+	}
+	line.status, _ = strconv.Atoi(str("status"))
+	if seconds, err := strconv.ParseFloat(str("request_time", "duration"), 64); err == nil {
+		line.latency = time.Duration(seconds * float64(time.Second))
+	}
+
+	var err error
+	switch {
+	case str("time", "timestamp", "@timestamp", "time_iso8601") != "":
+		line.at, err = time.Parse(time.RFC3339Nano, str("time", "timestamp", "@timestamp", "time_iso8601"))
+	case str("time_local") != "":
+		line.at, err = time.Parse(CombinedTimeLayout, str("time_local"))
+	default:
+		var msec float64
+		if msec, err = strconv.ParseFloat(str("msec"), 64); err == nil {
+			line.at = time.UnixMilli(int64(math.Round(msec * MillisPerSecond)))
+		}
+	}
+	return line, err == nil
+}
+
+// spreadSeconds spreads runs of requests logged in the same second evenly across that second
+func spreadSeconds(lines []logLine) {
+	for start := 0; start < len(lines); {
+		end := start + 1
+		for end < len(lines) && lines[end].at.Equal(lines[start].at) {
+			end++
+		}
+		n := end - start
+		for i := 1; i < n; i++ {
+			lines[start+i].at = lines[start+i].at.Add(time.Duration(i) * time.Second / time.Duration(n))
+		}
+		start = end
+	}
+}
+
+// resolve sets the url of the line to its path and query on target; false if that is no valid url
+func (l *logLine) resolve(target *url.URL) bool {
+	path := l.target
+	if u, err := url.Parse(l.target); err == nil && u.IsAbs() {
+		path = u.RequestURI()
+	}
+	l.url = target.Scheme + "://" + target.Host + path
+	_, err := url.Parse(l.url)
+	return err == nil
+}
+
+// replayRequest converts the line into a request, offset from the first request at first
+func (l *logLine) replayRequest(first time.Time) request.ReplayRequest {
+	header := http.Header{}
+	if l.userAgent != "" && l.userAgent != LogEmptyField {
+		header.Set(request.UserAgentHeader, l.userAgent)
+	}
+	if l.referer != "" && l.referer != LogEmptyField {
+		header.Set(RefererHeader, l.referer)
+	}
+	return request.ReplayRequest{
+		Method: l.method,
+		URL:    l.url,
+		Header: header,
+		Offset: l.at.Sub(first),
+		Status: l.status,
+		Time:   l.latency,
+	}
+}
@@ -0,0 +1,115 @@
+package replay
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+)
+
+// testCombinedLog has two requests in the same second, a POST, a line with Apache's
+// microsecond latency, an absolute request target, a path that is no valid url and a garbled
+// line, out of order.
+const testCombinedLog = `10.0.0.1 - - [01/May/2026:10:00:02 +0000] "GET /about HTTP/1.1" 200 512 "-" "curl/8.0" 0.250
+10.0.0.2 - - [01/May/2026:10:00:00 +0000] "GET /?q=1 HTTP/1.1" 200 1024 "https://ref.test/" "Mozilla/5.0" 0.120
+10.0.0.3 - - [01/May/2026:10:00:00 +0000] "POST /login HTTP/1.1" 302 0 "-" "Mozilla/5.0" 0.030
+10.0.0.4 - - [01/May/2026:10:00:00 +0000] "HEAD /health HTTP/1.1" 200 0 "-" "-" 4500
+10.0.0.5 - - [01/May/2026:10:00:04 +0000] "GET http://prod.example.com/img.png HTTP/1.1" 404 0
+10.0.0.6 - - [01/May/2026:10:00:03 +0000] "GET /a%zz HTTP/1.1" 400 0
+
+not a log line
+`
+
+func TestParseAccessLogCombined(t *testing.T) {
+	color.Green("~~ TestParseAccessLogCombined ~~")
+	parsed, err := ParseAccessLog(strings.NewReader(testCombinedLog),
+		LogOptions{Target: "https://staging.example.com/ignored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Skipped != 1 || parsed.Invalid != 2 || parsed.Span != 4*time.Second {
+		t.Errorf("skipped %d, invalid %d, span %s; want 1, 2, 4s", parsed.Skipped, parsed.Invalid, parsed.Span)
+	}
+
+	want := []struct {
+		method, url string
+		offset      time.Duration
+		status      int
+		latency     time.Duration
+		header      http.Header
+	}{
+		{"GET", "https://staging.example.com/?q=1", 0, 200, 120 * time.Millisecond,
+			http.Header{"User-Agent": {"Mozilla/5.0"}, "Referer": {"https://ref.test/"}}},
+		// The second request of 10:00:00 is spread to the middle of that second
+		{"HEAD", "https://staging.example.com/health", 500 * time.Millisecond, 200, 4500 * time.Microsecond,
+			http.Header{}},
+		{"GET", "https://staging.example.com/about", 2 * time.Second, 200, 250 * time.Millisecond,
+			http.Header{"User-Agent": {"curl/8.0"}}},
+		{"GET", "https://staging.example.com/img.png", 4 * time.Second, 404, 0, http.Header{}},
+	}
+	if len(parsed.Requests) != len(want) {
+		t.Fatalf("got %d requests, want %d: %+v", len(parsed.Requests), len(want), parsed.Requests)
+	}
+	for i, w := range want {
+		got := parsed.Requests[i]
+		if got.Method != w.method || got.URL != w.url || got.Offset != w.offset || got.Status != w.status ||
+			got.Time != w.latency || !reflect.DeepEqual(got.Header, w.header) {
+			t.Errorf("request %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestParseAccessLogJSON(t *testing.T) {
+	log := strings.Join([]string{
+		`{"time_iso8601":"2026-05-01T10:00:00.500Z","request_method":"GET","request_uri":"/b","status":"500",` +
+			`"request_time":"0.200"}`,
+		`{"time":"2026-05-01T10:00:00Z","request":"GET /a?x=1 HTTP/2.0","status":200,"request_time":0.05,` +
+			`"http_user_agent":"bot"}`,
+		`{"msec":1777629601.25,"method":"POST","uri":"/c","status":201}`,
+		`{"time":"yesterday","method":"GET","uri":"/d"}`,
+	}, "\n")
+	parsed, err := ParseAccessLog(strings.NewReader(log), LogOptions{
+		Format:  LogFormatAuto,
+		Target:  "http://localhost:8080",
+		Methods: []string{http.MethodGet, http.MethodPost},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Invalid != 1 || parsed.Skipped != 0 || len(parsed.Requests) != 3 {
+		t.Fatalf("invalid %d, skipped %d, %d requests; want 1, 0, 3", parsed.Invalid, parsed.Skipped,
+			len(parsed.Requests))
+	}
+	a, b, c := parsed.Requests[0], parsed.Requests[1], parsed.Requests[2]
+	if a.URL != "http://localhost:8080/a?x=1" || a.Status != 200 || a.Time != 50*time.Millisecond ||
+		a.Header.Get("User-Agent") != "bot" {
+		t.Errorf("first request = %+v", a)
+	}
+	if b.URL != "http://localhost:8080/b" || b.Offset != 500*time.Millisecond || b.Status != 500 {
+		t.Errorf("second request = %+v", b)
+	}
+	if c.Method != http.MethodPost || c.Offset != 1250*time.Millisecond || c.Status != 201 {
+		t.Errorf("third request = %+v", c)
+	}
+}
+
+func TestParseAccessLogInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		log  string
+		opts LogOptions
+	}{
+		"relative target": {testCombinedLog, LogOptions{Target: "/staging"}},
+		"bad format":      {testCombinedLog, LogOptions{Target: "http://a.test", Format: "w3c"}},
+		"nothing to send": {`1.1.1.1 - - [01/May/2026:10:00:00 +0000] "DELETE /x HTTP/1.1" 204 0`,
+			LogOptions{Target: "http://a.test"}},
+		"json as combined": {`{"method":"GET","uri":"/"}`,
+			LogOptions{Target: "http://a.test", Format: LogFormatCombined}},
+	} {
+		if _, err := ParseAccessLog(strings.NewReader(tc.log), tc.opts); err == nil {
+			t.Errorf("%s: ParseAccessLog should fail", name)
+		}
+	}
+}
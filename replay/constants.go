@@ -0,0 +1,63 @@
+// Package replay turns recorded traffic into request sequences that the load executor
+// can replay with their original methods, headers, bodies, order and timing.
+package replay
+
+import (
+	"net/http"
+
+	"github.com/Gosayram/goperf/request"
+)
+
+const (
+	// WWWPrefix is ignored when deciding whether two hosts belong to the same site
+	WWWPrefix = "www."
+	// PseudoHeaderPrefix starts the HTTP/2 pseudo-headers (:method, :path...) found in HAR files
+	PseudoHeaderPrefix = ":"
+	// CookieHeader specifies the request header carrying cookies
+	CookieHeader = "Cookie"
+	// RefererHeader specifies the request header carrying the referring page
+	RefererHeader = "Referer"
+
+	// LogFormatAuto detects the access log format from its first line
+	LogFormatAuto = "auto"
+	// LogFormatCombined selects the Nginx/Apache combined log format
+	LogFormatCombined = "combined"
+	// LogFormatJSON selects JSON access logs, one object per line
+	LogFormatJSON = "json"
+	// CombinedTimeLayout specifies the layout of combined log timestamps
+	CombinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+	// LogEmptyField is how access logs write a missing value
+	LogEmptyField = "-"
+	// LogLineBuffer specifies the initial buffer size for reading access log lines
+	LogLineBuffer = 64 * 1024
+	// MaxLogLineSize specifies the longest access log line that can be read
+	MaxLogLineSize = 1024 * 1024
+	// MillisPerSecond converts the epoch seconds of a msec field to milliseconds
+	MillisPerSecond = 1000
+
+	// StatusChangeSeparator joins the recorded and replayed status in a report, e.g. "200 -> 500"
+	StatusChangeSeparator = " -> "
+	// MaxReportPaths specifies how many of the busiest paths a replay report lists
+	MaxReportPaths = 20
+)
+
+// DefaultReplayMethods are the methods replayed from an access log when none are configured.
+// Logs hold no request bodies, so only requests without one are safe to replay.
+var DefaultReplayMethods = []string{http.MethodGet, http.MethodHead}
+
+// hopHeaders are headers the transport manages itself, so they are never replayed
+var hopHeaders = []string{
+	"Host", "Content-Length", "Connection", "Keep-Alive", "Proxy-Connection",
+	"Transfer-Encoding", "Te", "Trailer", "Upgrade",
+}
+
+// harResourceTypes maps the Chrome HAR resource types of page assets to asset types.
+// Any other resource type (xhr, fetch, manifest, ping...) is reported as other.
+var harResourceTypes = map[string]string{
+	"document":   request.AssetTypeDocument,
+	"stylesheet": request.AssetTypeCSS,
+	"script":     request.AssetTypeJS,
+	"image":      request.AssetTypeIMG,
+	"font":       request.AssetTypeFont,
+	"media":      request.AssetTypeMedia,
+}
@@ -0,0 +1,213 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Gosayram/goperf/request"
+)
+
+// harFile is the part of a HAR 1.2 file a replay needs
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+// harEntry is one recorded request and its response
+type harEntry struct {
+	StartedDateTime string  `json:"startedDateTime"`
+	Time            float64 `json:"time"` // milliseconds
+	ResourceType    string  `json:"_resourceType"`
+	Request         struct {
+		Method   string      `json:"method"`
+		URL      string      `json:"url"`
+		Headers  []harHeader `json:"headers"`
+		PostData *struct {
+			MimeType string      `json:"mimeType"`
+			Text     string      `json:"text"`
+			Params   []harHeader `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// harHeader is a HAR name/value pair: a header or a form parameter
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+/*
+HAROptions selects what is replayed from a HAR file.
+
+Structure Overview
+  - StripThirdParty - drop requests to hosts outside the site of the first request
+  - StripCookies - drop the recorded Cookie headers, so the replay runs with the virtual user's own cookies
+*/
+type HAROptions struct {
+	StripThirdParty bool
+	StripCookies    bool
+}
+
+// LoadHAR reads the HAR file at path and converts it with ParseHAR
+func LoadHAR(path string, opts HAROptions) ([]request.ReplayRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HAR file: %w", err)
+	}
+	requests, err := ParseHAR(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return requests, nil
+}
+
+/*
+ParseHAR converts the entries of a HAR file into a replayable request sequence ordered
+by start time.  Each request keeps its method, headers (except the ones the transport
+manages), body and offset from the first request; its asset type comes from the
+browser's resource type or the response media type.  Entries that are not http or
+https requests, such as data: urls, are skipped.
+*/
+func ParseHAR(data []byte, opts HAROptions) ([]request.ReplayRequest, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid HAR: %w", err)
+	}
+
+	entries := make([]harEntry, 0, len(har.Log.Entries))
+	started := make([]time.Time, 0, len(har.Log.Entries))
+	for _, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || (u.Scheme != request.HTTPScheme && u.Scheme != request.HTTPSScheme) {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339Nano, entry.StartedDateTime)
+		if err != nil {
+			return nil, fmt.Errorf("entry %s has an invalid startedDateTime %q", entry.Request.URL,
+				entry.StartedDateTime)
+		}
+		started = append(started, at)
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("the HAR has no http requests to replay")
+	}
+
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return started[order[a]].Before(started[order[b]]) })
+
+	first := started[order[0]]
+	site := siteOf(entries[order[0]].Request.URL)
+	requests := []request.ReplayRequest{}
+	for _, i := range order {
+		entry := &entries[i]
+		if opts.StripThirdParty && !sameSite(siteOf(entry.Request.URL), site) {
+			continue
+		}
+		requests = append(requests, request.ReplayRequest{
+			Method: strings.ToUpper(entry.Request.Method),
+			URL:    entry.Request.URL,
+			Header: entry.header(opts.StripCookies),
+			Body:   entry.body(),
+			Offset: started[i].Sub(first),
+			Type:   entry.assetType(),
+			Status: entry.Response.Status,
+			Time:   time.Duration(entry.Time * float64(time.Millisecond)),
+		})
+	}
+	return requests, nil
+}
+
+// header returns the replayable request headers of the entry
+func (e *harEntry) header(stripCookies bool) http.Header {
+	header := http.Header{}
+	for _, h := range e.Request.Headers {
+		if strings.HasPrefix(h.Name, PseudoHeaderPrefix) {
+			continue
+		}
+		header.Add(h.Name, h.Value)
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+	if stripCookies {
+		header.Del(CookieHeader)
+	}
+	return header
+}
+
+// body returns the request body of the entry, url-encoding form parameters recorded without text
+func (e *harEntry) body() string {
+	post := e.Request.PostData
+	if post == nil {
+		return ""
+	}
+	if post.Text != "" || len(post.Params) == 0 {
+		return post.Text
+	}
+	form := url.Values{}
+	for _, p := range post.Params {
+		form.Add(p.Name, p.Value)
+	}
+	return form.Encode()
+}
+
+// assetType maps the entry's resource type, or its response media type, to an asset type
+func (e *harEntry) assetType() string {
+	if e.ResourceType != "" {
+		if kind, ok := harResourceTypes[e.ResourceType]; ok {
+			return kind
+		}
+		return request.AssetTypeOther
+	}
+
+	mediaType := strings.ToLower(e.Response.Content.MimeType)
+	switch {
+	case strings.Contains(mediaType, "html"):
+		return request.AssetTypeDocument
+	case strings.Contains(mediaType, "css"):
+		return request.AssetTypeCSS
+	case strings.Contains(mediaType, "javascript") || strings.Contains(mediaType, "ecmascript"):
+		return request.AssetTypeJS
+	case strings.Contains(mediaType, "icon"):
+		return request.AssetTypeIcon
+	case strings.HasPrefix(mediaType, "image/"):
+		return request.AssetTypeIMG
+	case strings.Contains(mediaType, "font"):
+		return request.AssetTypeFont
+	case strings.HasPrefix(mediaType, "video/") || strings.HasPrefix(mediaType, "audio/"):
+		return request.AssetTypeMedia
+	default:
+		return request.AssetTypeOther
+	}
+}
+
+// siteOf returns the host name of rawURL without a leading www.
+func siteOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), WWWPrefix)
+}
+
+// sameSite reports whether host is site or one of its subdomains
+func sameSite(host, site string) bool {
+	return host == site || strings.HasSuffix(host, "."+site)
+}
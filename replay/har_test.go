@@ -0,0 +1,111 @@
+package replay
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/request"
+)
+
+// testHAR is a page load recorded out of order: the document, its stylesheet, a
+// third-party script, an XHR posting JSON, a form post, a data: image and a CDN image.
+const testHAR = `{"log": {"version": "1.2", "entries": [
+{"startedDateTime": "2026-05-01T10:00:00.250Z", "time": 40.5, "_resourceType": "stylesheet",
+ "request": {"method": "GET", "url": "https://www.example.com/site.css",
+  "headers": [{"name": ":authority", "value": "www.example.com"}, {"name": "accept", "value": "text/css"},
+   {"name": "cookie", "value": "session=abc"}]},
+ "response": {"status": 200, "content": {"mimeType": "text/css"}}},
+{"startedDateTime": "2026-05-01T10:00:00.000Z", "time": 120, "_resourceType": "document",
+ "request": {"method": "GET", "url": "https://www.example.com/",
+  "headers": [{"name": "Host", "value": "www.example.com"}, {"name": "Cookie", "value": "session=abc"}]},
+ "response": {"status": 200, "content": {"mimeType": "text/html"}}},
+{"startedDateTime": "2026-05-01T10:00:00.300Z", "time": 80,
+ "request": {"method": "GET", "url": "https://www.google-analytics.com/analytics.js", "headers": []},
+ "response": {"status": 200, "content": {"mimeType": "application/javascript"}}},
+{"startedDateTime": "2026-05-01T10:00:01.000Z", "time": 30, "_resourceType": "fetch",
+ "request": {"method": "post", "url": "https://api.example.com/cart",
+  "headers": [{"name": "Content-Type", "value": "application/json"}, {"name": "Content-Length", "value": "11"}],
+  "postData": {"mimeType": "application/json", "text": "{\"item\":42}"}},
+ "response": {"status": 201, "content": {"mimeType": "application/json"}}},
+{"startedDateTime": "2026-05-01T10:00:01.500Z", "time": 25,
+ "request": {"method": "POST", "url": "https://www.example.com/login", "headers": [],
+  "postData": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "user", "value": "a b"}]}},
+ "response": {"status": 302, "content": {"mimeType": "text/html"}}},
+{"startedDateTime": "2026-05-01T10:00:00.400Z", "time": 0,
+ "request": {"method": "GET", "url": "data:image/png;base64,AAAA", "headers": []},
+ "response": {"status": 200, "content": {"mimeType": "image/png"}}},
+{"startedDateTime": "2026-05-01T10:00:00.400Z", "time": 10,
+ "request": {"method": "GET", "url": "https://cdn.example.com/logo.svg", "headers": []},
+ "response": {"status": 200, "content": {"mimeType": "image/svg+xml"}}}
+]}}`
+
+func TestParseHAR(t *testing.T) {
+	color.Green("~~ TestParseHAR ~~")
+	requests, err := ParseHAR([]byte(testHAR), HAROptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []request.ReplayRequest{
+		{Method: "GET", URL: "https://www.example.com/", Header: http.Header{"Cookie": {"session=abc"}},
+			Type: request.AssetTypeDocument, Status: 200, Time: 120 * time.Millisecond},
+		{Method: "GET", URL: "https://www.example.com/site.css", Offset: 250 * time.Millisecond,
+			Header: http.Header{"Accept": {"text/css"}, "Cookie": {"session=abc"}}, Type: request.AssetTypeCSS,
+			Status: 200, Time: 40500 * time.Microsecond},
+		{Method: "GET", URL: "https://www.google-analytics.com/analytics.js", Offset: 300 * time.Millisecond,
+			Header: http.Header{}, Type: request.AssetTypeJS, Status: 200, Time: 80 * time.Millisecond},
+		{Method: "GET", URL: "https://cdn.example.com/logo.svg", Offset: 400 * time.Millisecond,
+			Header: http.Header{}, Type: request.AssetTypeIMG, Status: 200, Time: 10 * time.Millisecond},
+		{Method: "POST", URL: "https://api.example.com/cart", Offset: time.Second,
+			Header: http.Header{"Content-Type": {"application/json"}}, Body: `{"item":42}`,
+			Type: request.AssetTypeOther, Status: 201, Time: 30 * time.Millisecond},
+		{Method: "POST", URL: "https://www.example.com/login", Offset: 1500 * time.Millisecond,
+			Header: http.Header{}, Body: "user=a+b", Type: request.AssetTypeDocument, Status: 302,
+			Time: 25 * time.Millisecond},
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got %d requests, want %d: %+v", len(requests), len(want), requests)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(requests[i], want[i]) {
+			t.Errorf("request %d = %+v, want %+v", i, requests[i], want[i])
+		}
+	}
+}
+
+func TestParseHAROptions(t *testing.T) {
+	requests, err := ParseHAR([]byte(testHAR), HAROptions{StripThirdParty: true, StripCookies: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{}
+	for _, req := range requests {
+		urls = append(urls, req.URL)
+		if req.Header.Get(CookieHeader) != "" {
+			t.Errorf("%s kept its Cookie header", req.URL)
+		}
+	}
+	// Subdomains of the page's site are first-party
+	want := []string{"https://www.example.com/", "https://www.example.com/site.css",
+		"https://cdn.example.com/logo.svg", "https://api.example.com/cart", "https://www.example.com/login"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("replayed %v, want %v", urls, want)
+	}
+}
+
+func TestParseHARInvalid(t *testing.T) {
+	for name, har := range map[string]string{
+		"not json":    `{"log": [`,
+		"no entries":  `{"log": {"entries": []}}`,
+		"only data":   `{"log": {"entries": [{"startedDateTime": "2026-05-01T10:00:00Z", "request": {"url": "data:,x"}}]}}`,
+		"bad started": `{"log": {"entries": [{"startedDateTime": "yesterday", "request": {"url": "https://a.test/"}}]}}`,
+	} {
+		if _, err := ParseHAR([]byte(har), HAROptions{}); err == nil {
+			t.Errorf("%s: ParseHAR should fail", name)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/metrics"
+	"github.com/Gosayram/goperf/request"
+)
+
+/*
+RunOptions configures a timeline replay.
+
+Structure Overview
+  - Speed - 1 sends every request at its recorded offset, N compresses the timeline N times,
+    0 sends the requests in order as fast as Workers allow
+  - Workers - concurrent requests at speed 0; a timed replay sends each request on time however many are in flight
+  - Input - the client, user agent and cookies of every request
+*/
+type RunOptions struct {
+	Speed   float64
+	Workers int
+	Input   request.FetchInput
+}
+
+// Outcome is one replayed request next to its recording
+type Outcome struct {
+	Method         string        `json:"method"`
+	URL            string        `json:"url"`
+	RecordedStatus int           `json:"recordedStatus"`
+	Status         int           `json:"status"`
+	RecordedTime   time.Duration `json:"recordedTime"`
+	Time           time.Duration `json:"time"`
+	Lag            time.Duration `json:"lag"` // how late the request was sent compared to its schedule
+	Error          string        `json:"error,omitempty"`
+}
+
+/*
+Run replays requests on their recorded timeline and returns the outcome of every request
+that was sent before ctx was cancelled, in the recorded order.
+*/
+func Run(ctx context.Context, requests []request.ReplayRequest, opts RunOptions) []Outcome {
+	outcomes := make([]Outcome, len(requests))
+	sent := make([]bool, len(requests))
+	start := time.Now()
+	send := func(i int, scheduled time.Time) {
+		in := opts.Input
+		in.BaseURL = requests[i].URL
+		in.Method = requests[i].Method
+		in.Header = requests[i].Header
+		in.Body = requests[i].Body
+		lag := max(time.Since(scheduled), 0)
+		resp := request.Fetch(in)
+		outcomes[i] = Outcome{
+			Method:         requests[i].Method,
+			URL:            requests[i].URL,
+			RecordedStatus: requests[i].Status,
+			Status:         resp.Status,
+			RecordedTime:   requests[i].Time,
+			Time:           resp.Time,
+			Lag:            lag,
+			Error:          resp.Error,
+		}
+	}
+
+	var wg sync.WaitGroup
+	if opts.Speed > 0 {
+		for i := range requests {
+			scheduled := start.Add(time.Duration(float64(requests[i].Offset) / opts.Speed))
+			timer := time.NewTimer(time.Until(scheduled))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			sent[i] = true
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				send(i, scheduled)
+			}(i)
+		}
+	} else {
+		jobs := make(chan int)
+		for range max(opts.Workers, 1) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					send(i, time.Now())
+				}
+			}()
+		}
+		for i := range requests {
+			if ctx.Err() != nil {
+				break
+			}
+			sent[i] = true
+			jobs <- i
+		}
+		close(jobs)
+	}
+	wg.Wait()
+
+	done := make([]Outcome, 0, len(outcomes))
+	for i := range outcomes {
+		if sent[i] {
+			done = append(done, outcomes[i])
+		}
+	}
+	return done
+}
+
+// PathReport compares the recorded and replayed requests of one method and path
+type PathReport struct {
+	Method        string        `json:"method"`
+	Path          string        `json:"path"`
+	Requests      int           `json:"requests"`
+	StatusChanges int           `json:"statusChanges"`
+	Errors        int           `json:"errors"`
+	RecordedAvg   time.Duration `json:"recordedAvg"` // over the requests that recorded a latency
+	ReplayedAvg   time.Duration `json:"replayedAvg"` // over the requests that got a response
+
+	recorded, replayed           int
+	recordedTotal, replayedTotal time.Duration
+}
+
+/*
+Report compares a replay with its recording.
+
+Structure Overview
+  - Requests, Duration - what was replayed and how long it took, next to the recorded Span
+  - StatusMatches, StatusChanges - replayed statuses equal to the recorded one, and counts of
+    the changes such as "200 -> 500"
+  - Errors - replayed requests that got no response, such as connection errors; they are
+    left out of the status and latency comparisons
+  - Recorded, Replayed - latencies of the requests that recorded one and got a response
+  - Deviation - replayed minus recorded latency per request; positive means slower than production
+  - AvgLag, MaxLag - how late requests were sent, showing whether the replayer kept up with the timeline
+  - Paths - the busiest method and path pairs
+*/
+type Report struct {
+	Requests      int             `json:"requests"`
+	Span          time.Duration   `json:"span"`
+	Duration      time.Duration   `json:"duration"`
+	Skipped       int             `json:"skipped"`
+	Invalid       int             `json:"invalid"`
+	StatusMatches int             `json:"statusMatches"`
+	StatusChanges map[string]int  `json:"statusChanges"`
+	Errors        int             `json:"errors"`
+	Recorded      metrics.Latency `json:"recorded"`
+	Replayed      metrics.Latency `json:"replayed"`
+	Deviation     metrics.Latency `json:"deviation"`
+	AvgLag        time.Duration   `json:"avgLag"`
+	MaxLag        time.Duration   `json:"maxLag"`
+	Paths         []PathReport    `json:"paths"`
+}
+
+// NewReport compares the outcomes of a replay that took duration with the recording in log
+func NewReport(outcomes []Outcome, log *AccessLog, duration time.Duration) *Report {
+	report := &Report{
+		Requests:      len(outcomes),
+		Span:          log.Span,
+		Duration:      duration,
+		Skipped:       log.Skipped,
+		Invalid:       log.Invalid,
+		StatusChanges: map[string]int{},
+	}
+	var recorded, replayed, deviation []time.Duration
+	var totalLag time.Duration
+	paths := map[string]*PathReport{}
+	for i := range outcomes {
+		o := &outcomes[i]
+		totalLag += o.Lag
+		report.MaxLag = max(report.MaxLag, o.Lag)
+
+		key := o.Method + " " + pathOf(o.URL)
+		path, ok := paths[key]
+		if !ok {
+			path = &PathReport{Method: o.Method, Path: pathOf(o.URL)}
+			paths[key] = path
+		}
+		path.Requests++
+		if o.RecordedTime > 0 {
+			path.recorded++
+			path.recordedTotal += o.RecordedTime
+		}
+
+		// A request that got no response has neither a status nor a latency to compare
+		if o.Error != "" {
+			report.Errors++
+			path.Errors++
+			continue
+		}
+		if o.Status == o.RecordedStatus {
+			report.StatusMatches++
+		} else {
+			report.StatusChanges[strconv.Itoa(o.RecordedStatus)+StatusChangeSeparator+strconv.Itoa(o.Status)]++
+			path.StatusChanges++
+		}
+		if o.RecordedTime > 0 {
+			recorded = append(recorded, o.RecordedTime)
+			replayed = append(replayed, o.Time)
+			deviation = append(deviation, o.Time-o.RecordedTime)
+		}
+		path.replayed++
+		path.replayedTotal += o.Time
+	}
+	if len(outcomes) > 0 {
+		report.AvgLag = totalLag / time.Duration(len(outcomes))
+	}
+	report.Recorded = metrics.Summarize(recorded)
+	report.Replayed = metrics.Summarize(replayed)
+	report.Deviation = metrics.Summarize(deviation)
+
+	for _, path := range paths {
+		if path.recorded > 0 {
+			path.RecordedAvg = path.recordedTotal / time.Duration(path.recorded)
+		}
+		if path.replayed > 0 {
+			path.ReplayedAvg = path.replayedTotal / time.Duration(path.replayed)
+		}
+		report.Paths = append(report.Paths, *path)
+	}
+	sort.Slice(report.Paths, func(a, b int) bool {
+		if report.Paths[a].Requests != report.Paths[b].Requests {
+			return report.Paths[a].Requests > report.Paths[b].Requests
+		}
+		return report.Paths[a].Method+report.Paths[a].Path < report.Paths[b].Method+report.Paths[b].Path
+	})
+	report.Paths = report.Paths[:min(len(report.Paths), MaxReportPaths)]
+	return report
+}
+
+// pathOf returns the path of rawURL without its query
+func pathOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
+
+// PrintReport prints a replay report to stdout
+func PrintReport(r *Report) {
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	yellow := color.New(color.FgHiYellow, color.Underline).SprintfFunc()
+	grey := color.New(color.FgHiBlack).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("Replay Summary")
+	fmt.Printf(" - %-34s %s\n", yel("Requests"), white("%d (%d skipped, %d invalid lines)", r.Requests,
+		r.Skipped, r.Invalid))
+	fmt.Printf(" - %-34s %s\n", yel("Recorded Span / Replay Time"), white("%s / %s", r.Span, r.Duration))
+	fmt.Printf(" - %-34s %s\n", yel("Schedule Lag (avg / max)"), white("%s / %s", r.AvgLag, r.MaxLag))
+	fmt.Printf(" - %-34s %s\n", yel("Status Matches"), white("%d of %d", r.StatusMatches, r.Requests-r.Errors))
+	fmt.Printf(" - %-34s %s\n", yel("Errors (no response)"), white("%d", r.Errors))
+	changes := make([]string, 0, len(r.StatusChanges))
+	for change := range r.StatusChanges {
+		changes = append(changes, change)
+	}
+	sort.Strings(changes)
+	for _, change := range changes {
+		fmt.Printf("     %s\n", grey("%s: %d", change, r.StatusChanges[change]))
+	}
+
+	color.Red("Latency (recorded vs replayed)")
+	fmt.Printf(" - %-20s %-22s %-22s %-22s %-22s %s\n", yellow(""), yellow("Avg"), yellow("P50"), yellow("P95"),
+		yellow("P99"), yellow("Max"))
+	for i, row := range []struct {
+		name  string
+		stats metrics.Latency
+	}{{"Recorded", r.Recorded}, {"Replayed", r.Replayed}, {"Deviation", r.Deviation}} {
+		paint := white
+		if i%2 == 0 {
+			paint = grey
+		}
+		fmt.Printf(" - %-18s %-20s %-20s %-20s %-20s %s\n", paint(row.name), paint(row.stats.Avg.String()),
+			paint(row.stats.P50.String()), paint(row.stats.P95.String()), paint(row.stats.P99.String()),
+			paint(row.stats.Max.String()))
+	}
+
+	color.Red("Busiest Paths")
+	fmt.Printf(" - %-20s %-26s %-18s %-26s %-26s %s\n", yellow("Requests"), yellow("Status Changes"),
+		yellow("Errors"), yellow("Recorded Avg"), yellow("Replayed Avg"), yellow("Path"))
+	for i, path := range r.Paths {
+		paint := white
+		if i%2 == 0 {
+			paint = grey
+		}
+		fmt.Printf(" - %-18s %-24s %-16s %-24s %-24s %s\n", paint(strconv.Itoa(path.Requests)),
+			paint(strconv.Itoa(path.StatusChanges)), paint(strconv.Itoa(path.Errors)), paint(path.RecordedAvg.String()),
+			paint(path.ReplayedAvg.String()), paint(path.Method+" "+path.Path))
+	}
+}
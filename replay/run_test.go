@@ -0,0 +1,123 @@
+package replay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/request"
+)
+
+func TestRunReport(t *testing.T) {
+	color.Green("~~ TestRunReport ~~")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	log := strings.Join([]string{
+		`1.1.1.1 - - [01/May/2026:10:00:00 +0000] "GET / HTTP/1.1" 200 10 "-" "-" 0.001`,
+		`1.1.1.1 - - [01/May/2026:10:00:00 +0000] "GET /broken HTTP/1.1" 200 10 "-" "-" 0.001`,
+		`1.1.1.1 - - [01/May/2026:10:00:01 +0000] "GET /?page=2 HTTP/1.1" 200 10 "-" "-" 0.001`,
+		`1.1.1.1 - - [01/May/2026:10:00:02 +0000] "GET /gone HTTP/1.1" 404 10 "-" "-"`,
+	}, "\n")
+	parsed, err := ParseAccessLog(strings.NewReader(log), LogOptions{Target: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		speed float64
+		min   time.Duration
+	}{{10, 180 * time.Millisecond}, {0, 0}} {
+		start := time.Now()
+		outcomes := Run(context.Background(), parsed.Requests, RunOptions{
+			Speed:   tc.speed,
+			Workers: 2,
+			Input:   request.FetchInput{Client: server.Client()},
+		})
+		elapsed := time.Since(start)
+		// 2s recorded: 200ms at 10x, as fast as possible at 0
+		if elapsed < tc.min || elapsed > time.Second {
+			t.Errorf("speed %g took %s", tc.speed, elapsed)
+		}
+
+		report := NewReport(outcomes, parsed, elapsed)
+		if report.Requests != 4 || report.StatusMatches != 2 {
+			t.Errorf("speed %g: %d requests, %d status matches; want 4, 2", tc.speed, report.Requests,
+				report.StatusMatches)
+		}
+		changes := map[string]int{"200 -> 500": 1, "404 -> 200": 1}
+		for change, n := range changes {
+			if report.StatusChanges[change] != n {
+				t.Errorf("speed %g: status changes %v, want %v", tc.speed, report.StatusChanges, changes)
+			}
+		}
+		if deviation := report.Deviation.Avg - (report.Replayed.Avg - time.Millisecond); report.Recorded.Count != 3 ||
+			report.Recorded.Max != time.Millisecond || deviation < -time.Microsecond || deviation > time.Microsecond {
+			t.Errorf("speed %g: recorded %+v, replayed %+v, deviation %+v", tc.speed, report.Recorded,
+				report.Replayed, report.Deviation)
+		}
+		// The query is dropped, so both home page requests share a path
+		if top := report.Paths[0]; top.Path != "/" || top.Requests != 2 || len(report.Paths) != 3 {
+			t.Errorf("speed %g: paths %+v", tc.speed, report.Paths)
+		}
+	}
+}
+
+func TestRunReportErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+	// Nothing listens on the port of a closed server
+	closed := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	closed.Close()
+
+	requests := []request.ReplayRequest{
+		{Method: http.MethodGet, URL: server.URL + "/api", Status: http.StatusOK, Time: time.Millisecond},
+		{Method: http.MethodGet, URL: closed.URL + "/api", Status: http.StatusOK, Time: time.Millisecond},
+		{Method: http.MethodGet, URL: closed.URL + "/down", Status: http.StatusOK, Time: time.Millisecond},
+	}
+	outcomes := Run(context.Background(), requests, RunOptions{Workers: 1})
+	report := NewReport(outcomes, &AccessLog{Requests: requests}, time.Second)
+
+	// Connection errors are counted apart instead of passing for instant responses
+	if report.Requests != 3 || report.Errors != 2 || report.StatusMatches != 1 || len(report.StatusChanges) != 0 {
+		t.Errorf("%d requests, %d errors, %d matches, changes %v", report.Requests, report.Errors,
+			report.StatusMatches, report.StatusChanges)
+	}
+	if report.Recorded.Count != 1 || report.Replayed.Count != 1 || report.Deviation.Count != 1 ||
+		report.Replayed.Avg < 5*time.Millisecond || report.Deviation.Avg <= 0 {
+		t.Errorf("recorded %+v, replayed %+v, deviation %+v", report.Recorded, report.Replayed, report.Deviation)
+	}
+	for _, path := range report.Paths {
+		if path.Path == "/api" && (path.Requests != 2 || path.Errors != 1 || path.ReplayedAvg < 5*time.Millisecond) ||
+			path.Path == "/down" && (path.Errors != 1 || path.ReplayedAvg != 0 || path.RecordedAvg != time.Millisecond) {
+			t.Errorf("path %+v", path)
+		}
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	requests := []request.ReplayRequest{
+		{Method: http.MethodGet, URL: server.URL + "/now"},
+		{Method: http.MethodGet, URL: server.URL + "/later", Offset: time.Hour},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	outcomes := Run(ctx, requests, RunOptions{Speed: 1, Input: request.FetchInput{Client: server.Client()}})
+	if len(outcomes) != 1 || outcomes[0].URL != server.URL+"/now" || outcomes[0].Status != http.StatusOK {
+		t.Errorf("outcomes = %+v, want only the first request", outcomes)
+	}
+}
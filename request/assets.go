@@ -0,0 +1,98 @@
+package request
+
+import (
+	"github.com/Gosayram/goperf/interfaces"
+)
+
+// AssetTypes lists every asset type in report order
+var AssetTypes = []string{
+	AssetTypeJS, AssetTypeCSS, AssetTypeIMG, AssetTypeFont,
+	AssetTypeMedia, AssetTypeIcon, AssetTypeDocument, AssetTypeOther,
+}
+
+// resolveOrder is the order in which a browser's preload scanner discovers asset types.
+// It decides which type a URL referenced as two types is fetched as.
+var resolveOrder = []string{
+	AssetTypeCSS, AssetTypeJS, AssetTypeFont, AssetTypeIMG,
+	AssetTypeIcon, AssetTypeMedia, AssetTypeDocument, AssetTypeOther,
+}
+
+// AssetTypeTitle returns the display name of an asset type, e.g. "JS" or "Font"
+func AssetTypeTitle(kind string) string {
+	switch kind {
+	case AssetTypeJS:
+		return "JS"
+	case AssetTypeCSS:
+		return "CSS"
+	case AssetTypeIMG:
+		return "IMG"
+	case AssetTypeFont:
+		return "Font"
+	case AssetTypeMedia:
+		return "Media"
+	case AssetTypeIcon:
+		return "Icon"
+	case AssetTypeDocument:
+		return "Document"
+	default:
+		return "Other"
+	}
+}
+
+// assetsByType maps the categories of assets to asset types
+func assetsByType(assets *interfaces.Assets) map[string][]string {
+	return map[string][]string{
+		AssetTypeJS:       assets.JavaScript,
+		AssetTypeCSS:      assets.CSS,
+		AssetTypeIMG:      assets.Images,
+		AssetTypeFont:     assets.Fonts,
+		AssetTypeMedia:    assets.Media,
+		AssetTypeIcon:     assets.Icons,
+		AssetTypeDocument: assets.Documents,
+		AssetTypeOther:    assets.Other,
+	}
+}
+
+// Responses returns the asset responses of type kind
+func (r *FetchAllResponse) Responses(kind string) *[]FetchResponse {
+	switch kind {
+	case AssetTypeJS:
+		return &r.JSResponses
+	case AssetTypeCSS:
+		return &r.CSSResponses
+	case AssetTypeIMG:
+		return &r.IMGResponses
+	case AssetTypeFont:
+		return &r.FontResponses
+	case AssetTypeMedia:
+		return &r.MediaResponses
+	case AssetTypeIcon:
+		return &r.IconResponses
+	case AssetTypeDocument:
+		return &r.DocumentResponses
+	default:
+		return &r.OtherResponses
+	}
+}
+
+// Resps returns the aggregated asset results of type kind
+func (r *IterateReqRespAll) Resps(kind string) *[]IterateReqResp {
+	switch kind {
+	case AssetTypeJS:
+		return &r.JSResps
+	case AssetTypeCSS:
+		return &r.CSSResps
+	case AssetTypeIMG:
+		return &r.IMGResps
+	case AssetTypeFont:
+		return &r.FontResps
+	case AssetTypeMedia:
+		return &r.MediaResps
+	case AssetTypeIcon:
+		return &r.IconResps
+	case AssetTypeDocument:
+		return &r.DocumentResps
+	default:
+		return &r.OtherResps
+	}
+}
@@ -0,0 +1,109 @@
+package request
+
+import (
+	"net/url"
+	"sync"
+)
+
+/*
+BrowserOptions enables browser emulation in FetchAll.
+
+Structure Overview
+  - MaxConnsPerOrigin - concurrent asset fetches allowed per origin over HTTP/1.x (browsers use 6)
+  - MaxStreamsPerOrigin - concurrent asset fetches allowed per origin once it is known to speak HTTP/2
+*/
+type BrowserOptions struct {
+	MaxConnsPerOrigin   int `json:"max_conns_per_origin"`
+	MaxStreamsPerOrigin int `json:"max_streams_per_origin"`
+}
+
+// DefaultBrowserOptions returns the limits used by mainstream browsers
+func DefaultBrowserOptions() BrowserOptions {
+	return BrowserOptions{
+		MaxConnsPerOrigin:   DefaultMaxConnsPerOrigin,
+		MaxStreamsPerOrigin: DefaultMaxStreamsPerOrigin,
+	}
+}
+
+// OriginLimiter caps the number of in-flight asset fetches per origin.
+// Requests over the limit wait in a queue, and the time spent waiting is
+// reported separately from network time. Origins switch to the HTTP/2 limit
+// as soon as one of their responses is seen over HTTP/2.
+type OriginLimiter struct {
+	opts        BrowserOptions
+	mu          sync.Mutex
+	cond        *sync.Cond
+	active      map[string]int
+	multiplexed map[string]bool
+}
+
+// NewOriginLimiter creates a limiter for a single page load
+func NewOriginLimiter(opts BrowserOptions) *OriginLimiter {
+	l := &OriginLimiter{
+		opts:        opts,
+		active:      make(map[string]int),
+		multiplexed: make(map[string]bool),
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Acquire blocks until a slot for the origin of rawURL is free
+func (l *OriginLimiter) Acquire(rawURL string) {
+	origin := originOf(rawURL)
+	l.mu.Lock()
+	for l.active[origin] >= l.limit(origin) {
+		l.cond.Wait()
+	}
+	l.active[origin]++
+	l.mu.Unlock()
+}
+
+// Release frees the slot taken by Acquire. If http2 is true the origin is
+// marked as multiplexed and queued requests may proceed at the HTTP/2 limit.
+func (l *OriginLimiter) Release(rawURL string, http2 bool) {
+	origin := originOf(rawURL)
+	l.mu.Lock()
+	l.active[origin]--
+	if http2 {
+		l.multiplexed[origin] = true
+	}
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// MarkMultiplexed records that the origin of rawURL is served over HTTP/2
+func (l *OriginLimiter) MarkMultiplexed(rawURL string) {
+	l.mu.Lock()
+	l.multiplexed[originOf(rawURL)] = true
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// limit returns the concurrency allowed for origin; callers must hold l.mu
+func (l *OriginLimiter) limit(origin string) int {
+	limit := l.opts.MaxConnsPerOrigin
+	if l.multiplexed[origin] {
+		limit = l.opts.MaxStreamsPerOrigin
+	}
+	if limit <= 0 {
+		limit = 1
+	}
+	return limit
+}
+
+// originOf returns scheme://host:port for rawURL, or rawURL itself if it cannot be parsed
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == DefaultEmptyString {
+		return rawURL
+	}
+	port := u.Port()
+	if port == DefaultEmptyString {
+		port = DefaultHTTPPort
+		if u.Scheme == HTTPSScheme {
+			port = DefaultHTTPSPort
+		}
+	}
+	return u.Scheme + "://" + u.Hostname() + ":" + port
+}
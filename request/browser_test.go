@@ -0,0 +1,140 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+)
+
+// acquired reports whether the Acquire running behind done returns within wait
+func acquired(done chan struct{}, wait time.Duration) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(wait):
+		return false
+	}
+}
+
+// acquire calls l.Acquire(rawURL) in the background and returns a channel closed once it returns
+func acquire(l *OriginLimiter, rawURL string) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		l.Acquire(rawURL)
+		close(done)
+	}()
+	return done
+}
+
+func TestOriginLimiter(t *testing.T) {
+	color.Green("~~ TestOriginLimiter ~~")
+	l := NewOriginLimiter(BrowserOptions{MaxConnsPerOrigin: 2, MaxStreamsPerOrigin: 3})
+	l.Acquire("http://a.test/1.js")
+	l.Acquire("http://a.test:80/2.js")
+
+	// The third request to the origin waits; other origins do not
+	queued := acquire(l, "http://a.test/3.js")
+	if acquired(queued, 50*time.Millisecond) {
+		t.Fatal("a third request got a slot on an HTTP/1.x origin limited to 2")
+	}
+	if !acquired(acquire(l, "https://a.test/1.js"), time.Second) {
+		t.Fatal("https://a.test waits for http://a.test")
+	}
+
+	// A released slot goes to the queued request
+	l.Release("http://a.test/1.js", false)
+	if !acquired(queued, time.Second) {
+		t.Fatal("queued request still waiting after a release")
+	}
+
+	// Once the origin is known to speak HTTP/2, waiting requests proceed up to the stream limit
+	queued = acquire(l, "http://a.test/4.js")
+	if acquired(queued, 50*time.Millisecond) {
+		t.Fatal("a third request got a slot on an HTTP/1.x origin limited to 2")
+	}
+	l.MarkMultiplexed("http://a.test/")
+	if !acquired(queued, time.Second) {
+		t.Fatal("queued request not woken by MarkMultiplexed")
+	}
+	if acquired(acquire(l, "http://a.test/5.js"), 50*time.Millisecond) {
+		t.Fatal("a fourth request got a slot on an HTTP/2 origin limited to 3")
+	}
+
+	// Release switches an origin to HTTP/2 as well
+	l.Acquire("http://b.test/1.js")
+	l.Acquire("http://b.test/2.js")
+	queued = acquire(l, "http://b.test/3.js")
+	l.Release("http://b.test/1.js", true)
+	l.Acquire("http://b.test/4.js")
+	if !acquired(queued, time.Second) {
+		t.Fatal("queued request still waiting after an HTTP/2 release")
+	}
+}
+
+// concurrencyServer serves a page with eight scripts that take delay each and records
+// how many of them were in flight at once
+func concurrencyServer(delay time.Duration, peak *atomic.Int32) http.Handler {
+	var mu sync.Mutex
+	var inFlight int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			var page strings.Builder
+			for i := range 8 {
+				fmt.Fprintf(&page, `<script src="/a%d.js"></script>`, i)
+			}
+			_, _ = w.Write([]byte(page.String()))
+			return
+		}
+		mu.Lock()
+		inFlight++
+		peak.Store(max(peak.Load(), inFlight))
+		mu.Unlock()
+		time.Sleep(delay)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		_, _ = w.Write([]byte("asset"))
+	})
+}
+
+func TestBrowserLimits(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	opts := BrowserOptions{MaxConnsPerOrigin: 2, MaxStreamsPerOrigin: 8}
+
+	// HTTP/1.1: two assets at a time, the others queue
+	var peak atomic.Int32
+	http1 := httptest.NewServer(concurrencyServer(delay, &peak))
+	defer http1.Close()
+	resp := FetchAll(FetchInput{BaseURL: http1.URL + "/", Client: NewClient(DefaultTransportConfig()), Browser: &opts})
+	if len(resp.JSResponses) != 8 || peak.Load() != 2 {
+		t.Errorf("HTTP/1.1: %d scripts, %d in flight at once, want 8 and 2", len(resp.JSResponses), peak.Load())
+	}
+	var queued int
+	for _, js := range resp.JSResponses {
+		if js.QueueTime >= delay {
+			queued++
+		}
+	}
+	// Six of the eight scripts wait for at least one earlier script
+	if queued != 6 || resp.TotalQueueTime < 6*delay {
+		t.Errorf("HTTP/1.1: %d scripts queued for %s in total", queued, resp.TotalQueueTime)
+	}
+
+	// HTTP/2: the page response switches the origin to the stream limit
+	peak.Store(0)
+	http2 := httptest.NewUnstartedServer(concurrencyServer(delay, &peak))
+	http2.EnableHTTP2 = true
+	http2.StartTLS()
+	defer http2.Close()
+	resp = FetchAll(FetchInput{BaseURL: http2.URL + "/", Client: http2.Client(), Browser: &opts})
+	if resp.BaseURL.Protocol != "HTTP/2.0" || peak.Load() <= 2 {
+		t.Errorf("%s: %d scripts in flight at once, want more than 2", resp.BaseURL.Protocol, peak.Load())
+	}
+}
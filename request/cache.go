@@ -0,0 +1,197 @@
+package request
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPCache is a private (per virtual user) browser-like HTTP cache.
+// It honours Cache-Control (no-store, no-cache, max-age), Expires and the
+// ETag/Last-Modified validators so that repeat page views only hit the
+// network for stale or uncacheable resources.  It keeps the last variant of
+// every URL: an entry is only used by requests with the same values of the
+// headers its Vary header names, and responses with Vary: * are not stored.
+type HTTPCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is a stored response together with its freshness information
+type cacheEntry struct {
+	status       int
+	header       http.Header
+	body         string
+	hasBody      bool
+	etag         string
+	lastModified string
+	expires      time.Time
+	mustRevalid  bool
+	vary         map[string]string // request headers named by Vary and their values
+}
+
+// NewHTTPCache creates an empty cache
+func NewHTTPCache() *HTTPCache {
+	return &HTTPCache{entries: make(map[string]*cacheEntry)}
+}
+
+// lookup returns a copy of the entry for url matching the request header and whether
+// it can be used without revalidation. needBody is set when the caller wants the
+// document body, in which case entries stored without a body are ignored.
+func (c *HTTPCache) lookup(url string, header http.Header, needBody bool) (entry *cacheEntry, fresh bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored := c.entries[url]
+	if stored == nil || (needBody && !stored.hasBody) || !stored.matches(header) {
+		return nil, false
+	}
+	copied := *stored
+	fresh = !copied.mustRevalid && time.Now().Before(copied.expires)
+	return &copied, fresh
+}
+
+// store saves resp to the request header for url if its headers allow caching
+func (c *HTTPCache) store(url string, header http.Header, resp *http.Response, body string, keepBody bool) {
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	directives := parseCacheControl(resp.Header.Get(CacheControlHeader))
+	vary, varyAny := varyValues(resp.Header, header)
+	if _, noStore := directives[CacheDirectiveNoStore]; noStore || varyAny {
+		c.mu.Lock()
+		delete(c.entries, url)
+		c.mu.Unlock()
+		return
+	}
+
+	entry := &cacheEntry{
+		status:       resp.StatusCode,
+		header:       resp.Header.Clone(),
+		hasBody:      keepBody,
+		etag:         resp.Header.Get(ETagHeader),
+		lastModified: resp.Header.Get(LastModifiedHeader),
+		vary:         vary,
+	}
+	if keepBody {
+		entry.body = body
+	}
+	_, entry.mustRevalid = directives[CacheDirectiveNoCache]
+	entry.expires = time.Now().Add(freshnessLifetime(resp.Header, directives))
+
+	// Nothing to reuse and nothing to revalidate with
+	if !time.Now().Before(entry.expires) && entry.etag == DefaultEmptyString && entry.lastModified == DefaultEmptyString {
+		return
+	}
+
+	c.mu.Lock()
+	c.entries[url] = entry
+	c.mu.Unlock()
+}
+
+// refresh updates the freshness of an entry after a 304 Not Modified response
+func (c *HTTPCache) refresh(url string, resp *http.Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.entries[url]
+	if entry == nil {
+		return
+	}
+	for _, key := range []string{CacheControlHeader, ExpiresHeader, ETagHeader, LastModifiedHeader, DateHeader} {
+		if value := resp.Header.Get(key); value != DefaultEmptyString {
+			entry.header.Set(key, value)
+		}
+	}
+	directives := parseCacheControl(entry.header.Get(CacheControlHeader))
+	_, entry.mustRevalid = directives[CacheDirectiveNoCache]
+	entry.etag = entry.header.Get(ETagHeader)
+	entry.lastModified = entry.header.Get(LastModifiedHeader)
+	entry.expires = time.Now().Add(freshnessLifetime(entry.header, directives))
+}
+
+// matches reports whether the request header has the values the entry was stored with
+func (e *cacheEntry) matches(header http.Header) bool {
+	for name, value := range e.vary {
+		if strings.Join(header.Values(name), ",") != value {
+			return false
+		}
+	}
+	return true
+}
+
+// varyValues returns the request headers the Vary header of a response names, with their
+// values in the request header; varyAny is set for Vary: *
+func varyValues(respHeader, header http.Header) (vary map[string]string, varyAny bool) {
+	for _, value := range respHeader.Values(VaryHeader) {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			switch name {
+			case DefaultEmptyString:
+				continue
+			case VaryAny:
+				return nil, true
+			}
+			if vary == nil {
+				vary = make(map[string]string)
+			}
+			vary[name] = strings.Join(header.Values(name), ",")
+		}
+	}
+	return vary, false
+}
+
+// addValidators sets the conditional request headers for a stale entry
+func (e *cacheEntry) addValidators(req *http.Request) {
+	if e.etag != DefaultEmptyString {
+		req.Header.Set(IfNoneMatchHeader, e.etag)
+	}
+	if e.lastModified != DefaultEmptyString {
+		req.Header.Set(IfModifiedSinceHeader, e.lastModified)
+	}
+}
+
+// freshnessLifetime computes how long a response stays fresh, following RFC 9111:
+// max-age wins over Expires, and responses with only Last-Modified get the
+// usual 10% heuristic. The Age header is subtracted from the result.
+func freshnessLifetime(header http.Header, directives map[string]string) time.Duration {
+	var lifetime time.Duration
+	date, dateErr := http.ParseTime(header.Get(DateHeader))
+	if dateErr != nil {
+		date = time.Now()
+	}
+
+	if maxAge, ok := directives[CacheDirectiveMaxAge]; ok {
+		if seconds, err := strconv.Atoi(maxAge); err == nil {
+			lifetime = time.Duration(seconds) * time.Second
+		}
+	} else if expires := header.Get(ExpiresHeader); expires != DefaultEmptyString {
+		if t, err := http.ParseTime(expires); err == nil {
+			lifetime = t.Sub(date)
+		}
+	} else if lastModified, err := http.ParseTime(header.Get(LastModifiedHeader)); err == nil {
+		lifetime = date.Sub(lastModified) / HeuristicFreshnessDivisor
+	}
+
+	if age, err := strconv.Atoi(header.Get(AgeHeader)); err == nil {
+		lifetime -= time.Duration(age) * time.Second
+	}
+	if lifetime < 0 {
+		return 0
+	}
+	return lifetime
+}
+
+// parseCacheControl splits a Cache-Control header into lower-cased directives
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == DefaultEmptyString {
+			continue
+		}
+		name, arg, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+	}
+	return directives
+}
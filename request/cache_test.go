@@ -0,0 +1,144 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+)
+
+func TestFreshnessLifetime(t *testing.T) {
+	color.Green("~~ TestFreshnessLifetime ~~")
+	now := time.Now().UTC().Truncate(time.Second)
+	date := now.Format(http.TimeFormat)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"max-age", http.Header{CacheControlHeader: {"public, max-age=60"}}, time.Minute},
+		{"max-age wins over Expires", http.Header{CacheControlHeader: {"max-age=60"}, DateHeader: {date},
+			ExpiresHeader: {now.Add(time.Hour).Format(http.TimeFormat)}}, time.Minute},
+		{"Expires from Date", http.Header{DateHeader: {date},
+			ExpiresHeader: {now.Add(time.Hour).Format(http.TimeFormat)}}, time.Hour},
+		{"Expires in the past", http.Header{DateHeader: {date},
+			ExpiresHeader: {now.Add(-time.Hour).Format(http.TimeFormat)}}, 0},
+		{"Expires wins over Last-Modified", http.Header{DateHeader: {date},
+			ExpiresHeader:      {now.Add(time.Hour).Format(http.TimeFormat)},
+			LastModifiedHeader: {now.Add(-100 * time.Hour).Format(http.TimeFormat)}}, time.Hour},
+		{"Last-Modified heuristic", http.Header{DateHeader: {date},
+			LastModifiedHeader: {now.Add(-10 * time.Hour).Format(http.TimeFormat)}}, time.Hour},
+		{"Age is subtracted", http.Header{CacheControlHeader: {"max-age=60"}, AgeHeader: {"45"}}, 15 * time.Second},
+		{"Age beyond the lifetime", http.Header{CacheControlHeader: {"max-age=60"}, AgeHeader: {"90"}}, 0},
+		{"no information", http.Header{}, 0},
+	}
+	for _, tt := range tests {
+		directives := parseCacheControl(tt.header.Get(CacheControlHeader))
+		if got := freshnessLifetime(tt.header, directives); got != tt.want {
+			t.Errorf("%s: lifetime %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// cacheStep is one fetch of a cache test: the request it sends and what it should get
+type cacheStep struct {
+	language string // Accept-Language of the request
+	retdat   bool   // whether the body is wanted
+	want     string // expected CacheStatus
+}
+
+func TestHTTPCache(t *testing.T) {
+	lastModified := time.Now().Add(-10 * time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		name string
+		// respond sets the headers of the n-th request (from 0) to reach the server
+		respond func(n int, header http.Header)
+		steps   []cacheStep
+		// the If-None-Match sent by every request reaching the server
+		validators []string
+	}{
+		{"max-age", func(_ int, h http.Header) { h.Set(CacheControlHeader, "max-age=60") },
+			[]cacheStep{{want: CacheStatusMiss}, {want: CacheStatusHit}}, []string{""}},
+		{"Age uses up max-age", func(_ int, h http.Header) {
+			h.Set(CacheControlHeader, "max-age=60")
+			h.Set(AgeHeader, "60")
+		}, []cacheStep{{want: CacheStatusMiss}, {want: CacheStatusMiss}}, []string{"", ""}},
+		{"Last-Modified heuristic", func(_ int, h http.Header) { h.Set(LastModifiedHeader, lastModified) },
+			[]cacheStep{{want: CacheStatusMiss}, {want: CacheStatusHit}}, []string{""}},
+		{"no-cache revalidates", func(_ int, h http.Header) {
+			h.Set(CacheControlHeader, "no-cache")
+			h.Set(ETagHeader, `"v1"`)
+		}, []cacheStep{{want: CacheStatusMiss}, {want: CacheStatusRevalidated}, {want: CacheStatusRevalidated}},
+			[]string{"", `"v1"`, `"v1"`}},
+		// The 304 makes the entry fresh for a minute
+		{"refresh after a 304", func(n int, h http.Header) {
+			h.Set(ETagHeader, `"v1"`)
+			h.Set(CacheControlHeader, "max-age=0")
+			if n > 0 {
+				h.Set(CacheControlHeader, "max-age=60")
+			}
+		}, []cacheStep{{want: CacheStatusMiss}, {want: CacheStatusRevalidated}, {want: CacheStatusHit}},
+			[]string{"", `"v1"`}},
+		// A no-store response evicts the stale entry, so nothing is revalidated afterwards
+		{"no-store eviction", func(n int, h http.Header) {
+			h.Set(ETagHeader, `"v1"`)
+			h.Set(CacheControlHeader, "max-age=0")
+			if n == 1 {
+				h.Set(ETagHeader, `"v2"`)
+				h.Set(CacheControlHeader, "no-store")
+			}
+		}, []cacheStep{{want: CacheStatusMiss}, {want: CacheStatusMiss}, {want: CacheStatusMiss}},
+			[]string{"", `"v1"`, ""}},
+		// An entry stored without its body cannot serve a request wanting one
+		{"needBody lookup", func(_ int, h http.Header) { h.Set(CacheControlHeader, "max-age=60") },
+			[]cacheStep{{want: CacheStatusMiss}, {retdat: true, want: CacheStatusMiss},
+				{retdat: true, want: CacheStatusHit}, {want: CacheStatusHit}}, []string{"", ""}},
+		{"Vary", func(_ int, h http.Header) {
+			h.Set(CacheControlHeader, "max-age=60")
+			h.Set(VaryHeader, "Accept-Encoding, Accept-Language")
+		}, []cacheStep{{language: "en", want: CacheStatusMiss}, {language: "en", want: CacheStatusHit},
+			{language: "fr", want: CacheStatusMiss}, {language: "fr", want: CacheStatusHit},
+			{want: CacheStatusMiss}}, []string{"", "", ""}},
+		{"Vary *", func(_ int, h http.Header) {
+			h.Set(CacheControlHeader, "max-age=60")
+			h.Set(VaryHeader, VaryAny)
+		}, []cacheStep{{want: CacheStatusMiss}, {want: CacheStatusMiss}}, []string{"", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validators []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.respond(len(validators), w.Header())
+				validators = append(validators, r.Header.Get(IfNoneMatchHeader))
+				if match := r.Header.Get(IfNoneMatchHeader); match != "" && match == w.Header().Get(ETagHeader) {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = w.Write([]byte("page " + r.Header.Get("Accept-Language")))
+			}))
+			defer server.Close()
+
+			cache := NewHTTPCache()
+			for i, step := range tt.steps {
+				resp := Fetch(FetchInput{BaseURL: server.URL, Retdat: step.retdat, Cache: cache,
+					Header: http.Header{"Accept-Language": {step.language}}})
+				if resp.CacheStatus != step.want || resp.Status != http.StatusOK && resp.Status != http.StatusNotModified {
+					t.Fatalf("fetch %d: status %d, cache %q, want %q", i, resp.Status, resp.CacheStatus, step.want)
+				}
+				if step.retdat && resp.Body != "page "+step.language {
+					t.Errorf("fetch %d: body %q", i, resp.Body)
+				}
+			}
+			if len(validators) != len(tt.validators) {
+				t.Fatalf("%d requests reached the server, want %d", len(validators), len(tt.validators))
+			}
+			for i := range validators {
+				if validators[i] != tt.validators[i] {
+					t.Errorf("request %d: If-None-Match %q, want %q", i, validators[i], tt.validators[i])
+				}
+			}
+		})
+	}
+}
@@ -13,36 +13,41 @@ func Combine(results []IterateReqRespAll) *IterateReqRespAll {
 	baseStatus := []int{}
 	baseRespTimes := []time.Duration{}
 	baseBytes := 0
-	jsResps := map[string][]IterateReqResp{}
-	cssResps := map[string][]IterateReqResp{}
-	imgResps := map[string][]IterateReqResp{}
+	baseReused := 0
+	assetResps := make(map[string]map[string][]IterateReqResp, AssetTypesCount)
+	for _, kind := range AssetTypes {
+		assetResps[kind] = map[string][]IterateReqResp{}
+	}
 
 	var totalAvglRespTimes int64
 	var totalAvgLinearlRespTimes int64
+	var totalAvgQueueTimes int64
 	var count int64
+	firstView, repeatView := ViewStats{}, ViewStats{}
 	for i := range results {
 		resp := &results[i]
+		firstView.Merge(&resp.FirstView)
+		repeatView.Merge(&resp.RepeatView)
 		totalReqs += len(resp.BaseURL.Status)
 		baseStatus = append(baseStatus, resp.BaseURL.Status...)
 		baseRespTimes = append(baseRespTimes, resp.BaseURL.RespTimes...)
 		baseBytes += resp.BaseURL.Bytes
+		baseReused += resp.BaseURL.ConnReused
 		totalAvglRespTimes += int64(resp.AvgTotalRespTime)
 		totalAvgLinearlRespTimes += int64(resp.AvgTotalLinearRespTime)
+		totalAvgQueueTimes += int64(resp.AvgTotalQueueTime)
 		count++
 
-		for _, jsresp := range resp.JSResps {
-			jsResps[jsresp.URL] = append(jsResps[jsresp.URL], jsresp)
-		}
-		for _, cssresp := range resp.CSSResps {
-			cssResps[cssresp.URL] = append(cssResps[cssresp.URL], cssresp)
-		}
-		for _, imgresp := range resp.IMGResps {
-			imgResps[imgresp.URL] = append(imgResps[imgresp.URL], imgresp)
+		for _, kind := range AssetTypes {
+			for _, assetresp := range *resp.Resps(kind) {
+				assetResps[kind][assetresp.URL] = append(assetResps[kind][assetresp.URL], assetresp)
+			}
 		}
 	}
 
 	avgTotalRespTimes := time.Duration(totalAvglRespTimes / count)
 	avgTotalLinearRespTimes := time.Duration(totalAvgLinearlRespTimes / count)
+	avgTotalQueueTimes := time.Duration(totalAvgQueueTimes / count)
 
 	combine := func(resps map[string][]IterateReqResp) []IterateReqResp {
 		allResps := []IterateReqResp{}
@@ -50,10 +55,14 @@ func Combine(results []IterateReqRespAll) *IterateReqRespAll {
 			status := []int{}
 			respTimes := []time.Duration{}
 			bytes := 0
+			reused := 0
+			queueTime := time.Duration(0)
 			for _, resp := range v {
 				status = append(status, resp.Status...)
 				respTimes = append(respTimes, resp.RespTimes...)
 				bytes += resp.Bytes
+				reused += resp.ConnReused
+				queueTime += resp.QueueTime
 			}
 			allResps = append(allResps, IterateReqResp{
 				URL:         k,
@@ -61,23 +70,81 @@ func Combine(results []IterateReqRespAll) *IterateReqRespAll {
 				RespTimes:   respTimes,
 				NumRequests: len(status),
 				Bytes:       bytes,
+				ConnReused:  reused,
+				QueueTime:   queueTime,
 			})
 		}
 		return allResps
 	}
 
-	return &IterateReqRespAll{
+	combined := &IterateReqRespAll{
 		AvgTotalRespTime:       avgTotalRespTimes,
 		AvgTotalLinearRespTime: avgTotalLinearRespTimes,
+		AvgTotalQueueTime:      avgTotalQueueTimes,
+		FirstView:              firstView,
+		RepeatView:             repeatView,
 		BaseURL: IterateReqResp{
 			URL:         results[0].BaseURL.URL,
 			Status:      baseStatus,
 			RespTimes:   baseRespTimes,
 			NumRequests: len(baseStatus),
 			Bytes:       baseBytes,
+			ConnReused:  baseReused,
 		},
-		JSResps:  combine(jsResps),
-		CSSResps: combine(cssResps),
-		IMGResps: combine(imgResps),
 	}
+	for _, kind := range AssetTypes {
+		*combined.Resps(kind) = combine(assetResps[kind])
+	}
+	combined.Targets = combineTargets(results)
+	combined.Operations = combineOperations(results)
+	combined.Protocols = ProtocolCounts{}
+	combined.TLS = TLSCounts{}
+	for i := range results {
+		combined.Protocols.Merge(results[i].Protocols)
+		combined.TLS.Merge(results[i].TLS)
+		combined.Proxy.Merge(&results[i].Proxy)
+	}
+	return combined
+}
+
+// combineTargets merges the per-target stats of every result, keeping the order targets were first seen in
+func combineTargets(results []IterateReqRespAll) []TargetStats {
+	var targets []TargetStats
+	index := map[string]int{}
+	for i := range results {
+		for j := range results[i].Targets {
+			target := &results[i].Targets[j]
+			key := target.Method + " " + target.URL + " " + target.Operation
+			k, ok := index[key]
+			if !ok {
+				k = len(targets)
+				index[key] = k
+				targets = append(targets, TargetStats{URL: target.URL, Method: target.Method,
+					Operation: target.Operation, Weight: target.Weight, Base: IterateReqResp{URL: target.URL}})
+			}
+			targets[k].Merge(target)
+		}
+	}
+	return targets
+}
+
+// combineOperations merges the GraphQL operation stats of every result by name, keeping the order
+// operations were first seen in
+func combineOperations(results []IterateReqRespAll) []OperationStats {
+	var operations []OperationStats
+	index := map[string]int{}
+	for i := range results {
+		for j := range results[i].Operations {
+			operation := &results[i].Operations[j]
+			k, ok := index[operation.Name]
+			if !ok {
+				k = len(operations)
+				index[operation.Name] = k
+				operations = append(operations, OperationStats{Name: operation.Name,
+					Base: IterateReqResp{URL: operation.Base.URL}})
+			}
+			operations[k].Merge(operation)
+		}
+	}
+	return operations
 }
@@ -1,5 +1,7 @@
 package request
 
+import "time"
+
 const (
 	// HTTPStatusOK represents the HTTP 200 status code for successful requests
 	HTTPStatusOK = 200
@@ -8,16 +10,8 @@ const (
 	// HTTPStatusConnectionError represents a connection error status code
 	HTTPStatusConnectionError = -100 // Connection error status
 
-	// HTTPProtocolLength specifies the length of the HTTP protocol prefix
-	HTTPProtocolLength = 4 // Length of "http" string
-
-	// AssetTypesCount specifies the number of different asset types (JS, CSS, images)
-	AssetTypesCount = 3 // Number of asset types: JS, CSS, IMG
-
-	// FirstPathElement specifies the index of the first path element in URL parsing
-	FirstPathElement = 0 // Index of first character in path
-	// SecondPathElement specifies the index of the second path element in URL parsing
-	SecondPathElement = 1 // Index of second character in path
+	// AssetTypesCount specifies the number of different asset types (see AssetTypes)
+	AssetTypesCount = 8 // Number of asset types: JS, CSS, IMG, font, media, icon, document, other
 
 	// DefaultJSONIndent specifies the default indentation for JSON formatting
 	DefaultJSONIndent = "  " // Default JSON indentation
@@ -32,15 +26,160 @@ const (
 	UserAgentHeader = "User-Agent"
 	// CookieHeader specifies the HTTP Cookie header name
 	CookieHeader = "cookie"
+	// ProxyAuthorizationHeader specifies the HTTP Proxy-Authorization header name
+	ProxyAuthorizationHeader = "Proxy-Authorization"
 
 	// ErrorRequestFailed specifies the default error message for failed requests
 	ErrorRequestFailed = "Request failed"
+	// ErrorAuth specifies the error of a request its auth provider could not authenticate
+	ErrorAuth = "Authentication failed"
+	// ErrorGraphQL prefixes the error of a GraphQL response listing errors
+	ErrorGraphQL = "GraphQL error"
 
 	// HTTPScheme specifies the HTTP protocol scheme for URLs
 	HTTPScheme = "http"
 	// HTTPSScheme specifies the HTTPS protocol scheme for secure URLs
 	HTTPSScheme = "https"
 
-	// PathSeparator specifies the path separator character for URLs
-	PathSeparator = "/"
+	// ProtocolAuto lets the transport negotiate HTTP/2 via ALPN and fall back to HTTP/1.1
+	ProtocolAuto = "auto"
+	// ProtocolHTTP1 forces HTTP/1.1 for every connection
+	ProtocolHTTP1 = "http1"
+	// ProtocolHTTP2 forces HTTP/2 over TLS for every connection
+	ProtocolHTTP2 = "http2"
+	// ProtocolH2C speaks HTTP/2 with prior knowledge over cleartext connections, and over TLS for https urls
+	ProtocolH2C = "h2c"
+
+	// DefaultMaxIdleConns specifies the default size of the idle connection pool
+	DefaultMaxIdleConns = 100 // Default idle connections kept per transport
+	// DefaultIdleConnTimeout specifies how long idle connections stay in the pool
+	DefaultIdleConnTimeout = 90 * time.Second // Default idle connection timeout
+	// DefaultDialTimeout specifies the maximum time spent establishing a TCP connection
+	DefaultDialTimeout = 30 * time.Second // Default TCP dial timeout
+	// DefaultTCPKeepAlive specifies the interval between TCP keep-alive probes
+	DefaultTCPKeepAlive = 30 * time.Second // Default TCP keep-alive period
+	// DefaultTLSHandshakeTimeout specifies the maximum time spent on a TLS handshake
+	DefaultTLSHandshakeTimeout = 10 * time.Second // Default TLS handshake timeout
+	// ProxySchemeSOCKS5 is the scheme of SOCKS5 proxies
+	ProxySchemeSOCKS5 = "socks5"
+	// ProxySchemeSOCKS5H is the scheme of SOCKS5 proxies resolving host names themselves
+	ProxySchemeSOCKS5H = "socks5h"
+	// ProxyDirect sends requests directly, ignoring the proxy environment variables
+	ProxyDirect = "direct"
+	// ResolveOverrideParts is the number of parts of a "host:port:addr" resolve override
+	ResolveOverrideParts = 3
+	// DNSPort is the port of a DNS server given without one
+	DNSPort = "53"
+	// DialSchemeUnix is the scheme of "unix:///path" dial targets
+	DialSchemeUnix = "unix"
+	// DialSchemeTCP is the scheme of "tcp://host:port" dial targets
+	DialSchemeTCP = "tcp"
+	// DefaultExpectContinueTimeout specifies how long to wait for a 100-continue response
+	DefaultExpectContinueTimeout = 1 * time.Second // Default Expect: 100-continue timeout
+
+	// DefaultMaxConnsPerOrigin specifies how many parallel HTTP/1.x connections a browser opens per origin
+	DefaultMaxConnsPerOrigin = 6 // Browser per-origin connection limit
+	// DefaultMaxStreamsPerOrigin specifies how many concurrent streams are used on a multiplexed HTTP/2 origin
+	DefaultMaxStreamsPerOrigin = 100 // Typical HTTP/2 SETTINGS_MAX_CONCURRENT_STREAMS
+	// HTTP2ProtoMajor specifies the major protocol version reported for HTTP/2 responses
+	HTTP2ProtoMajor = 2 // http.Response.ProtoMajor for HTTP/2
+	// DefaultHTTPPort specifies the implicit port of http URLs
+	DefaultHTTPPort = "80"
+	// DefaultHTTPSPort specifies the implicit port of https URLs
+	DefaultHTTPSPort = "443"
+	// DefaultSOCKSPort specifies the implicit port of SOCKS5 proxies
+	DefaultSOCKSPort = "1080"
+
+	// CacheStatusMiss marks a response that was fetched from the network and possibly stored
+	CacheStatusMiss = "miss"
+	// CacheStatusHit marks a response served from the cache without a request
+	CacheStatusHit = "hit"
+	// CacheStatusRevalidated marks a response confirmed with a 304 Not Modified
+	CacheStatusRevalidated = "revalidated"
+
+	// SkipReasonType marks an asset skipped because of its type
+	SkipReasonType = "type"
+	// SkipReasonCrossOrigin marks an asset skipped because it is not same-origin with the page
+	SkipReasonCrossOrigin = "cross-origin"
+	// SkipReasonDeniedHost marks an asset skipped because its host is denied
+	SkipReasonDeniedHost = "denied host"
+	// SkipReasonHostNotAllowed marks an asset skipped because its host is not allowed
+	SkipReasonHostNotAllowed = "host not allowed"
+	// SkipReasonExcluded marks an asset skipped because its URL matches an exclude pattern
+	SkipReasonExcluded = "excluded"
+	// SkipReasonNotIncluded marks an asset skipped because its URL matches no include pattern
+	SkipReasonNotIncluded = "not included"
+	// RegexPatternPrefix marks an asset URL pattern as a regular expression instead of a glob
+	RegexPatternPrefix = "re:"
+
+	// CacheControlHeader specifies the HTTP Cache-Control header name
+	CacheControlHeader = "Cache-Control"
+	// ExpiresHeader specifies the HTTP Expires header name
+	ExpiresHeader = "Expires"
+	// ETagHeader specifies the HTTP ETag header name
+	ETagHeader = "ETag"
+	// LastModifiedHeader specifies the HTTP Last-Modified header name
+	LastModifiedHeader = "Last-Modified"
+	// DateHeader specifies the HTTP Date header name
+	DateHeader = "Date"
+	// AgeHeader specifies the HTTP Age header name
+	AgeHeader = "Age"
+	// IfNoneMatchHeader specifies the HTTP If-None-Match header name
+	IfNoneMatchHeader = "If-None-Match"
+	// IfModifiedSinceHeader specifies the HTTP If-Modified-Since header name
+	IfModifiedSinceHeader = "If-Modified-Since"
+	// VaryHeader specifies the HTTP Vary header name
+	VaryHeader = "Vary"
+	// VaryAny is the Vary value of responses that depend on more than request headers
+	VaryAny = "*"
+
+	// CacheDirectiveNoStore forbids storing the response
+	CacheDirectiveNoStore = "no-store"
+	// CacheDirectiveNoCache requires revalidation before every reuse
+	CacheDirectiveNoCache = "no-cache"
+	// CacheDirectiveMaxAge sets the freshness lifetime in seconds
+	CacheDirectiveMaxAge = "max-age"
+	// HeuristicFreshnessDivisor gives 10% of the time since Last-Modified as heuristic freshness
+	HeuristicFreshnessDivisor = 10 // RFC 9111 section 4.2.2 heuristic
+
+	// AssetTypeDocument identifies HTML documents: the base page in a waterfall, iframes and frames
+	AssetTypeDocument = "document"
+	// AssetTypeJS identifies JavaScript assets
+	AssetTypeJS = "js"
+	// AssetTypeCSS identifies stylesheet assets
+	AssetTypeCSS = "css"
+	// AssetTypeIMG identifies image assets
+	AssetTypeIMG = "img"
+	// AssetTypeFont identifies web fonts
+	AssetTypeFont = "font"
+	// AssetTypeMedia identifies audio and video resources
+	AssetTypeMedia = "media"
+	// AssetTypeIcon identifies favicons and touch icons
+	AssetTypeIcon = "icon"
+	// AssetTypeOther identifies manifests, embeds, objects and fetch preloads
+	AssetTypeOther = "other"
+
+	// PhaseQueue is the time spent waiting for a per-origin slot
+	PhaseQueue = "queue"
+	// PhaseDNS is the time spent resolving the host name
+	PhaseDNS = "dns"
+	// PhaseConnect is the time spent establishing the TCP connection
+	PhaseConnect = "connect"
+	// PhaseProxy is the time spent opening a tunnel through the proxy
+	PhaseProxy = "proxy"
+	// PhaseTLS is the time spent on the TLS handshake
+	PhaseTLS = "tls"
+	// PhaseWait is the time spent waiting for the first response byte
+	PhaseWait = "wait"
+	// PhaseDownload is the time spent reading the response body
+	PhaseDownload = "download"
+
+	// WaterfallWidth specifies the number of characters used for the terminal timeline
+	WaterfallWidth = 60 // Terminal waterfall bar width
+	// WaterfallURLWidth specifies how many characters of each URL are shown in the terminal waterfall
+	WaterfallURLWidth = 40 // Terminal waterfall URL column width
+	// WaterfallPercentPrecision specifies the decimals used for HTML waterfall bar positions
+	WaterfallPercentPrecision = 3 // Decimal places for CSS percentages
+	// PercentageBase specifies the base value for percentage calculations
+	PercentageBase = 100.0 // Base for percentage calculations
 )
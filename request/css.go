@@ -0,0 +1,107 @@
+package request
+
+import (
+	"net/http"
+
+	"github.com/Gosayram/goperf/httputils"
+)
+
+// stylesheetRef is a sub-resource referenced by a fetched stylesheet
+type stylesheetRef struct {
+	url       string
+	initiator string
+}
+
+/*
+fetchStylesheets fetches the page's stylesheets and then, one level at a time and up
+to input.CSSDepth levels deep, the imported stylesheets, fonts and images they
+reference. Each sub-resource records the stylesheet that referenced it as its
+Initiator, and a level only starts once the stylesheets that reference it are
+loaded, like in a browser.
+
+seen holds every URL already requested for the page. Sub-resources in it are not
+requested again and the discovered ones are added to it. Sub-resources rejected by
+input.Filter for the page at page are returned as skipped.
+*/
+func fetchStylesheets(files []string, input FetchInput, limiter *OriginLimiter,
+	seen map[string]bool, page string) (found map[string][]FetchResponse, skipped []SkippedAsset) {
+	found = map[string][]FetchResponse{AssetTypeCSS: fetchAssets(files, input, limiter)}
+	sheets := found[AssetTypeCSS]
+	for depth := 0; depth < input.CSSDepth && len(sheets) > 0; depth++ {
+		refs, levelSkipped := stylesheetRefs(sheets, seen, input.Filter, page)
+		skipped = append(skipped, levelSkipped...)
+		level := fetchStylesheetRefs(refs, input, limiter)
+		for kind, resps := range level {
+			found[kind] = append(found[kind], resps...)
+		}
+		sheets = level[AssetTypeCSS]
+	}
+	return found, skipped
+}
+
+// stylesheetRefs parses the loaded stylesheets in sheets and returns their unseen
+// sub-resources by asset type, resolved against each stylesheet's own URL, and
+// the ones filter skips for the page at page
+func stylesheetRefs(sheets []FetchResponse, seen map[string]bool, filter *AssetFilter,
+	page string) (refs map[string][]stylesheetRef, skipped []SkippedAsset) {
+	refs = make(map[string][]stylesheetRef)
+	for i := range sheets {
+		sheet := &sheets[i]
+		if !loaded(sheet) {
+			continue
+		}
+		sheetURL := sheet.FinalURL()
+		resolver, err := NewAssetResolver(sheetURL, "")
+		if err != nil {
+			continue
+		}
+		assets := assetsByType(httputils.ParseStylesheet(sheet.Body))
+		for _, kind := range resolveOrder {
+			for _, assetURL := range resolver.Resolve(assets[kind]) {
+				if seen[assetURL] {
+					continue
+				}
+				seen[assetURL] = true
+				if reason := filter.Skip(kind, assetURL, page); reason != "" {
+					skipped = append(skipped, SkippedAsset{URL: assetURL, Type: kind, Reason: reason, Initiator: sheetURL})
+					continue
+				}
+				refs[kind] = append(refs[kind], stylesheetRef{url: assetURL, initiator: sheetURL})
+			}
+		}
+	}
+	return refs, skipped
+}
+
+// fetchStylesheetRefs fetches one level of stylesheet sub-resources, every asset type concurrently
+func fetchStylesheetRefs(refs map[string][]stylesheetRef, input FetchInput,
+	limiter *OriginLimiter) map[string][]FetchResponse {
+	chans := make(map[string]chan []FetchResponse, len(refs))
+	for kind, kindRefs := range refs {
+		files := make([]string, len(kindRefs))
+		for i, ref := range kindRefs {
+			files[i] = ref.url
+		}
+		chans[kind] = make(chan []FetchResponse)
+		go GoFetchAllAssetArray(files, input, limiter, chans[kind])
+	}
+
+	level := make(map[string][]FetchResponse, len(refs))
+	for kind, c := range chans {
+		resps := <-c
+		for i := range resps {
+			resps[i].Initiator = refs[kind][i].initiator
+		}
+		level[kind] = resps
+	}
+	return level
+}
+
+// loaded reports whether resp holds a stylesheet the browser would apply
+func loaded(resp *FetchResponse) bool {
+	if resp.Body == "" {
+		return false
+	}
+	return resp.Status >= http.StatusOK && resp.Status < http.StatusMultipleChoices ||
+		resp.CacheStatus == CacheStatusRevalidated
+}
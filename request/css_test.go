@@ -0,0 +1,107 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+// stylesheetSite serves a page whose stylesheet imports another one, three levels deep
+func stylesheetSite() *httptest.Server {
+	files := map[string]string{
+		"/":             `<html><head><link rel="stylesheet" href="/css/site.css"></head><body></body></html>`,
+		"/css/site.css": `@import "parts/base.css"; .hero { background: url(../img/hero.png) }`,
+		"/css/parts/base.css": `@import url(deep.css);
+@font-face { font-family: A; src: url(../../fonts/a.woff2) format("woff2"); }
+body { background-image: url("/img/hero.png"); }`,
+		"/css/parts/deep.css": `.x { background: url(x.png) }`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+func TestFetchAllStylesheetAssets(t *testing.T) {
+	color.Green("~~ TestFetchAllStylesheetAssets ~~")
+	server := stylesheetSite()
+	defer server.Close()
+
+	tests := []struct {
+		depth     int
+		wantCSS   []string
+		wantImg   []string
+		wantFonts []string
+	}{
+		{0, []string{"/css/site.css"}, nil, nil},
+		{1, []string{"/css/site.css", "/css/parts/base.css"}, []string{"/img/hero.png"}, nil},
+		{2, []string{"/css/site.css", "/css/parts/base.css", "/css/parts/deep.css"}, []string{"/img/hero.png"},
+			[]string{"/fonts/a.woff2"}},
+		{5, []string{"/css/site.css", "/css/parts/base.css", "/css/parts/deep.css"},
+			[]string{"/img/hero.png", "/css/parts/x.png"}, []string{"/fonts/a.woff2"}},
+	}
+	for _, tt := range tests {
+		resp := FetchAll(FetchInput{BaseURL: server.URL + "/", CSSDepth: tt.depth})
+		checkPaths(t, tt.depth, "css", resp.CSSResponses, server.URL, tt.wantCSS)
+		checkPaths(t, tt.depth, "img", resp.IMGResponses, server.URL, tt.wantImg)
+		checkPaths(t, tt.depth, "font", resp.FontResponses, server.URL, tt.wantFonts)
+		if want := 1 + len(tt.wantCSS) + len(tt.wantImg) + len(tt.wantFonts); resp.TotalRequests != want {
+			t.Errorf("depth %d: TotalRequests = %d, want %d", tt.depth, resp.TotalRequests, want)
+		}
+	}
+}
+
+func TestFetchAllStylesheetInitiator(t *testing.T) {
+	server := stylesheetSite()
+	defer server.Close()
+
+	resp := FetchAll(FetchInput{BaseURL: server.URL + "/", CSSDepth: 3})
+	initiators := map[string]string{}
+	for _, kind := range AssetTypes {
+		for _, r := range *resp.Responses(kind) {
+			initiators[r.URL[len(server.URL):]] = r.Initiator
+		}
+	}
+	want := map[string]string{
+		"/css/site.css":       "",
+		"/css/parts/base.css": server.URL + "/css/site.css",
+		"/img/hero.png":       server.URL + "/css/site.css",
+		"/css/parts/deep.css": server.URL + "/css/parts/base.css",
+		"/fonts/a.woff2":      server.URL + "/css/parts/base.css",
+		"/css/parts/x.png":    server.URL + "/css/parts/deep.css",
+	}
+	for path, initiator := range want {
+		if got, ok := initiators[path]; !ok || got != initiator {
+			t.Errorf("initiator of %s = %q, want %q", path, got, initiator)
+		}
+	}
+}
+
+// checkPaths compares the paths of resps, relative to base, with want in any order
+func checkPaths(t *testing.T, depth int, kind string, resps []FetchResponse, base string, want []string) {
+	t.Helper()
+	got := make([]string, 0, len(resps))
+	for _, r := range resps {
+		got = append(got, r.URL[len(base):])
+	}
+	want = append([]string{}, want...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Errorf("depth %d: %s = %v, want %v", depth, kind, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("depth %d: %s = %v, want %v", depth, kind, got, want)
+			return
+		}
+	}
+}
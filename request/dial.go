@@ -0,0 +1,157 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync/atomic"
+)
+
+/*
+DialOptions chooses the endpoints of connections.  The url, and so the Host header and
+TLS server name, stay those of the request whatever is dialed.
+
+Structure Overview
+  - Target - "unix:///path/to/socket" or "tcp://host:port" dialed for every connection instead
+    of the url's host, e.g. a sidecar or a local service.  Connections to a target bypass proxies.
+  - Sources - local IPs or network interface names outgoing connections are bound to, taken in
+    turn so that connections spread over them
+*/
+type DialOptions struct {
+	Target  string   `json:"target"`
+	Sources []string `json:"sources"`
+}
+
+// sourceTurn picks the source address of the next connection, shared by all the transports
+// so that the connections of every virtual user spread over the sources
+var sourceTurn atomic.Uint64
+
+// Validate checks the dial target and that every source is an IP or an interface with addresses
+func (o *DialOptions) Validate() error {
+	if o.Target != "" {
+		network, addr, err := o.target()
+		if err != nil {
+			return err
+		}
+		if network == DialSchemeUnix && len(o.Sources) > 0 {
+			return fmt.Errorf("source addresses cannot be used with unix socket %s", addr)
+		}
+	}
+	_, err := o.sourceIPs()
+	return err
+}
+
+// target returns the network and address of the dial target
+func (o *DialOptions) target() (network, addr string, err error) {
+	u, err := url.Parse(o.Target)
+	if err == nil {
+		switch u.Scheme {
+		case DialSchemeUnix:
+			if u.Host == "" && u.Path != "" {
+				return DialSchemeUnix, u.Path, nil
+			}
+		case DialSchemeTCP:
+			if host, port, splitErr := net.SplitHostPort(u.Host); splitErr == nil && host != "" && validPort(port) {
+				return DialSchemeTCP, u.Host, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("invalid dial target %q (expected unix:///path or tcp://host:port)", o.Target)
+}
+
+// sourceIPs returns the addresses of the sources; an interface contributes its unicast
+// addresses except link-local ones
+func (o *DialOptions) sourceIPs() ([]net.IP, error) {
+	var ips []net.IP
+	for _, source := range o.Sources {
+		if ip := net.ParseIP(source); ip != nil {
+			ips = append(ips, ip)
+			continue
+		}
+		iface, err := net.InterfaceByName(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source address %q (expected an IP or a network interface)", source)
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("source interface %s: %w", source, err)
+		}
+		count := len(ips)
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+				ips = append(ips, ipNet.IP)
+			}
+		}
+		if len(ips) == count {
+			return nil, fmt.Errorf("source interface %s has no usable address", source)
+		}
+	}
+	return ips, nil
+}
+
+// dialFunc returns the dial function of a transport: connections go to the target, else
+// to the addresses resolved following resolve, from the next source address
+func (o *DialOptions) dialFunc(resolve ResolveOptions, dialer *net.Dialer) dialFunc {
+	network, target := "", ""
+	if o.Target != "" {
+		var err error
+		if network, target, err = o.target(); err != nil {
+			return failedDial(err)
+		}
+	}
+	if network == DialSchemeUnix {
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, DialSchemeUnix, target)
+		}
+	}
+
+	dial := dialer.DialContext
+	if len(o.Sources) > 0 {
+		ips, err := o.sourceIPs()
+		if err != nil {
+			return failedDial(err)
+		}
+		dial = newSourceDialer(ips, dialer)
+	}
+	dial = newHostDialer(resolve, dialer, dial)
+	if target == "" {
+		return dial
+	}
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dial(ctx, network, target)
+	}
+}
+
+// failedDial returns a dial function failing with err, for options NewTransport cannot apply
+func failedDial(err error) dialFunc {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, err
+	}
+}
+
+// newSourceDialer returns a dial function binding each connection to the next of ips able
+// to reach the address: an IPv4 source cannot dial an IPv6 address and the reverse
+func newSourceDialer(ips []net.IP, dialer *net.Dialer) dialFunc {
+	dialers := make([]*net.Dialer, len(ips))
+	for i, ip := range ips {
+		bound := *dialer
+		bound.LocalAddr = &net.TCPAddr{IP: ip}
+		dialers[i] = &bound
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		remote := net.ParseIP(host)
+		start := sourceTurn.Add(1) - 1
+		for i := range uint64(len(dialers)) {
+			bound := dialers[(start+i)%uint64(len(dialers))]
+			if remote == nil || (remote.To4() == nil) == (bound.LocalAddr.(*net.TCPAddr).IP.To4() == nil) {
+				return bound.DialContext(ctx, network, addr)
+			}
+		}
+		return nil, fmt.Errorf("no source address can reach %s", addr)
+	}
+}
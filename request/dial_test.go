@@ -0,0 +1,113 @@
+package request
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+func TestDialOptions(t *testing.T) {
+	color.Green("~~ TestDialOptions ~~")
+	var (
+		mu      sync.Mutex
+		hosts   []string
+		remotes []string
+	)
+	handler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		remotes = append(remotes, r.RemoteAddr[:max(strings.LastIndex(r.RemoteAddr, ":"), 0)])
+		mu.Unlock()
+	})
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	unixListener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unixServer := httptest.NewUnstartedServer(handler)
+	unixServer.Listener = unixListener
+	unixServer.Start()
+	defer unixServer.Close()
+	tcpListener, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcpServer := httptest.NewUnstartedServer(handler)
+	tcpServer.Listener = tcpListener
+	tcpServer.Start()
+	defer tcpServer.Close()
+	port := strconv.Itoa(tcpListener.Addr().(*net.TCPAddr).Port)
+
+	tests := []struct {
+		name    string
+		url     string
+		opts    DialOptions
+		proxy   ProxyOptions
+		host    string
+		remotes string
+	}{
+		{"unix socket", "http://sidecar.test/", DialOptions{Target: "unix://" + socket},
+			ProxyOptions{URL: "http://127.0.0.1:1"}, "sidecar.test", ""},
+		{"tcp target", "http://api.test:8080/", DialOptions{Target: "tcp://127.0.0.1:" + port},
+			ProxyOptions{URL: "http://127.0.0.1:1"}, "api.test:8080", "127.0.0.1,127.0.0.1,127.0.0.1,127.0.0.1"},
+		{"sources in turn", "http://127.0.0.1:" + port + "/",
+			DialOptions{Sources: []string{"127.0.0.2", "127.0.0.3"}}, ProxyOptions{URL: ProxyDirect},
+			"127.0.0.1:" + port, "127.0.0.2,127.0.0.3,127.0.0.2,127.0.0.3"},
+		{"sources skip other families", "http://127.0.0.1:" + port + "/",
+			DialOptions{Sources: []string{"::1", "127.0.0.2"}}, ProxyOptions{URL: ProxyDirect},
+			"127.0.0.1:" + port, "127.0.0.2,127.0.0.2,127.0.0.2,127.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			hosts, remotes = nil, nil
+			mu.Unlock()
+			sourceTurn.Store(0)
+			cfg := DefaultTransportConfig()
+			cfg.DisableKeepAlives = true
+			cfg.Dial, cfg.Proxy = tt.opts, tt.proxy
+			client := NewClient(cfg)
+			for range 4 {
+				if resp := Fetch(FetchInput{BaseURL: tt.url, Client: client}); resp.Status != http.StatusOK {
+					t.Fatalf("status %d: %s", resp.Status, resp.URL)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if hosts[0] != tt.host || (tt.remotes != "" && strings.Join(remotes, ",") != tt.remotes) {
+				t.Errorf("Host header %q, connected from %v", hosts[0], remotes)
+			}
+		})
+	}
+}
+
+func TestDialOptionsValidate(t *testing.T) {
+	for name, opts := range map[string]DialOptions{
+		"unix with sources":   {Target: "unix:///run/app.sock", Sources: []string{"127.0.0.1"}},
+		"unix without a path": {Target: "unix://"},
+		"tcp without a port":  {Target: "tcp://127.0.0.1"},
+		"tcp without a host":  {Target: "tcp://:8080"},
+		"unknown scheme":      {Target: "udp://127.0.0.1:53"},
+		"unknown interface":   {Sources: []string{"no-such-interface0"}},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate with %s should fail", name)
+		}
+	}
+	for _, opts := range []DialOptions{
+		{},
+		{Target: "unix:///run/app.sock"},
+		{Target: "tcp://backend.test:8080", Sources: []string{"10.0.0.1", "::1"}},
+	} {
+		if err := opts.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", opts, err)
+		}
+	}
+}
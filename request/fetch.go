@@ -1,11 +1,18 @@
 package request
 
 import (
+	"crypto/tls"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
 )
 
 /*
@@ -16,6 +23,21 @@ Structure Overview
   - Retdat - if true then the document data is returned
   - Cookies - a cookie string to set on each request
   - UserAge - default is golang, but can be set to anything.`
+  - Client - the http.Client (and therefore connection pool) to use.  A shared default is used when nil.
+  - Browser - if set FetchAll limits concurrent asset fetches per origin like a browser does.
+  - Cache - if set responses are served from and stored in this per-user HTTP cache.
+  - Parser - extracts asset urls for FetchAll.  httputils.GetAssets is used when nil.
+  - CSSDepth - how many levels of stylesheet sub-resources (@import, fonts, url()) FetchAll follows.  0 disables it.
+  - Filter - if set FetchAll only requests the assets it allows and reports the others as skipped.
+  - Method - the request method, GET when empty.  FetchAll uses it for the page only; assets are always GETs.
+  - Header - extra request headers.  Like Method they only apply to the page in FetchAll.
+    They replace the User-Agent and Cookie headers set from UserAgent and Cookies.
+  - Body - the request body, none when empty.
+  - Auth - if set it authenticates the request once its headers and body are set.  Like Header it
+    only applies to the page in FetchAll.  The time spent, e.g. fetching an OAuth2 token, is not
+    part of the response time.
+  - GraphQL - if true the page is a GraphQL request: a response listing errors fails like an
+    error status does (FetchResponse.GraphQLErrors) and FetchAll looks for no assets.
 */
 type FetchInput struct {
 	BaseURL   string
@@ -23,6 +45,17 @@ type FetchInput struct {
 	Cookies   string
 	Headers   string
 	UserAgent string
+	Client    *http.Client
+	Browser   *BrowserOptions
+	Cache     *HTTPCache
+	Parser    interfaces.AssetParser
+	CSSDepth  int
+	Filter    *AssetFilter
+	Method    string
+	Header    http.Header
+	Body      string
+	Auth      interfaces.AuthProvider
+	GraphQL   bool
 }
 
 /*
@@ -35,19 +68,45 @@ Structure Overview:
   - Bytes - The number of bytes returned
   - Runes - The number of runes returned
   - Time - How long the Resp took.
+  - QueueTime - How long the request waited for a free per-origin slot before it was sent (browser mode).
   - Statue - the HttpResp status code.
-  - Error - Any errors that were returned
+  - ConnReused - true if the request was sent on a pooled keep-alive connection
+  - Proxied - true if the request was sent through a proxy
+  - Protocol - the protocol the response came over, e.g. "HTTP/1.1" or "HTTP/2.0"
+  - TLSVersion - the negotiated TLS version, e.g. "TLS 1.3"; empty for plain HTTP
+  - TLSResumed - true if the TLS session of the connection was resumed from a ticket
+  - CacheStatus - "miss", "hit" or "revalidated" when an HTTPCache is in use
+  - Timings - DNS, connect, proxy, TLS, wait and download phases of the request
+  - StartOffset/EndOffset - when the request was sent and finished relative to the page's base request (FetchAll)
+  - Started/Finished - wall clock times the request was sent and its body was read
+  - Initiator - the stylesheet that referenced the asset (FetchAll); empty for assets of the page itself
+  - GraphQLErrors - the number of errors a GraphQL response listed (FetchInput.GraphQL)
+  - Error - Any errors that were returned; the first error listed by a GraphQL response
 */
 type FetchResponse struct {
-	URL     string              `json:"url"`
-	Resp    *http.Response      `json:"resp"`
-	Body    string              `json:"body"`
-	Headers map[string][]string `json:"headers"`
-	Bytes   int                 `json:"bytes"`
-	Runes   int                 `json:"runes"`
-	Time    time.Duration       `json:"time"`
-	Status  int                 `json:"status"`
-	Error   string              `json:"error"`
+	URL           string              `json:"url"`
+	Resp          *http.Response      `json:"-"`
+	Body          string              `json:"body"`
+	Headers       map[string][]string `json:"headers"`
+	Bytes         int                 `json:"bytes"`
+	Runes         int                 `json:"runes"`
+	Time          time.Duration       `json:"time"`
+	QueueTime     time.Duration       `json:"queueTime"`
+	Status        int                 `json:"status"`
+	ConnReused    bool                `json:"connReused"`
+	Proxied       bool                `json:"proxied,omitempty"`
+	Protocol      string              `json:"protocol,omitempty"`
+	TLSVersion    string              `json:"tlsVersion,omitempty"`
+	TLSResumed    bool                `json:"tlsResumed,omitempty"`
+	CacheStatus   string              `json:"cacheStatus,omitempty"`
+	Timings       Timings             `json:"timings"`
+	StartOffset   time.Duration       `json:"startOffset"`
+	EndOffset     time.Duration       `json:"endOffset"`
+	Started       time.Time           `json:"-"`
+	Finished      time.Time           `json:"-"`
+	Initiator     string              `json:"initiator,omitempty"`
+	GraphQLErrors int                 `json:"graphqlErrors,omitempty"`
+	Error         string              `json:"error"`
 }
 
 /*
@@ -60,9 +119,30 @@ func Fetch(input FetchInput) *FetchResponse {
 	cookies := input.Cookies
 	headers := strings.Split(input.Headers, "=")
 
-	// Set up the http request
-	client := &http.Client{}
-	req, _ := http.NewRequest("GET", url, http.NoBody)
+	// Set up the http request on the caller's connection pool
+	client := input.Client
+	if client == nil {
+		client = defaultClient
+	}
+	method := input.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var reqBody io.Reader = http.NoBody
+	if input.Body != "" {
+		reqBody = strings.NewReader(input.Body)
+	}
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		now := time.Now()
+		return &FetchResponse{
+			URL:      err.Error(),
+			Status:   HTTPStatusConnectionError,
+			Error:    ErrorRequestFailed,
+			Started:  now,
+			Finished: now,
+		}
+	}
 
 	// Set the header only if we have a valid key=value format
 	if len(headers) >= 2 && headers[0] != DefaultEmptyString {
@@ -77,15 +157,64 @@ func Fetch(input FetchInput) *FetchResponse {
 	// csrftoken_vagrant=taZjH9jskTjfbvDDq7OzdtQnTaB72zIk"
 	req.Header.Add(CookieHeader, cookies)
 
+	for name, values := range input.Header {
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	// Serve fresh responses from the cache and revalidate stale ones.  Only GETs are cacheable.
+	cache := input.Cache
+	if method != http.MethodGet {
+		cache = nil
+	}
+	var cached *cacheEntry
+	if cache != nil {
+		entry, fresh := cache.lookup(url, req.Header, retdat)
+		if fresh {
+			return cachedResponse(url, entry, retdat)
+		}
+		if entry != nil {
+			entry.addValidators(req)
+			cached = entry
+		}
+	}
+
+	// Authenticate before tracing, so requests of the provider (e.g. for an OAuth2 token)
+	// are not recorded as phases of this one
+	if input.Auth != nil {
+		if err := input.Auth.Authenticate(req); err != nil {
+			now := time.Now()
+			return &FetchResponse{
+				URL:      err.Error(),
+				Status:   HTTPStatusConnectionError,
+				Error:    ErrorAuth,
+				Started:  now,
+				Finished: now,
+			}
+		}
+	}
+
+	// Record connection reuse, the proxy and the DNS/connect/proxy/TLS/wait/download phases
+	tracer := &phaseTracer{}
+	req = req.WithContext(tracer.withContext(req.Context()))
+
 	// Fetch the url and time the request
 	start := time.Now()
 	resp, err := client.Do(req)
 
 	if err != nil {
+		finished := time.Now()
+		timings, _, proxied := tracer.finish(finished)
 		return &FetchResponse{
-			URL:    err.Error(),
-			Status: HTTPStatusConnectionError,
-			Error:  ErrorRequestFailed,
+			URL:      err.Error(),
+			Status:   HTTPStatusConnectionError,
+			Error:    ErrorRequestFailed,
+			Started:  start,
+			Finished: finished,
+			Timings:  timings,
+			Proxied:  proxied,
 		}
 	}
 	defer resp.Body.Close()
@@ -93,6 +222,8 @@ func Fetch(input FetchInput) *FetchResponse {
 
 	// Read the html 'body' content from the response object
 	body, err := io.ReadAll(resp.Body)
+	finished := time.Now()
+	timings, reused, proxied := tracer.finish(finished)
 	Error := ""
 	if err != nil {
 		body = []byte("")
@@ -102,15 +233,40 @@ func Fetch(input FetchInput) *FetchResponse {
 	responseBody := string(body)
 
 	output := FetchResponse{
-		URL:     url,
-		Resp:    resp,
-		Body:    responseBody,
-		Headers: resp.Header,
-		Bytes:   len(responseBody),
-		Runes:   utf8.RuneCountInString(responseBody),
-		Time:    responseTime,
-		Status:  resp.StatusCode,
-		Error:   Error,
+		URL:        url,
+		Resp:       resp,
+		Body:       responseBody,
+		Headers:    resp.Header,
+		Bytes:      len(responseBody),
+		Runes:      utf8.RuneCountInString(responseBody),
+		Time:       responseTime,
+		Status:     resp.StatusCode,
+		ConnReused: reused,
+		Proxied:    proxied,
+		Protocol:   resp.Proto,
+		Error:      Error,
+		Started:    start,
+		Finished:   finished,
+		Timings:    timings,
+	}
+	if resp.TLS != nil {
+		output.TLSVersion = tls.VersionName(resp.TLS.Version)
+		output.TLSResumed = resp.TLS.DidResume
+	}
+	if input.GraphQL && Error == "" {
+		output.GraphQLErrors, output.Error = graphQLError(body)
+	}
+
+	if cache != nil {
+		output.CacheStatus = CacheStatusMiss
+		if cached != nil && resp.StatusCode == http.StatusNotModified {
+			// Nothing but headers crossed the wire; the document comes from the cache
+			cache.refresh(url, resp)
+			output.CacheStatus = CacheStatusRevalidated
+			output.Body = cached.body
+		} else {
+			cache.store(url, req.Header, resp, responseBody, retdat)
+		}
 	}
 
 	if !retdat { // we don't want the document data or headers
@@ -120,3 +276,61 @@ func Fetch(input FetchInput) *FetchResponse {
 	// Close the response body and return the output
 	return &output
 }
+
+// FinalURL returns the URL the response was served from after redirects
+func (r *FetchResponse) FinalURL() string {
+	if r.Resp != nil && r.Resp.Request != nil && r.Resp.Request.URL != nil {
+		return r.Resp.Request.URL.String()
+	}
+	return r.URL
+}
+
+// cachedResponse builds the FetchResponse for a fresh cache hit; no network time or bytes are spent
+func cachedResponse(url string, entry *cacheEntry, retdat bool) *FetchResponse {
+	now := time.Now()
+	output := &FetchResponse{
+		URL:         url,
+		Headers:     entry.header,
+		Status:      entry.status,
+		CacheStatus: CacheStatusHit,
+		Started:     now,
+		Finished:    now,
+	}
+	if retdat {
+		output.Body = entry.body
+	} else {
+		output.Headers = make(map[string][]string)
+	}
+	return output
+}
+
+/*
+PrintFetchResponse takes a FetchResponse object and prints the results to stdout
+*/
+func PrintFetchResponse(resp *FetchResponse) {
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	fmt.Print(resp.Body)
+
+	color.Red("Fetch Results")
+	fmt.Printf(" - %-34s %-25s\n", yel("Status:"), white(strconv.Itoa(resp.Status)))
+	fmt.Printf(" - %-34s %-25s\n", yel("Url:"), white(resp.URL))
+	fmt.Printf(" - %-34s %-25s\n", yel("Protocol"), white(resp.Protocol))
+	if resp.TLSVersion != "" {
+		fmt.Printf(" - %-34s %-25s\n", yel("TLS Version"), white(resp.TLSVersion))
+	}
+	fmt.Printf(" - %-34s %-25s\n", yel("Time to first byte"), resp.Time.String())
+	fmt.Printf(" - %-34s %-25s\n", yel("DNS / Connect / TLS"),
+		fmt.Sprintf("%s / %s / %s", resp.Timings.DNS, resp.Timings.Connect, resp.Timings.TLS))
+	if resp.Proxied {
+		fmt.Printf(" - %-34s %-25s\n", yel("Proxy Tunnel"), resp.Timings.Proxy.String())
+	}
+	fmt.Printf(" - %-34s %-25s\n", yel("Wait / Download"),
+		fmt.Sprintf("%s / %s", resp.Timings.Wait, resp.Timings.Download))
+	fmt.Printf(" - %-34s %-25s\n", yel("Bytes"), strconv.Itoa(resp.Bytes))
+	fmt.Printf(" - %-34s %-25s\n", yel("Runes"), strconv.Itoa(resp.Runes))
+	if resp.Error != DefaultEmptyString {
+		fmt.Printf(" - %-34s %-25s\n", yel("Error"), white(resp.Error))
+	}
+}
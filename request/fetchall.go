@@ -2,25 +2,40 @@ package request
 
 import (
 	"fmt"
-	"net/url"
+	"log"
 	"strconv"
 	"time"
 
 	"github.com/gnulnx/color"
 
 	"github.com/Gosayram/goperf/httputils"
+	"github.com/Gosayram/goperf/interfaces"
 )
 
 // FetchAllResponse is the return structure from FetchAll
 type FetchAllResponse struct {
-	BaseURL         *FetchResponse  `json:"BaseURL"`
-	Time            time.Duration   `json:"time"`
-	TotalTime       time.Duration   `json:"totalTime"`
-	TotalLinearTime time.Duration   `json:"totalLinearTime"`
-	TotalBytes      int             `json:"totalBytes"`
-	JSResponses     []FetchResponse `json:"jsResponses"`
-	IMGResponses    []FetchResponse `json:"imgResponses"`
-	CSSResponses    []FetchResponse `json:"cssResponses"`
+	BaseURL           *FetchResponse  `json:"BaseURL"`
+	Time              time.Duration   `json:"time"`
+	TotalTime         time.Duration   `json:"totalTime"`
+	TotalLinearTime   time.Duration   `json:"totalLinearTime"`
+	TotalQueueTime    time.Duration   `json:"totalQueueTime"`
+	TotalBytes        int             `json:"totalBytes"`
+	TotalRequests     int             `json:"totalRequests"`
+	ReusedConns       int             `json:"reusedConns"`
+	Protocols         ProtocolCounts  `json:"protocols,omitempty"`
+	TLS               TLSCounts       `json:"tls,omitempty"`
+	Proxy             ProxyStats      `json:"proxy"`
+	NotModified       int             `json:"notModified"`
+	CacheHits         int             `json:"cacheHits"`
+	JSResponses       []FetchResponse `json:"jsResponses"`
+	IMGResponses      []FetchResponse `json:"imgResponses"`
+	CSSResponses      []FetchResponse `json:"cssResponses"`
+	FontResponses     []FetchResponse `json:"fontResponses"`
+	MediaResponses    []FetchResponse `json:"mediaResponses"`
+	IconResponses     []FetchResponse `json:"iconResponses"`
+	DocumentResponses []FetchResponse `json:"documentResponses"` // iframes and frames, not the base page
+	OtherResponses    []FetchResponse `json:"otherResponses"`
+	Skipped           []SkippedAsset  `json:"skipped,omitempty"`
 
 	Body string `json:"body"`
 }
@@ -29,9 +44,15 @@ type FetchAllResponse struct {
 FetchAll takes a FetchInput object and proceeds to fetch
 the BaseURL and then fetch all of it's assets.
 
-Assets currently refer to script, style, and img tags.
+Assets are everything a browser would load for the page: scripts, stylesheets,
+images, fonts, media, icons, frames and other resources (see AssetTypes).
 
 Each asset class is fetched in it's own go routine.
+Stylesheets are parsed as they arrive and the fonts, images and stylesheets they
+reference are fetched too, up to input.CSSDepth levels deep (see fetchStylesheets).
+When input.Browser is set the number of in-flight asset requests per origin is
+capped the way a browser caps them and the rest are queued.
+Assets rejected by input.Filter are not requested and are listed in Skipped instead.
 If retdata is False we don't return the Body or Header.
 This is useful if you only want the timing data.
 For instance you might find it useful to fetch with retdat=true
@@ -48,66 +69,108 @@ func FetchAll(input FetchInput) *FetchAllResponse {
 	input.Retdat = true
 	output := Fetch(input)
 
-	// Now parse output for js, css, img urls
-	jsfiles, imgfiles, cssfiles := httputils.GetAssets(output.Body)
-
-	// Now lets create some go routines and fetch all the js, img, css files
-	c1 := make(chan []FetchResponse)
-	c2 := make(chan []FetchResponse)
-	c3 := make(chan []FetchResponse)
-
-	go GoFetchAllAssetArray(jsfiles, input, c1)
-	go GoFetchAllAssetArray(imgfiles, input, c2)
-	go GoFetchAllAssetArray(cssfiles, input, c3)
-
-	jsResponses := []FetchResponse{}
-	imgResponses := []FetchResponse{}
-	cssResponses := []FetchResponse{}
+	// Now parse output for assets and resolve them the way a browser would.  GraphQL responses have none.
+	files := map[string][]string{}
+	if !input.GraphQL {
+		files = resolveAssets(output, httputils.GetBaseHref(output.Body), parseAssets(input.Parser, output.Body))
+	}
 
-	for i := 0; i < AssetTypesCount; i++ {
-		select {
-		case jsResponses = <-c1:
-		case imgResponses = <-c2:
-		case cssResponses = <-c3:
+	// Browsers share their per-origin connection budget across every asset class
+	var limiter *OriginLimiter
+	if input.Browser != nil {
+		limiter = NewOriginLimiter(*input.Browser)
+		if output.Resp != nil && output.Resp.ProtoMajor == HTTP2ProtoMajor {
+			limiter.MarkMultiplexed(output.URL)
 		}
 	}
+
+	// Assets are plain GETs, whatever the page request was
+	input.Method, input.Header, input.Auth, input.GraphQL = "", nil, nil, false
+	resp := FetchAllResponse{BaseURL: output}
+	resp.fetchAssets(output, files, input, limiter)
 	totalTime2 := time.Since(start)
 
+	// Place every request on the page timeline relative to the base request
+	base := output.Started
+	if base.IsZero() {
+		base = start
+	}
+	output.StartOffset = output.Started.Sub(base)
+	output.EndOffset = output.Finished.Sub(base)
+	for _, kind := range AssetTypes {
+		setOffsets(base, *resp.Responses(kind))
+	}
+
 	if !retdat {
 		output.Body = DefaultEmptyString
 		output.Headers = make(map[string][]string)
 	}
 
+	resp.tally()
+	resp.TotalTime = totalTime2
+	resp.Body = output.Body
+
+	return &resp
+}
+
+// tally sums the time, bytes, requests, reused connections, protocols, TLS handshakes, proxy
+// connections and cache results of the page and its assets
+func (r *FetchAllResponse) tally() {
+	output := r.BaseURL
+	reusedConns, notModified, cacheHits := 0, 0, 0
+	protocols := ProtocolCounts{}
+	versions := TLSCounts{}
+	proxy := ProxyStats{}
+	countConn := func(val *FetchResponse) {
+		if val.ConnReused {
+			reusedConns++
+		}
+		protocols.Add(val)
+		versions.Add(val)
+		proxy.Add(val)
+		switch val.CacheStatus {
+		case CacheStatusRevalidated:
+			notModified++
+		case CacheStatusHit:
+			cacheHits++
+		}
+	}
+	countConn(output)
+	totalQueueTime := time.Duration(0)
 	calcTotal := func(resp []FetchResponse) (time.Duration, int) {
 		totalTime := time.Duration(0)
 		totalBytes := 0
-		for _, val := range resp {
-			totalTime += val.Time
-			totalBytes += val.Bytes
+		for i := range resp {
+			totalTime += resp[i].Time
+			totalBytes += resp[i].Bytes
+			totalQueueTime += resp[i].QueueTime
+			countConn(&resp[i])
 		}
 		return totalTime, totalBytes
 	}
 
-	jsTime, jsBytes := calcTotal(jsResponses)
-	cssTime, cssBytes := calcTotal(cssResponses)
-	imgTime, imgBytes := calcTotal(imgResponses)
-
-	totalLinearTime := output.Time + jsTime + cssTime + imgTime
-	totalBytes := output.Bytes + jsBytes + cssBytes + imgBytes
-
-	resp := FetchAllResponse{
-		BaseURL:         output,
-		Time:            output.Time,
-		TotalTime:       totalTime2,
-		TotalLinearTime: totalLinearTime,
-		TotalBytes:      totalBytes,
-		Body:            output.Body,
-		JSResponses:     jsResponses,
-		IMGResponses:    imgResponses,
-		CSSResponses:    cssResponses,
+	totalLinearTime := output.Time
+	totalBytes := output.Bytes
+	totalRequests := 1
+	for _, kind := range AssetTypes {
+		assets := *r.Responses(kind)
+		assetTime, assetBytes := calcTotal(assets)
+		totalLinearTime += assetTime
+		totalBytes += assetBytes
+		totalRequests += len(assets)
 	}
 
-	return &resp
+	r.Time = output.Time
+	r.TotalLinearTime = totalLinearTime
+	r.TotalQueueTime = totalQueueTime
+	r.TotalBytes = totalBytes
+	r.TotalRequests = totalRequests
+	r.ReusedConns = reusedConns
+	r.Protocols = protocols
+	r.TLS = versions
+	r.Proxy = proxy
+	r.NotModified = notModified
+	r.CacheHits = cacheHits
 }
 
 /*
@@ -137,60 +200,165 @@ func PrintFetchAllResponse(resp *FetchAllResponse) {
 	fmt.Printf(" - %-34s %-25s\n", yel("Bytes"), strconv.Itoa(resp.BaseURL.Bytes))
 	fmt.Printf(" - %-34s %-25s\n", yel("Runes"), strconv.Itoa(resp.BaseURL.Runes))
 	fmt.Printf(" - %-34s %-25s\n", yel("TotalTime"), resp.TotalTime.String())
+	fmt.Printf(" - %-34s %-25s\n", yel("TotalQueueTime"), resp.TotalQueueTime.String())
 	fmt.Printf(" - %-34s %-25s\n", yel("TotalBytes"), strconv.Itoa(resp.TotalBytes))
+	fmt.Printf(" - %-34s %-25s\n", yel("Reused Connections"),
+		fmt.Sprintf("%d/%d", resp.ReusedConns, resp.TotalRequests))
+	for _, protocol := range resp.Protocols.Names() {
+		stats := resp.Protocols[protocol]
+		fmt.Printf(" - %-34s %-25s\n", yel(protocol), fmt.Sprintf("%d requests / %d connections",
+			stats.Requests, stats.Connections))
+	}
+	for _, version := range resp.TLS.Versions() {
+		stats := resp.TLS[version]
+		fmt.Printf(" - %-34s %-25s\n", yel(version), fmt.Sprintf("%d handshakes (%d resumed), %s average",
+			stats.Handshakes, stats.Resumed, stats.AvgHandshake()))
+	}
+	if resp.Proxy.Requests > 0 {
+		fmt.Printf(" - %-34s %-25s\n", yel("Proxy Connect / Tunnel"), fmt.Sprintf("%s / %s average, %d connections",
+			resp.Proxy.AvgConnect(), resp.Proxy.AvgTunnel(), resp.Proxy.Connections))
+	}
+	fmt.Printf(" - %-34s %-25s\n", yel("Cache Hits / 304s"), fmt.Sprintf("%d / %d", resp.CacheHits, resp.NotModified))
 
 	printAssets := func(title string, results []FetchResponse) {
 		color.Red(title)
-		fmt.Printf(" - %-24s %-22s %-21s\n", yellow("Time"), yellow("Bytes"), yellow("Url"))
+		fmt.Printf(" - %-24s %-24s %-22s %-21s\n", yellow("Time"), yellow("Queued"), yellow("Bytes"), yellow("Url"))
 		for i, val := range results {
-			if i%2 == 0 {
-				fmt.Printf(" - %-22s %-20s %-10s \n", white(val.Time.String()), white(strconv.Itoa(val.Bytes)), white(val.URL))
-			} else {
-				fmt.Printf(" - %-22s %-20s %-10s \n", grey(val.Time.String()), grey(strconv.Itoa(val.Bytes)), grey(val.URL))
+			paint := white
+			if i%2 != 0 {
+				paint = grey
+			}
+			fmt.Printf(" - %-22s %-22s %-20s %-10s \n", paint(val.Time.String()), paint(val.QueueTime.String()),
+				paint(strconv.Itoa(val.Bytes)), paint(val.URL))
+			if val.Initiator != "" {
+				fmt.Printf("     %s\n", grey("from %s", val.Initiator))
 			}
 		}
 	}
 
-	printAssets("JS Responses", resp.JSResponses)
-	printAssets("CSS Responses", resp.CSSResponses)
-	printAssets("IMG Responses", resp.IMGResponses)
-}
+	for _, kind := range AssetTypes {
+		if assets := *resp.Responses(kind); len(assets) > 0 {
+			printAssets(AssetTypeTitle(kind)+" Responses", assets)
+		}
+	}
 
-// DefineAssetURL is used to determine if an asset is a local resource
-func DefineAssetURL(baseURL, asseturl string) string {
-	/*
-		If the url starts with a / we know it's a local resource
-		so we prepend the baseURL to it
-	*/
-	if asseturl[:HTTPProtocolLength] == HTTPScheme {
-		return asseturl
+	if len(resp.Skipped) > 0 {
+		color.Red("Skipped Assets")
+		fmt.Printf(" - %-18s %-26s %-21s\n", yellow("Type"), yellow("Reason"), yellow("Url"))
+		for i, val := range resp.Skipped {
+			paint := white
+			if i%2 != 0 {
+				paint = grey
+			}
+			fmt.Printf(" - %-16s %-24s %s\n", paint(AssetTypeTitle(val.Type)), paint(val.Reason), paint(val.URL))
+			if val.Initiator != "" {
+				fmt.Printf("     %s\n", grey("from %s", val.Initiator))
+			}
+		}
 	}
+}
 
-	u, err := url.Parse(baseURL)
+// parseAssets extracts the asset references of body with parser, falling back to
+// httputils.GetAllAssets when no parser is set or the parser fails
+func parseAssets(parser interfaces.AssetParser, body string) *interfaces.Assets {
+	if parser == nil {
+		return httputils.GetAllAssets(body)
+	}
+	assets, err := parser.ParseAssets(body)
 	if err != nil {
-		panic(err)
+		log.Printf("Asset parser failed, falling back to the default parser: %v", err)
+		return httputils.GetAllAssets(body)
 	}
-	baseURL = fmt.Sprintf("%s://%s", u.Scheme, u.Hostname())
+	return assets
+}
 
-	if asseturl[FirstPathElement] == '/' {
-		asseturl = baseURL + asseturl
-	} else {
-		asseturl = baseURL + PathSeparator + asseturl
+// fetchAssets fetches the page's assets, a go routine per asset class, and stores the responses in r.
+// Stylesheets are followed by their sub-resources, which are added to the responses of their class.
+func (r *FetchAllResponse) fetchAssets(output *FetchResponse, files map[string][]string, input FetchInput,
+	limiter *OriginLimiter) {
+	page := output.FinalURL()
+	seen := map[string]bool{output.URL: true, page: true}
+	for _, kind := range AssetTypes {
+		for _, assetURL := range files[kind] {
+			seen[assetURL] = true
+		}
+		var skipped []SkippedAsset
+		files[kind], skipped = input.Filter.filter(kind, files[kind], page, "")
+		r.Skipped = append(r.Skipped, skipped...)
 	}
-	return asseturl
+
+	type stylesheetResult struct {
+		found   map[string][]FetchResponse
+		skipped []SkippedAsset
+	}
+	sheets := make(chan stylesheetResult, 1)
+	go func() {
+		found, skipped := fetchStylesheets(files[AssetTypeCSS], input, limiter, seen, page)
+		sheets <- stylesheetResult{found, skipped}
+	}()
+	chans := make(map[string]chan []FetchResponse, AssetTypesCount)
+	for _, kind := range AssetTypes {
+		if kind == AssetTypeCSS {
+			continue
+		}
+		chans[kind] = make(chan []FetchResponse)
+		go GoFetchAllAssetArray(files[kind], input, limiter, chans[kind])
+	}
+
+	for kind, c := range chans {
+		*r.Responses(kind) = <-c
+	}
+	result := <-sheets
+	for kind, resps := range result.found {
+		*r.Responses(kind) = append(*r.Responses(kind), resps...)
+	}
+	r.Skipped = append(r.Skipped, result.skipped...)
 }
 
-// GoFetchAllAssetArray fetches all assets from the provided URLs concurrently
-// It creates goroutines for each asset URL and returns the results via a channel
-func GoFetchAllAssetArray(files []string, input FetchInput, resp chan []FetchResponse) {
-	baseURL := input.BaseURL
+// fetchAssets fetches files concurrently and returns the responses in the same order
+func fetchAssets(files []string, input FetchInput, limiter *OriginLimiter) []FetchResponse {
+	c := make(chan []FetchResponse)
+	go GoFetchAllAssetArray(files, input, limiter, c)
+	return <-c
+}
 
+// resolveAssets turns the raw asset references of the page in output into absolute,
+// de-duplicated URLs keyed by asset type. Relative references resolve against the final
+// page URL after redirects, or against the document's <base href> when it has one.
+func resolveAssets(output *FetchResponse, baseHref string, assets *interfaces.Assets) map[string][]string {
+	files := make(map[string][]string, AssetTypesCount)
+	resolver, err := NewAssetResolver(output.FinalURL(), baseHref)
+	if err != nil {
+		return files
+	}
+	refs := assetsByType(assets)
+	for _, kind := range resolveOrder {
+		files[kind] = resolver.Resolve(refs[kind])
+	}
+	return files
+}
+
+// GoFetchAllAssetArray fetches all assets from the provided absolute URLs concurrently
+// It creates goroutines for each asset URL and returns the results via a channel.
+// If limiter is not nil each fetch waits for a free per-origin slot first and
+// the wait is recorded as the response's QueueTime.
+func GoFetchAllAssetArray(files []string, input FetchInput, limiter *OriginLimiter, resp chan []FetchResponse) {
 	chanHolder := []chan FetchResponse{}
 	for i, assetURL := range files {
 		chanHolder = append(chanHolder, make(chan FetchResponse))
 		go func(c chan FetchResponse, assetURL string, input FetchInput) {
-			input.BaseURL = DefineAssetURL(baseURL, assetURL)
-			c <- *Fetch(input)
+			input.BaseURL = assetURL
+			if limiter == nil {
+				c <- *Fetch(input)
+				return
+			}
+			queued := time.Now()
+			limiter.Acquire(input.BaseURL)
+			queueTime := time.Since(queued)
+			output := Fetch(input)
+			limiter.Release(input.BaseURL, output.Resp != nil && output.Resp.ProtoMajor == HTTP2ProtoMajor)
+			output.QueueTime = queueTime
+			c <- *output
 		}(chanHolder[i], assetURL, input)
 	}
 
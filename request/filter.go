@@ -0,0 +1,215 @@
+package request
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+/*
+FilterOptions selects the assets FetchAll requests.  The base page is always fetched.
+
+Structure Overview
+  - SameOrigin - only fetch assets served from the page's own scheme, host and port
+  - AllowHosts - if set only assets from these hosts are fetched
+  - DenyHosts - assets from these hosts are never fetched
+  - Include - if set only assets whose URL matches one of these patterns are fetched
+  - Exclude - assets whose URL matches one of these patterns are never fetched
+  - Types - if set only these asset types (see AssetTypes) are fetched
+  - SkipTypes - these asset types are never fetched
+
+A host matches itself and all of its subdomains, so "example.com" and "*.example.com"
+both cover "cdn.example.com".  URL patterns are globs where * matches any run of
+characters and ? a single one, or regular expressions when prefixed with "re:".
+*/
+type FilterOptions struct {
+	SameOrigin bool     `json:"same_origin"`
+	AllowHosts []string `json:"allow_hosts"`
+	DenyHosts  []string `json:"deny_hosts"`
+	Include    []string `json:"include"`
+	Exclude    []string `json:"exclude"`
+	Types      []string `json:"types"`
+	SkipTypes  []string `json:"skip_types"`
+}
+
+// AssetFilter applies FilterOptions to asset URLs. It is immutable and safe for concurrent use.
+type AssetFilter struct {
+	sameOrigin bool
+	allowHosts []string
+	denyHosts  []string
+	include    []*regexp.Regexp
+	exclude    []*regexp.Regexp
+	types      []string
+	skipTypes  []string
+}
+
+/*
+SkippedAsset is an asset FetchAll did not request because of the AssetFilter.
+
+Structure Overview
+  - URL, Type - the asset and its type
+  - Reason - which rule skipped it, one of the SkipReason constants
+  - Initiator - the stylesheet that referenced the asset; empty for assets of the page itself
+*/
+type SkippedAsset struct {
+	URL       string `json:"url"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	Initiator string `json:"initiator,omitempty"`
+}
+
+// NewAssetFilter validates opts and compiles its URL patterns
+func NewAssetFilter(opts FilterOptions) (*AssetFilter, error) {
+	f := &AssetFilter{
+		sameOrigin: opts.SameOrigin,
+		allowHosts: normalizeHosts(opts.AllowHosts),
+		denyHosts:  normalizeHosts(opts.DenyHosts),
+	}
+	for _, kinds := range []struct {
+		dst *[]string
+		src []string
+	}{{&f.types, opts.Types}, {&f.skipTypes, opts.SkipTypes}} {
+		for _, kind := range kinds.src {
+			kind = strings.ToLower(strings.TrimSpace(kind))
+			if !slices.Contains(AssetTypes, kind) {
+				return nil, fmt.Errorf("unknown asset type %q (want one of %s)", kind, strings.Join(AssetTypes, ", "))
+			}
+			*kinds.dst = append(*kinds.dst, kind)
+		}
+	}
+	var err error
+	if f.include, err = compilePatterns(opts.Include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compilePatterns(opts.Exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+/*
+Skip returns why the asset of type kind at assetURL must not be fetched for the
+page at pageURL, or an empty string if it may be fetched.  A nil filter allows
+everything.  Rules are checked in the order: type, origin, host, URL pattern.
+*/
+func (f *AssetFilter) Skip(kind, assetURL, pageURL string) string {
+	if f == nil {
+		return ""
+	}
+	if (len(f.types) > 0 && !slices.Contains(f.types, kind)) || slices.Contains(f.skipTypes, kind) {
+		return SkipReasonType
+	}
+	if f.sameOrigin && originOf(assetURL) != originOf(pageURL) {
+		return SkipReasonCrossOrigin
+	}
+	host := hostOf(assetURL)
+	if matchHost(f.denyHosts, host) {
+		return SkipReasonDeniedHost
+	}
+	if len(f.allowHosts) > 0 && !matchHost(f.allowHosts, host) {
+		return SkipReasonHostNotAllowed
+	}
+	if matchAny(f.exclude, assetURL) {
+		return SkipReasonExcluded
+	}
+	if len(f.include) > 0 && !matchAny(f.include, assetURL) {
+		return SkipReasonNotIncluded
+	}
+	return ""
+}
+
+// filter splits urls into the ones to fetch and the ones f skips
+func (f *AssetFilter) filter(kind string, urls []string, pageURL, initiator string) ([]string, []SkippedAsset) {
+	if f == nil {
+		return urls, nil
+	}
+	keep := make([]string, 0, len(urls))
+	var skipped []SkippedAsset
+	for _, assetURL := range urls {
+		if reason := f.Skip(kind, assetURL, pageURL); reason != "" {
+			skipped = append(skipped, SkippedAsset{URL: assetURL, Type: kind, Reason: reason, Initiator: initiator})
+			continue
+		}
+		keep = append(keep, assetURL)
+	}
+	return keep, skipped
+}
+
+// normalizeHosts lower-cases host patterns and strips a leading wildcard label
+func normalizeHosts(hosts []string) []string {
+	normalized := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		host = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), "*.")
+		if host != "" {
+			normalized = append(normalized, host)
+		}
+	}
+	return normalized
+}
+
+// hostOf returns the lower-case host name of rawURL without its port
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// matchHost reports whether host is one of hosts or a subdomain of one
+func matchHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny reports whether s matches one of patterns
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePatterns compiles URL globs and "re:" regular expressions
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		expr, isRegex := strings.CutPrefix(pattern, RegexPatternPrefix)
+		if !isRegex {
+			expr = globToRegex(pattern)
+		}
+		r, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// globToRegex converts a glob where * matches any run of characters and ? one character
+// into an anchored regular expression
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+func TestAssetFilterSkip(t *testing.T) {
+	color.Green("~~ TestAssetFilterSkip ~~")
+	const page = "https://shop.example.com/item"
+	tests := []struct {
+		name  string
+		opts  FilterOptions
+		kind  string
+		asset string
+		want  string
+	}{
+		{"no rules", FilterOptions{}, AssetTypeJS, "https://ads.test/a.js", ""},
+		{"same origin", FilterOptions{SameOrigin: true}, AssetTypeJS, "https://shop.example.com/a.js", ""},
+		{"same origin default port", FilterOptions{SameOrigin: true}, AssetTypeJS,
+			"https://shop.example.com:443/a.js", ""},
+		{"cross origin host", FilterOptions{SameOrigin: true}, AssetTypeJS, "https://cdn.example.com/a.js",
+			SkipReasonCrossOrigin},
+		{"cross origin scheme", FilterOptions{SameOrigin: true}, AssetTypeJS, "http://shop.example.com/a.js",
+			SkipReasonCrossOrigin},
+		{"deny host", FilterOptions{DenyHosts: []string{"google-analytics.com"}}, AssetTypeJS,
+			"https://www.google-analytics.com/analytics.js", SkipReasonDeniedHost},
+		{"deny host is not a suffix match", FilterOptions{DenyHosts: []string{"example.com"}}, AssetTypeJS,
+			"https://notexample.com/a.js", ""},
+		{"deny wildcard", FilterOptions{DenyHosts: []string{"*.doubleclick.net"}}, AssetTypeIMG,
+			"https://ad.doubleclick.net/pixel.gif", SkipReasonDeniedHost},
+		{"allow host", FilterOptions{AllowHosts: []string{"EXAMPLE.com"}}, AssetTypeCSS,
+			"https://cdn.example.com/a.css", ""},
+		{"host not allowed", FilterOptions{AllowHosts: []string{"example.com"}}, AssetTypeCSS,
+			"https://fonts.googleapis.com/css", SkipReasonHostNotAllowed},
+		{"deny wins over allow", FilterOptions{AllowHosts: []string{"example.com"},
+			DenyHosts: []string{"ads.example.com"}}, AssetTypeJS, "https://ads.example.com/a.js", SkipReasonDeniedHost},
+		{"exclude glob", FilterOptions{Exclude: []string{"*/tracking/*"}}, AssetTypeJS,
+			"https://shop.example.com/tracking/t.js", SkipReasonExcluded},
+		{"exclude glob no match", FilterOptions{Exclude: []string{"*/tracking/*"}}, AssetTypeJS,
+			"https://shop.example.com/js/t.js", ""},
+		{"exclude regex", FilterOptions{Exclude: []string{`re:\.(gif|png)$`}}, AssetTypeIMG,
+			"https://shop.example.com/a.png", SkipReasonExcluded},
+		{"include glob", FilterOptions{Include: []string{"https://shop.example.com/static/*"}}, AssetTypeJS,
+			"https://shop.example.com/static/a.js", ""},
+		{"not included", FilterOptions{Include: []string{"https://shop.example.com/static/*"}}, AssetTypeJS,
+			"https://shop.example.com/a.js", SkipReasonNotIncluded},
+		{"glob question mark", FilterOptions{Include: []string{"*/v?/app.js"}}, AssetTypeJS,
+			"https://shop.example.com/v2/app.js", ""},
+		{"glob quotes meta characters", FilterOptions{Include: []string{"*.min.js"}}, AssetTypeJS,
+			"https://shop.example.com/aminxjs", SkipReasonNotIncluded},
+		{"types", FilterOptions{Types: []string{"css", "JS"}}, AssetTypeJS, "https://shop.example.com/a.js", ""},
+		{"type not listed", FilterOptions{Types: []string{"css"}}, AssetTypeFont,
+			"https://shop.example.com/a.woff2", SkipReasonType},
+		{"skip types", FilterOptions{SkipTypes: []string{"media"}}, AssetTypeMedia,
+			"https://shop.example.com/a.mp4", SkipReasonType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewAssetFilter(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filter.Skip(tt.kind, tt.asset, page); got != tt.want {
+				t.Errorf("Skip(%s, %s) = %q, want %q", tt.kind, tt.asset, got, tt.want)
+			}
+		})
+	}
+
+	var nilFilter *AssetFilter
+	if got := nilFilter.Skip(AssetTypeJS, "https://ads.test/a.js", page); got != "" {
+		t.Errorf("nil filter skipped an asset: %q", got)
+	}
+}
+
+func TestNewAssetFilterInvalid(t *testing.T) {
+	for _, opts := range []FilterOptions{
+		{Types: []string{"scripts"}},
+		{SkipTypes: []string{"js", "video"}},
+		{Include: []string{"re:(unclosed"}},
+		{Exclude: []string{"re:[z-a]"}},
+	} {
+		if _, err := NewAssetFilter(opts); err == nil {
+			t.Errorf("NewAssetFilter(%+v) should fail", opts)
+		}
+	}
+}
+
+func TestFetchAllSkipped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<link rel="stylesheet" href="/site.css">
+<script src="/app.js"></script>
+<script src="https://www.google-analytics.com/analytics.js"></script>
+<img src="/tracking/pixel.gif">`))
+		case "/site.css":
+			_, _ = w.Write([]byte(`@import url(https://fonts.googleapis.com/css?family=Inter); .a { background: url(a.png) }`))
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	filter, err := NewAssetFilter(FilterOptions{SameOrigin: true, Exclude: []string{"*/tracking/*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := FetchAll(FetchInput{BaseURL: server.URL + "/", CSSDepth: 1, Filter: filter})
+
+	if resp.TotalRequests != 4 {
+		t.Errorf("TotalRequests = %d, want 4 (page, site.css, app.js, a.png)", resp.TotalRequests)
+	}
+	want := map[string]SkippedAsset{
+		"https://www.google-analytics.com/analytics.js": {Type: AssetTypeJS, Reason: SkipReasonCrossOrigin},
+		server.URL + "/tracking/pixel.gif":              {Type: AssetTypeIMG, Reason: SkipReasonExcluded},
+		"https://fonts.googleapis.com/css?family=Inter": {Type: AssetTypeCSS, Reason: SkipReasonCrossOrigin,
+			Initiator: server.URL + "/site.css"},
+	}
+	if len(resp.Skipped) != len(want) {
+		t.Fatalf("Skipped = %+v, want %d assets", resp.Skipped, len(want))
+	}
+	for _, skipped := range resp.Skipped {
+		w, ok := want[skipped.URL]
+		w.URL = skipped.URL
+		if !ok || skipped != w {
+			t.Errorf("skipped %+v, want %+v", skipped, w)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// graphQLResponse is the part of a GraphQL response that tells whether it failed
+type graphQLResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+/*
+graphQLError returns the number of entries of the errors array of a GraphQL response body,
+and an error message naming the first of them.  GraphQL servers answer failed operations
+with 200 OK and the errors listed in the body, so the status alone does not tell.
+Bodies that are not JSON objects have no errors.
+*/
+func graphQLError(body []byte) (count int, message string) {
+	var resp graphQLResponse
+	if json.Unmarshal(body, &resp) != nil || len(resp.Errors) == 0 {
+		return 0, ""
+	}
+	message = fmt.Sprintf("%s: %s", ErrorGraphQL, resp.Errors[0].Message)
+	if len(resp.Errors) > 1 {
+		message += fmt.Sprintf(" (and %d more)", len(resp.Errors)-1)
+	}
+	return len(resp.Errors), message
+}
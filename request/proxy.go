@@ -0,0 +1,123 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxySchemes are the schemes of the proxies a transport can use
+var proxySchemes = []string{HTTPScheme, HTTPSScheme, ProxySchemeSOCKS5, ProxySchemeSOCKS5H}
+
+/*
+ProxyOptions selects the proxy of the requests.  Requests to localhost and loopback
+addresses never go through a proxy.
+
+Structure Overview
+  - URL - an http:// or https:// proxy (tunnelling https urls with CONNECT) or a socks5:// or
+    socks5h:// one, with optional user:password credentials.  Empty uses the HTTP_PROXY,
+    HTTPS_PROXY and NO_PROXY environment variables; ProxyDirect sends every request directly.
+  - NoProxy - hosts, domains (".example.com"), IPs and CIDR ranges reached directly, comma
+    separated like NO_PROXY, which it replaces
+*/
+type ProxyOptions struct {
+	URL     string `json:"url"`
+	NoProxy string `json:"no_proxy"`
+}
+
+// Validate checks the proxy url
+func (o *ProxyOptions) Validate() error {
+	if o.URL == "" || o.URL == ProxyDirect {
+		return nil
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil || !slices.Contains(proxySchemes, u.Scheme) || u.Host == "" {
+		return fmt.Errorf("invalid proxy %q (expected http://, https://, socks5:// or socks5h://host:port)", o.URL)
+	}
+	return nil
+}
+
+// proxyFunc returns the proxy of each request url, nil for direct requests
+func (o *ProxyOptions) proxyFunc() func(*url.URL) (*url.URL, error) {
+	config := httpproxy.FromEnvironment()
+	switch o.URL {
+	case "":
+	case ProxyDirect:
+		return nil
+	default:
+		config.HTTPProxy, config.HTTPSProxy = o.URL, o.URL
+	}
+	if o.NoProxy != "" {
+		config.NoProxy = o.NoProxy
+	}
+	return config.ProxyFunc()
+}
+
+// proxy returns the Proxy function of a transport, which also tells the phase tracer of
+// each proxied request that its connection goes through a proxy
+func (o *ProxyOptions) proxy() func(*http.Request) (*url.URL, error) {
+	proxyFunc := o.proxyFunc()
+	if proxyFunc == nil {
+		return nil
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := proxyFunc(req.URL)
+		if proxyURL != nil {
+			if tracer, ok := req.Context().Value(phaseTracerKey{}).(*phaseTracer); ok {
+				tracer.proxied()
+			}
+		}
+		return proxyURL, err
+	}
+}
+
+// ProxyStats counts the requests sent through a proxy and the connections they opened.
+// ConnectTime sums the TCP connects to the proxy and TunnelTime the CONNECT or SOCKS5
+// handshakes that followed, the share of the connection time not spent on the origin.
+type ProxyStats struct {
+	Requests    int           `json:"requests"`
+	Connections int           `json:"connections"`
+	ConnectTime time.Duration `json:"connectTime"`
+	TunnelTime  time.Duration `json:"tunnelTime"`
+}
+
+// Add records one response; direct requests are not counted
+func (p *ProxyStats) Add(resp *FetchResponse) {
+	if !resp.Proxied {
+		return
+	}
+	p.Requests++
+	if !resp.ConnReused {
+		p.Connections++
+		p.ConnectTime += resp.Timings.Connect
+		p.TunnelTime += resp.Timings.Proxy
+	}
+}
+
+// Merge adds the counts of other
+func (p *ProxyStats) Merge(other *ProxyStats) {
+	p.Requests += other.Requests
+	p.Connections += other.Connections
+	p.ConnectTime += other.ConnectTime
+	p.TunnelTime += other.TunnelTime
+}
+
+// AvgConnect returns the average time to connect to the proxy, or 0 without new connections
+func (p *ProxyStats) AvgConnect() time.Duration {
+	if p.Connections == 0 {
+		return 0
+	}
+	return p.ConnectTime / time.Duration(p.Connections)
+}
+
+// AvgTunnel returns the average time to open a tunnel through the proxy, or 0 without new connections
+func (p *ProxyStats) AvgTunnel() time.Duration {
+	if p.Connections == 0 {
+		return 0
+	}
+	return p.TunnelTime / time.Duration(p.Connections)
+}
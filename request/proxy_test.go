@@ -0,0 +1,235 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+// tunnel copies between the client and a connection to origin until either side closes
+func tunnel(client io.ReadWriteCloser, origin string) {
+	defer client.Close()
+	conn, err := net.Dial("tcp", origin)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	go func() { _, _ = io.Copy(conn, client) }()
+	_, _ = io.Copy(client, conn)
+}
+
+// connectProxy is an HTTP proxy tunnelling CONNECT requests with user:secret credentials
+// to origin, whatever host they ask for
+func connectProxy(t *testing.T, origin string, tunnels *atomic.Int64) string {
+	t.Helper()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpzZWNyZXQ=" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		tunnels.Add(1)
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		_ = buf.Flush()
+		tunnel(conn, origin)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy.Listener.Addr().String()
+}
+
+// socksProxy is a SOCKS5 proxy accepting user:secret and connecting every request to origin
+func socksProxy(t *testing.T, origin string, tunnels *atomic.Int64) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	handshake := func(conn net.Conn) bool {
+		r := bufio.NewReader(conn)
+		// Greeting: version, methods; then the username/password subnegotiation
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(r, header); err != nil || header[0] != 5 {
+			return false
+		}
+		_, _ = io.ReadFull(r, make([]byte, header[1]))
+		_, _ = conn.Write([]byte{5, 2})
+		credentials := []string{}
+		_, _ = r.ReadByte()
+		for range 2 {
+			n, _ := r.ReadByte()
+			field := make([]byte, n)
+			_, _ = io.ReadFull(r, field)
+			credentials = append(credentials, string(field))
+		}
+		if credentials[0] != "user" || credentials[1] != "secret" {
+			_, _ = conn.Write([]byte{1, 1})
+			return false
+		}
+		_, _ = conn.Write([]byte{1, 0})
+		// CONNECT to a domain name and port
+		request := make([]byte, 5)
+		if _, err := io.ReadFull(r, request); err != nil || request[1] != 1 || request[3] != 3 {
+			return false
+		}
+		_, _ = io.ReadFull(r, make([]byte, int(request[4])+2))
+		_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		return r.Buffered() == 0
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				if !handshake(conn) {
+					conn.Close()
+					return
+				}
+				tunnels.Add(1)
+				tunnel(conn, origin)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestProxy(t *testing.T) {
+	color.Green("~~ TestProxy ~~")
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer origin.Close()
+	port := strconv.Itoa(origin.Listener.Addr().(*net.TCPAddr).Port)
+	url := "https://origin.test:" + port + "/"
+	trusted := origin.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	trusted.ServerName = "example.com"
+
+	var tunnels atomic.Int64
+	connect := connectProxy(t, origin.Listener.Addr().String(), &tunnels)
+	socks := socksProxy(t, origin.Listener.Addr().String(), &tunnels)
+	direct := ResolveOptions{Overrides: map[string][]string{"origin.test:" + port: {"127.0.0.1"}}}
+
+	tests := []struct {
+		name    string
+		proxy   ProxyOptions
+		env     string
+		status  int
+		proxied bool
+	}{
+		{"http connect", ProxyOptions{URL: "http://user:secret@" + connect}, "", http.StatusOK, true},
+		{"socks5", ProxyOptions{URL: "socks5h://user:secret@" + socks}, "", http.StatusOK, true},
+		{"environment", ProxyOptions{}, "http://user:secret@" + connect, http.StatusOK, true},
+		{"direct despite the environment", ProxyOptions{URL: ProxyDirect}, "http://user:secret@" + connect,
+			http.StatusOK, false},
+		{"no proxy", ProxyOptions{URL: "http://user:secret@" + connect, NoProxy: "localhost,.test"}, "",
+			http.StatusOK, false},
+		{"wrong credentials", ProxyOptions{URL: "http://user:guess@" + connect}, "", HTTPStatusConnectionError, true},
+		{"socks5 wrong credentials", ProxyOptions{URL: "socks5://user:guess@" + socks}, "",
+			HTTPStatusConnectionError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HTTPS_PROXY", tt.env)
+			tunnels.Store(0)
+			cfg := DefaultTransportConfig()
+			cfg.TLS, cfg.Resolve, cfg.Proxy = trusted, direct, tt.proxy
+			client := NewClient(cfg)
+			defer client.CloseIdleConnections()
+
+			stats := ProxyStats{}
+			for range 2 {
+				resp := Fetch(FetchInput{BaseURL: url, Client: client})
+				if resp.Status != tt.status || resp.Proxied != tt.proxied {
+					t.Fatalf("status %d, proxied %v: %s", resp.Status, resp.Proxied, resp.URL)
+				}
+				if tt.status == http.StatusOK && !resp.ConnReused && tt.proxied != (resp.Timings.Proxy > 0) {
+					t.Errorf("proxy phase %s", resp.Timings.Proxy)
+				}
+				stats.Add(resp)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			// The second request reuses the connection, tunnelled once
+			if tt.proxied && (tunnels.Load() != 1 || stats.Requests != 2 || stats.Connections != 1 ||
+				stats.AvgConnect() <= 0 || stats.AvgTunnel() <= 0) {
+				t.Errorf("%d tunnels, stats %+v", tunnels.Load(), stats)
+			}
+			if !tt.proxied && (tunnels.Load() != 0 || stats.Requests != 0) {
+				t.Errorf("%d tunnels, stats %+v", tunnels.Load(), stats)
+			}
+		})
+	}
+}
+
+func TestNewDialer(t *testing.T) {
+	origin := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer origin.Close()
+	port := strconv.Itoa(origin.Listener.Addr().(*net.TCPAddr).Port)
+	trusted := origin.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	trusted.ServerName = "example.com"
+
+	var tunnels atomic.Int64
+	connect := connectProxy(t, origin.Listener.Addr().String(), &tunnels)
+	socks := socksProxy(t, origin.Listener.Addr().String(), &tunnels)
+	for name, tt := range map[string]struct {
+		proxy   string
+		tunnels int64
+		ok      bool
+	}{
+		"direct":            {ProxyDirect, 0, true},
+		"http connect":      {"http://user:secret@" + connect, 1, true},
+		"socks5":            {"socks5h://user:secret@" + socks, 1, true},
+		"wrong credentials": {"http://user:guess@" + connect, 0, false},
+	} {
+		tunnels.Store(0)
+		cfg := DefaultTransportConfig()
+		cfg.Proxy = ProxyOptions{URL: tt.proxy}
+		cfg.Resolve = ResolveOptions{Overrides: map[string][]string{"origin.test:" + port: {"127.0.0.1"}}}
+		conn, err := NewDialer(cfg, HTTPSScheme)(context.Background(), "origin.test:"+port)
+		if err == nil {
+			// The connection reaches the origin, which completes a TLS handshake
+			err = tls.Client(conn, trusted).Handshake()
+			conn.Close()
+		}
+		if (err == nil) != tt.ok || tunnels.Load() != tt.tunnels {
+			t.Errorf("%s: %v, %d tunnels", name, err, tunnels.Load())
+		}
+	}
+}
+
+func TestProxyOptionsValidate(t *testing.T) {
+	for proxy, valid := range map[string]bool{
+		"":                        true,
+		ProxyDirect:               true,
+		"http://proxy:3128":       true,
+		"https://u:p@proxy:443":   true,
+		"socks5://proxy:1080":     true,
+		"socks5h://u:p@proxy":     true,
+		"ftp://proxy:21":          false,
+		"proxy:3128":              false,
+		"http://":                 false,
+		"http://proxy:3128/%zz":   false,
+		"socks4://127.0.0.1:1080": false,
+	} {
+		if err := (&ProxyOptions{URL: proxy}).Validate(); (err == nil) != valid {
+			t.Errorf("Validate(%q) = %v", proxy, err)
+		}
+	}
+}
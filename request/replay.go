@@ -0,0 +1,91 @@
+package request
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*
+ReplayRequest is one recorded request of a replay, such as a HAR entry.
+
+Structure Overview
+  - Method, URL, Header, Body - the request to send
+  - Offset - when the request was sent, relative to the first request of the recording
+  - Type - the asset type the response is reported under (see AssetTypes); other when empty
+  - Status, Time - the recorded response status and latency, 0 when unknown
+*/
+type ReplayRequest struct {
+	Method string        `json:"method"`
+	URL    string        `json:"url"`
+	Header http.Header   `json:"header,omitempty"`
+	Body   string        `json:"body,omitempty"`
+	Offset time.Duration `json:"offset"`
+	Type   string        `json:"type,omitempty"`
+	Status int           `json:"status,omitempty"`
+	Time   time.Duration `json:"time,omitempty"`
+}
+
+/*
+Replay sends a recorded request sequence and returns it as a page load: the first
+request is the base url and every other one is reported as an asset of its Type.
+
+With a positive speed each request is sent at its recorded offset divided by speed,
+so 1 reproduces the recorded pacing, 2 runs twice as fast, and requests that overlapped
+in the recording overlap again.  With speed 0 the requests are sent one after the
+other as fast as possible.  Either way requests are sent in the recorded order.
+
+The client, user agent, cookies, cache, auth and Retdat of input apply to every request;
+its BaseURL, Method, Header and Body are replaced by the recorded ones.
+*/
+func Replay(input FetchInput, requests []ReplayRequest, speed float64) *FetchAllResponse {
+	resps := make([]*FetchResponse, len(requests))
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range requests {
+		in := input
+		in.BaseURL = requests[i].URL
+		in.Method = requests[i].Method
+		in.Header = requests[i].Header
+		in.Body = requests[i].Body
+		if speed <= 0 {
+			resps[i] = Fetch(in)
+			continue
+		}
+
+		time.Sleep(time.Until(start.Add(time.Duration(float64(requests[i].Offset) / speed))))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resps[i] = Fetch(in)
+		}(i)
+	}
+	wg.Wait()
+	totalTime := time.Since(start)
+
+	resp := FetchAllResponse{}
+	if len(resps) == 0 {
+		resp.BaseURL = &FetchResponse{}
+		return &resp
+	}
+	resp.BaseURL = resps[0]
+	for i := 1; i < len(resps); i++ {
+		assets := resp.Responses(requests[i].Type)
+		*assets = append(*assets, *resps[i])
+	}
+
+	base := resps[0].Started
+	if base.IsZero() {
+		base = start
+	}
+	resps[0].StartOffset = resps[0].Started.Sub(base)
+	resps[0].EndOffset = resps[0].Finished.Sub(base)
+	for _, kind := range AssetTypes {
+		setOffsets(base, *resp.Responses(kind))
+	}
+
+	resp.tally()
+	resp.TotalTime = totalTime
+	resp.Body = resp.BaseURL.Body
+	return &resp
+}
@@ -0,0 +1,120 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+)
+
+// received is a request seen by the replay test server
+type received struct {
+	method, path, body, header string
+	at                         time.Time
+}
+
+func replayServer(t *testing.T) (*httptest.Server, func() []received) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		seen = append(seen, received{r.Method, r.URL.Path, string(body), r.Header.Get("X-Replay"), time.Now()})
+		mu.Unlock()
+		_, _ = w.Write([]byte("ok " + r.URL.Path))
+	}))
+	return server, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), seen...)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	color.Green("~~ TestReplay ~~")
+	server, seen := replayServer(t)
+	defer server.Close()
+
+	requests := []ReplayRequest{
+		{Method: http.MethodGet, URL: server.URL + "/", Type: AssetTypeDocument},
+		{Method: http.MethodGet, URL: server.URL + "/app.js", Offset: 20 * time.Millisecond, Type: AssetTypeJS},
+		{Method: http.MethodPost, URL: server.URL + "/api", Offset: 100 * time.Millisecond,
+			Header: http.Header{"X-Replay": {"yes"}}, Body: `{"a":1}`},
+	}
+	start := time.Now()
+	resp := Replay(FetchInput{UserAgent: "goperf"}, requests, 1)
+	elapsed := time.Since(start)
+
+	if elapsed < 100*time.Millisecond {
+		t.Errorf("replay at 1x took %s, the last request was recorded at 100ms", elapsed)
+	}
+	if resp.BaseURL.URL != server.URL+"/" || len(resp.JSResponses) != 1 || len(resp.OtherResponses) != 1 {
+		t.Errorf("base %s, %d js, %d other; want the page, 1 js and 1 other", resp.BaseURL.URL,
+			len(resp.JSResponses), len(resp.OtherResponses))
+	}
+	if resp.TotalRequests != 3 || resp.TotalBytes == 0 || resp.TotalTime < 100*time.Millisecond {
+		t.Errorf("totals: %d requests, %d bytes, %s", resp.TotalRequests, resp.TotalBytes, resp.TotalTime)
+	}
+	if offset := resp.OtherResponses[0].StartOffset; offset < 100*time.Millisecond {
+		t.Errorf("api started at %s, want at least 100ms", offset)
+	}
+
+	got := seen()
+	if len(got) != 3 {
+		t.Fatalf("server saw %d requests, want 3", len(got))
+	}
+	api := got[2]
+	if api.method != http.MethodPost || api.path != "/api" || api.body != `{"a":1}` || api.header != "yes" {
+		t.Errorf("api request = %+v", api)
+	}
+	if got[0].path != "/" || got[1].path != "/app.js" {
+		t.Errorf("requests arrived as %s, %s, want the recorded order", got[0].path, got[1].path)
+	}
+}
+
+func TestReplayInvalidURL(t *testing.T) {
+	server, seen := replayServer(t)
+	defer server.Close()
+
+	// A recorded url that is no valid url fails alone
+	resp := Replay(FetchInput{}, []ReplayRequest{
+		{Method: http.MethodGet, URL: server.URL + "/"},
+		{Method: http.MethodGet, URL: server.URL + "/a%zz"},
+	}, 0)
+	if len(resp.OtherResponses) != 1 || resp.OtherResponses[0].Error != ErrorRequestFailed {
+		t.Fatalf("other responses %+v, want one failed request", resp.OtherResponses)
+	}
+	if resp.BaseURL.Status != http.StatusOK || len(seen()) != 1 {
+		t.Errorf("base status %d, server saw %d requests; want 200, 1", resp.BaseURL.Status, len(seen()))
+	}
+}
+
+func TestReplaySpeed(t *testing.T) {
+	server, seen := replayServer(t)
+	defer server.Close()
+
+	requests := []ReplayRequest{
+		{Method: http.MethodGet, URL: server.URL + "/a"},
+		{Method: http.MethodGet, URL: server.URL + "/b", Offset: time.Second},
+		{Method: http.MethodGet, URL: server.URL + "/c", Offset: 2 * time.Second},
+	}
+	for _, speed := range []float64{0, 20} {
+		start := time.Now()
+		resp := Replay(FetchInput{}, requests, speed)
+		// 2s recorded: as fast as possible, or 100ms at 20x
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("replay at speed %g took %s", speed, elapsed)
+		}
+		if resp.TotalRequests != 3 {
+			t.Errorf("speed %g: %d requests, want 3", speed, resp.TotalRequests)
+		}
+	}
+	if got := seen(); len(got) != 6 || got[5].at.Sub(got[3].at) < 90*time.Millisecond {
+		t.Errorf("at 20x the last request should come about 100ms after the first: %+v", got)
+	}
+}
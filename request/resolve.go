@@ -0,0 +1,189 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+ResolveOptions controls how the host names of requests are resolved.  The url, and so the
+Host header and TLS server name, keep the host name whatever address is dialed.
+
+Structure Overview
+  - Overrides - addresses dialed instead of resolving "host:port", e.g. from ParseOverrides
+  - Server - "ip[:port]" of a DNS server queried instead of the system resolver
+  - Cache - resolve each host once per connection pool (virtual user) instead of for every new connection
+  - RoundRobin - spread new connections over all the addresses of a host instead of dialing the first one
+*/
+type ResolveOptions struct {
+	Overrides  map[string][]string `json:"overrides"`
+	Server     string              `json:"server"`
+	Cache      bool                `json:"cache"`
+	RoundRobin bool                `json:"round_robin"`
+}
+
+/*
+ParseOverrides parses "host:port:addr[,addr...]" entries like curl's --resolve into the
+Overrides of ResolveOptions.  IPv6 addresses may be enclosed in brackets.
+*/
+func ParseOverrides(entries []string) (map[string][]string, error) {
+	overrides := map[string][]string{}
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, ":", ResolveOverrideParts)
+		if len(parts) != ResolveOverrideParts || parts[0] == "" {
+			return nil, fmt.Errorf("invalid resolve override %q (expected host:port:addr)", entry)
+		}
+		if !validPort(parts[1]) {
+			return nil, fmt.Errorf("invalid port in resolve override %q", entry)
+		}
+		var addrs []string
+		for _, addr := range strings.Split(parts[2], ",") {
+			addr = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(addr), "["), "]")
+			if net.ParseIP(addr) == nil {
+				return nil, fmt.Errorf("invalid address %q in resolve override %q", addr, entry)
+			}
+			addrs = append(addrs, addr)
+		}
+		host := net.JoinHostPort(strings.ToLower(parts[0]), parts[1])
+		overrides[host] = append(overrides[host], addrs...)
+	}
+	return overrides, nil
+}
+
+// Validate checks the DNS server address
+func (o *ResolveOptions) Validate() error {
+	if o.Server == "" {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(o.serverAddr())
+	if err != nil || net.ParseIP(host) == nil || !validPort(port) {
+		return fmt.Errorf("invalid DNS server %q (expected ip or ip:port)", o.Server)
+	}
+	return nil
+}
+
+// validPort reports whether port is a port number
+func validPort(port string) bool {
+	number, err := strconv.Atoi(port)
+	return err == nil && number > 0 && number <= math.MaxUint16
+}
+
+// serverAddr returns the address of the DNS server, on the DNS port unless it names one
+func (o *ResolveOptions) serverAddr() string {
+	if net.ParseIP(strings.Trim(o.Server, "[]")) != nil {
+		return net.JoinHostPort(strings.Trim(o.Server, "[]"), DNSPort)
+	}
+	return o.Server
+}
+
+// custom reports whether connections need more than the dialer's own resolution
+func (o *ResolveOptions) custom() bool {
+	return len(o.Overrides) > 0 || o.Server != "" || o.Cache || o.RoundRobin
+}
+
+// dialFunc dials the connections of a transport
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// hostDialer dials connections following ResolveOptions.  It belongs to one transport, so
+// its cache and round-robin positions are those of one connection pool.
+type hostDialer struct {
+	opts     ResolveOptions
+	dial     dialFunc
+	resolver *net.Resolver
+
+	mu     sync.Mutex
+	cached map[string][]string
+	next   map[string]int
+}
+
+// newHostDialer returns a dial function resolving host names following opts and dialing
+// the addresses with dial; DNS servers are reached with dialer
+func newHostDialer(opts ResolveOptions, dialer *net.Dialer, dial dialFunc) dialFunc {
+	if !opts.custom() {
+		return dial
+	}
+	d := &hostDialer{opts: opts, dial: dial, resolver: net.DefaultResolver,
+		cached: map[string][]string{}, next: map[string]int{}}
+	if opts.Server != "" {
+		server := opts.serverAddr()
+		d.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return d.DialContext
+}
+
+// DialContext resolves the host of addr and dials its addresses in turn until one connects
+func (d *hostDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := d.lookup(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+	ips = d.rotate(host, ips)
+
+	var conn net.Conn
+	for _, ip := range ips {
+		if conn, err = d.dial(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// lookup returns the addresses of host: its overrides, else the cached or resolved ones.
+// The lookups run on the request's context, so their time is recorded as its DNS phase.
+func (d *hostDialer) lookup(ctx context.Context, host, port string) ([]string, error) {
+	if ips, ok := d.opts.Overrides[net.JoinHostPort(strings.ToLower(host), port)]; ok {
+		return ips, nil
+	}
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	if d.opts.Cache {
+		d.mu.Lock()
+		ips, ok := d.cached[host]
+		d.mu.Unlock()
+		if ok {
+			return ips, nil
+		}
+	}
+	addrs, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]string, len(addrs))
+	for i := range addrs {
+		ips[i] = addrs[i].String()
+	}
+	if d.opts.Cache {
+		d.mu.Lock()
+		d.cached[host] = ips
+		d.mu.Unlock()
+	}
+	return ips, nil
+}
+
+// rotate starts ips at the next address of host when connections are spread round-robin;
+// the others are still tried if it does not connect
+func (d *hostDialer) rotate(host string, ips []string) []string {
+	if !d.opts.RoundRobin || len(ips) < 2 {
+		return ips
+	}
+	d.mu.Lock()
+	start := d.next[host] % len(ips)
+	d.next[host] = start + 1
+	d.mu.Unlock()
+	return append(ips[start:len(ips):len(ips)], ips[:start]...)
+}
@@ -0,0 +1,154 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gnulnx/color"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsServer answers every A query with 127.0.0.1 and 127.0.0.2, counting the queries, and
+// returns its address
+func dnsServer(t *testing.T, queries *atomic.Int64) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil || len(query.Questions) != 1 {
+				continue
+			}
+			question := query.Questions[0]
+			reply := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+				Questions: query.Questions,
+			}
+			if question.Type == dnsmessage.TypeA {
+				queries.Add(1)
+				for _, ip := range [][4]byte{{127, 0, 0, 1}, {127, 0, 0, 2}} {
+					reply.Answers = append(reply.Answers, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA,
+							Class: dnsmessage.ClassINET, TTL: 60},
+						Body: &dnsmessage.AResource{A: ip},
+					})
+				}
+			}
+			packed, _ := reply.Pack()
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestResolve(t *testing.T) {
+	color.Green("~~ TestResolve ~~")
+	var (
+		mu     sync.Mutex
+		hosts  []string
+		locals []string
+	)
+	listener, err := net.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		local := r.Context().Value(http.LocalAddrContextKey).(net.Addr).String()
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		locals = append(locals, local[:strings.LastIndex(local, ":")])
+		mu.Unlock()
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+	port := fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+	var queries atomic.Int64
+	resolver := dnsServer(t, &queries)
+
+	tests := []struct {
+		name      string
+		opts      ResolveOptions
+		queries   int64
+		locals    string
+		dnsPhases int
+	}{
+		{"override", ResolveOptions{Overrides: map[string][]string{"api.test:" + port: {"127.0.0.2"}}},
+			0, "127.0.0.2,127.0.0.2,127.0.0.2,127.0.0.2", 0},
+		{"per connection", ResolveOptions{Server: resolver}, 4, "127.0.0.1,127.0.0.1,127.0.0.1,127.0.0.1", 4},
+		{"cached per user", ResolveOptions{Server: resolver, Cache: true}, 1, "127.0.0.1,127.0.0.1,127.0.0.1,127.0.0.1", 1},
+		{"round robin", ResolveOptions{Server: resolver, Cache: true, RoundRobin: true},
+			1, "127.0.0.1,127.0.0.2,127.0.0.1,127.0.0.2", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries.Store(0)
+			mu.Lock()
+			hosts, locals = nil, nil
+			mu.Unlock()
+			cfg := DefaultTransportConfig()
+			cfg.DisableKeepAlives = true
+			cfg.Resolve = tt.opts
+			client := NewClient(cfg)
+			dnsPhases := 0
+			for range 4 {
+				resp := Fetch(FetchInput{BaseURL: "http://api.test:" + port + "/", Client: client})
+				if resp.Status != http.StatusOK {
+					t.Fatalf("status %d: %s", resp.Status, resp.URL)
+				}
+				if resp.Timings.DNS > 0 {
+					dnsPhases++
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if queries.Load() != tt.queries || strings.Join(locals, ",") != tt.locals || dnsPhases != tt.dnsPhases {
+				t.Errorf("%d queries, %d DNS phases, connected to %v", queries.Load(), dnsPhases, locals)
+			}
+			if hosts[0] != "api.test:"+port {
+				t.Errorf("Host header %q", hosts[0])
+			}
+		})
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	overrides, err := ParseOverrides([]string{
+		"API.test:443:10.0.0.1,[::1]", "api.test:443:10.0.0.2", "b.test:80:10.0.0.3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"api.test:443": {"10.0.0.1", "::1", "10.0.0.2"}, "b.test:80": {"10.0.0.3"}}
+	if fmt.Sprint(overrides) != fmt.Sprint(want) {
+		t.Errorf("ParseOverrides = %v, want %v", overrides, want)
+	}
+	for _, entry := range []string{"api.test:443", ":443:10.0.0.1", "api.test:https:10.0.0.1", "api.test:0:10.0.0.1",
+		"api.test:443:backend"} {
+		if _, err := ParseOverrides([]string{entry}); err == nil {
+			t.Errorf("ParseOverrides(%q) should fail", entry)
+		}
+	}
+
+	for server, valid := range map[string]bool{"10.0.0.2": true, "10.0.0.2:5353": true, "::1": true, "[::1]:53": true,
+		"dns.test:53": false, "10.0.0.2:": false} {
+		if err := (&ResolveOptions{Server: server}).Validate(); (err == nil) != valid {
+			t.Errorf("Validate(%q) = %v", server, err)
+		}
+	}
+}
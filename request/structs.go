@@ -1,7 +1,9 @@
 package request
 
 import (
+	"maps"
 	"net/http"
+	"slices"
 	"time"
 )
 
@@ -13,17 +15,198 @@ type IterateReqResp struct {
 	RespTimes   []time.Duration `json:"respTimes"`
 	NumRequests int             `json:"numRequests"`
 	Bytes       int             `json:"bytes"`
+	ConnReused  int             `json:"connReused"`
+	QueueTime   time.Duration   `json:"queueTime"`
 }
 
 // IterateReqRespAll represents the complete performance test results including base URL and assets
-// It combines metrics from the main page and all discovered assets (JS, CSS, images)
+// It combines metrics from the main page and all discovered assets, grouped by asset type
 type IterateReqRespAll struct {
 	AvgTotalRespTime       time.Duration    `json:"avgTotalRespTime"`
 	AvgTotalLinearRespTime time.Duration    `json:"avgTotalLinearRespTime"`
+	AvgTotalQueueTime      time.Duration    `json:"avgTotalQueueTime"`
+	FirstView              ViewStats        `json:"firstView"`
+	RepeatView             ViewStats        `json:"repeatView"`
 	BaseURL                IterateReqResp   `json:"baseURL"`
 	JSResps                []IterateReqResp `json:"jsResponses"`
 	CSSResps               []IterateReqResp `json:"cssResponses"`
 	IMGResps               []IterateReqResp `json:"imgResponses"`
+	FontResps              []IterateReqResp `json:"fontResponses"`
+	MediaResps             []IterateReqResp `json:"mediaResponses"`
+	IconResps              []IterateReqResp `json:"iconResponses"`
+	DocumentResps          []IterateReqResp `json:"documentResponses"`
+	OtherResps             []IterateReqResp `json:"otherResponses"`
+	Targets                []TargetStats    `json:"targets,omitempty"`
+	Operations             []OperationStats `json:"operations,omitempty"`
+	Protocols              ProtocolCounts   `json:"protocols,omitempty"`
+	TLS                    TLSCounts        `json:"tls,omitempty"`
+	Proxy                  ProxyStats       `json:"proxy"`
+}
+
+// ProtocolStats counts the requests sent over one protocol and the connections they opened.
+// HTTP/2 multiplexes many requests (streams) on a connection while HTTP/1.x sends one at a time.
+type ProtocolStats struct {
+	Requests    int `json:"requests"`
+	Connections int `json:"connections"`
+}
+
+// StreamsPerConn returns the average number of requests sent on each connection
+func (p ProtocolStats) StreamsPerConn() float64 {
+	if p.Connections == 0 {
+		return float64(p.Requests)
+	}
+	return float64(p.Requests) / float64(p.Connections)
+}
+
+// ProtocolCounts holds the ProtocolStats of each negotiated protocol, e.g. "HTTP/2.0"
+type ProtocolCounts map[string]ProtocolStats
+
+// Add records one response.  Cache hits and failed requests have no protocol and are not counted.
+func (p ProtocolCounts) Add(resp *FetchResponse) {
+	if resp.Protocol == "" {
+		return
+	}
+	stats := p[resp.Protocol]
+	stats.Requests++
+	if !resp.ConnReused {
+		stats.Connections++
+	}
+	p[resp.Protocol] = stats
+}
+
+// Merge adds the counts of other
+func (p ProtocolCounts) Merge(other ProtocolCounts) {
+	for protocol, counts := range other {
+		stats := p[protocol]
+		stats.Requests += counts.Requests
+		stats.Connections += counts.Connections
+		p[protocol] = stats
+	}
+}
+
+// Names returns the protocols in sorted order
+func (p ProtocolCounts) Names() []string {
+	return slices.Sorted(maps.Keys(p))
+}
+
+// TargetStats aggregates the page loads of one load test target when a test spreads
+// its traffic over several pages.  Base holds the target's own requests and View the
+// whole page loads, assets included.  Operation names the GraphQL operation of the target,
+// which tells apart the targets sharing the url of a GraphQL endpoint.
+type TargetStats struct {
+	URL       string         `json:"url"`
+	Method    string         `json:"method"`
+	Operation string         `json:"operation,omitempty"`
+	Weight    int            `json:"weight"`
+	Base      IterateReqResp `json:"base"`
+	View      ViewStats      `json:"view"`
+}
+
+// Add records one page load of the target
+func (t *TargetStats) Add(resp *FetchAllResponse) {
+	t.Base.Status = append(t.Base.Status, resp.BaseURL.Status)
+	t.Base.RespTimes = append(t.Base.RespTimes, resp.BaseURL.Time)
+	t.Base.NumRequests++
+	t.Base.Bytes += resp.BaseURL.Bytes
+	if resp.BaseURL.ConnReused {
+		t.Base.ConnReused++
+	}
+	t.View.Add(resp)
+}
+
+// Merge adds the page loads recorded in other
+func (t *TargetStats) Merge(other *TargetStats) {
+	t.Base.Status = append(t.Base.Status, other.Base.Status...)
+	t.Base.RespTimes = append(t.Base.RespTimes, other.Base.RespTimes...)
+	t.Base.NumRequests += other.Base.NumRequests
+	t.Base.Bytes += other.Base.Bytes
+	t.Base.ConnReused += other.Base.ConnReused
+	t.View.Merge(&other.View)
+}
+
+// OperationStats aggregates the requests of one GraphQL operation, whichever targets sent it.
+// Failed counts the requests that got no response, an error status or a response listing
+// GraphQL errors; Errored counts the latter, which come with 200 OK.
+type OperationStats struct {
+	Name    string         `json:"name"`
+	Base    IterateReqResp `json:"base"`
+	Failed  int            `json:"failed"`
+	Errored int            `json:"errored"`
+}
+
+// Add records one request of the operation
+func (o *OperationStats) Add(resp *FetchResponse) {
+	o.Base.Status = append(o.Base.Status, resp.Status)
+	o.Base.RespTimes = append(o.Base.RespTimes, resp.Time)
+	o.Base.NumRequests++
+	o.Base.Bytes += resp.Bytes
+	if resp.ConnReused {
+		o.Base.ConnReused++
+	}
+	if resp.GraphQLErrors > 0 {
+		o.Errored++
+	}
+	if resp.GraphQLErrors > 0 || resp.Status == HTTPStatusConnectionError || resp.Status >= http.StatusBadRequest {
+		o.Failed++
+	}
+}
+
+// Merge adds the requests recorded in other
+func (o *OperationStats) Merge(other *OperationStats) {
+	o.Base.Status = append(o.Base.Status, other.Base.Status...)
+	o.Base.RespTimes = append(o.Base.RespTimes, other.Base.RespTimes...)
+	o.Base.NumRequests += other.Base.NumRequests
+	o.Base.Bytes += other.Base.Bytes
+	o.Base.ConnReused += other.Base.ConnReused
+	o.Failed += other.Failed
+	o.Errored += other.Errored
+}
+
+// ViewStats aggregates page loads of one kind: first views start with an empty
+// HTTP cache while repeat views reuse whatever the previous loads cached.
+type ViewStats struct {
+	Pages       int           `json:"pages"`
+	TotalTime   time.Duration `json:"totalTime"`
+	Bytes       int           `json:"bytes"`
+	Requests    int           `json:"requests"`
+	NotModified int           `json:"notModified"`
+	CacheHits   int           `json:"cacheHits"`
+}
+
+// Add records one page load
+func (v *ViewStats) Add(resp *FetchAllResponse) {
+	v.Pages++
+	v.TotalTime += resp.TotalTime
+	v.Bytes += resp.TotalBytes
+	v.Requests += resp.TotalRequests - resp.CacheHits
+	v.NotModified += resp.NotModified
+	v.CacheHits += resp.CacheHits
+}
+
+// Merge adds the page loads recorded in other
+func (v *ViewStats) Merge(other *ViewStats) {
+	v.Pages += other.Pages
+	v.TotalTime += other.TotalTime
+	v.Bytes += other.Bytes
+	v.Requests += other.Requests
+	v.NotModified += other.NotModified
+	v.CacheHits += other.CacheHits
+}
+
+// AvgPageTime returns the average page load time, or 0 when no pages were loaded
+func (v *ViewStats) AvgPageTime() time.Duration {
+	if v.Pages == 0 {
+		return 0
+	}
+	return v.TotalTime / time.Duration(v.Pages)
+}
+
+// AvgBytes returns the average number of bytes transferred per page load
+func (v *ViewStats) AvgBytes() int {
+	if v.Pages == 0 {
+		return 0
+	}
+	return v.Bytes / v.Pages
 }
 
 // Result represents a single HTTP request result with detailed timing and response information.
@@ -0,0 +1,181 @@
+package request
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// tlsVersions maps the versions accepted by TLSOptions to their crypto/tls values
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+/*
+TLSOptions describes how TLS connections are verified and negotiated, e.g. to test services
+requiring client certificates (mTLS) or staging hosts with self-signed certificates.
+
+Structure Overview
+  - CertFile, KeyFile - PEM client certificate and key presented to servers asking for one
+  - CAFile - PEM bundle of CAs trusted in addition to the system roots
+  - Insecure - skip certificate verification
+  - MinVersion, MaxVersion - "1.0", "1.1", "1.2" or "1.3"; empty keeps the crypto/tls defaults
+  - CipherSuites - TLS 1.0-1.2 cipher suites by name, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256;
+    the TLS 1.3 suites are not configurable
+  - ServerName - the name sent as SNI and verified instead of the url's host
+  - SessionResumption - resume TLS sessions on new connections; each connection pool caches its own tickets
+*/
+type TLSOptions struct {
+	CertFile          string   `json:"cert_file"`
+	KeyFile           string   `json:"key_file"`
+	CAFile            string   `json:"ca_file"`
+	Insecure          bool     `json:"insecure"`
+	MinVersion        string   `json:"min_version"`
+	MaxVersion        string   `json:"max_version"`
+	CipherSuites      []string `json:"cipher_suites"`
+	ServerName        string   `json:"server_name"`
+	SessionResumption bool     `json:"session_resumption"`
+}
+
+// ClientConfig loads the certificates and returns the TLS configuration of client connections
+func (o *TLSOptions) ClientConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: o.Insecure, //nolint:gosec // opted into with -insecure for self-signed test hosts
+		ServerName:         o.ServerName,
+		// Without tickets every new connection pays for a full handshake
+		SessionTicketsDisabled: !o.SessionResumption,
+	}
+	if o.SessionResumption {
+		config.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+
+	var err error
+	if config.MinVersion, err = tlsVersion(o.MinVersion); err != nil {
+		return nil, err
+	}
+	if config.MaxVersion, err = tlsVersion(o.MaxVersion); err != nil {
+		return nil, err
+	}
+	if config.MinVersion != 0 && config.MaxVersion != 0 && config.MinVersion > config.MaxVersion {
+		return nil, fmt.Errorf("minimum TLS version %s is above the maximum %s", o.MinVersion, o.MaxVersion)
+	}
+	if config.CipherSuites, err = cipherSuites(o.CipherSuites); err != nil {
+		return nil, err
+	}
+
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, fmt.Errorf("a client certificate needs both a certificate and a key file")
+	}
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file: %w", err)
+		}
+		if config.RootCAs, err = x509.SystemCertPool(); err != nil {
+			config.RootCAs = x509.NewCertPool()
+		}
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in the CA file %s", o.CAFile)
+		}
+	}
+	return config, nil
+}
+
+// tlsVersion returns the crypto/tls value of version, or 0 when it is empty
+func tlsVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+	value, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q (expected one of %v)", version,
+			slices.Sorted(maps.Keys(tlsVersions)))
+	}
+	return value, nil
+}
+
+// cipherSuites returns the ids of the named cipher suites, or nil for the crypto/tls defaults
+func cipherSuites(names []string) ([]uint16, error) {
+	var ids []uint16
+	for _, name := range names {
+		i := slices.IndexFunc(tls.CipherSuites(), func(s *tls.CipherSuite) bool { return s.Name == name })
+		if i >= 0 {
+			ids = append(ids, tls.CipherSuites()[i].ID)
+			continue
+		}
+		i = slices.IndexFunc(tls.InsecureCipherSuites(), func(s *tls.CipherSuite) bool { return s.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, tls.InsecureCipherSuites()[i].ID)
+	}
+	return ids, nil
+}
+
+// TLSStats counts the requests sent over one TLS version and the handshakes of the connections
+// they opened.  Resumed handshakes reused a session ticket and skipped the certificate exchange.
+type TLSStats struct {
+	Requests      int           `json:"requests"`
+	Handshakes    int           `json:"handshakes"`
+	Resumed       int           `json:"resumed"`
+	HandshakeTime time.Duration `json:"handshakeTime"`
+}
+
+// AvgHandshake returns the average handshake time, or 0 when no handshake was made
+func (t TLSStats) AvgHandshake() time.Duration {
+	if t.Handshakes == 0 {
+		return 0
+	}
+	return t.HandshakeTime / time.Duration(t.Handshakes)
+}
+
+// TLSCounts holds the TLSStats of each negotiated TLS version, e.g. "TLS 1.3"
+type TLSCounts map[string]TLSStats
+
+// Add records one response.  Plain HTTP, cache hits and failed requests are not counted.
+func (t TLSCounts) Add(resp *FetchResponse) {
+	if resp.TLSVersion == "" {
+		return
+	}
+	stats := t[resp.TLSVersion]
+	stats.Requests++
+	if resp.Timings.TLS > 0 {
+		stats.Handshakes++
+		stats.HandshakeTime += resp.Timings.TLS
+		if resp.TLSResumed {
+			stats.Resumed++
+		}
+	}
+	t[resp.TLSVersion] = stats
+}
+
+// Merge adds the counts of other
+func (t TLSCounts) Merge(other TLSCounts) {
+	for version, counts := range other {
+		stats := t[version]
+		stats.Requests += counts.Requests
+		stats.Handshakes += counts.Handshakes
+		stats.Resumed += counts.Resumed
+		stats.HandshakeTime += counts.HandshakeTime
+		t[version] = stats
+	}
+}
+
+// Versions returns the TLS versions in sorted order
+func (t TLSCounts) Versions() []string {
+	return slices.Sorted(maps.Keys(t))
+}
@@ -0,0 +1,172 @@
+package request
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+)
+
+// writePEM writes a PEM block of type kind to path
+func writePEM(t *testing.T, path, kind string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// clientCertificate writes a client certificate and its key to dir, signed by a new CA which is returned
+func clientCertificate(t *testing.T, dir string) *x509.CertPool {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "goperf test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "goperf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	writePEM(t, filepath.Join(dir, "client.pem"), "CERTIFICATE", der)
+	writePEM(t, filepath.Join(dir, "client-key.pem"), "PRIVATE KEY", keyDER)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return pool
+}
+
+func TestTLSOptions(t *testing.T) {
+	color.Green("~~ TestTLSOptions ~~")
+	dir := t.TempDir()
+	var suite atomic.Uint32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		suite.Store(uint32(r.TLS.CipherSuite))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCertificate(t, dir)}
+	server.StartTLS()
+	defer server.Close()
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", server.Certificate().Raw)
+
+	fetch := func(opts TLSOptions) []FetchResponse {
+		t.Helper()
+		tlsConfig, err := opts.ClientConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := DefaultTransportConfig()
+		cfg.DisableKeepAlives = true
+		cfg.TLS = tlsConfig
+		client := NewClient(cfg)
+		defer client.CloseIdleConnections()
+		var responses []FetchResponse
+		for range 3 {
+			responses = append(responses, *Fetch(FetchInput{BaseURL: server.URL, Client: client}))
+		}
+		return responses
+	}
+
+	// The test certificate is issued for example.com and 127.0.0.1
+	mutual := TLSOptions{
+		CertFile:          filepath.Join(dir, "client.pem"),
+		KeyFile:           filepath.Join(dir, "client-key.pem"),
+		CAFile:            filepath.Join(dir, "ca.pem"),
+		MinVersion:        "1.2",
+		MaxVersion:        "1.2",
+		CipherSuites:      []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+		ServerName:        "example.com",
+		SessionResumption: true,
+	}
+	counts := TLSCounts{}
+	for _, resp := range fetch(mutual) {
+		if resp.Status != http.StatusOK || resp.TLSVersion != "TLS 1.2" || resp.Timings.TLS <= 0 {
+			t.Fatalf("status %d over %q, handshake %s", resp.Status, resp.TLSVersion, resp.Timings.TLS)
+		}
+		counts.Add(&resp)
+	}
+	if stats := counts["TLS 1.2"]; stats.Requests != 3 || stats.Handshakes != 3 || stats.Resumed != 2 ||
+		stats.AvgHandshake() <= 0 {
+		t.Errorf("counts = %+v, want 3 handshakes of which 2 resumed", counts)
+	}
+	if got := uint16(suite.Load()); got != tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 {
+		t.Errorf("cipher suite %s", tls.CipherSuiteName(got))
+	}
+
+	// Without resumption every connection pays for a full handshake
+	mutual.SessionResumption = false
+	for _, resp := range fetch(mutual) {
+		if resp.Status != http.StatusOK || resp.TLSResumed {
+			t.Errorf("status %d, resumed %v", resp.Status, resp.TLSResumed)
+		}
+	}
+	// The server asks for a client certificate, and the system roots do not trust its own
+	for name, opts := range map[string]TLSOptions{
+		"no client certificate": {CAFile: mutual.CAFile, ServerName: mutual.ServerName},
+		"untrusted server":      {CertFile: mutual.CertFile, KeyFile: mutual.KeyFile},
+	} {
+		if resp := fetch(opts)[0]; resp.Status != HTTPStatusConnectionError {
+			t.Errorf("%s: status %d", name, resp.Status)
+		}
+	}
+	insecure := fetch(TLSOptions{CertFile: mutual.CertFile, KeyFile: mutual.KeyFile, Insecure: true})[0]
+	if insecure.Status != http.StatusOK || insecure.TLSVersion != "TLS 1.3" {
+		t.Errorf("insecure: status %d over %q", insecure.Status, insecure.TLSVersion)
+	}
+}
+
+func TestTLSOptionsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, opts := range map[string]TLSOptions{
+		"unknown version":     {MinVersion: "1.4"},
+		"min above max":       {MinVersion: "1.3", MaxVersion: "1.2"},
+		"unknown cipher":      {CipherSuites: []string{"TLS_NULL"}},
+		"certificate only":    {CertFile: filepath.Join(dir, "client.pem")},
+		"missing certificate": {CertFile: filepath.Join(dir, "client.pem"), KeyFile: filepath.Join(dir, "key.pem")},
+		"missing CA file":     {CAFile: filepath.Join(dir, "missing.pem")},
+		"CA file without PEM": {CAFile: notPEM},
+	} {
+		if _, err := opts.ClientConfig(); err == nil {
+			t.Errorf("ClientConfig with %s should fail", name)
+		}
+	}
+	if config, err := (&TLSOptions{}).ClientConfig(); err != nil || config.ClientSessionCache != nil ||
+		!config.SessionTicketsDisabled {
+		t.Errorf("default config %+v, %v", config, err)
+	}
+}
@@ -0,0 +1,129 @@
+package request
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+/*
+Timings holds the phases of a single request, similar to the timing tab of browser devtools.
+
+Structure Overview
+  - DNS - name resolution (0 when the connection was reused)
+  - Connect - TCP connect, to the proxy for proxied requests (0 when the connection was reused)
+  - Proxy - HTTP CONNECT or SOCKS5 handshake opening a tunnel through the proxy (0 without one)
+  - TLS - TLS handshake (0 for plain HTTP or reused connections)
+  - Wait - from the request being written until the first response byte
+  - Download - from the first response byte until the body was read
+*/
+type Timings struct {
+	DNS      time.Duration `json:"dns"`
+	Connect  time.Duration `json:"connect"`
+	Proxy    time.Duration `json:"proxy,omitempty"`
+	TLS      time.Duration `json:"tls"`
+	Wait     time.Duration `json:"wait"`
+	Download time.Duration `json:"download"`
+}
+
+// phaseTracerKey is the context key of the phase tracer of a request
+type phaseTracerKey struct{}
+
+// phaseTracer records request phases through net/http/httptrace hooks.
+// Hooks may fire on transport goroutines, so every field is guarded by mu.
+// The proxy phase runs from the connection to the proxy to the TLS handshake with the
+// origin, or to the connection being handed over for plain HTTP.
+type phaseTracer struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	reused       bool
+	isProxied    bool
+	timings      Timings
+}
+
+// withContext returns ctx carrying the tracer and its httptrace hooks
+func (p *phaseTracer) withContext(ctx context.Context) context.Context {
+	return context.WithValue(httptrace.WithClientTrace(ctx, p.clientTrace()), phaseTracerKey{}, p)
+}
+
+// proxied marks the request as sent through a proxy
+func (p *phaseTracer) proxied() {
+	p.mu.Lock()
+	p.isProxied = true
+	p.mu.Unlock()
+}
+
+// tunnelled ends the proxy phase of a new proxied connection
+func (p *phaseTracer) tunnelled() {
+	p.mu.Lock()
+	if p.isProxied && !p.connectDone.IsZero() && p.timings.Proxy == 0 {
+		p.timings.Proxy = time.Since(p.connectDone)
+	}
+	p.mu.Unlock()
+}
+
+// clientTrace returns the httptrace hooks that feed the tracer
+func (p *phaseTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { p.mark(&p.dnsStart) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.measure(&p.timings.DNS, p.dnsStart)
+		},
+		ConnectStart: func(_, _ string) { p.mark(&p.connectStart) },
+		ConnectDone: func(_, _ string, _ error) {
+			p.measure(&p.timings.Connect, p.connectStart)
+			p.mark(&p.connectDone)
+		},
+		TLSHandshakeStart: func() {
+			p.tunnelled()
+			p.mark(&p.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.measure(&p.timings.TLS, p.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.tunnelled()
+			p.mu.Lock()
+			p.reused = info.Reused
+			p.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { p.mark(&p.wroteRequest) },
+		GotFirstResponseByte: func() { p.mark(&p.firstByte) },
+	}
+}
+
+// mark stores the current time in t
+func (p *phaseTracer) mark(t *time.Time) {
+	p.mu.Lock()
+	*t = time.Now()
+	p.mu.Unlock()
+}
+
+// measure stores the time elapsed since start in d
+func (p *phaseTracer) measure(d *time.Duration, start time.Time) {
+	p.mu.Lock()
+	if !start.IsZero() {
+		*d = time.Since(start)
+	}
+	p.mu.Unlock()
+}
+
+// finish completes the timings once the body has been read at end
+func (p *phaseTracer) finish(end time.Time) (timings Timings, reused, proxied bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.wroteRequest.IsZero() && !p.firstByte.IsZero() {
+		p.timings.Wait = p.firstByte.Sub(p.wroteRequest)
+	}
+	if !p.firstByte.IsZero() {
+		p.timings.Download = end.Sub(p.firstByte)
+	}
+	return p.timings, p.reused, p.isProxied
+}
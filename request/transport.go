@@ -0,0 +1,128 @@
+package request
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+/*
+TransportConfig describes how HTTP connections are pooled and negotiated.
+
+Structure Overview
+  - MaxIdleConns - total number of idle keep-alive connections kept in the pool
+  - MaxConnsPerHost - cap on dialing, active and idle connections per host (0 means unlimited)
+  - IdleConnTimeout - how long an idle connection stays in the pool before it is closed
+  - DisableKeepAlives - if true every request opens a new connection
+  - DisableCompression - if true the transport does not ask for gzip encoded bodies
+  - Protocol - "auto", "http1", "http2" or "h2c" (HTTP/2 without TLS, for servers that expect prior knowledge)
+  - Timeout - overall request timeout applied to the client (0 means no timeout)
+  - TLS - the TLS configuration of client connections (see TLSOptions); nil keeps the defaults
+  - Resolve - DNS overrides, resolver, caching and round-robin of the connections
+  - Proxy - the proxy of the requests; the zero value follows the proxy environment variables
+  - Dial - unix socket or tcp target dialed instead of the url's host, and source addresses
+*/
+type TransportConfig struct {
+	MaxIdleConns       int            `json:"max_idle_conns"`
+	MaxConnsPerHost    int            `json:"max_conns_per_host"`
+	IdleConnTimeout    time.Duration  `json:"idle_conn_timeout"`
+	DisableKeepAlives  bool           `json:"disable_keep_alives"`
+	DisableCompression bool           `json:"disable_compression"`
+	Protocol           string         `json:"protocol"`
+	Timeout            time.Duration  `json:"timeout"`
+	TLS                *tls.Config    `json:"-"`
+	Resolve            ResolveOptions `json:"resolve"`
+	Proxy              ProxyOptions   `json:"proxy"`
+	Dial               DialOptions    `json:"dial"`
+}
+
+// DefaultTransportConfig returns a TransportConfig that keeps connections alive
+// and lets the transport negotiate the protocol, similar to a browser.
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:    DefaultMaxIdleConns,
+		IdleConnTimeout: DefaultIdleConnTimeout,
+		Protocol:        ProtocolAuto,
+	}
+}
+
+// defaultClient is used by Fetch when the caller does not supply a client so that
+// ad-hoc fetches still share one connection pool instead of dialing every time.
+var defaultClient = NewClient(DefaultTransportConfig())
+
+// ValidateProtocol returns an error if protocol is not one of the supported values
+func ValidateProtocol(protocol string) error {
+	switch protocol {
+	case ProtocolAuto, ProtocolHTTP1, ProtocolHTTP2, ProtocolH2C:
+		return nil
+	default:
+		return fmt.Errorf("unsupported protocol %q (expected %s, %s, %s or %s)",
+			protocol, ProtocolAuto, ProtocolHTTP1, ProtocolHTTP2, ProtocolH2C)
+	}
+}
+
+// NewTransport builds an *http.Transport from the provided configuration
+func NewTransport(cfg TransportConfig) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   DefaultDialTimeout,
+		KeepAlive: DefaultTCPKeepAlive,
+	}
+
+	idlePerHost := cfg.MaxIdleConns
+	if cfg.MaxConnsPerHost > 0 && cfg.MaxConnsPerHost < idlePerHost {
+		idlePerHost = cfg.MaxConnsPerHost
+	}
+
+	transport := &http.Transport{
+		Proxy:                 cfg.Proxy.proxy(),
+		DialContext:           cfg.Dial.dialFunc(cfg.Resolve, dialer),
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   idlePerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		DisableKeepAlives:     cfg.DisableKeepAlives,
+		DisableCompression:    cfg.DisableCompression,
+		TLSHandshakeTimeout:   DefaultTLSHandshakeTimeout,
+		ExpectContinueTimeout: DefaultExpectContinueTimeout,
+	}
+	if cfg.Dial.Target != "" {
+		transport.Proxy = nil
+	}
+	if cfg.TLS != nil {
+		transport.TLSClientConfig = cfg.TLS.Clone()
+		if cfg.TLS.ClientSessionCache != nil {
+			// Every pool resumes its own sessions, like independent browsers
+			transport.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+		}
+	}
+
+	protocols := new(http.Protocols)
+	switch cfg.Protocol {
+	case ProtocolHTTP1:
+		protocols.SetHTTP1(true)
+	case ProtocolHTTP2:
+		protocols.SetHTTP2(true)
+	case ProtocolH2C:
+		protocols.SetUnencryptedHTTP2(true)
+		protocols.SetHTTP2(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		transport.ForceAttemptHTTP2 = true
+	}
+	transport.Protocols = protocols
+
+	return transport
+}
+
+// NewClient builds an *http.Client with its own transport from the provided configuration.
+// Each client owns a separate connection pool, so one client per virtual user
+// mimics independent browsers while a single shared client mimics a proxy.
+func NewClient(cfg TransportConfig) *http.Client {
+	return &http.Client{
+		Transport: NewTransport(cfg),
+		Timeout:   cfg.Timeout,
+	}
+}
@@ -0,0 +1,240 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+)
+
+// protocolServer serves a page with eight scripts
+func protocolServer() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			_, _ = w.Write([]byte("asset"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		var page strings.Builder
+		for i := range 8 {
+			fmt.Fprintf(&page, `<script src="/a%d.js"></script>`, i)
+		}
+		_, _ = w.Write([]byte(page.String()))
+	})
+}
+
+func TestProtocols(t *testing.T) {
+	color.Green("~~ TestProtocols ~~")
+	h2c := httptest.NewUnstartedServer(protocolServer())
+	h2c.Config.Protocols = new(http.Protocols)
+	h2c.Config.Protocols.SetHTTP1(true)
+	h2c.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2c.Start()
+	defer h2c.Close()
+
+	tls := httptest.NewUnstartedServer(protocolServer())
+	tls.EnableHTTP2 = true
+	tls.StartTLS()
+	defer tls.Close()
+
+	tests := []struct {
+		name     string
+		server   *httptest.Server
+		protocol string
+		want     string
+	}{
+		{"h2c prior knowledge", h2c, ProtocolH2C, "HTTP/2.0"},
+		{"http1 on an h2c server", h2c, ProtocolHTTP1, "HTTP/1.1"},
+		{"auto over cleartext", h2c, ProtocolAuto, "HTTP/1.1"},
+		{"http2 over tls", tls, ProtocolHTTP2, "HTTP/2.0"},
+		{"auto over tls", tls, ProtocolAuto, "HTTP/2.0"},
+		{"http1 over tls", tls, ProtocolHTTP1, "HTTP/1.1"},
+		{"h2c over tls", tls, ProtocolH2C, "HTTP/2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultTransportConfig()
+			cfg.Protocol = tt.protocol
+			client := NewClient(cfg)
+			defer client.CloseIdleConnections()
+			if tt.server.TLS != nil {
+				// Trust the test certificate; ALPN is left to the transport
+				trusted := tt.server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+				trusted.NextProtos = nil
+				client.Transport.(*http.Transport).TLSClientConfig = trusted
+			}
+
+			resp := FetchAll(FetchInput{BaseURL: tt.server.URL + "/", Client: client})
+			if resp.BaseURL.Protocol != tt.want {
+				t.Fatalf("protocol = %q, want %q", resp.BaseURL.Protocol, tt.want)
+			}
+			for _, js := range resp.JSResponses {
+				if js.Protocol != tt.want || js.Status != http.StatusOK {
+					t.Errorf("%s: protocol %q status %d, want %q", js.URL, js.Protocol, js.Status, tt.want)
+				}
+			}
+			stats, ok := resp.Protocols[tt.want]
+			if len(resp.Protocols) != 1 || !ok || stats.Requests != 9 {
+				t.Fatalf("Protocols = %+v, want 9 %s requests", resp.Protocols, tt.want)
+			}
+			// HTTP/2 multiplexes the concurrent asset requests on the page's connection
+			if tt.want == "HTTP/2.0" && stats.Connections != 1 {
+				t.Errorf("%d HTTP/2 connections for %d requests, want 1", stats.Connections, stats.Requests)
+			}
+		})
+	}
+}
+
+func TestProtocolCounts(t *testing.T) {
+	counts := ProtocolCounts{}
+	for _, resp := range []FetchResponse{
+		{Protocol: "HTTP/2.0"},
+		{Protocol: "HTTP/2.0", ConnReused: true},
+		{Protocol: "HTTP/2.0", ConnReused: true},
+		{Protocol: "HTTP/1.1"},
+		{CacheStatus: CacheStatusHit},
+		{Status: HTTPStatusConnectionError},
+	} {
+		counts.Add(&resp)
+	}
+	counts.Merge(ProtocolCounts{"HTTP/1.1": {Requests: 1, Connections: 1}})
+
+	want := ProtocolCounts{"HTTP/2.0": {Requests: 3, Connections: 1}, "HTTP/1.1": {Requests: 2, Connections: 2}}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	if names := counts.Names(); strings.Join(names, ",") != "HTTP/1.1,HTTP/2.0" {
+		t.Errorf("Names() = %v", names)
+	}
+	if got := counts["HTTP/2.0"].StreamsPerConn(); got != 3 {
+		t.Errorf("StreamsPerConn() = %v, want 3", got)
+	}
+}
+
+func TestValidateProtocol(t *testing.T) {
+	for _, protocol := range []string{ProtocolAuto, ProtocolHTTP1, ProtocolHTTP2, ProtocolH2C} {
+		if err := ValidateProtocol(protocol); err != nil {
+			t.Errorf("ValidateProtocol(%q) = %v", protocol, err)
+		}
+	}
+	if err := ValidateProtocol("http3"); err == nil {
+		t.Error("ValidateProtocol(http3) should fail")
+	}
+}
+
+// connServer counts the connections opened to it and the requests in flight at once
+type connServer struct {
+	*httptest.Server
+	conns    atomic.Int32
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func newConnServer(t *testing.T, delay time.Duration) *connServer {
+	t.Helper()
+	s := &connServer{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.mu.Lock()
+		s.inFlight++
+		s.peak = max(s.peak, s.inFlight)
+		s.mu.Unlock()
+		time.Sleep(delay)
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+		_, _ = w.Write([]byte("ok"))
+	}))
+	s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			s.conns.Add(1)
+		}
+	}
+	s.Start()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestConnectionPools(t *testing.T) {
+	const users, requests = 4, 5
+	noKeepAlive := DefaultTransportConfig()
+	noKeepAlive.DisableKeepAlives = true
+	tests := []struct {
+		name   string
+		cfg    TransportConfig
+		shared bool
+		conns  int32
+		reused int
+	}{
+		// Every user keeps its first connection for its next requests
+		{"per-user pools", DefaultTransportConfig(), false, users, users * (requests - 1)},
+		// Users taking turns on a shared pool all use one connection
+		{"shared pool", DefaultTransportConfig(), true, 1, users*requests - 1},
+		{"keep-alives disabled", noKeepAlive, true, users * requests, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newConnServer(t, 0)
+			clients := make([]*http.Client, users)
+			shared := NewClient(tt.cfg)
+			for i := range clients {
+				clients[i] = shared
+				if !tt.shared {
+					clients[i] = NewClient(tt.cfg)
+				}
+			}
+			reused := 0
+			for range requests {
+				for _, client := range clients {
+					resp := Fetch(FetchInput{BaseURL: server.URL, Client: client})
+					if resp.Status != http.StatusOK {
+						t.Fatalf("status %d: %s", resp.Status, resp.URL)
+					}
+					if resp.ConnReused {
+						reused++
+					}
+				}
+			}
+			if server.conns.Load() != tt.conns || reused != tt.reused {
+				t.Errorf("%d connections and %d/%d requests reused one, want %d and %d",
+					server.conns.Load(), reused, users*requests, tt.conns, tt.reused)
+			}
+		})
+	}
+}
+
+func TestMaxConnsPerHost(t *testing.T) {
+	cfg := DefaultTransportConfig()
+	cfg.MaxConnsPerHost = 2
+	cfg.Timeout = 5 * time.Second
+	transport := NewTransport(cfg)
+	if transport.MaxConnsPerHost != 2 || transport.MaxIdleConnsPerHost != 2 {
+		t.Errorf("MaxConnsPerHost %d, MaxIdleConnsPerHost %d", transport.MaxConnsPerHost, transport.MaxIdleConnsPerHost)
+	}
+
+	// Concurrent requests queue for the two connections of the host instead of opening more
+	server := newConnServer(t, 20*time.Millisecond)
+	client := NewClient(cfg)
+	if client.Timeout != cfg.Timeout {
+		t.Errorf("client timeout %s", client.Timeout)
+	}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Fetch(FetchInput{BaseURL: server.URL, Client: client})
+		}()
+	}
+	wg.Wait()
+	if server.conns.Load() != 2 || server.peak != 2 {
+		t.Errorf("%d connections, %d requests in flight at once, want 2 and 2", server.conns.Load(), server.peak)
+	}
+}
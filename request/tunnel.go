@@ -0,0 +1,154 @@
+package request
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+/*
+NewDialer returns the dial function of connections opened outside of an http.Transport,
+e.g. by a gRPC client, to reach scheme://addr urls.  Like NewTransport it follows the Dial
+and Resolve options of cfg and goes through the proxy the url would use: SOCKS5 proxies
+connect to addr and HTTP ones open a CONNECT tunnel to it.  TLS with addr is left to the
+caller.
+*/
+func NewDialer(cfg TransportConfig, scheme string) func(ctx context.Context, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   DefaultDialTimeout,
+		KeepAlive: DefaultTCPKeepAlive,
+	}
+	dial := cfg.Dial.dialFunc(cfg.Resolve, dialer)
+	proxyFunc := cfg.Proxy.proxyFunc()
+	if cfg.Dial.Target != "" {
+		proxyFunc = nil
+	}
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		if proxyFunc != nil {
+			proxyURL, err := proxyFunc(&url.URL{Scheme: scheme, Host: addr})
+			if err != nil {
+				return nil, err
+			}
+			if proxyURL != nil {
+				return dialProxy(ctx, dial, proxyURL, addr)
+			}
+		}
+		return dial(ctx, "tcp", addr)
+	}
+}
+
+// dialProxy connects to addr through the proxy at proxyURL
+func dialProxy(ctx context.Context, dial dialFunc, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyURL = withProxyPort(proxyURL)
+	if proxyURL.Scheme == ProxySchemeSOCKS5 || proxyURL.Scheme == ProxySchemeSOCKS5H {
+		socks, err := proxy.FromURL(proxyURL, contextDialer(dial))
+		if err != nil {
+			return nil, err
+		}
+		return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	}
+
+	conn, err := dial(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == HTTPSScheme {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	tunnel, err := connectTunnel(ctx, conn, proxyURL, addr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// connectTunnel asks the HTTP proxy on conn to open a tunnel to addr and returns the tunnel
+func connectTunnel(ctx context.Context, conn net.Conn, proxyURL *url.URL, addr string) (net.Conn, error) {
+	connect := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		connect.Header.Set(ProxyAuthorizationHeader, "Basic "+credentials)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := connect.Write(conn); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, connect)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("proxy %s refused the tunnel to %s: %s", proxyURL.Host, addr, resp.Status)
+	}
+	if reader.Buffered() > 0 {
+		// The origin spoke first, e.g. an HTTP/2 server sending its settings
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection whose first bytes were read into reader
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read implements net.Conn
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// withProxyPort returns proxyURL with the default port of its scheme when it has none
+func withProxyPort(proxyURL *url.URL) *url.URL {
+	if proxyURL.Port() != "" {
+		return proxyURL
+	}
+	port := DefaultHTTPPort
+	switch proxyURL.Scheme {
+	case HTTPSScheme:
+		port = DefaultHTTPSPort
+	case ProxySchemeSOCKS5, ProxySchemeSOCKS5H:
+		port = DefaultSOCKSPort
+	}
+	withPort := *proxyURL
+	withPort.Host = net.JoinHostPort(proxyURL.Hostname(), port)
+	return &withPort
+}
+
+// contextDialer adapts a dial function to the dialers of golang.org/x/net/proxy
+type contextDialer dialFunc
+
+// Dial implements proxy.Dialer
+func (d contextDialer) Dial(network, addr string) (net.Conn, error) {
+	return d(context.Background(), network, addr)
+}
+
+// DialContext implements proxy.ContextDialer
+func (d contextDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d(ctx, network, addr)
+}
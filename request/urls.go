@@ -0,0 +1,91 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFetchable is returned for asset references that a browser would not request
+// over the network, such as data:, blob: and javascript: URLs or empty attributes.
+var ErrNotFetchable = errors.New("asset reference is not fetchable")
+
+/*
+DefineAssetURL resolves an asset reference found on a page against the page URL.
+
+It follows the same rules as a browser: relative paths are resolved against the
+directory of baseURL, protocol-relative references ("//cdn.example.com/x.js")
+inherit the page scheme, the port of the page is kept and fragments are dropped.
+References that do not resolve to an http or https URL return ErrNotFetchable.
+*/
+func DefineAssetURL(baseURL, asseturl string) (string, error) {
+	base, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil {
+		return DefaultEmptyString, fmt.Errorf("invalid base url %q: %w", baseURL, err)
+	}
+	return resolveAgainst(base, asseturl)
+}
+
+// resolveAgainst resolves ref against an already parsed base URL
+func resolveAgainst(base *url.URL, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == DefaultEmptyString {
+		return DefaultEmptyString, ErrNotFetchable
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return DefaultEmptyString, fmt.Errorf("invalid asset url %q: %w", ref, err)
+	}
+	resolved := base.ResolveReference(refURL)
+	if resolved.Scheme != HTTPScheme && resolved.Scheme != HTTPSScheme {
+		return DefaultEmptyString, ErrNotFetchable
+	}
+	if resolved.Host == DefaultEmptyString {
+		return DefaultEmptyString, fmt.Errorf("asset url %q has no host: %w", ref, ErrNotFetchable)
+	}
+	resolved.Fragment = DefaultEmptyString
+	resolved.RawFragment = DefaultEmptyString
+	return resolved.String(), nil
+}
+
+/*
+AssetResolver turns the asset references of one page into absolute URLs.
+
+The document base is the page URL, or the page's <base href> when present
+(itself resolved against the page URL). Every URL is returned at most once
+across all calls to Resolve, so an image referenced by both <img> and a
+background style is fetched once, as a browser would.
+*/
+type AssetResolver struct {
+	base *url.URL
+	seen map[string]bool
+}
+
+// NewAssetResolver creates a resolver for the page at pageURL with an optional <base href> value
+func NewAssetResolver(pageURL, baseHref string) (*AssetResolver, error) {
+	base, err := url.Parse(strings.TrimSpace(pageURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid page url %q: %w", pageURL, err)
+	}
+	if href := strings.TrimSpace(baseHref); href != DefaultEmptyString {
+		if hrefURL, hrefErr := url.Parse(href); hrefErr == nil {
+			base = base.ResolveReference(hrefURL)
+		}
+	}
+	return &AssetResolver{base: base, seen: make(map[string]bool)}, nil
+}
+
+// Resolve returns the absolute, fetchable and not yet seen URLs for refs, in order
+func (r *AssetResolver) Resolve(refs []string) []string {
+	resolved := []string{}
+	for _, ref := range refs {
+		assetURL, err := resolveAgainst(r.base, ref)
+		if err != nil || r.seen[assetURL] {
+			continue
+		}
+		r.seen[assetURL] = true
+		resolved = append(resolved, assetURL)
+	}
+	return resolved
+}
@@ -0,0 +1,169 @@
+package request
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gnulnx/color"
+)
+
+func TestDefineAssetURL(t *testing.T) {
+	color.Green("~~ TestDefineAssetURL ~~")
+	tests := []struct {
+		name    string
+		base    string
+		asset   string
+		want    string
+		wantErr error
+	}{
+		{"absolute http", "https://example.com/", "http://cdn.example.com/a.js", "http://cdn.example.com/a.js", nil},
+		{"absolute https", "http://example.com/", "https://cdn.example.com/a.js", "https://cdn.example.com/a.js", nil},
+		{"root relative", "https://example.com/shop/item", "/static/a.css", "https://example.com/static/a.css", nil},
+		{"path relative", "https://example.com/shop/item", "img/a.png", "https://example.com/shop/img/a.png", nil},
+		{"path relative dir", "https://example.com/shop/", "img/a.png", "https://example.com/shop/img/a.png", nil},
+		{"dot segments", "https://example.com/a/b/c", "../x.js", "https://example.com/a/x.js", nil},
+		{"keeps port", "http://127.0.0.1:8080/index.html", "/a.css", "http://127.0.0.1:8080/a.css", nil},
+		{"keeps port relative", "http://localhost:3000/app/", "main.js", "http://localhost:3000/app/main.js", nil},
+		{"protocol relative https", "https://example.com/", "//cdn.example.com/a.js", "https://cdn.example.com/a.js", nil},
+		{"protocol relative http", "http://example.com/", "//cdn.example.com/a.js", "http://cdn.example.com/a.js", nil},
+		{"http prefixed path", "https://example.com/js/", "httpfoo/x.js", "https://example.com/js/httpfoo/x.js", nil},
+		{"query only", "https://example.com/page?a=1", "?v=2", "https://example.com/page?v=2", nil},
+		{"query kept", "https://example.com/", "/a.js?v=1.2", "https://example.com/a.js?v=1.2", nil},
+		{"fragment dropped", "https://example.com/", "/sprite.svg#icon", "https://example.com/sprite.svg", nil},
+		{"whitespace trimmed", "https://example.com/", "  /a.js\n", "https://example.com/a.js", nil},
+		{"single char", "https://example.com/dir/", "a", "https://example.com/dir/a", nil},
+		{"uppercase scheme", "https://example.com/", "HTTPS://cdn.example.com/a.js", "https://cdn.example.com/a.js", nil},
+		{"empty", "https://example.com/", "", "", ErrNotFetchable},
+		{"blank", "https://example.com/", "   ", "", ErrNotFetchable},
+		{"data uri", "https://example.com/", "data:image/png;base64,iVBORw0KGgo=", "", ErrNotFetchable},
+		{"blob uri", "https://example.com/", "blob:https://example.com/1234", "", ErrNotFetchable},
+		{"javascript uri", "https://example.com/", "javascript:void(0)", "", ErrNotFetchable},
+		{"mailto", "https://example.com/", "mailto:me@example.com", "", ErrNotFetchable},
+		{"ftp", "https://example.com/", "ftp://example.com/a.png", "", ErrNotFetchable},
+		{"fragment only", "https://example.com/page", "#top", "https://example.com/page", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DefineAssetURL(tt.base, tt.asset)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("DefineAssetURL(%q, %q) error = %v, want %v", tt.base, tt.asset, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DefineAssetURL(%q, %q) unexpected error: %v", tt.base, tt.asset, err)
+			}
+			if got != tt.want {
+				t.Errorf("DefineAssetURL(%q, %q) = %q, want %q", tt.base, tt.asset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefineAssetURLInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		asset string
+	}{
+		{"invalid base", "http://[::1", "/a.js"},
+		{"invalid asset", "https://example.com/", "http://[::1/a.js"},
+		{"invalid escape", "https://example.com/", "/a%zz.js"},
+		{"base without host", "/relative/page", "a.js"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DefineAssetURL(tt.base, tt.asset)
+			if err == nil {
+				t.Fatalf("DefineAssetURL(%q, %q) = %q, want error", tt.base, tt.asset, got)
+			}
+		})
+	}
+}
+
+func TestAssetResolver(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		baseHref string
+		refs     []string
+		want     []string
+	}{
+		{
+			name: "no base href",
+			page: "https://example.com/blog/post",
+			refs: []string{"a.css", "/b.css"},
+			want: []string{"https://example.com/blog/a.css", "https://example.com/b.css"},
+		},
+		{
+			name:     "absolute base href",
+			page:     "https://example.com/blog/post",
+			baseHref: "https://static.example.com/v2/",
+			refs:     []string{"a.css", "/b.css", "//cdn.example.com/c.css"},
+			want: []string{
+				"https://static.example.com/v2/a.css",
+				"https://static.example.com/b.css",
+				"https://cdn.example.com/c.css",
+			},
+		},
+		{
+			name:     "relative base href",
+			page:     "http://localhost:8080/blog/post",
+			baseHref: "/assets/",
+			refs:     []string{"app.js"},
+			want:     []string{"http://localhost:8080/assets/app.js"},
+		},
+		{
+			name: "duplicates removed",
+			page: "https://example.com/",
+			refs: []string{"/a.png", "a.png", "https://example.com/a.png", "/a.png#x", "/b.png"},
+			want: []string{"https://example.com/a.png", "https://example.com/b.png"},
+		},
+		{
+			name: "non fetchable filtered",
+			page: "https://example.com/",
+			refs: []string{"data:image/gif;base64,R0lGOD", "", "javascript:alert(1)", "blob:x", "/ok.png"},
+			want: []string{"https://example.com/ok.png"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := NewAssetResolver(tt.page, tt.baseHref)
+			if err != nil {
+				t.Fatalf("NewAssetResolver(%q, %q) error: %v", tt.page, tt.baseHref, err)
+			}
+			got := resolver.Resolve(tt.refs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.refs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssetResolverSharedAcrossTypes(t *testing.T) {
+	resolver, err := NewAssetResolver("https://example.com/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := resolver.Resolve([]string{"/hero.jpg"})
+	second := resolver.Resolve([]string{"/hero.jpg", "/other.jpg"})
+	if !reflect.DeepEqual(first, []string{"https://example.com/hero.jpg"}) {
+		t.Errorf("first Resolve = %q", first)
+	}
+	if !reflect.DeepEqual(second, []string{"https://example.com/other.jpg"}) {
+		t.Errorf("second Resolve = %q, want only the unseen URL", second)
+	}
+}
+
+func BenchmarkAssetResolver(b *testing.B) {
+	refs := []string{"/a.js", "b.css", "//cdn.example.com/c.png", "data:image/png;base64,AAAA", "../d.jpg"}
+	for i := 0; i < b.N; i++ {
+		resolver, _ := NewAssetResolver("https://example.com/shop/item", "")
+		resolver.Resolve(refs)
+	}
+}
@@ -0,0 +1,301 @@
+package request
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gnulnx/color"
+)
+
+/*
+WaterfallEntry is one row of a page load waterfall.
+
+Structure Overview
+  - URL, Type, Status, Bytes - what was fetched and what came back
+  - QueueTime - time spent waiting for a per-origin slot before StartOffset
+  - StartOffset/EndOffset - when the request was sent and finished, relative to the base request
+  - Timings - phase breakdown of the request
+  - ConnReused, CacheStatus - whether a pooled connection or the HTTP cache was used
+  - Protocol - the protocol of the response, e.g. "HTTP/2.0"
+  - Initiator - the stylesheet that referenced the asset, if it was not referenced by the page
+*/
+type WaterfallEntry struct {
+	URL         string        `json:"url"`
+	Type        string        `json:"type"`
+	Status      int           `json:"status"`
+	Bytes       int           `json:"bytes"`
+	QueueTime   time.Duration `json:"queueTime"`
+	StartOffset time.Duration `json:"startOffset"`
+	EndOffset   time.Duration `json:"endOffset"`
+	Timings     Timings       `json:"timings"`
+	ConnReused  bool          `json:"connReused"`
+	Protocol    string        `json:"protocol,omitempty"`
+	CacheStatus string        `json:"cacheStatus,omitempty"`
+	Initiator   string        `json:"initiator,omitempty"`
+}
+
+// waterfallSpan is a contiguous phase of an entry on the timeline
+type waterfallSpan struct {
+	Phase string
+	From  time.Duration
+	To    time.Duration
+}
+
+// setOffsets fills StartOffset and EndOffset of resps relative to base
+func setOffsets(base time.Time, resps []FetchResponse) {
+	for i := range resps {
+		if resps[i].Started.IsZero() {
+			continue
+		}
+		resps[i].StartOffset = resps[i].Started.Sub(base)
+		resps[i].EndOffset = resps[i].Finished.Sub(base)
+	}
+}
+
+// Waterfall returns the base request and every asset ordered by start offset
+func (r *FetchAllResponse) Waterfall() []WaterfallEntry {
+	entries := []WaterfallEntry{newWaterfallEntry(r.BaseURL, AssetTypeDocument)}
+	for _, kind := range AssetTypes {
+		resps := *r.Responses(kind)
+		for i := range resps {
+			entries = append(entries, newWaterfallEntry(&resps[i], kind))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartOffset-entries[i].QueueTime < entries[j].StartOffset-entries[j].QueueTime
+	})
+	return entries
+}
+
+func newWaterfallEntry(resp *FetchResponse, kind string) WaterfallEntry {
+	return WaterfallEntry{
+		URL:         resp.URL,
+		Type:        kind,
+		Status:      resp.Status,
+		Bytes:       resp.Bytes,
+		QueueTime:   resp.QueueTime,
+		StartOffset: resp.StartOffset,
+		EndOffset:   resp.EndOffset,
+		Timings:     resp.Timings,
+		ConnReused:  resp.ConnReused,
+		Protocol:    resp.Protocol,
+		CacheStatus: resp.CacheStatus,
+		Initiator:   resp.Initiator,
+	}
+}
+
+// spans splits an entry into queue, dns, connect, proxy, tls, wait and download phases.
+// Connection setup runs forward from the start offset and the download runs back
+// from the end offset; whatever is left in between is waiting on the server.
+func (e *WaterfallEntry) spans() []waterfallSpan {
+	spans := []waterfallSpan{}
+	add := func(phase string, from, to time.Duration) {
+		if to > from {
+			spans = append(spans, waterfallSpan{Phase: phase, From: from, To: to})
+		}
+	}
+	add(PhaseQueue, e.StartOffset-e.QueueTime, e.StartOffset)
+
+	t := e.StartOffset
+	for _, phase := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{PhaseDNS, e.Timings.DNS}, {PhaseConnect, e.Timings.Connect}, {PhaseProxy, e.Timings.Proxy},
+		{PhaseTLS, e.Timings.TLS},
+	} {
+		to := min(t+phase.d, e.EndOffset)
+		add(phase.name, t, to)
+		t = to
+	}
+
+	downloadStart := max(e.EndOffset-e.Timings.Download, t)
+	add(PhaseWait, t, downloadStart)
+	add(PhaseDownload, downloadStart, e.EndOffset)
+	return spans
+}
+
+// waterfallEnd returns the largest end offset of entries
+func waterfallEnd(entries []WaterfallEntry) time.Duration {
+	end := time.Duration(0)
+	for i := range entries {
+		end = max(end, entries[i].EndOffset)
+	}
+	return end
+}
+
+/*
+PrintWaterfall renders the page load as a devtools-like waterfall on stdout.
+Each row shows the request's start offset, duration and status followed by a bar:
+queueing (-), dns (d), connect (c), proxy tunnel (p), tls (s), waiting (w) and download (#).
+*/
+func PrintWaterfall(resp *FetchAllResponse) {
+	entries := resp.Waterfall()
+	end := waterfallEnd(entries)
+	if end <= 0 {
+		end = time.Nanosecond
+	}
+	yellow := color.New(color.FgHiYellow, color.Underline).SprintfFunc()
+	grey := color.New(color.FgHiBlack).SprintfFunc()
+	paint := map[string]func(string, ...interface{}) string{
+		PhaseQueue:    grey,
+		PhaseDNS:      color.New(color.FgCyan).SprintfFunc(),
+		PhaseConnect:  color.New(color.FgYellow).SprintfFunc(),
+		PhaseProxy:    color.New(color.FgRed).SprintfFunc(),
+		PhaseTLS:      color.New(color.FgMagenta).SprintfFunc(),
+		PhaseWait:     color.New(color.FgGreen).SprintfFunc(),
+		PhaseDownload: color.New(color.FgBlue).SprintfFunc(),
+	}
+
+	color.Red("Waterfall")
+	fmt.Printf(" - %s %s %s %s %s  %s\n", yellow("%-9s", "Start"), yellow("%-9s", "Time"), yellow("%-6s", "Status"),
+		yellow("%-6s", "Conn"), yellow("%-*s", WaterfallURLWidth, "Url"), yellow("Timeline %s", end.String()))
+	for i := range entries {
+		e := &entries[i]
+		conn := "new"
+		switch {
+		case e.CacheStatus == CacheStatusHit:
+			conn = "cache"
+		case e.ConnReused:
+			conn = "reused"
+		}
+		fmt.Printf(" - %-9s %-9s %-6s %-6s %-*s |%s|\n",
+			e.StartOffset.Round(time.Microsecond).String(),
+			(e.EndOffset - e.StartOffset).Round(time.Microsecond).String(),
+			strconv.Itoa(e.Status), conn, WaterfallURLWidth, truncateURL(e.URL, WaterfallURLWidth),
+			renderBar(e.spans(), end, paint))
+	}
+	fmt.Printf("   %s\n", grey("- queued  d dns  c connect  p proxy  s tls  w waiting  # download"))
+}
+
+// renderBar draws spans on a fixed width character timeline scaled to end
+func renderBar(spans []waterfallSpan, end time.Duration, paint map[string]func(string, ...interface{}) string) string {
+	symbols := map[string]string{
+		PhaseQueue: "-", PhaseDNS: "d", PhaseConnect: "c", PhaseProxy: "p", PhaseTLS: "s", PhaseWait: "w",
+		PhaseDownload: "#",
+	}
+	column := func(d time.Duration) int {
+		return int(int64(d) * WaterfallWidth / int64(end))
+	}
+
+	var bar strings.Builder
+	pos := 0
+	for _, span := range spans {
+		from, to := column(span.From), column(span.To)
+		if to <= from {
+			to = from + 1 // keep very short phases visible
+		}
+		from = max(from, pos)
+		to = min(to, WaterfallWidth)
+		if to <= from {
+			continue
+		}
+		bar.WriteString(strings.Repeat(" ", from-pos))
+		bar.WriteString(paint[span.Phase]("%s", strings.Repeat(symbols[span.Phase], to-from)))
+		pos = to
+	}
+	bar.WriteString(strings.Repeat(" ", max(WaterfallWidth-pos, 0)))
+	return bar.String()
+}
+
+// truncateURL shortens u to width characters keeping its tail, which is usually the file name
+func truncateURL(u string, width int) string {
+	if len(u) <= width {
+		return u
+	}
+	return "..." + u[len(u)-width+len("..."):]
+}
+
+// waterfallRow is the template data for one HTML waterfall row
+type waterfallRow struct {
+	WaterfallEntry
+	Duration time.Duration
+	Bars     []waterfallBar
+}
+
+// waterfallBar is a phase positioned in percent of the timeline
+type waterfallBar struct {
+	Phase string
+	Left  string
+	Width string
+}
+
+var waterfallTemplate = template.Must(template.New("waterfall").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GoPerf waterfall - {{.URL}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; }
+table { border-collapse: collapse; width: 100%; }
+td, th { padding: 2px 6px; text-align: left; white-space: nowrap; }
+tr:nth-child(even) { background: #f4f4f4; }
+td.url { max-width: 420px; overflow: hidden; text-overflow: ellipsis; }
+td.timeline { width: 50%; position: relative; }
+.bar { position: absolute; top: 4px; height: 12px; }
+.queue { background: #c8c8c8; } .dns { background: #1fa2a2; } .connect { background: #e3a21a; }
+.proxy { background: #d9534f; } .tls { background: #b04ad1; } .wait { background: #2fb14d; }
+.download { background: #2d6fd6; }
+.legend span { display: inline-block; padding: 0 6px; margin-right: 4px; color: #fff; }
+</style>
+</head>
+<body>
+<h1>{{.URL}}</h1>
+<p>Total time {{.Total}} &middot; {{.Requests}} requests &middot; {{.Bytes}} bytes</p>
+<p class="legend"><span class="queue">queued</span><span class="dns">dns</span><span class="connect">connect</span>
+<span class="proxy">proxy</span><span class="tls">tls</span><span class="wait">waiting</span>
+<span class="download">download</span></p>
+<table>
+<tr><th>Url</th><th>Type</th><th>Status</th><th>Bytes</th><th>Start</th><th>Time</th><th>Conn</th><th>Protocol</th>
+<th>Timeline</th></tr>
+{{range .Rows}}<tr>
+<td class="url" title="{{.URL}}{{if .Initiator}} (from {{.Initiator}}){{end}}">{{.URL}}</td>
+<td>{{.Type}}</td><td>{{.Status}}</td><td>{{.Bytes}}</td>
+<td>{{.StartOffset}}</td><td>{{.Duration}}</td>
+<td>{{if .CacheStatus}}{{.CacheStatus}}{{else if .ConnReused}}reused{{else}}new{{end}}</td>
+<td>{{.Protocol}}</td>
+<td class="timeline">{{range .Bars}}
+<div class="bar {{.Phase}}" style="left:{{.Left}};width:{{.Width}}"></div>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteWaterfallHTML writes a standalone HTML page with the page load waterfall
+func WriteWaterfallHTML(w io.Writer, resp *FetchAllResponse) error {
+	entries := resp.Waterfall()
+	end := waterfallEnd(entries)
+	if end <= 0 {
+		end = time.Nanosecond
+	}
+	percent := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)*PercentageBase/float64(end), 'f', WaterfallPercentPrecision, 64) + "%"
+	}
+
+	rows := make([]waterfallRow, 0, len(entries))
+	for i := range entries {
+		row := waterfallRow{WaterfallEntry: entries[i], Duration: entries[i].EndOffset - entries[i].StartOffset}
+		for _, span := range entries[i].spans() {
+			row.Bars = append(row.Bars, waterfallBar{
+				Phase: span.Phase,
+				Left:  percent(span.From),
+				Width: percent(span.To - span.From),
+			})
+		}
+		rows = append(rows, row)
+	}
+
+	return waterfallTemplate.Execute(w, struct {
+		URL      string
+		Total    time.Duration
+		Requests int
+		Bytes    int
+		Rows     []waterfallRow
+	}{resp.BaseURL.URL, resp.TotalTime, resp.TotalRequests, resp.TotalBytes, rows})
+}
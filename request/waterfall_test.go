@@ -0,0 +1,111 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+)
+
+// waterfallServer serves a page with a stylesheet and three scripts, each answered after a short delay
+func waterfallServer() *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		if r.URL.Path != "/" {
+			_, _ = w.Write([]byte(strings.Repeat("x", 4096)))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<link rel="stylesheet" href="/site.css">` +
+			`<script src="/a.js"></script><script src="/b.js"></script><script src="/c.js"></script>`))
+	}))
+}
+
+// printed returns what print writes to stdout
+func printed(t *testing.T, print func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	print()
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestWaterfall(t *testing.T) {
+	color.Green("~~ TestWaterfall ~~")
+	server := waterfallServer()
+	defer server.Close()
+
+	// One connection per origin queues the assets behind each other
+	resp := FetchAll(FetchInput{BaseURL: server.URL + "/", Client: server.Client(),
+		Browser: &BrowserOptions{MaxConnsPerOrigin: 1, MaxStreamsPerOrigin: 1}})
+	entries := resp.Waterfall()
+	if len(entries) != 5 || entries[0].Type != AssetTypeDocument || entries[0].StartOffset != 0 {
+		t.Fatalf("waterfall starts with %+v, %d entries", entries[0], len(entries))
+	}
+
+	// The base request opened the connection: connect and TLS come before waiting and the download
+	base := entries[0].Timings
+	if base.Connect <= 0 || base.TLS <= 0 || base.Wait <= 0 || base.Download < 0 || entries[0].ConnReused {
+		t.Errorf("base request timings %+v, reused %v", base, entries[0].ConnReused)
+	}
+
+	queued := 0
+	for i := range entries {
+		e := &entries[i]
+		if e.Status != http.StatusOK || e.EndOffset < e.StartOffset || e.StartOffset < 0 {
+			t.Errorf("%s: status %d, offsets %s-%s", e.URL, e.Status, e.StartOffset, e.EndOffset)
+		}
+		if i > 0 && e.StartOffset-e.QueueTime < entries[i-1].StartOffset-entries[i-1].QueueTime {
+			t.Errorf("%s starts before %s", e.URL, entries[i-1].URL)
+		}
+		if e.Timings.DNS+e.Timings.Connect+e.Timings.TLS+e.Timings.Wait+e.Timings.Download > e.EndOffset-e.StartOffset {
+			t.Errorf("%s: phases %+v longer than the request", e.URL, e.Timings)
+		}
+		if e.QueueTime > 0 {
+			queued++
+		}
+
+		// Spans follow each other from the queue to the end of the download
+		spans := e.spans()
+		if len(spans) == 0 || spans[len(spans)-1].To != e.EndOffset {
+			t.Fatalf("%s: spans %+v end before %s", e.URL, spans, e.EndOffset)
+		}
+		for j := 1; j < len(spans); j++ {
+			if spans[j].From != spans[j-1].To || spans[j].To <= spans[j].From {
+				t.Errorf("%s: span %+v after %+v", e.URL, spans[j], spans[j-1])
+			}
+		}
+	}
+	if queued < 3 {
+		t.Errorf("%d of 4 assets queued on a single connection", queued)
+	}
+
+	out := printed(t, func() { PrintWaterfall(resp) })
+	if rows := strings.Count(out, "|\n"); rows != len(entries) || !strings.Contains(out, "/site.css") {
+		t.Errorf("PrintWaterfall printed %d rows:\n%s", rows, out)
+	}
+
+	var page bytes.Buffer
+	if err := WriteWaterfallHTML(&page, resp); err != nil {
+		t.Fatal(err)
+	}
+	html := page.String()
+	if !strings.Contains(html, "<title>GoPerf waterfall - "+server.URL+"/</title>") ||
+		strings.Count(html, `<td class="url"`) != len(entries) ||
+		strings.Count(html, `<div class="bar tls"`) != 1 || !strings.HasSuffix(html, "</html>\n") {
+		t.Errorf("waterfall HTML:\n%s", html)
+	}
+}
@@ -0,0 +1,36 @@
+/*
+Package stream load tests streaming HTTP endpoints: Server-Sent Events, chunked responses
+and long polls.  Every virtual user holds a request open and reads its events as they
+arrive, measuring the time to the first event, the gaps between events and the event rate.
+
+Responses of type text/event-stream are parsed as Server-Sent Events; any other response
+is read line by line, each non-empty line being an event, so newline-delimited JSON streams
+and long-poll responses are measured the same way.
+*/
+package stream
+
+import "time"
+
+const (
+	// ContentTypeEventStream is the content type of Server-Sent Events responses
+	ContentTypeEventStream = "text/event-stream"
+	// HeaderLastEventID resumes an event stream after the last event received
+	HeaderLastEventID = "Last-Event-ID"
+	// DefaultEventType is the type of Server-Sent Events without an event field
+	DefaultEventType = "message"
+	// LineEventType is the type of the events of responses that are not event streams
+	LineEventType = "line"
+
+	// ResultTypeConnect marks the requests, timed up to their response headers, recorded in the metrics collector
+	ResultTypeConnect = "connect"
+	// ResultTypeFirstEvent marks the time to the first event of a response recorded in the metrics collector
+	ResultTypeFirstEvent = "first-event"
+	// ResultTypeEvent marks the gaps between events recorded in the metrics collector
+	ResultTypeEvent = "event"
+
+	// DefaultTimeout is how long the response headers may take when Options.Timeout is not set
+	DefaultTimeout = 10 * time.Second
+	// ReconnectDelay is how long a user waits before opening a stream again after an error,
+	// or after an event stream ended unless the server set another retry delay
+	ReconnectDelay = 500 * time.Millisecond
+)
@@ -0,0 +1,425 @@
+package stream
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/executor"
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
+	"github.com/Gosayram/goperf/request"
+)
+
+/*
+Options configures a streaming load test.
+
+Structure Overview
+  - URL - the http:// or https:// streaming endpoint, requested with GET
+  - Users, Duration - how many streams are held open and for how long
+  - MaxConnection - caps how long a stream is held; it is then closed and opened again.
+    0 holds every stream until the server ends it or the test is over.
+  - Timeout - how long the response headers may take (DefaultTimeout when 0)
+  - Header - extra request headers, e.g. Accept: text/event-stream
+  - Transport, SharedTransport - connection pooling, one pool per user unless shared
+  - Collector, Session - when set every request, first event and event gap is also recorded there
+
+A stream the server ends is opened again: at once for a long poll, after the retry delay
+of the server (ReconnectDelay by default) for an event stream, sending the id of the last
+event received as Last-Event-ID.
+*/
+type Options struct {
+	URL             string
+	Users           int
+	Duration        time.Duration
+	MaxConnection   time.Duration
+	Timeout         time.Duration
+	Header          http.Header
+	Transport       request.TransportConfig
+	SharedTransport bool
+	Collector       interfaces.MetricsCollector
+	Session         *interfaces.TestSession
+}
+
+/*
+Result summarizes a streaming load test.
+
+Structure Overview
+  - Connections, ConnectErrors - streams opened with a 2xx response, and requests that failed
+    or got another status
+  - Completed - streams the server ended, as long polls do after every response
+  - Capped - streams closed by the client after MaxConnection
+  - Dropped - streams that broke before the end of the test
+  - Connect, FirstEvent, Gap - latencies up to the response headers, from the request to
+    the first event, and between the following events of a stream
+  - Events, Types - events received, in total and by event type ("line" outside event streams)
+*/
+type Result struct {
+	executor.Summary
+	Connections     int             `json:"connections"`
+	ConnectErrors   int             `json:"connectErrors"`
+	Completed       int             `json:"completed"`
+	Capped          int             `json:"capped"`
+	Dropped         int             `json:"dropped"`
+	Connect         metrics.Latency `json:"connect"`
+	FirstEvent      metrics.Latency `json:"firstEvent"`
+	Gap             metrics.Latency `json:"gap"`
+	Events          int             `json:"events"`
+	EventsPerSecond float64         `json:"eventsPerSecond"`
+	Types           map[string]int  `json:"types"`
+	BytesReceived   int             `json:"bytesReceived"`
+}
+
+// userStats are the measurements of one virtual user
+type userStats struct {
+	connect       []time.Duration
+	firstEvent    []time.Duration
+	gaps          []time.Duration
+	connections   int
+	connectErrors int
+	completed     int
+	capped        int
+	dropped       int
+	events        int
+	types         map[string]int
+	bytesReceived int
+	lastError     string
+
+	lastEventID string        // sent as Last-Event-ID when the stream is opened again
+	retry       time.Duration // reconnection delay set by the server
+}
+
+// runner runs the virtual users of a test
+type runner struct {
+	opts     Options
+	recorder executor.Recorder
+	shared   *http.Client
+}
+
+// Run holds opts.Users streams for opts.Duration, or until ctx is cancelled, and returns their results
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || (u.Scheme != request.HTTPScheme && u.Scheme != request.HTTPSScheme) || u.Host == "" {
+		return nil, fmt.Errorf("invalid streaming url %q (expected http:// or https://)", opts.URL)
+	}
+	if opts.MaxConnection < 0 {
+		return nil, fmt.Errorf("the maximum stream duration must not be negative")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	opts.Transport.Timeout = 0 // streams last as long as the test
+	r := &runner{opts: opts, recorder: executor.Recorder{Collector: opts.Collector, Session: opts.Session}}
+	r.shared = request.NewClient(opts.Transport)
+	defer r.shared.CloseIdleConnections()
+
+	users, elapsed, err := executor.Run(ctx, opts.Users, opts.Duration, r.user)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(&opts, users, elapsed), nil
+}
+
+// user opens streams one after the other until the test is over.  The first user, and every
+// user when the transport is shared, uses the shared client.
+func (r *runner) user(ctx context.Context, i int, stats *userStats) {
+	client := r.shared
+	if !r.opts.SharedTransport && i > 0 {
+		client = request.NewClient(r.opts.Transport)
+		defer client.CloseIdleConnections()
+	}
+	stats.types = map[string]int{}
+	for ctx.Err() == nil {
+		delay := r.open(ctx, client, stats)
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+		}
+	}
+}
+
+// open holds one stream until it ends and returns how long to wait before opening the next one
+func (r *runner) open(ctx context.Context, client *http.Client, stats *userStats) time.Duration {
+	streamCtx, cancel := ctx, context.CancelFunc(func() {})
+	if r.opts.MaxConnection > 0 {
+		streamCtx, cancel = context.WithTimeout(ctx, r.opts.MaxConnection)
+	}
+	defer cancel()
+	resp, start, err := r.request(streamCtx, client, stats)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0 // the test ended before the response
+		}
+		r.failed(stats, start, err)
+		return ReconnectDelay
+	}
+	defer resp.Body.Close()
+
+	body := &countingReader{reader: resp.Body}
+	eventStream := isEventStream(resp.Header)
+	err = r.read(bufio.NewReader(body), eventStream, start, stats)
+	stats.bytesReceived += body.count
+	switch {
+	case ctx.Err() != nil:
+		return 0
+	case streamCtx.Err() != nil:
+		stats.capped++
+		return 0
+	case errors.Is(err, io.EOF):
+		stats.completed++
+		if eventStream {
+			return cmp.Or(stats.retry, ReconnectDelay)
+		}
+		return 0 // long poll
+	default:
+		stats.dropped++
+		stats.lastError = err.Error()
+		return ReconnectDelay
+	}
+}
+
+// request sends the request of a stream and waits up to the timeout for a 2xx response,
+// returning it with the time it was sent
+func (r *runner) request(ctx context.Context, client *http.Client,
+	stats *userStats) (*http.Response, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.opts.URL, http.NoBody)
+	if err != nil {
+		return nil, time.Now(), err
+	}
+	for name, values := range r.opts.Header {
+		req.Header[name] = values
+	}
+	if stats.lastEventID != "" {
+		req.Header.Set(HeaderLastEventID, stats.lastEventID)
+	}
+
+	// The timeout only applies to the headers, the body is read as long as the stream lasts
+	reqCtx, cancel := context.WithCancel(ctx)
+	req = req.WithContext(reqCtx)
+	timer := time.AfterFunc(r.opts.Timeout, cancel)
+	start := time.Now()
+	resp, err := client.Do(req)
+	elapsed := time.Since(start)
+	if !timer.Stop() && ctx.Err() == nil {
+		if err == nil {
+			resp.Body.Close()
+		}
+		err = fmt.Errorf("no response headers within %s", r.opts.Timeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, start, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		resp.Body.Close()
+		cancel()
+		return nil, start, &statusError{code: resp.StatusCode, status: resp.Status}
+	}
+	r.recorder.Record(&interfaces.RequestResult{
+		URL: r.opts.URL, Type: ResultTypeConnect, StatusCode: resp.StatusCode, Duration: elapsed,
+		Success: true, Timestamp: start,
+	})
+	stats.connections++
+	stats.connect = append(stats.connect, elapsed)
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, start, nil
+}
+
+// statusError is the error of a request answered with a status other than 2xx
+type statusError struct {
+	code   int
+	status string
+}
+
+// Error returns the status of the response
+func (e *statusError) Error() string {
+	return "unexpected status " + e.status
+}
+
+// failed counts and records a request sent at start that did not open a stream
+func (r *runner) failed(stats *userStats, start time.Time, err error) {
+	stats.connectErrors++
+	stats.lastError = err.Error()
+	result := &interfaces.RequestResult{
+		URL: r.opts.URL, Type: ResultTypeConnect, Duration: time.Since(start), ErrorMessage: err.Error(),
+		Timestamp: start,
+	}
+	var status *statusError
+	if errors.As(err, &status) {
+		result.StatusCode = status.code
+	}
+	r.recorder.Record(result)
+}
+
+/*
+read receives the events of a stream sent at start until it ends, with io.EOF when the
+server ended it.  Events streams are parsed as Server-Sent Events: an event is dispatched
+by a blank line once it has data, comments are skipped, and the id and retry fields are
+kept for the next stream.  Other responses have an event per non-empty line.
+*/
+func (r *runner) read(reader *bufio.Reader, eventStream bool, start time.Time, stats *userStats) error {
+	last, first := start, true
+	dispatch := func(kind string, size int) {
+		now := time.Now()
+		gap, resultType := now.Sub(last), ResultTypeEvent
+		if first {
+			stats.firstEvent = append(stats.firstEvent, gap)
+			resultType, first = ResultTypeFirstEvent, false
+		} else {
+			stats.gaps = append(stats.gaps, gap)
+		}
+		last = now
+		stats.events++
+		stats.types[kind]++
+		r.recorder.Record(&interfaces.RequestResult{
+			URL: r.opts.URL, Type: resultType, Duration: gap, Size: size, Success: true, Timestamp: now.Add(-gap),
+		})
+	}
+
+	var (
+		kind    string
+		size    int
+		hasData bool
+	)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !eventStream && strings.TrimSpace(line) != "" {
+			dispatch(LineEventType, len(line)) // a long-poll response without a final newline
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if !eventStream {
+			if strings.TrimSpace(line) != "" {
+				dispatch(LineEventType, len(line))
+			}
+			continue
+		}
+
+		if line == "" {
+			if hasData {
+				dispatch(cmp.Or(kind, DefaultEventType), size)
+			}
+			kind, size, hasData = "", 0, false
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":") // comments have no field name and are skipped
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			size += len(value)
+			hasData = true
+		case "event":
+			kind = value
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				stats.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				stats.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// isEventStream tells whether a response is a Server-Sent Events stream
+func isEventStream(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == ContentTypeEventStream
+}
+
+// countingReader counts the bytes read from a response body
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+// Read reads from the body and counts the bytes
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.count += n
+	return n, err
+}
+
+// cancelBody releases the context of a request once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// newResult merges the measurements of every user of a test that took elapsed
+func newResult(opts *Options, users []userStats, elapsed time.Duration) *Result {
+	result := &Result{Summary: executor.NewSummary(opts.URL, users, elapsed, func(u *userStats) string {
+		return u.lastError
+	}), Types: map[string]int{}}
+	for i := range users {
+		u := &users[i]
+		result.Connections += u.connections
+		result.ConnectErrors += u.connectErrors
+		result.Completed += u.completed
+		result.Capped += u.capped
+		result.Dropped += u.dropped
+		result.Events += u.events
+		result.BytesReceived += u.bytesReceived
+		for kind, n := range u.types {
+			result.Types[kind] += n
+		}
+	}
+	result.Connect = executor.Latency(users, func(u *userStats) []time.Duration { return u.connect })
+	result.FirstEvent = executor.Latency(users, func(u *userStats) []time.Duration { return u.firstEvent })
+	result.Gap = executor.Latency(users, func(u *userStats) []time.Duration { return u.gaps })
+	result.EventsPerSecond = result.PerSecond(result.Events)
+	return result
+}
+
+// PrintResult prints a streaming load test result to stdout
+func PrintResult(result *Result) {
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("Streaming Results")
+	fmt.Printf(" - %-34s %s\n", yel("Url"), white(result.URL))
+	fmt.Printf(" - %-34s %s\n", yel("Users / Duration"), white("%d / %s", result.Users,
+		result.Duration.Round(time.Millisecond)))
+	fmt.Printf(" - %-34s %s\n", yel("Streams"), white("%d (%d failed, %d ended, %d capped, %d dropped)",
+		result.Connections, result.ConnectErrors, result.Completed, result.Capped, result.Dropped))
+	fmt.Printf(" - %-34s %s\n", yel("Connect (avg / p95 / max)"), white("%s / %s / %s", result.Connect.Avg,
+		result.Connect.P95, result.Connect.Max))
+	fmt.Printf(" - %-34s %s\n", yel("First Event (avg / p50 / p95 / p99)"), white("%s / %s / %s / %s",
+		result.FirstEvent.Avg, result.FirstEvent.P50, result.FirstEvent.P95, result.FirstEvent.P99))
+	fmt.Printf(" - %-34s %s\n", yel("Event Gap (avg / p50 / p95 / p99)"), white("%s / %s / %s / %s",
+		result.Gap.Avg, result.Gap.P50, result.Gap.P95, result.Gap.P99))
+	fmt.Printf(" - %-34s %s\n", yel("Events"), white("%d, %.1f per second", result.Events, result.EventsPerSecond))
+	for _, kind := range slices.Sorted(maps.Keys(result.Types)) {
+		fmt.Printf(" - %-34s %s\n", yel("  "+kind), white("%d", result.Types[kind]))
+	}
+	fmt.Printf(" - %-34s %s\n", yel("Bytes Received"), white("%d", result.BytesReceived))
+	if result.LastError != "" {
+		fmt.Printf(" - %-34s %s\n", yel("Last Error"), white(result.LastError))
+	}
+}
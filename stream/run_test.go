@@ -0,0 +1,192 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
+	"github.com/Gosayram/goperf/request"
+)
+
+// streamServer serves handler and returns its url
+func streamServer(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// run runs a test of url with two users for 300ms
+func run(t *testing.T, url string, opts Options) *Result {
+	t.Helper()
+	opts.URL, opts.Users, opts.Duration = url, 2, 300*time.Millisecond
+	opts.Transport = request.DefaultTransportConfig()
+	result, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestRunEventStream(t *testing.T) {
+	color.Green("~~ TestRunEventStream ~~")
+	var (
+		mu      sync.Mutex
+		resumed []string
+	)
+	// Five events 20ms apart, then the stream ends and is resumed after the event with id 4
+	url := streamServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		fmt.Fprint(w, ": connected\n\nretry: 50\n\n")
+		w.(http.Flusher).Flush()
+		if id := r.Header.Get(HeaderLastEventID); id != "" {
+			mu.Lock()
+			resumed = append(resumed, id)
+			mu.Unlock()
+		}
+		for i := range 5 {
+			time.Sleep(20 * time.Millisecond)
+			if i%2 == 0 {
+				fmt.Fprintf(w, "event: tick\r\nid: %d\r\ndata: {\"n\":%d}\r\n\r\n", i, i)
+			} else {
+				fmt.Fprintf(w, "id: %d\ndata: line one\ndata: line two\n\n", i)
+			}
+			w.(http.Flusher).Flush()
+		}
+	})
+	collector := metrics.NewCollector()
+	session, _ := collector.StartTest(&interfaces.TestConfig{Target: &interfaces.Request{URL: url}})
+
+	result := run(t, url, Options{Collector: collector, Session: session})
+	if result.Connections < 4 || result.ConnectErrors != 0 || result.Completed < 2 || result.Dropped != 0 {
+		t.Errorf("streams %d, errors %d, completed %d, dropped %d", result.Connections, result.ConnectErrors,
+			result.Completed, result.Dropped)
+	}
+	if result.Events < 10 || result.Types["tick"] == 0 || result.Types[DefaultEventType] == 0 ||
+		result.Types["tick"]+result.Types[DefaultEventType] != result.Events {
+		t.Errorf("events %d by type %v", result.Events, result.Types)
+	}
+	if result.FirstEvent.Count != result.Connections || result.FirstEvent.P50 < 15*time.Millisecond ||
+		result.Gap.Count != result.Events-result.Connections || result.Gap.P50 < 15*time.Millisecond {
+		t.Errorf("first event %+v, gap %+v", result.FirstEvent, result.Gap)
+	}
+	mu.Lock()
+	if len(resumed) == 0 || resumed[0] != "4" {
+		t.Errorf("resumed after %v, want event 4", resumed)
+	}
+	mu.Unlock()
+
+	report, err := collector.FinishTest(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for _, stats := range report.AssetStats {
+		counts[stats.Type] += stats.Count
+	}
+	if counts[ResultTypeConnect] != result.Connections || counts[ResultTypeFirstEvent] != result.FirstEvent.Count ||
+		counts[ResultTypeEvent] != result.Gap.Count {
+		t.Errorf("collected %v", counts)
+	}
+}
+
+func TestRunLines(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		opts    Options
+		check   func(*Result) bool
+	}{
+		{
+			name: "long poll",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(30 * time.Millisecond)
+				fmt.Fprint(w, `{"events":[1,2]}`)
+			},
+			check: func(r *Result) bool {
+				return r.Completed >= 10 && r.Events == r.Completed && r.Types[LineEventType] == r.Events &&
+					r.FirstEvent.P50 >= 25*time.Millisecond && r.Gap.Count == 0
+			},
+		},
+		{
+			name: "chunked lines capped",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				for i := 0; r.Context().Err() == nil; i++ {
+					fmt.Fprintf(w, "{\"n\":%d}\n\n", i)
+					w.(http.Flusher).Flush()
+					time.Sleep(10 * time.Millisecond)
+				}
+			},
+			opts: Options{MaxConnection: 100 * time.Millisecond},
+			check: func(r *Result) bool {
+				return r.Capped >= 4 && r.Completed == 0 && r.Dropped == 0 && r.Events >= 40 &&
+					r.EventsPerSecond > 0
+			},
+		},
+		{
+			name: "dropped",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Length", "100")
+				fmt.Fprint(w, "partial\n")
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			},
+			check: func(r *Result) bool {
+				return r.Dropped >= 2 && r.Events == r.Connections && r.LastError != ""
+			},
+		},
+		{
+			name: "error status",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+			},
+			check: func(r *Result) bool {
+				return r.ConnectErrors >= 2 && r.Connections == 0 &&
+					r.LastError == "unexpected status 503 Service Unavailable"
+			},
+		},
+		{
+			name: "header timeout",
+			handler: func(_ http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			},
+			opts: Options{Timeout: 50 * time.Millisecond},
+			check: func(r *Result) bool {
+				return r.ConnectErrors >= 2 && r.Connections == 0 &&
+					r.LastError == "no response headers within 50ms"
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if result := run(t, streamServer(t, tt.handler), tt.opts); !tt.check(result) {
+				t.Errorf("result %+v", result)
+			}
+		})
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"websocket url":  {URL: "ws://localhost:1", Duration: time.Second},
+		"no host":        {URL: "http://", Duration: time.Second},
+		"no duration":    {URL: "http://localhost:1"},
+		"negative limit": {URL: "http://localhost:1", Duration: time.Second, MaxConnection: -time.Second},
+	} {
+		if _, err := Run(context.Background(), opts); err == nil {
+			t.Errorf("Run with %s should fail", name)
+		}
+	}
+	if !isEventStream(http.Header{"Content-Type": {"text/event-stream;charset=UTF-8"}}) ||
+		isEventStream(http.Header{"Content-Type": {"application/x-ndjson"}}) {
+		t.Error("isEventStream should only match text/event-stream")
+	}
+}
@@ -0,0 +1,29 @@
+// Package ws load tests WebSocket endpoints: every virtual user holds a connection,
+// sends scripted messages at a configured rate and matches the replies to measure
+// connect time, round-trip latency, throughput and disconnects.
+package ws
+
+import "time"
+
+const (
+	// SchemeWS is the scheme of plain WebSocket urls
+	SchemeWS = "ws"
+	// SchemeWSS is the scheme of WebSocket urls over TLS
+	SchemeWSS = "wss"
+	// MessageIDVariable is the {{id}} variable holding the id of the message being sent
+	MessageIDVariable = "id"
+	// MessageIDFormat builds message ids from the user and message numbers
+	MessageIDFormat = "%d-%d"
+
+	// ResultTypeConnect marks the connection handshakes recorded in the metrics collector
+	ResultTypeConnect = "connect"
+	// ResultTypeMessage marks the message round trips recorded in the metrics collector
+	ResultTypeMessage = "message"
+	// ErrorNoReply is recorded for a message that got no reply within the timeout
+	ErrorNoReply = "no reply within timeout"
+
+	// DefaultTimeout is how long a connection handshake or a reply may take when Options.Timeout is not set
+	DefaultTimeout = 5 * time.Second
+	// ReconnectDelay is how long a user waits before connecting again after a failed or dropped connection
+	ReconnectDelay = 500 * time.Millisecond
+)
@@ -0,0 +1,429 @@
+package ws
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gnulnx/color"
+	"golang.org/x/net/websocket"
+
+	"github.com/Gosayram/goperf/executor"
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
+	"github.com/Gosayram/goperf/perf"
+)
+
+/*
+Options configures a WebSocket load test.
+
+Structure Overview
+  - URL - the ws:// or wss:// endpoint
+  - Users, Duration - how many connections are held open and for how long
+  - Messages - the script every user sends in order, starting over after the last one.
+    {{id}} is replaced by a unique message id and the other {{name}} references by Variables.
+    Without messages the connections are only held open and server pushes are counted.
+  - Rate - messages per second per user; 0 sends the next message as soon as the previous one is answered
+  - IDField - matches a reply to its message by this top-level JSON field, e.g. "id".
+    When empty a reply is matched to the message it echoes.
+  - Timeout - how long a handshake or a reply may take (DefaultTimeout when 0)
+  - Origin, Header - the Origin and extra headers of the handshake; Origin defaults to the url's host
+  - TLS - the TLS configuration of wss:// connections; nil keeps the defaults
+  - Collector, Session - when set every handshake and round trip is also recorded there
+*/
+type Options struct {
+	URL       string
+	Users     int
+	Duration  time.Duration
+	Messages  []string
+	Rate      float64
+	IDField   string
+	Timeout   time.Duration
+	Origin    string
+	Header    http.Header
+	TLS       *tls.Config
+	Variables perf.Variables
+	Collector interfaces.MetricsCollector
+	Session   *interfaces.TestSession
+}
+
+/*
+Result summarizes a WebSocket load test.
+
+Structure Overview
+  - Connections, ConnectErrors - handshakes that succeeded and failed
+  - Disconnects - connections the server or network closed before the end of the test
+  - Connect, RoundTrip - handshake and message round-trip latencies
+  - Sent, Received - messages sent and received; Matched received messages answered a sent one,
+    the others were pushed by the server
+  - Lost - messages that got no reply within the timeout or before their connection dropped
+  - SentPerSecond, ReceivedPerSecond - message throughput over the test
+*/
+type Result struct {
+	executor.Summary
+	Connections       int             `json:"connections"`
+	ConnectErrors     int             `json:"connectErrors"`
+	Disconnects       int             `json:"disconnects"`
+	Connect           metrics.Latency `json:"connect"`
+	RoundTrip         metrics.Latency `json:"roundTrip"`
+	Sent              int             `json:"sent"`
+	Received          int             `json:"received"`
+	Matched           int             `json:"matched"`
+	Lost              int             `json:"lost"`
+	BytesSent         int             `json:"bytesSent"`
+	BytesReceived     int             `json:"bytesReceived"`
+	SentPerSecond     float64         `json:"sentPerSecond"`
+	ReceivedPerSecond float64         `json:"receivedPerSecond"`
+}
+
+// userStats are the measurements of one virtual user.  The reader goroutine of a
+// connection shares them with the sender, so every field is guarded by mu.
+type userStats struct {
+	mu            sync.Mutex
+	pending       map[string][]time.Time // send times of the unanswered messages by correlation key
+	connect       []time.Duration
+	roundTrips    []time.Duration
+	connections   int
+	connectErrors int
+	disconnects   int
+	sent          int
+	received      int
+	matched       int
+	lost          int
+	bytesSent     int
+	bytesReceived int
+	lastError     string
+}
+
+// runner runs the virtual users of a test
+type runner struct {
+	opts     Options
+	config   *websocket.Config
+	recorder executor.Recorder
+}
+
+// Run holds opts.Users connections for opts.Duration, or until ctx is cancelled, and returns their results
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	config, err := newConfig(&opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Variables == nil {
+		opts.Variables = perf.Variables{}
+	}
+	r := &runner{opts: opts, config: config}
+	r.recorder = executor.Recorder{Collector: opts.Collector, Session: opts.Session}
+
+	users, elapsed, err := executor.Run(ctx, opts.Users, opts.Duration, r.user)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(&opts, users, elapsed), nil
+}
+
+// newConfig checks the url and builds the handshake configuration
+func newConfig(opts *Options) (*websocket.Config, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || (u.Scheme != SchemeWS && u.Scheme != SchemeWSS) || u.Host == "" {
+		return nil, fmt.Errorf("invalid WebSocket url %q (expected ws:// or wss://)", opts.URL)
+	}
+	origin := opts.Origin
+	if origin == "" {
+		origin = "http://" + u.Host
+		if u.Scheme == SchemeWSS {
+			origin = "https://" + u.Host
+		}
+	}
+	config, err := websocket.NewConfig(opts.URL, origin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebSocket origin %q: %w", origin, err)
+	}
+	for name, values := range opts.Header {
+		config.Header[name] = values
+	}
+	config.TlsConfig = opts.TLS
+	return config, nil
+}
+
+// user connects again and again until the test is over
+func (r *runner) user(ctx context.Context, id int, stats *userStats) {
+	stats.pending = map[string][]time.Time{}
+	for ctx.Err() == nil {
+		conn := r.connect(ctx, stats)
+		if conn != nil && r.converse(ctx, conn, id, stats) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(ReconnectDelay):
+		}
+	}
+}
+
+// connect opens a connection, or returns nil when the handshake failed
+func (r *runner) connect(ctx context.Context, stats *userStats) *websocket.Conn {
+	dialCtx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := r.config.DialContext(dialCtx)
+	elapsed := time.Since(start)
+	if err != nil && ctx.Err() != nil {
+		return nil // the test ended during the handshake
+	}
+
+	result := &interfaces.RequestResult{
+		URL: r.opts.URL, Type: ResultTypeConnect, StatusCode: http.StatusSwitchingProtocols,
+		Duration: elapsed, Success: err == nil, Timestamp: start,
+	}
+	stats.mu.Lock()
+	if err != nil {
+		stats.connectErrors++
+		stats.lastError = err.Error()
+		result.StatusCode, result.ErrorMessage = 0, err.Error()
+	} else {
+		stats.connections++
+		stats.connect = append(stats.connect, elapsed)
+	}
+	stats.mu.Unlock()
+	r.recorder.Record(result)
+	return conn
+}
+
+/*
+converse sends the message script on conn until the test ends, when it waits up to the
+timeout for the last replies and returns true, or until the connection drops.
+*/
+func (r *runner) converse(ctx context.Context, conn *websocket.Conn, id int, stats *userStats) bool {
+	replies := make(chan struct{}, 1)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		r.read(conn, stats, replies)
+	}()
+	defer func() {
+		conn.Close()
+		<-closed
+		r.expire(stats, time.Now()) // nothing left can be answered
+	}()
+
+	var tick <-chan time.Time
+	if r.opts.Rate > 0 && len(r.opts.Messages) > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / r.opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for seq := 0; ; seq++ {
+		if len(r.opts.Messages) > 0 {
+			if err := r.send(conn, id, seq, stats); err != nil {
+				r.disconnected(stats, err)
+				return false
+			}
+		}
+		next, answered := tick, (<-chan struct{})(nil)
+		if tick == nil && len(r.opts.Messages) > 0 {
+			// closed loop: the reply, or the timeout, triggers the next message
+			next, answered = time.After(r.opts.Timeout), replies
+		}
+		select {
+		case <-ctx.Done():
+			r.drain(stats, replies, closed)
+			return true
+		case <-closed:
+			r.disconnected(stats, nil)
+			return false
+		case <-answered:
+		case <-next:
+		}
+		r.expire(stats, time.Now().Add(-r.opts.Timeout))
+	}
+}
+
+// send sends message seq of the script
+func (r *runner) send(conn *websocket.Conn, id, seq int, stats *userStats) error {
+	vars := maps.Clone(r.opts.Variables)
+	vars[MessageIDVariable] = fmt.Sprintf(MessageIDFormat, id, seq)
+	text := vars.Expand(r.opts.Messages[seq%len(r.opts.Messages)])
+	key := text
+	if r.opts.IDField != "" {
+		key = vars[MessageIDVariable]
+	}
+
+	stats.mu.Lock()
+	stats.pending[key] = append(stats.pending[key], time.Now())
+	stats.sent++
+	stats.bytesSent += len(text)
+	stats.mu.Unlock()
+	return websocket.Message.Send(conn, text)
+}
+
+// read receives messages until conn is closed and matches them to the pending ones
+func (r *runner) read(conn *websocket.Conn, stats *userStats, replies chan<- struct{}) {
+	for {
+		var text string
+		if err := websocket.Message.Receive(conn, &text); err != nil {
+			return
+		}
+		received := time.Now()
+		key := r.correlationKey(text)
+
+		stats.mu.Lock()
+		stats.received++
+		stats.bytesReceived += len(text)
+		sent, ok := stats.pending[key]
+		var rtt time.Duration
+		if ok {
+			rtt = received.Sub(sent[0])
+			stats.matched++
+			stats.roundTrips = append(stats.roundTrips, rtt)
+			if len(sent) == 1 {
+				delete(stats.pending, key)
+			} else {
+				stats.pending[key] = sent[1:]
+			}
+		}
+		stats.mu.Unlock()
+		if !ok {
+			continue // pushed by the server
+		}
+
+		r.recorder.Record(&interfaces.RequestResult{
+			URL: r.opts.URL, Type: ResultTypeMessage, Duration: rtt, Size: len(text), Success: true,
+			Timestamp: received.Add(-rtt),
+		})
+		select {
+		case replies <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// correlationKey returns the key that matches a reply to its message
+func (r *runner) correlationKey(text string) string {
+	if r.opts.IDField == "" {
+		return text
+	}
+	var fields map[string]any
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if decoder.Decode(&fields) != nil || fields[r.opts.IDField] == nil {
+		return ""
+	}
+	return fmt.Sprint(fields[r.opts.IDField])
+}
+
+// drain waits up to the timeout for the replies to the messages in flight when the test ended
+func (r *runner) drain(stats *userStats, replies <-chan struct{}, closed <-chan struct{}) {
+	deadline := time.After(r.opts.Timeout)
+	for {
+		stats.mu.Lock()
+		waiting := len(stats.pending)
+		stats.mu.Unlock()
+		if waiting == 0 {
+			return
+		}
+		select {
+		case <-replies:
+		case <-closed:
+			return
+		case <-deadline:
+			return
+		}
+	}
+}
+
+// expire counts the messages sent at or before cutoff as lost
+func (r *runner) expire(stats *userStats, cutoff time.Time) {
+	var lost []time.Time
+	stats.mu.Lock()
+	for key, sent := range stats.pending {
+		n := 0
+		for n < len(sent) && !sent[n].After(cutoff) {
+			n++
+		}
+		lost = append(lost, sent[:n]...)
+		if n == len(sent) {
+			delete(stats.pending, key)
+		} else {
+			stats.pending[key] = sent[n:]
+		}
+	}
+	stats.lost += len(lost)
+	stats.mu.Unlock()
+
+	for _, sent := range lost {
+		r.recorder.Record(&interfaces.RequestResult{
+			URL: r.opts.URL, Type: ResultTypeMessage, Duration: time.Since(sent), ErrorMessage: ErrorNoReply,
+			Timestamp: sent,
+		})
+	}
+}
+
+// disconnected counts a connection that dropped before the end of the test
+func (r *runner) disconnected(stats *userStats, err error) {
+	stats.mu.Lock()
+	stats.disconnects++
+	if err != nil {
+		stats.lastError = err.Error()
+	}
+	stats.mu.Unlock()
+}
+
+// newResult merges the measurements of every user of a test that took elapsed
+func newResult(opts *Options, users []userStats, elapsed time.Duration) *Result {
+	result := &Result{Summary: executor.NewSummary(opts.URL, users, elapsed, func(u *userStats) string {
+		return u.lastError
+	})}
+	for i := range users {
+		u := &users[i]
+		result.Connections += u.connections
+		result.ConnectErrors += u.connectErrors
+		result.Disconnects += u.disconnects
+		result.Sent += u.sent
+		result.Received += u.received
+		result.Matched += u.matched
+		result.Lost += u.lost
+		result.BytesSent += u.bytesSent
+		result.BytesReceived += u.bytesReceived
+	}
+	result.Connect = executor.Latency(users, func(u *userStats) []time.Duration { return u.connect })
+	result.RoundTrip = executor.Latency(users, func(u *userStats) []time.Duration { return u.roundTrips })
+	result.SentPerSecond = result.PerSecond(result.Sent)
+	result.ReceivedPerSecond = result.PerSecond(result.Received)
+	return result
+}
+
+// PrintResult prints a WebSocket load test result to stdout
+func PrintResult(result *Result) {
+	yel := color.New(color.FgHiYellow).SprintfFunc()
+	white := color.New(color.FgWhite).SprintfFunc()
+
+	color.Red("WebSocket Results")
+	fmt.Printf(" - %-34s %s\n", yel("Url"), white(result.URL))
+	fmt.Printf(" - %-34s %s\n", yel("Users / Duration"), white("%d / %s", result.Users,
+		result.Duration.Round(time.Millisecond)))
+	fmt.Printf(" - %-34s %s\n", yel("Connections"), white("%d (%d failed, %d dropped)", result.Connections,
+		result.ConnectErrors, result.Disconnects))
+	fmt.Printf(" - %-34s %s\n", yel("Connect (avg / p95 / max)"), white("%s / %s / %s", result.Connect.Avg,
+		result.Connect.P95, result.Connect.Max))
+	fmt.Printf(" - %-34s %s\n", yel("Messages Sent / Received"), white("%d / %d (%d replies, %d lost)", result.Sent,
+		result.Received, result.Matched, result.Lost))
+	fmt.Printf(" - %-34s %s\n", yel("Throughput (sent / received)"), white("%.1f / %.1f messages per second",
+		result.SentPerSecond, result.ReceivedPerSecond))
+	fmt.Printf(" - %-34s %s\n", yel("Round Trip (avg / p50 / p95 / p99)"), white("%s / %s / %s / %s",
+		result.RoundTrip.Avg, result.RoundTrip.P50, result.RoundTrip.P95, result.RoundTrip.P99))
+	fmt.Printf(" - %-34s %s\n", yel("Bytes (sent / received)"), white("%d / %d", result.BytesSent,
+		result.BytesReceived))
+	if result.LastError != "" {
+		fmt.Printf(" - %-34s %s\n", yel("Last Error"), white(result.LastError))
+	}
+}
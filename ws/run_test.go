@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gnulnx/color"
+	"golang.org/x/net/websocket"
+
+	"github.com/Gosayram/goperf/interfaces"
+	"github.com/Gosayram/goperf/metrics"
+)
+
+// wsServer serves handler over WebSocket and returns its ws:// url
+func wsServer(t *testing.T, handler func(*websocket.Conn)) string {
+	t.Helper()
+	server := httptest.NewServer(websocket.Handler(handler))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// echo sends every message back
+func echo(conn *websocket.Conn) {
+	for {
+		var text string
+		if websocket.Message.Receive(conn, &text) != nil {
+			return
+		}
+		if websocket.Message.Send(conn, text) != nil {
+			return
+		}
+	}
+}
+
+func TestRunEcho(t *testing.T) {
+	color.Green("~~ TestRunEcho ~~")
+	url := wsServer(t, echo)
+	collector := metrics.NewCollector()
+	session, _ := collector.StartTest(&interfaces.TestConfig{Target: &interfaces.Request{URL: url}})
+
+	result, err := Run(context.Background(), Options{
+		URL:       url,
+		Users:     3,
+		Duration:  300 * time.Millisecond,
+		Messages:  []string{"hello {{id}}", "bye {{id}} {{name}}"},
+		Variables: map[string]string{"name": "ann"},
+		Collector: collector,
+		Session:   session,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Connections != 3 || result.ConnectErrors != 0 || result.Disconnects != 0 {
+		t.Errorf("connections %d, errors %d, disconnects %d", result.Connections, result.ConnectErrors,
+			result.Disconnects)
+	}
+	// Closed loop: every message but the ones in flight at the end is answered before the next is sent
+	if result.Sent < 30 || result.Matched != result.Sent || result.Received != result.Sent || result.Lost != 0 {
+		t.Errorf("sent %d, received %d, matched %d, lost %d", result.Sent, result.Received, result.Matched,
+			result.Lost)
+	}
+	if result.RoundTrip.Count != result.Matched || result.RoundTrip.P50 <= 0 || result.Connect.Count != 3 {
+		t.Errorf("round trips %+v, connect %+v", result.RoundTrip, result.Connect)
+	}
+	if result.BytesSent != result.BytesReceived || result.ReceivedPerSecond <= 0 {
+		t.Errorf("bytes %d / %d, %.1f received per second", result.BytesSent, result.BytesReceived,
+			result.ReceivedPerSecond)
+	}
+
+	report, err := collector.FinishTest(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.AssetStats) != 2 || report.AssetStats[0].Type != ResultTypeConnect ||
+		report.AssetStats[0].Count != 3 || report.AssetStats[1].Count != result.Matched {
+		t.Errorf("collected %+v %+v", report.AssetStats[0], report.AssetStats[len(report.AssetStats)-1])
+	}
+}
+
+func TestRunCorrelation(t *testing.T) {
+	// Replies come back out of order and the server pushes a greeting and heartbeats
+	url := wsServer(t, func(conn *websocket.Conn) {
+		_ = websocket.Message.Send(conn, "welcome")
+		var held string
+		for {
+			var text string
+			if websocket.Message.Receive(conn, &text) != nil {
+				return
+			}
+			var msg map[string]any
+			if json.Unmarshal([]byte(text), &msg) != nil || msg["op"] == "ignore" {
+				continue
+			}
+			reply, _ := json.Marshal(map[string]any{"id": msg["id"], "ok": true})
+			if held == "" {
+				held = string(reply)
+				continue
+			}
+			_ = websocket.Message.Send(conn, string(reply))
+			_ = websocket.Message.Send(conn, held)
+			_ = websocket.Message.Send(conn, `{"type":"heartbeat"}`)
+			held = ""
+		}
+	})
+
+	get, ignore := `{"id":"{{id}}","op":"get"}`, `{"id":"{{id}}","op":"ignore"}`
+	result, err := Run(context.Background(), Options{
+		URL:      url,
+		Users:    2,
+		Duration: 300 * time.Millisecond,
+		Messages: []string{get, get, ignore},
+		Rate:     100,
+		IDField:  "id",
+		Timeout:  50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 users sending 100 messages per second for 0.3 seconds
+	if result.Sent < 50 || result.Sent > 70 {
+		t.Errorf("sent %d messages at 100 per second, want about 60", result.Sent)
+	}
+	if result.Matched < 20 || result.Lost < result.Sent/3-2 || result.Lost > result.Sent/3+4 {
+		t.Errorf("sent %d, matched %d, lost %d: every third message should be lost", result.Sent,
+			result.Matched, result.Lost)
+	}
+	// Greetings and heartbeats are received but answer nothing
+	if result.Received <= result.Matched {
+		t.Errorf("received %d, matched %d", result.Received, result.Matched)
+	}
+}
+
+func TestRunDisconnects(t *testing.T) {
+	url := wsServer(t, func(conn *websocket.Conn) {
+		var text string
+		_ = websocket.Message.Receive(conn, &text)
+		_ = websocket.Message.Send(conn, text)
+	})
+	result, err := Run(context.Background(), Options{
+		URL:      url,
+		Users:    1,
+		Duration: 1200 * time.Millisecond,
+		Messages: []string{"{{id}}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The server hangs up after one message, the user reconnects after ReconnectDelay
+	if result.Connections < 2 || result.Disconnects < 2 || result.Matched != result.Connections {
+		t.Errorf("connections %d, disconnects %d, matched %d", result.Connections, result.Disconnects,
+			result.Matched)
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	closed := httptest.NewServer(nil)
+	closed.Close()
+	result, err := Run(context.Background(), Options{
+		URL:      "ws" + strings.TrimPrefix(closed.URL, "http"),
+		Users:    1,
+		Duration: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Connections != 0 || result.ConnectErrors == 0 || result.LastError == "" {
+		t.Errorf("connections %d, errors %d (%s)", result.Connections, result.ConnectErrors, result.LastError)
+	}
+
+	for _, opts := range []Options{
+		{URL: "http://localhost/", Duration: time.Second},
+		{URL: "ws://", Duration: time.Second},
+		{URL: "ws://localhost/"},
+	} {
+		if _, err = Run(context.Background(), opts); err == nil {
+			t.Errorf("Run(%+v) should fail", opts)
+		}
+	}
+}